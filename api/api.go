@@ -3,11 +3,14 @@ package api
 import (
 	"context"
 	"downloader_gochat/api/middleware"
+	"downloader_gochat/cloudStorage"
 	"downloader_gochat/configs"
 	_ "downloader_gochat/docs"
 	"downloader_gochat/internal/handler"
 	"downloader_gochat/internal/repository"
+	"downloader_gochat/model"
 	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
 	"errors"
 	"slices"
 	"time"
@@ -26,12 +29,17 @@ import (
 var router *fiber.App
 
 type Handlers struct {
-	UserHandler  *handler.UserHandler
-	WsHandler    *handler.WsHandler
-	NotifHandler *handler.NotificationHandler
-	MediaHandler *handler.MediaHandler
-	AdminHandler *handler.AdminHandler
-	UserRepo     *repository.UserRepository
+	UserHandler             *handler.UserHandler
+	WsHandler               *handler.WsHandler
+	NotifHandler            *handler.NotificationHandler
+	MediaHandler            *handler.MediaHandler
+	AdminHandler            *handler.AdminHandler
+	StorageHandler          *handler.StorageHandler
+	AuditLogHandler         *handler.AuditLogHandler
+	DataExportHandler       *handler.DataExportHandler
+	FollowSuggestionHandler *handler.FollowSuggestionHandler
+	ApiTokenHandler         *handler.ApiTokenHandler
+	UserRepo                *repository.UserRepository
 }
 
 func InitRouter(handlers *Handlers) {
@@ -86,50 +94,133 @@ func InitRouter(handlers *Handlers) {
 		WaitForDelivery: false,
 	}))
 
+	// routes that accept personal api tokens, other routes only accept jwt
+	notificationsScope := middleware.ApiTokenScope(model.ApiTokenScopeReadNotifications)
+	messagesScope := middleware.ApiTokenScope(model.ApiTokenScopeSendMessages)
+	followsScope := middleware.ApiTokenScope(model.ApiTokenScopeManageFollows)
+
 	userRoutes := router.Group("v1/user")
 	{
 		userRoutes.Post("/signup", handlers.UserHandler.RegisterUser)
 		userRoutes.Post("/login", handlers.UserHandler.Login)
+		userRoutes.Post("/login/twoFactor", limiterMiddleware, handlers.UserHandler.LoginTwoFactor)
+		userRoutes.Post("/magicLink", limiterMiddleware, handlers.UserHandler.SendMagicLink)
+		userRoutes.Post("/login/magicLink", limiterMiddleware, handlers.UserHandler.LoginMagicLink)
+		userRoutes.Get("/login/magicLink/:token", limiterMiddleware, handlers.UserHandler.OpenMagicLink)
+		userRoutes.Get("/oidc/providers", handlers.UserHandler.GetOidcProviders)
+		userRoutes.Post("/oidc/login", limiterMiddleware, handlers.UserHandler.LoginOidc)
+		userRoutes.Get("/oidc/callback", limiterMiddleware, handlers.UserHandler.OidcCallback)
+		userRoutes.Get("/oidc/identities", middleware.AuthMiddleware, handlers.UserHandler.GetUserIdentities)
+		userRoutes.Delete("/oidc/identities/:provider", middleware.AuthMiddleware, handlers.UserHandler.UnlinkUserIdentity)
+		userRoutes.Post("/oidc/:provider/start", limiterMiddleware, handlers.UserHandler.StartOidcLogin)
+		userRoutes.Post("/oidc/:provider/link", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.LinkOidcProvider)
+		userRoutes.Post("/pairing/start", limiterMiddleware, handlers.UserHandler.StartDevicePairing)
+		userRoutes.Get("/pairing/wait/:code", handlers.UserHandler.WaitDevicePairing)
+		userRoutes.Post("/pairing/complete", limiterMiddleware, handlers.UserHandler.CompleteDevicePairing)
+		userRoutes.Get("/pairing/:code", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.GetDevicePairing)
+		userRoutes.Put("/pairing/answer/:code/:approve", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.AnswerDevicePairing)
 		userRoutes.Put("/getToken", middleware.IsAuthRefreshToken, handlers.UserHandler.GetToken)
 		userRoutes.Put("/logout", middleware.AuthMiddleware, handlers.UserHandler.LogOut)
 		userRoutes.Put("/setNotifToken/:notifToken", middleware.AuthMiddleware, handlers.UserHandler.SetNotifToken)
-		userRoutes.Post("/follow/:followId", middleware.AuthMiddleware, handlers.UserHandler.FollowUser)
-		userRoutes.Delete("/unfollow/:followId", middleware.AuthMiddleware, handlers.UserHandler.UnFollowUser)
-		userRoutes.Get("/followers/:userId/:skip/:limit", middleware.AuthMiddleware, handlers.UserHandler.GetUserFollowers)
-		userRoutes.Get("/followings/:userId/:skip/:limit", middleware.AuthMiddleware, handlers.UserHandler.GetUserFollowings)
+		userRoutes.Post("/follow/:followId", followsScope, middleware.AuthMiddleware, handlers.UserHandler.FollowUser)
+		userRoutes.Delete("/unfollow/:followId", followsScope, middleware.AuthMiddleware, handlers.UserHandler.UnFollowUser)
+		userRoutes.Get("/followers/:userId/:skip/:limit", followsScope, middleware.AuthMiddleware, handlers.UserHandler.GetUserFollowers)
+		userRoutes.Get("/followings/:userId/:skip/:limit", followsScope, middleware.AuthMiddleware, handlers.UserHandler.GetUserFollowings)
+		userRoutes.Get("/mutualFollowers/:userId/:skip/:limit", followsScope, middleware.AuthMiddleware, handlers.UserHandler.GetMutualFollowers)
+		userRoutes.Put("/privateAccount/:enabled", middleware.AuthMiddleware, handlers.UserHandler.SetPrivateAccount)
+		userRoutes.Get("/followRequests/:skip/:limit", followsScope, middleware.AuthMiddleware, handlers.UserHandler.GetFollowRequests)
+		userRoutes.Put("/followRequests/approve/:requesterId", followsScope, middleware.AuthMiddleware, handlers.UserHandler.ApproveFollowRequest)
+		userRoutes.Delete("/followRequests/decline/:requesterId", followsScope, middleware.AuthMiddleware, handlers.UserHandler.DeclineFollowRequest)
+		userRoutes.Delete("/followRequests/cancel/:followId", followsScope, middleware.AuthMiddleware, handlers.UserHandler.CancelFollowRequest)
+		userRoutes.Get("/search", middleware.AuthMiddleware, handlers.UserHandler.SearchUsers)
+		userRoutes.Get("/followSuggestions/:skip/:limit", followsScope, middleware.AuthMiddleware, handlers.FollowSuggestionHandler.GetFollowSuggestions)
+		userRoutes.Put("/followSuggestions/dismiss/:userId", followsScope, middleware.AuthMiddleware, handlers.FollowSuggestionHandler.DismissFollowSuggestion)
 		userRoutes.Get("/userSettings/:settingName", middleware.AuthMiddleware, handlers.UserHandler.GetUserSettings)
 		userRoutes.Put("/updateUserSettings/:settingName", middleware.AuthMiddleware, handlers.UserHandler.UpdateUserSettings)
 		userRoutes.Put("/updateFavoriteGenres/:genres", middleware.AuthMiddleware, handlers.UserHandler.UpdateUserFavoriteGenres)
 		userRoutes.Get("/activeSessions", middleware.AuthMiddleware, handlers.UserHandler.GetActiveSessions)
+		userRoutes.Get("/devices", middleware.AuthMiddleware, handlers.UserHandler.GetUserDevices)
+		userRoutes.Put("/devices/trust/:deviceKey/:trusted", middleware.AuthMiddleware, handlers.UserHandler.UpdateDeviceTrusted)
+		userRoutes.Get("/loginHistory/:skip/:limit", middleware.AuthMiddleware, handlers.UserHandler.GetLoginHistory)
+		userRoutes.Get("/auditLogs", middleware.AuthMiddleware, handlers.AuditLogHandler.GetUserAuditLogs)
 		userRoutes.Get("/profile", middleware.AuthMiddleware, handlers.UserHandler.GetUserProfile)
 		userRoutes.Get("/roles_and_permissions", middleware.AuthMiddleware, handlers.UserHandler.GetUserRolePermission)
 		userRoutes.Post("/editProfile", middleware.AuthMiddleware, handlers.UserHandler.EditUserProfile)
-		userRoutes.Put("/updatePassword", middleware.AuthMiddleware, handlers.UserHandler.UpdateUserPassword)
+		userRoutes.Post("/changeEmail", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.ChangeEmail)
+		userRoutes.Get("/changeEmail/confirm/:userId/:token", limiterMiddleware, handlers.UserHandler.ConfirmEmailChange)
+		userRoutes.Get("/changeEmail/revert/:userId/:token", limiterMiddleware, handlers.UserHandler.RevertEmailChange)
+		userRoutes.Put("/updatePassword", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.UpdateUserPassword)
+		userRoutes.Post("/forgetPassword", limiterMiddleware, handlers.UserHandler.ForgetPassword)
+		userRoutes.Post("/resetPassword", limiterMiddleware, handlers.UserHandler.ResetPassword)
 		userRoutes.Get("/sendVerifyEmail", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.SendVerifyEmail)
 		userRoutes.Get("/verifyEmail/:userId/:token", limiterMiddleware, handlers.UserHandler.VerifyEmail)
+		userRoutes.Get("/unlockAccount/:userId/:token", limiterMiddleware, handlers.UserHandler.UnlockAccount)
 		userRoutes.Delete("/deleteAccount", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.SendDeleteAccount)
 		userRoutes.Get("/deleteAccount/:userId/:token", limiterMiddleware, handlers.UserHandler.DeleteUserAccount)
+		userRoutes.Post("/dataExport", limiterMiddleware, middleware.AuthMiddleware, handlers.DataExportHandler.RequestDataExport)
+		userRoutes.Get("/dataExport", middleware.AuthMiddleware, handlers.DataExportHandler.GetDataExports)
+		userRoutes.Get("/dataExport/download/:userId/:token", limiterMiddleware, handlers.DataExportHandler.DownloadDataExport)
+		userRoutes.Post("/apiTokens", limiterMiddleware, middleware.AuthMiddleware, handlers.ApiTokenHandler.CreateApiToken)
+		userRoutes.Get("/apiTokens", middleware.AuthMiddleware, handlers.ApiTokenHandler.GetApiTokens)
+		userRoutes.Delete("/apiTokens/:tokenId", middleware.AuthMiddleware, handlers.ApiTokenHandler.RevokeApiToken)
+		userRoutes.Post("/twoFactor/setup", middleware.AuthMiddleware, handlers.UserHandler.SetupTwoFactor)
+		userRoutes.Post("/twoFactor/confirm", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.ConfirmTwoFactor)
+		userRoutes.Post("/twoFactor/disable", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.DisableTwoFactor)
+		userRoutes.Post("/twoFactor/recoveryCodes", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.RegenerateTwoFactorRecoveryCodes)
 		userRoutes.Post("/uploadProfileImage", middleware.AuthMiddleware, handlers.UserHandler.UploadProfileImage)
 		userRoutes.Delete("/removeProfileImage/:fileName", middleware.AuthMiddleware, handlers.UserHandler.RemoveProfileImage)
 		userRoutes.Put("/forceLogout/:deviceId", middleware.AuthMiddleware, handlers.UserHandler.ForceLogoutDevice)
-		userRoutes.Put("/forceLogoutAll", middleware.AuthMiddleware, handlers.UserHandler.ForceLogoutAll)
-		userRoutes.Get("/notifications/:skip/:limit", middleware.AuthMiddleware, handlers.NotifHandler.GetUserNotifications)
+		userRoutes.Put("/forceLogoutAll", limiterMiddleware, middleware.AuthMiddleware, handlers.UserHandler.ForceLogoutAll)
+		userRoutes.Get("/notifications/:skip/:limit", notificationsScope, middleware.AuthMiddleware, handlers.NotifHandler.GetUserNotifications)
 		userRoutes.Put("/notifications/batchUpdateStatus/:id/:entityTypeId/:status", middleware.AuthMiddleware, handlers.NotifHandler.BatchUpdateUserNotificationStatus)
 		userRoutes.Post("/media/upload", middleware.AuthMiddleware, handlers.MediaHandler.UploadFile)
+		userRoutes.Post("/media/presignedUpload", middleware.AuthMiddleware, handlers.MediaHandler.CreatePresignedUpload)
+		userRoutes.Post("/media/confirmUpload", middleware.AuthMiddleware, handlers.MediaHandler.ConfirmUpload)
 	}
 
 	router.Get("/ws/addClient/:deviceId", middleware.AuthMiddleware, handlers.WsHandler.AddClient)
 	router.Get("/ws/singleChat/messages", middleware.AuthMiddleware, handlers.WsHandler.GetSingleChatMessages)
 	router.Get("/ws/singleChat/list", middleware.AuthMiddleware, handlers.WsHandler.GetSingleChatList)
+	router.Post("/ws/singleChat/send", messagesScope, middleware.AuthMiddleware, handlers.WsHandler.SendSingleChatMessage)
 
 	adminRoutes := router.Group("v1/admin")
 	{
 		adminRoutes.Get("/status", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, "admin_get_server_status"), handlers.AdminHandler.GetServerStatus)
+		adminRoutes.Get("/lockedAccounts", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.LoginLocksPermission), handlers.AdminHandler.GetLockedAccounts)
+		adminRoutes.Put("/unlockAccount/:userId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.LoginLocksPermission), handlers.AdminHandler.UnlockAccount)
+		adminRoutes.Get("/roles", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.GetRoles)
+		adminRoutes.Post("/roles", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.CreateRole)
+		adminRoutes.Put("/roles/:roleId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.UpdateRole)
+		adminRoutes.Delete("/roles/:roleId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.DeleteRole)
+		adminRoutes.Put("/roles/:roleId/permissions", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.SetRolePermissions)
+		adminRoutes.Get("/permissions", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.GetPermissions)
+		adminRoutes.Post("/permissions", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.CreatePermission)
+		adminRoutes.Put("/permissions/:permissionId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.UpdatePermission)
+		adminRoutes.Delete("/permissions/:permissionId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.DeletePermission)
+		adminRoutes.Get("/users/:userId/roles", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.GetUserRoles)
+		adminRoutes.Put("/users/:userId/roles/:roleId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.AddUserRole)
+		adminRoutes.Delete("/users/:userId/roles/:roleId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageRolesPermission), handlers.AdminHandler.RemoveUserRole)
+		adminRoutes.Get("/users", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageUsersPermission), handlers.AdminHandler.SearchUsers)
+		adminRoutes.Get("/users/:userId", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageUsersPermission), handlers.AdminHandler.GetUserDetails)
+		adminRoutes.Put("/users/:userId/suspend", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageUsersPermission), handlers.AdminHandler.SuspendUser)
+		adminRoutes.Put("/users/:userId/unsuspend", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageUsersPermission), handlers.AdminHandler.UnsuspendUser)
+		adminRoutes.Put("/users/:userId/forceLogout", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.ManageUsersPermission), handlers.AdminHandler.ForceLogoutUser)
+		adminRoutes.Get("/auditLogs", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.AuditLogsPermission), handlers.AuditLogHandler.SearchAuditLogs)
+		adminRoutes.Get("/auditLogs/export", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.AuditLogsPermission), handlers.AuditLogHandler.ExportAuditLogs)
+		adminRoutes.Get("/storage/orphanMedia", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, model.StoragePermission), handlers.AdminHandler.GetOrphanMediaReport)
+	}
+
+	if handlers.StorageHandler != nil {
+		// only when using local storage instead of s3
+		router.Get(cloudStorage.LocalStorageRoute+"/:bucketName/:fileName", handlers.StorageHandler.GetFile)
+		router.Put(cloudStorage.LocalStorageRoute+"/:bucketName/:fileName", handlers.StorageHandler.PutFile)
 	}
 
 	router.Get("/", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, "admin_get_server_status"), HealthCheck)
 	router.Get("/metrics", middleware.AuthMiddleware, middleware.CheckUserPermission(handlers.UserRepo, "admin_get_server_status"), monitor.New())
 
+	router.Get("/.well-known/jwks.json", Jwks)
+
 	router.Get("/swagger/*", swagger.HandlerDefault) // default
 }
 
@@ -137,6 +228,11 @@ func Start(addr string) error {
 	return router.Listen(addr)
 }
 
+// Shutdown stops accepting new connections and waits for the active requests
+func Shutdown(timeout time.Duration) error {
+	return router.ShutdownWithTimeout(timeout)
+}
+
 func timeoutMiddleware(timeout time.Duration) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 
@@ -181,3 +277,16 @@ func HealthCheck(c *fiber.Ctx) error {
 
 	return nil
 }
+
+// Jwks godoc
+//
+//	@Summary		Json Web Key Set
+//	@Description	Public keys that verify access tokens, match the kid header of the token.
+//	@Description	Keys are published before they sign tokens and removed when retired, cache it for a few minutes.
+//	@Tags			System
+//	@Success		200	{object}	util.Jwks
+//	@Router			/.well-known/jwks.json [get]
+func Jwks(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(util.GetJwks())
+}
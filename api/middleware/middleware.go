@@ -3,6 +3,7 @@ package middleware
 import (
 	"downloader_gochat/internal/repository"
 	services "downloader_gochat/internal/service"
+	"downloader_gochat/model"
 	"downloader_gochat/pkg/response"
 	"downloader_gochat/util"
 	"fmt"
@@ -15,6 +16,11 @@ import (
 )
 
 func AuthMiddleware(c *fiber.Ctx) error {
+	if apiToken, ok := getApiToken(c); ok {
+		// personal api tokens dont have refreshToken
+		return apiTokenAuth(c, apiToken)
+	}
+
 	isBotRequest := c.Get("isbotrequest", "") == "true"
 	if !isBotRequest {
 		isBotRequest = c.Get("isBotRequest", "") == "true"
@@ -35,7 +41,7 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		}
 
 		result, err := services.GetJwtDataCache(refreshToken)
-		if result != "" && err != nil && err.Error() != "redis: nil" {
+		if result != "" && err == nil {
 			return response.ResponseError(c, "Unauthorized, refreshToken is in blacklist", fiber.StatusUnauthorized)
 		}
 
@@ -73,11 +79,69 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		return response.ResponseError(c, "Unauthorized, Conflict for jwt.isBotRequest !== headers.isBotRequest", fiber.StatusUnauthorized)
 	}
 
+	suspension, err := services.GetUserSuspension(claims2.UserId)
+	if err != nil {
+		return response.ResponseError(c, response.ServerError, fiber.StatusInternalServerError)
+	}
+	if suspension != nil {
+		return response.ResponseError(c, suspension.Error(), fiber.StatusForbidden)
+	}
+
 	c.Locals("accessToken", accessToken)
 	c.Locals("jwtUserData", claims2)
 	return c.Next()
 }
 
+// ApiTokenScope lets personal api tokens that have the scope to use the route, it must be placed before AuthMiddleware.
+// routes without it only accept jwt
+func ApiTokenScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("apiTokenScope", scope)
+		return c.Next()
+	}
+}
+
+func getApiToken(c *fiber.Ctx) (string, bool) {
+	strArr := strings.Split(c.Get("Authorization", ""), " ")
+	token := strArr[len(strArr)-1]
+	return token, strings.HasPrefix(token, model.ApiTokenPrefix)
+}
+
+func apiTokenAuth(c *fiber.Ctx, apiToken string) error {
+	ip := c.IP()
+	if ips := c.IPs(); len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	claims, err := services.AuthenticateApiToken(apiToken, ip)
+	if err != nil {
+		return response.ResponseError(c, response.ServerError, fiber.StatusInternalServerError)
+	}
+	if claims == nil {
+		return response.ResponseError(c, "Unauthorized, Invalid/Revoked/Expired api token", fiber.StatusUnauthorized)
+	}
+
+	scope, _ := c.Locals("apiTokenScope").(string)
+	if scope == "" {
+		return response.ResponseError(c, "Forbidden, api tokens cannot be used on this route", fiber.StatusForbidden)
+	}
+	if !slices.Contains(claims.ApiTokenScopes, scope) {
+		message := fmt.Sprintf("Forbidden, api token needs ([%v]) scope", scope)
+		return response.ResponseError(c, message, fiber.StatusForbidden)
+	}
+
+	suspension, err := services.GetUserSuspension(claims.UserId)
+	if err != nil {
+		return response.ResponseError(c, response.ServerError, fiber.StatusInternalServerError)
+	}
+	if suspension != nil {
+		return response.ResponseError(c, suspension.Error(), fiber.StatusForbidden)
+	}
+
+	c.Locals("jwtUserData", claims)
+	return c.Next()
+}
+
 func IsAuthRefreshToken(c *fiber.Ctx) error {
 	refreshToken := c.Cookies("refreshToken", "")
 	if refreshToken == "" {
@@ -92,7 +156,7 @@ func IsAuthRefreshToken(c *fiber.Ctx) error {
 	}
 
 	result, err := services.GetJwtDataCache(refreshToken)
-	if result != "" && err != nil && err.Error() != "redis: nil" {
+	if result != "" && err == nil {
 		return response.ResponseError(c, "Unauthorized, refreshToken is in blacklist", fiber.StatusUnauthorized)
 	}
 
@@ -113,6 +177,12 @@ func CheckUserPermission(userRepo *repository.UserRepository, needPermission str
 	return func(c *fiber.Ctx) error {
 		jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
 
+		rolesUpdatedAt, _ := services.GetUserRolesUpdatedCache(jwtUserData.UserId)
+		if jwtUserData.GeneratedAt < rolesUpdatedAt {
+			// roles of user changed after the token is generated, new token has the new roleIds
+			return response.ResponseError(c, "Unauthorized, roles are changed, get a new token", fiber.StatusUnauthorized)
+		}
+
 		permissions, _ := services.GetRolePermissionsCache(jwtUserData.RoleIds)
 		if permissions == nil || len(permissions) == 0 {
 			permissionsModel, err := userRepo.GetUserPermissionsByRoleIds(jwtUserData.RoleIds)
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"downloader_gochat/configs"
+	"downloader_gochat/db/redis"
+	"downloader_gochat/db/redis/mockredis"
+	"downloader_gochat/internal/repository"
+	services "downloader_gochat/internal/service"
+	"downloader_gochat/model"
+	"downloader_gochat/util"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+// fakeApiTokenRepo authenticates the tokens of the map, other methods of IApiTokenRepository are not implemented
+type fakeApiTokenRepo struct {
+	repository.IApiTokenRepository
+	tokens map[string]*model.ApiTokenAuthData // hash of token: auth data
+}
+
+func (r *fakeApiTokenRepo) UseApiToken(tokenHash string, ip string) (*model.ApiTokenAuthData, error) {
+	authData, ok := r.tokens[tokenHash]
+	if !ok || (authData.ExpiresAt != nil && authData.ExpiresAt.Before(time.Now())) {
+		return nil, nil
+	}
+	result := *authData
+	return &result, nil
+}
+
+func (r *fakeApiTokenRepo) RevokeApiToken(userId int64, tokenId int64) (string, error) {
+	for hash, authData := range r.tokens {
+		if authData.Id == tokenId && authData.UserId == userId {
+			delete(r.tokens, hash)
+			return hash, nil
+		}
+	}
+	return "", nil
+}
+
+const (
+	readToken    = model.ApiTokenPrefix + "read-notifications"
+	sendToken    = model.ApiTokenPrefix + "send-messages"
+	expiredToken = model.ApiTokenPrefix + "expired"
+	bannedToken  = model.ApiTokenPrefix + "banned-user"
+)
+
+func newApiTokenTestApp(t *testing.T) (*fiber.App, *fakeApiTokenRepo, *mockredis.Server) {
+	t.Helper()
+	server, err := mockredis.NewServer()
+	if err != nil {
+		t.Fatalf("could not start mock redis: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+	t.Setenv("REDIS_URL", server.Addr())
+	configs.LoadEnvVariables()
+	redis.ConnectRedis()
+
+	expiredAt := time.Now().Add(-time.Hour)
+	repo := &fakeApiTokenRepo{tokens: map[string]*model.ApiTokenAuthData{
+		util.HashToken(readToken): {Id: 1, UserId: 1, Username: "user1", Scopes: pq.StringArray{model.ApiTokenScopeReadNotifications}},
+		util.HashToken(sendToken): {Id: 2, UserId: 1, Username: "user1", Scopes: pq.StringArray{model.ApiTokenScopeSendMessages}},
+		util.HashToken(expiredToken): {Id: 3, UserId: 1, Username: "user1", Scopes: pq.StringArray{model.ApiTokenScopeReadNotifications},
+			ExpiresAt: &expiredAt},
+		util.HashToken(bannedToken): {Id: 4, UserId: 2, Username: "user2", Scopes: pq.StringArray{model.ApiTokenScopeReadNotifications}},
+	}}
+	services.NewApiTokenService(repo)
+
+	ban, _ := json.Marshal(model.UserSuspension{UserId: 2, Banned: true, Reason: "spam"})
+	server.Set("userSuspension:2", string(ban))
+	// user 1 is not suspended, the db is not read
+	notSuspended, _ := json.Marshal(model.UserSuspension{UserId: 1})
+	server.Set("userSuspension:1", string(notSuspended))
+
+	app := fiber.New()
+	handler := func(c *fiber.Ctx) error {
+		claims := c.Locals("jwtUserData").(*util.MyJwtClaims)
+		return c.SendString(strconv.FormatInt(claims.ApiTokenId, 10))
+	}
+	app.Get("/notifications", ApiTokenScope(model.ApiTokenScopeReadNotifications), AuthMiddleware, handler)
+	app.Post("/messages", ApiTokenScope(model.ApiTokenScopeSendMessages), AuthMiddleware, handler)
+	app.Get("/profile", AuthMiddleware, handler)
+	return app, repo, server
+}
+
+func doApiTokenRequest(t *testing.T, app *fiber.App, method string, path string, token string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%v %v: %v", method, path, err)
+	}
+	return res.StatusCode
+}
+
+func TestApiTokenScope(t *testing.T) {
+	app, _, _ := newApiTokenTestApp(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"token has the scope", "GET", "/notifications", readToken, fiber.StatusOK},
+		{"other scope", "POST", "/messages", sendToken, fiber.StatusOK},
+		{"token doesn't have the scope", "GET", "/notifications", sendToken, fiber.StatusForbidden},
+		{"token doesn't have the other scope", "POST", "/messages", readToken, fiber.StatusForbidden},
+		{"route doesn't accept api tokens", "GET", "/profile", readToken, fiber.StatusForbidden},
+		{"unknown token", "GET", "/notifications", model.ApiTokenPrefix + "unknown", fiber.StatusUnauthorized},
+		{"expired token", "GET", "/notifications", expiredToken, fiber.StatusUnauthorized},
+		{"banned user", "GET", "/notifications", bannedToken, fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := doApiTokenRequest(t, app, tt.method, tt.path, tt.token); got != tt.want {
+				t.Errorf("status = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApiTokenScope_Revoked(t *testing.T) {
+	app, repo, _ := newApiTokenTestApp(t)
+
+	// token is cached after the first request
+	if got := doApiTokenRequest(t, app, "GET", "/notifications", readToken); got != fiber.StatusOK {
+		t.Fatalf("status = %v, want %v", got, fiber.StatusOK)
+	}
+
+	apiTokenService := services.NewApiTokenService(repo)
+	if err := apiTokenService.RevokeApiToken(1, 1, nil); err != nil {
+		t.Fatalf("RevokeApiToken: %v", err)
+	}
+	if got := doApiTokenRequest(t, app, "GET", "/notifications", readToken); got != fiber.StatusUnauthorized {
+		t.Errorf("revoked token: status = %v, want %v", got, fiber.StatusUnauthorized)
+	}
+}
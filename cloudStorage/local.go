@@ -0,0 +1,373 @@
+package cloudStorage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"downloader_gochat/configs"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// LocalStorage is a filesystem implementation of IS3Storage, used for development and offline runs.
+// objects are saved in {LocalStoragePath}/{bucketName}/{fileName} and served by handler.StorageHandler
+type LocalStorage struct {
+	rootPath  string
+	secretKey []byte
+	Configs   *configs.ConfigStruct
+}
+
+const (
+	LocalStorageRoute    = "/storage"
+	localStorageMetaDir  = ".meta"
+	localStorageHashDir  = ".checksum"
+	localStorageDirPerm  = 0755
+	localStorageFilePerm = 0644
+)
+
+var InvalidLocalStorageSignature = errors.New("invalid or expired signature")
+var LocalStorageChecksumMismatch = errors.New("content does not match the signed sha256 checksum")
+
+func StartLocalStorageService() *LocalStorage {
+	config := configs.GetConfigs()
+
+	secretKey := []byte(config.LocalStorageSecret)
+	if len(secretKey) == 0 {
+		// signed urls won't survive restarts, that's fine for local runs
+		secretKey = make([]byte, 32)
+		_, _ = rand.Read(secretKey)
+	}
+
+	return &LocalStorage{
+		rootPath:  config.LocalStoragePath,
+		secretKey: secretKey,
+		Configs:   &config,
+	}
+}
+
+// StartStorageService returns the storage backend selected by CLOUAD_STORAGE_TYPE
+func StartStorageService() IS3Storage {
+	if configs.GetConfigs().CloudStorageType == "local" {
+		return StartLocalStorageService()
+	}
+	return StartS3StorageService()
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (l *LocalStorage) UploadFile(bucketName string, fileName string, file multipart.File) (*s3.PutObjectOutput, error) {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	err := l.SaveFile(bucketName, fileName, "", "", file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (l *LocalStorage) UploadLargeFile(bucketName string, fileName string, file multipart.File) (*manager.UploadOutput, error) {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	err := l.SaveFile(bucketName, fileName, "", "", file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager.UploadOutput{
+		Location: l.fileUrl(bucketName, fileName),
+		Key:      aws.String(fileName),
+	}, nil
+}
+
+func (l *LocalStorage) RemoveFile(bucketName string, fileName string) error {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	filePath, err := l.FilePath(bucketName, fileName)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(filepath.Join(filepath.Dir(filePath), localStorageMetaDir, fileName))
+	_ = os.Remove(filepath.Join(filepath.Dir(filePath), localStorageHashDir, fileName))
+	err = os.Remove(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		// same as s3, removing a missing object is not an error
+		return nil
+	}
+	return err
+}
+
+func (l *LocalStorage) PresignUploadFile(bucketName string, fileName string, contentType string, fileSize int64, checksumSha256 string, expire time.Duration) (*v4.PresignedHTTPRequest, error) {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expire).Unix(), 10)
+	size := strconv.FormatInt(fileSize, 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("contentType", contentType)
+	query.Set("size", size)
+	query.Set("checksum", checksumSha256)
+	query.Set("signature", l.sign(http.MethodPut, bucketName, fileName, expires, contentType, size, checksumSha256))
+
+	signedHeader := http.Header{}
+	signedHeader.Set("Content-Type", contentType)
+	signedHeader.Set("Content-Length", size)
+	if checksumSha256 != "" {
+		// same header as s3, the content is checked against it on upload
+		signedHeader.Set("x-amz-checksum-sha256", checksumSha256)
+	}
+
+	return &v4.PresignedHTTPRequest{
+		URL:          l.fileUrl(bucketName, fileName) + "?" + query.Encode(),
+		Method:       http.MethodPut,
+		SignedHeader: signedHeader,
+	}, nil
+}
+
+func (l *LocalStorage) HeadFile(bucketName string, fileName string) (*s3.HeadObjectOutput, error) {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	filePath, err := l.FilePath(bucketName, fileName)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &types.NotFound{}
+		}
+		return nil, err
+	}
+
+	result := &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(info.Size()),
+		ContentType:   aws.String(l.ContentType(bucketName, fileName)),
+		LastModified:  aws.Time(info.ModTime()),
+	}
+	if checksum, err := os.ReadFile(filepath.Join(filepath.Dir(filePath), localStorageHashDir, fileName)); err == nil {
+		result.ChecksumSHA256 = aws.String(string(checksum))
+	}
+	return result, nil
+}
+
+func (l *LocalStorage) DownloadFile(bucketName string, fileName string, byteRange string) (io.ReadCloser, error) {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	filePath, err := l.FilePath(bucketName, fileName)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &types.NoSuchKey{}
+		}
+		return nil, err
+	}
+	if byteRange == "" {
+		return file, nil
+	}
+
+	var start, end int64
+	_, err = fmt.Sscanf(byteRange, "bytes=%d-%d", &start, &end)
+	if err != nil || start < 0 || end < start {
+		_ = file.Close()
+		return nil, fmt.Errorf("invalid byte range: %v", byteRange)
+	}
+	return &limitedReadCloser{
+		Reader: io.NewSectionReader(file, start, end-start+1),
+		Closer: file,
+	}, nil
+}
+
+func (l *LocalStorage) PresignGetFile(bucketName string, fileName string, expire time.Duration) (string, error) {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expire).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", l.sign(http.MethodGet, bucketName, fileName, expires, "", "", ""))
+
+	return l.fileUrl(bucketName, fileName) + "?" + query.Encode(), nil
+}
+
+func (l *LocalStorage) ListFiles(bucketName string) ([]types.Object, error) {
+	if l.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = l.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	result := make([]types.Object, 0)
+	entries, err := os.ReadDir(filepath.Join(l.rootPath, bucketName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return result, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isValidLocalStorageName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		result = append(result, types.Object{
+			Key:          aws.String(entry.Name()),
+			Size:         aws.Int64(info.Size()),
+			LastModified: aws.Time(info.ModTime()),
+		})
+	}
+
+	return result, nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// SaveFile writes the object into the bucket directory, bucketName must already have the prefix.
+// sha256 of the content is saved like s3 ChecksumSHA256, if checksumSha256 (base64) is set, content must match it
+func (l *LocalStorage) SaveFile(bucketName string, fileName string, contentType string, checksumSha256 string, file io.Reader) error {
+	filePath, err := l.FilePath(bucketName, fileName)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filePath), localStorageDirPerm); err != nil {
+		return err
+	}
+
+	// write to a temp file first, readers never see a partially written object
+	tempFile, err := os.CreateTemp(filepath.Dir(filePath), "."+fileName+".*")
+	if err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tempFile, hasher), file)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	checksum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+	if err == nil && checksumSha256 != "" && checksum != checksumSha256 {
+		err = LocalStorageChecksumMismatch
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+	hashDir := filepath.Join(filepath.Dir(filePath), localStorageHashDir)
+	if err = os.MkdirAll(hashDir, localStorageDirPerm); err != nil {
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(hashDir, fileName), []byte(checksum), localStorageFilePerm); err != nil {
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+	if err = os.Rename(tempFile.Name(), filePath); err != nil {
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+
+	if contentType != "" {
+		metaDir := filepath.Join(filepath.Dir(filePath), localStorageMetaDir)
+		if err = os.MkdirAll(metaDir, localStorageDirPerm); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(metaDir, fileName), []byte(contentType), localStorageFilePerm)
+	}
+	return nil
+}
+
+// FilePath returns the path of the object on disk, bucketName must already have the prefix
+func (l *LocalStorage) FilePath(bucketName string, fileName string) (string, error) {
+	if !isValidLocalStorageName(bucketName) || !isValidLocalStorageName(fileName) {
+		return "", fmt.Errorf("invalid object name: %v/%v", bucketName, fileName)
+	}
+	return filepath.Join(l.rootPath, bucketName, fileName), nil
+}
+
+// ContentType returns the content-type that object uploaded with, falls back to the file extension
+func (l *LocalStorage) ContentType(bucketName string, fileName string) string {
+	metaPath := filepath.Join(l.rootPath, bucketName, localStorageMetaDir, fileName)
+	if contentType, err := os.ReadFile(metaPath); err == nil && len(contentType) > 0 {
+		return string(contentType)
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(fileName)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// IsPrivateBucket reports whether objects of the bucket can only be read with a signed url
+func (l *LocalStorage) IsPrivateBucket(bucketName string) bool {
+	if bucketName == l.Configs.CloudStorageBucketNamePrefix+DataExportBucketName {
+		return true
+	}
+	return l.Configs.PrivateMediaFiles && bucketName == l.Configs.CloudStorageBucketNamePrefix+MediaFileBucketName
+}
+
+// VerifySignature checks a signature generated by PresignUploadFile or PresignGetFile
+func (l *LocalStorage) VerifySignature(method string, bucketName string, fileName string, expires string, contentType string, size string, checksum string, signature string) error {
+	expireTime, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expireTime {
+		return InvalidLocalStorageSignature
+	}
+	expected := l.sign(method, bucketName, fileName, expires, contentType, size, checksum)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return InvalidLocalStorageSignature
+	}
+	return nil
+}
+
+func (l *LocalStorage) sign(method string, bucketName string, fileName string, expires string, contentType string, size string, checksum string) string {
+	mac := hmac.New(sha256.New, l.secretKey)
+	mac.Write([]byte(strings.Join([]string{method, bucketName, fileName, expires, contentType, size, checksum}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *LocalStorage) fileUrl(bucketName string, fileName string) string {
+	return fmt.Sprintf("%v%v/%v/%v", l.Configs.ServerAddress, LocalStorageRoute, bucketName, url.PathEscape(fileName))
+}
+
+func isValidLocalStorageName(name string) bool {
+	return name != "" && name != "." && name != ".." && name != localStorageMetaDir && name != localStorageHashDir &&
+		!strings.ContainsAny(name, "/\\") && !strings.HasPrefix(name, ".")
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -3,18 +3,27 @@ package cloudStorage
 import (
 	"context"
 	"downloader_gochat/configs"
+	"io"
 	"mime/multipart"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type IS3Storage interface {
 	UploadFile(bucketName string, fileName string, file multipart.File) (*s3.PutObjectOutput, error)
 	UploadLargeFile(bucketName string, fileName string, file multipart.File) (*manager.UploadOutput, error)
 	RemoveFile(bucketName string, fileName string) error
+	PresignUploadFile(bucketName string, fileName string, contentType string, fileSize int64, checksumSha256 string, expire time.Duration) (*v4.PresignedHTTPRequest, error)
+	HeadFile(bucketName string, fileName string) (*s3.HeadObjectOutput, error)
+	DownloadFile(bucketName string, fileName string, byteRange string) (io.ReadCloser, error)
+	PresignGetFile(bucketName string, fileName string, expire time.Duration) (string, error)
+	ListFiles(bucketName string) ([]types.Object, error)
 }
 
 type S3Storage struct {
@@ -45,6 +54,7 @@ const (
 	DownloadSubtitleBucketName        = "download-subtitle"
 	CastBucketName                    = "cast"
 	ServerStaticFilesBucketName       = "serverstatic"
+	DataExportBucketName              = "data-export"
 	partMiBs                    int64 = 5
 	publicReadACL                     = "public-read"
 )
@@ -53,6 +63,7 @@ const (
 //------------------------------------------
 
 func (s *S3Storage) UploadFile(bucketName string, fileName string, file multipart.File) (*s3.PutObjectOutput, error) {
+	acl := s.objectACL(bucketName)
 	if s.Configs.CloudStorageBucketNamePrefix != "" {
 		bucketName = s.Configs.CloudStorageBucketNamePrefix + bucketName
 	}
@@ -61,13 +72,14 @@ func (s *S3Storage) UploadFile(bucketName string, fileName string, file multipar
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(fileName),
 		Body:   file,
-		ACL:    publicReadACL,
+		ACL:    acl,
 	})
 
 	return result, err
 }
 
 func (s *S3Storage) UploadLargeFile(bucketName string, fileName string, file multipart.File) (*manager.UploadOutput, error) {
+	acl := s.objectACL(bucketName)
 	if s.Configs.CloudStorageBucketNamePrefix != "" {
 		bucketName = s.Configs.CloudStorageBucketNamePrefix + bucketName
 	}
@@ -79,7 +91,7 @@ func (s *S3Storage) UploadLargeFile(bucketName string, fileName string, file mul
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(fileName),
 		Body:   file,
-		ACL:    publicReadACL,
+		ACL:    acl,
 	})
 
 	return result, err
@@ -97,3 +109,121 @@ func (s *S3Storage) RemoveFile(bucketName string, fileName string) error {
 
 	return err
 }
+
+//------------------------------------------
+//------------------------------------------
+
+// PresignUploadFile returns a PUT request that lets the client upload the object directly,
+// content-type and content-length are part of the signature and cannot be changed by the client
+// PresignUploadFile signs a PUT of the object, checksumSha256 (base64) is optional, when it's set
+// storage rejects the upload if the content doesn't match and HeadFile returns it as ChecksumSHA256
+func (s *S3Storage) PresignUploadFile(bucketName string, fileName string, contentType string, fileSize int64, checksumSha256 string, expire time.Duration) (*v4.PresignedHTTPRequest, error) {
+	acl := s.objectACL(bucketName)
+	if s.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = s.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(bucketName),
+		Key:           aws.String(fileName),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(fileSize),
+		ACL:           acl,
+	}
+	if checksumSha256 != "" {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmSha256
+		input.ChecksumSHA256 = aws.String(checksumSha256)
+	}
+	presignClient := s3.NewPresignClient(s.client, s3.WithPresignExpires(expire))
+	result, err := presignClient.PresignPutObject(context.TODO(), input)
+
+	return result, err
+}
+
+func (s *S3Storage) HeadFile(bucketName string, fileName string) (*s3.HeadObjectOutput, error) {
+	if s.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = s.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	result, err := s.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket:       aws.String(bucketName),
+		Key:          aws.String(fileName),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+
+	return result, err
+}
+
+// DownloadFile returns the object body, byteRange is optional and uses the http Range format (bytes=0-511)
+func (s *S3Storage) DownloadFile(bucketName string, fileName string, byteRange string) (io.ReadCloser, error) {
+	if s.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = s.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileName),
+	}
+	if byteRange != "" {
+		input.Range = aws.String(byteRange)
+	}
+	result, err := s.client.GetObject(context.TODO(), input)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Body, nil
+}
+
+// PresignGetFile returns a temporary url to read the object, used for files that are not public-read
+func (s *S3Storage) PresignGetFile(bucketName string, fileName string, expire time.Duration) (string, error) {
+	if s.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = s.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	presignClient := s3.NewPresignClient(s.client, s3.WithPresignExpires(expire))
+	result, err := presignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+func (s *S3Storage) ListFiles(bucketName string) ([]types.Object, error) {
+	if s.Configs.CloudStorageBucketNamePrefix != "" {
+		bucketName = s.Configs.CloudStorageBucketNamePrefix + bucketName
+	}
+
+	result := make([]types.Object, 0)
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page.Contents...)
+	}
+
+	return result, nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// objectACL keeps chat media files private when PrivateMediaFiles is enabled, data exports are always private,
+// everything else stays public-read
+func (s *S3Storage) objectACL(bucketName string) types.ObjectCannedACL {
+	if bucketName == MediaFileBucketName && s.Configs.PrivateMediaFiles {
+		return ""
+	}
+	if bucketName == DataExportBucketName {
+		return ""
+	}
+	return publicReadACL
+}
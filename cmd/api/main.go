@@ -14,6 +14,9 @@ import (
 	"downloader_gochat/pkg/geoip"
 	"downloader_gochat/rabbitmq"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/getsentry/sentry-go"
@@ -90,7 +93,20 @@ func main() {
 	rabbit := rabbitmq.Start(ctx)
 	defer cancel()
 
-	cloudStorageSvc := cloudStorage.StartS3StorageService()
+	cloudStorageSvc := cloudStorage.StartStorageService()
+	var storageHandler *handler.StorageHandler
+	if localStorageSvc, ok := cloudStorageSvc.(*cloudStorage.LocalStorage); ok {
+		storageHandler = handler.NewStorageHandler(localStorageSvc)
+	}
+
+	jwtKeyRep := repository.NewJwtKeyRepository(dbConn.GetDB())
+	jwtKeySvc := service.NewJwtKeyService(jwtKeyRep)
+	jwtKeySvc.StartJwtKeyReloadJob()
+
+	// created before other services, they record the audit logs
+	auditRep := repository.NewAuditRepository(dbConn.GetDB())
+	auditSvc := service.NewAuditLogService(auditRep)
+	auditLogHandler := handler.NewAuditLogHandler(auditSvc)
 
 	userRep := repository.NewUserRepository(dbConn.GetDB(), mongoDB.GetDB())
 	userSvc := service.NewUserService(userRep, rabbit, cloudStorageSvc)
@@ -104,7 +120,7 @@ func main() {
 	telegramMessageSvc := service.NewTelegramMessageService()
 
 	wsRep := repository.NewWsRepository(dbConn.GetDB(), mongoDB.GetDB())
-	wsSvc := service.NewWsService(wsRep, userRep, rabbit)
+	wsSvc := service.NewWsService(wsRep, userRep, rabbit, cloudStorageSvc)
 	wsHandler := handler.NewWsHandler(wsSvc)
 
 	movieRep := repository.NewMovieRepository(dbConn.GetDB(), mongoDB.GetDB())
@@ -117,18 +133,49 @@ func main() {
 	mediaSvc := service.NewMediaService(mediaRep, userRep, wsRep, rabbit, cloudStorageSvc)
 	mediaHandler := handler.NewMediaHandler(mediaSvc)
 
+	dataExportRep := repository.NewDataExportRepository(dbConn.GetDB())
+	dataExportSvc := service.NewDataExportService(dataExportRep, userRep, movieRep, rabbit, cloudStorageSvc)
+	dataExportHandler := handler.NewDataExportHandler(dataExportSvc)
+
+	followSuggestionRep := repository.NewFollowSuggestionRepository(dbConn.GetDB())
+	followSuggestionSvc := service.NewFollowSuggestionService(followSuggestionRep)
+	followSuggestionHandler := handler.NewFollowSuggestionHandler(followSuggestionSvc)
+
+	apiTokenRep := repository.NewApiTokenRepository(dbConn.GetDB())
+	apiTokenSvc := service.NewApiTokenService(apiTokenRep)
+	apiTokenHandler := handler.NewApiTokenHandler(apiTokenSvc)
+
 	castRep := repository.NewCastRepository(dbConn.GetDB(), mongoDB.GetDB())
 	_ = service.NewBlurHashService(movieRep, castRep, rabbit)
 
 	handlers := &api.Handlers{
-		UserHandler:  userHandler,
-		WsHandler:    wsHandler,
-		NotifHandler: notifHandler,
-		MediaHandler: mediaHandler,
-		AdminHandler: adminHandler,
-		UserRepo:     userRep,
+		UserHandler:             userHandler,
+		WsHandler:               wsHandler,
+		NotifHandler:            notifHandler,
+		MediaHandler:            mediaHandler,
+		AdminHandler:            adminHandler,
+		StorageHandler:          storageHandler,
+		AuditLogHandler:         auditLogHandler,
+		DataExportHandler:       dataExportHandler,
+		FollowSuggestionHandler: followSuggestionHandler,
+		ApiTokenHandler:         apiTokenHandler,
+		UserRepo:                userRep,
 	}
 
 	api.InitRouter(handlers)
-	api.Start("0.0.0.0:" + configs.GetConfigs().Port)
+	go func() {
+		if err := api.Start("0.0.0.0:" + configs.GetConfigs().Port); err != nil {
+			log.Fatalf("could not start the server: %s", err)
+		}
+	}()
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signalCtx.Done()
+
+	if err := api.Shutdown(10 * time.Second); err != nil {
+		log.Printf("error on shutting down the server: %s", err)
+	}
+	// requests are done, save the buffered audit logs
+	auditSvc.Close()
 }
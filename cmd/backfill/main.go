@@ -0,0 +1,52 @@
+package main
+
+import (
+	"downloader_gochat/configs"
+	"downloader_gochat/db"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// fills denormalized data that triggers keep in sync only for new changes,
+// run once after migration. each batch is a short transaction, so it's safe to run while api servers are up.
+
+const usage = `usage:
+  backfill followcounts [-after <userId>] [-batch <size>]`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	configs.LoadEnvVariables()
+	dbConn, err := db.NewDatabase()
+	if err != nil {
+		log.Fatalf("could not initialize database connection: %s", err)
+	}
+
+	switch os.Args[1] {
+	case "followcounts":
+		cmd := flag.NewFlagSet("followcounts", flag.ExitOnError)
+		after := cmd.Int64("after", 0, "continue from users with greater id")
+		batch := cmd.Int64("batch", 1000, "number of user ids in each transaction")
+		_ = cmd.Parse(os.Args[2:])
+		if *batch <= 0 {
+			log.Fatal(usage)
+		}
+
+		for lastUserId := *after; ; lastUserId += *batch {
+			hasMore, err := dbConn.BackfillFollowCounts(lastUserId, *batch)
+			if err != nil {
+				log.Fatalf("could not backfill follow counts after user %v, rerun with -after %v: %s", lastUserId, lastUserId, err)
+			}
+			if !hasMore {
+				break
+			}
+		}
+		fmt.Println("follow counts backfilled")
+	default:
+		log.Fatal(usage)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"downloader_gochat/configs"
+	"downloader_gochat/db"
+	"downloader_gochat/internal/repository"
+	"downloader_gochat/internal/service"
+	"downloader_gochat/util"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// manages the keys that sign access tokens, api servers reload the keys every minute.
+// rotation without downtime:
+//  1. generate a new key, it's published in jwks but doesn't sign yet
+//  2. after other services have refreshed their jwks cache, activate it
+//  3. after the access token lifetime has passed, retire the old key
+
+const usage = `usage:
+  jwtkeys generate [-alg RS256|EdDSA] [-activate]
+  jwtkeys activate -kid <kid>
+  jwtkeys retire -kid <kid>
+  jwtkeys list`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	configs.LoadEnvVariables()
+	dbConn, err := db.NewDatabase()
+	if err != nil {
+		log.Fatalf("could not initialize database connection: %s", err)
+	}
+	jwtKeySvc := service.NewJwtKeyService(repository.NewJwtKeyRepository(dbConn.GetDB()))
+
+	switch os.Args[1] {
+	case "generate":
+		cmd := flag.NewFlagSet("generate", flag.ExitOnError)
+		algorithm := cmd.String("alg", util.JwtAlgorithmEdDSA, "signing algorithm, RS256 or EdDSA")
+		activate := cmd.Bool("activate", false, "sign new tokens with this key immediately")
+		_ = cmd.Parse(os.Args[2:])
+
+		kid, err := jwtKeySvc.GenerateJwtKey(*algorithm, *activate)
+		if err != nil {
+			log.Fatalf("could not generate jwt key: %s", err)
+		}
+		fmt.Printf("generated key %v (%v), activated: %v\n", kid, *algorithm, *activate)
+	case "activate", "retire":
+		cmd := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+		kid := cmd.String("kid", "", "key id")
+		_ = cmd.Parse(os.Args[2:])
+		if *kid == "" {
+			log.Fatal(usage)
+		}
+
+		if os.Args[1] == "activate" {
+			err = jwtKeySvc.ActivateJwtKey(*kid)
+		} else {
+			err = jwtKeySvc.RetireJwtKey(*kid)
+		}
+		if err != nil {
+			log.Fatalf("could not %v jwt key: %s", os.Args[1], err)
+		}
+		fmt.Printf("key %v: %vd\n", *kid, os.Args[1])
+	case "list":
+		keys, err := jwtKeySvc.ListJwtKeys()
+		if err != nil {
+			log.Fatalf("could not list jwt keys: %s", err)
+		}
+		for _, k := range keys {
+			fmt.Printf("%v\t%v\t%v\t%v\n", k.Kid, k.Algorithm, k.Status, k.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+	default:
+		log.Fatal(usage)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"downloader_gochat/pkg/oidc/mockoidc"
+	"flag"
+	"log"
+	"net/http"
+)
+
+// local OpenID Connect issuer for testing provider login, users are approved without a login page.
+// run with: go run ./cmd/mockoidc -email test@example.com
+// the email of each login can be changed with 'login_hint' query param of the authorization url
+
+func main() {
+	addr := flag.String("addr", ":9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer url")
+	clientId := flag.String("client-id", "mock-client", "accepted client id")
+	clientSecret := flag.String("client-secret", "", "accepted client secret, empty accepts any")
+	email := flag.String("email", "mock.user@example.com", "email of logged-in user")
+	emailVerified := flag.Bool("email-verified", true, "value of email_verified claim")
+	flag.Parse()
+
+	server, err := mockoidc.NewServer(*issuer, *clientId, *clientSecret, *email, *emailVerified)
+	if err != nil {
+		log.Fatalf("could not create mock issuer: %s", err)
+	}
+
+	log.Printf("mock oidc issuer %v listening on %v", server.Issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
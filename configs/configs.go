@@ -13,6 +13,7 @@ type ConfigStruct struct {
 	Port                         string
 	DbUrl                        string
 	AccessTokenSecret            string
+	AllowHmacAccessToken         bool
 	RefreshTokenSecret           string
 	MigrateOnStart               bool
 	DefaultProfileImage          string
@@ -31,16 +32,32 @@ type ConfigStruct struct {
 	RabbitMqUrl                  string
 	FirebaseAuthKey              string
 	CorsAllowedOrigins           []string
+	CloudStorageType             string
 	CloudStorageEndpoint         string
 	CloudStorageWebsiteEndpoint  string
 	CloudStorageAccessKey        string
 	CloudStorageSecretAccessKey  string
 	CloudStorageBucketNamePrefix string
+	PrivateMediaFiles            bool
+	LocalStoragePath             string
+	LocalStorageSecret           string
+	OrphanMediaGraceHours        int
+	AccountDeletionGraceDays     int
+	LoginMaxFailedAttempts       int
+	LoginLockoutMinutes          int
 	SentryDns                    string
 	SentryRelease                string
 	PrintErrors                  bool
 	Domain                       string
 	AppDeepLink                  string
+	OidcProviders                []OidcProviderConfig
+}
+
+type OidcProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
 }
 
 var configs = ConfigStruct{}
@@ -69,11 +86,43 @@ func LoadEnvVariables() {
 	configs.MainServerAddress = os.Getenv("MAIN_SERVER_ADDRESS")
 	configs.RabbitMqUrl = os.Getenv("RABBITMQ_URL")
 	configs.FirebaseAuthKey = os.Getenv("FIREBASE_AUTH_KEY")
+	configs.CloudStorageType = os.Getenv("CLOUAD_STORAGE_TYPE")
 	configs.CloudStorageEndpoint = os.Getenv("CLOUAD_STORAGE_ENDPOINT")
 	configs.CloudStorageWebsiteEndpoint = os.Getenv("CLOUAD_STORAGE_WEBSITE_ENDPOINT")
 	configs.CloudStorageAccessKey = os.Getenv("CLOUAD_STORAGE_ACCESS_KEY")
 	configs.CloudStorageSecretAccessKey = os.Getenv("CLOUAD_STORAGE_SECRET_ACCESS_KEY")
 	configs.CloudStorageBucketNamePrefix = os.Getenv("BUCKET_NAME_PREFIX")
+	configs.PrivateMediaFiles = os.Getenv("PRIVATE_MEDIA_FILES") == "true"
+	configs.AllowHmacAccessToken = os.Getenv("ALLOW_HMAC_ACCESS_TOKEN") == "true"
+	configs.LocalStoragePath = os.Getenv("LOCAL_STORAGE_PATH")
+	if configs.LocalStoragePath == "" {
+		configs.LocalStoragePath = "./storage"
+	}
+	configs.LocalStorageSecret = os.Getenv("LOCAL_STORAGE_SECRET")
+	orphanMediaGraceHours, err := strconv.Atoi(os.Getenv("ORPHAN_MEDIA_GRACE_HOURS"))
+	if err != nil || orphanMediaGraceHours < 1 {
+		configs.OrphanMediaGraceHours = 24
+	} else {
+		configs.OrphanMediaGraceHours = orphanMediaGraceHours
+	}
+	accountDeletionGraceDays, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+	if err != nil || accountDeletionGraceDays < 1 {
+		configs.AccountDeletionGraceDays = 30
+	} else {
+		configs.AccountDeletionGraceDays = accountDeletionGraceDays
+	}
+	loginMaxFailedAttempts, err := strconv.Atoi(os.Getenv("LOGIN_MAX_FAILED_ATTEMPTS"))
+	if err != nil || loginMaxFailedAttempts < 1 {
+		configs.LoginMaxFailedAttempts = 10
+	} else {
+		configs.LoginMaxFailedAttempts = loginMaxFailedAttempts
+	}
+	loginLockoutMinutes, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_MINUTES"))
+	if err != nil || loginLockoutMinutes < 1 {
+		configs.LoginLockoutMinutes = 30
+	} else {
+		configs.LoginLockoutMinutes = loginLockoutMinutes
+	}
 	sessionLimit, err := strconv.Atoi(os.Getenv("ACTIVE_SESSIONS_LIMIT"))
 	if err != nil || sessionLimit == 0 {
 		configs.ActiveSessionsLimit = 5
@@ -98,4 +147,29 @@ func LoadEnvVariables() {
 	configs.PrintErrors = os.Getenv("PRINT_ERRORS") == "true"
 	configs.Domain = os.Getenv("DOMAIN")
 	configs.AppDeepLink = os.Getenv("APP_DEEP_LINK")
+	configs.OidcProviders = loadOidcProviders()
+}
+
+// loadOidcProviders reads OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET of each name in OIDC_PROVIDERS
+func loadOidcProviders() []OidcProviderConfig {
+	providers := make([]OidcProviderConfig, 0)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), "---") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		envPrefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OidcProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimSpace(os.Getenv(envPrefix + "ISSUER")),
+			ClientId:     strings.TrimSpace(os.Getenv(envPrefix + "CLIENT_ID")),
+			ClientSecret: strings.TrimSpace(os.Getenv(envPrefix + "CLIENT_SECRET")),
+		}
+		if provider.Issuer == "" || provider.ClientId == "" {
+			log.Printf("Oidc provider %v is missing issuer or client id, ignored", name)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
 }
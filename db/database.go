@@ -12,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Database struct {
@@ -79,9 +80,9 @@ func (d *Database) AutoMigrate() {
 	err = d.db.AutoMigrate(
 		&model.User{},
 		&model.Movie{}, &model.RelatedMovie{},
-		&model.Follow{},
+		&model.Follow{}, &model.FollowRequest{}, &model.FollowSuggestionDismiss{},
 		&model.ProfileImage{},
-		&model.ActiveSession{},
+		&model.ActiveSession{}, &model.TwoFactorRecoveryCode{},
 		&model.ComputedFavoriteGenres{},
 		&model.DownloadLinksSettings{}, &model.MovieSettings{}, &model.NotificationSettings{},
 		&model.Staff{}, &model.Character{},
@@ -91,19 +92,196 @@ func (d *Database) AutoMigrate() {
 		&model.FollowMovie{}, &model.LikeDislikeMovie{}, &model.WatchedMovie{},
 		&model.WatchListGroup{}, &model.WatchListMovie{},
 		&model.UserCollection{}, &model.UserCollectionMovie{},
-		&model.Room{}, &model.Message{}, &model.UserMessageRead{}, &model.MediaFile{},
+		&model.Room{}, &model.Message{}, &model.UserMessageRead{}, &model.MediaFile{}, &model.MediaBlob{},
 		&model.Bot{}, &model.UserBot{},
+		&model.JwtKey{}, &model.UserDevice{}, &model.LoginHistory{}, &model.UserIdentity{}, &model.ApiToken{},
+		&model.AuditLog{}, &model.DataExport{},
 	)
 	if err != nil {
 		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
 		errorHandler.SaveError(errorMessage, err)
 	}
 
-	err = d.db.Model(&model.NotificationEntityType{}).CreateInBatches(model.NotificationEntityTypesAndId, 10).Error
+	// keys that were activated before activatedAt existed
+	err = d.db.Exec(`UPDATE "JwtKey" SET "activatedAt" = "updatedAt" WHERE "activatedAt" IS NULL AND status = 'signing';`).Error
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	// keeps MediaBlob.refCount in sync with MediaFile rows, including the ones removed by cascade
+	err = d.db.Exec(`CREATE OR REPLACE FUNCTION "MediaBlob_refCount"() RETURNS trigger AS $$
+		BEGIN
+			IF (TG_OP = 'INSERT') THEN
+				IF NEW.hash <> '' THEN
+					UPDATE "MediaBlob" SET "refCount" = "refCount" + 1, "updatedAt" = now() at time zone 'utc' WHERE hash = NEW.hash;
+				END IF;
+				RETURN NEW;
+			END IF;
+			IF OLD.hash <> '' THEN
+				UPDATE "MediaBlob" SET "refCount" = "refCount" - 1, "updatedAt" = now() at time zone 'utc' WHERE hash = OLD.hash;
+			END IF;
+			RETURN OLD;
+		END;
+		$$ LANGUAGE plpgsql;`).Error
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	err = d.db.Exec(`CREATE OR REPLACE TRIGGER "MediaFile_MediaBlob_refCount" AFTER INSERT OR DELETE ON "MediaFile"
+		FOR EACH ROW EXECUTE FUNCTION "MediaBlob_refCount"();`).Error
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	// keeps followersCount and followingsCount of User in sync with Follow rows, including the ones removed by cascade.
+	// users pending deletion are not counted, same as the followers/followings lists.
+	// counts of existing users are filled by `go run ./cmd/backfill followcounts`
+	err = d.db.Exec(`CREATE OR REPLACE FUNCTION "Follow_User_counts"() RETURNS trigger AS $$
+		DECLARE
+			f "Follow";
+			delta integer := 1;
+		BEGIN
+			f := NEW;
+			IF (TG_OP = 'DELETE') THEN
+				f := OLD;
+				delta := -1;
+			END IF;
+			UPDATE "User" SET "followingsCount" = greatest("followingsCount" + delta, 0)
+				WHERE "userId" = f."followerId" AND EXISTS(SELECT 1 FROM "User" WHERE "userId" = f."followingId" AND "deletionDate" = 0);
+			UPDATE "User" SET "followersCount" = greatest("followersCount" + delta, 0)
+				WHERE "userId" = f."followingId" AND EXISTS(SELECT 1 FROM "User" WHERE "userId" = f."followerId" AND "deletionDate" = 0);
+			RETURN f;
+		END;
+		$$ LANGUAGE plpgsql;`).Error
+	if err == nil {
+		err = d.db.Exec(`CREATE OR REPLACE TRIGGER "Follow_User_counts" AFTER INSERT OR DELETE ON "Follow"
+			FOR EACH ROW EXECUTE FUNCTION "Follow_User_counts"();`).Error
+	}
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	// follows of a user are removed from/added back to counts of other users when deletion is scheduled/canceled
+	err = d.db.Exec(`CREATE OR REPLACE FUNCTION "User_deletion_followCounts"() RETURNS trigger AS $$
+		DECLARE
+			delta integer := -1;
+		BEGIN
+			IF NEW."deletionDate" = 0 THEN
+				delta := 1;
+			END IF;
+			UPDATE "User" u SET "followersCount" = greatest(u."followersCount" + delta, 0)
+				FROM "Follow" f WHERE f."followerId" = NEW."userId" AND u."userId" = f."followingId";
+			UPDATE "User" u SET "followingsCount" = greatest(u."followingsCount" + delta, 0)
+				FROM "Follow" f WHERE f."followingId" = NEW."userId" AND u."userId" = f."followerId";
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;`).Error
+	if err == nil {
+		err = d.db.Exec(`CREATE OR REPLACE TRIGGER "User_deletion_followCounts" AFTER UPDATE OF "deletionDate" ON "User"
+			FOR EACH ROW WHEN ((OLD."deletionDate" = 0) <> (NEW."deletionDate" = 0))
+			EXECUTE FUNCTION "User_deletion_followCounts"();`).Error
+	}
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	// audit logs are append-only
+	err = d.db.Exec(`CREATE OR REPLACE FUNCTION "AuditLog_appendOnly"() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'AuditLog is append-only';
+		END;
+		$$ LANGUAGE plpgsql;`).Error
+	if err == nil {
+		err = d.db.Exec(`CREATE OR REPLACE TRIGGER "AuditLog_appendOnly" BEFORE UPDATE OR DELETE ON "AuditLog"
+			FOR EACH STATEMENT EXECUTE FUNCTION "AuditLog_appendOnly"();`).Error
+	}
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	// only one export of each user can be in progress
+	err = d.db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS "DataExport_userId_inProgress_key" ON "DataExport" ("userId")
+		WHERE status IN ('pending', 'processing');`).Error
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	// user search, trigram indexes are used for similarity and pattern indexes for short prefixes
+	err = d.db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm;`).Error
+	if err == nil {
+		err = d.db.Exec(`CREATE INDEX IF NOT EXISTS "User_username_trgm_idx" ON "User" USING gin (username gin_trgm_ops);
+			CREATE INDEX IF NOT EXISTS "User_publicName_trgm_idx" ON "User" USING gin ("publicName" gin_trgm_ops);
+			CREATE INDEX IF NOT EXISTS "User_username_pattern_idx" ON "User" (username text_pattern_ops);
+			CREATE INDEX IF NOT EXISTS "User_publicName_pattern_idx" ON "User" (lower("publicName") text_pattern_ops);`).Error
+	}
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on AutoMigrate: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	// skip existing types so new ones get added on databases that are already migrated
+	err = d.db.Model(&model.NotificationEntityType{}).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(model.NotificationEntityTypesAndId, 10).Error
 	if err != nil {
 		errorMessage := fmt.Sprintf("error on Inserting Notification entity types: %v", err)
 		errorHandler.SaveError(errorMessage, err)
 	}
+
+	// roles are created by the main server, main admin gets the permissions of administration api
+	adminPermissions := map[string]string{
+		model.ManageRolesPermission: "manage roles, permissions and roles of users",
+		model.ManageUsersPermission: "search users, suspend, ban and force logout users",
+		model.AuditLogsPermission:   "search and export audit logs of all users",
+		model.LoginLocksPermission:  "list and unlock accounts that are locked because of failed logins",
+		model.StoragePermission:     "view the orphan media report of storage buckets",
+	}
+	for name, description := range adminPermissions {
+		err = d.db.Exec(`INSERT INTO "Permission" (name, description, "createdAt", "updatedAt")
+			VALUES (?, ?, now(), now()) ON CONFLICT (name) DO NOTHING;`,
+			name, description).Error
+		if err == nil {
+			err = d.db.Exec(`INSERT INTO "RoleToPermission" ("roleId", "permissionId")
+				SELECT "Role".id, "Permission".id FROM "Role", "Permission" WHERE "Role".name = ? AND "Permission".name = ?
+				ON CONFLICT DO NOTHING;`,
+				string(model.MainAdminRole), name).Error
+		}
+		if err != nil {
+			errorMessage := fmt.Sprintf("error on Inserting %v permission: %v", name, err)
+			errorHandler.SaveError(errorMessage, err)
+		}
+	}
+}
+
+// BackfillFollowCounts recomputes followersCount and followingsCount of users with id in (afterUserId, afterUserId+batchSize].
+// rows of the batch are locked first, so follows that are added or removed meanwhile wait and are applied after the new counts.
+// returns false when there is no user after the batch
+func (d *Database) BackfillFollowCounts(afterUserId int64, batchSize int64) (bool, error) {
+	hasMore := false
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		lastUserId := afterUserId + batchSize
+		err := tx.Exec(`SELECT 1 FROM "User" WHERE "userId" > ? AND "userId" <= ? ORDER BY "userId" FOR UPDATE;`,
+			afterUserId, lastUserId).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`UPDATE "User" u SET
+			"followersCount" = (SELECT count(*) FROM "Follow" f JOIN "User" fu ON fu."userId" = f."followerId"
+				WHERE f."followingId" = u."userId" AND fu."deletionDate" = 0),
+			"followingsCount" = (SELECT count(*) FROM "Follow" f JOIN "User" fu ON fu."userId" = f."followingId"
+				WHERE f."followerId" = u."userId" AND fu."deletionDate" = 0)
+			WHERE u."userId" > ? AND u."userId" <= ?;`,
+			afterUserId, lastUserId).Error
+		if err != nil {
+			return err
+		}
+		return tx.Raw(`SELECT EXISTS(SELECT 1 FROM "User" WHERE "userId" > ?);`, lastUserId).Scan(&hasMore).Error
+	})
+	return hasMore, err
 }
 
 func (d *Database) Close() {
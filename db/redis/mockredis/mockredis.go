@@ -0,0 +1,371 @@
+package mockredis
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"downloader_gochat/db/redis"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// in-memory redis server for tests, speaks RESP2 and supports the commands that services use.
+// lua is not available, the scripts of db/redis are run by their go equivalent.
+// keys expire by the server clock, tests can move it forward with FastForward
+
+type entry struct {
+	value     string
+	expiresAt time.Time // zero: no expiration
+}
+
+type Server struct {
+	listener net.Listener
+	lock     sync.Mutex
+	data     map[string]entry
+	offset   time.Duration
+	scripts  map[string]func(s *Server, keys []string, args []string) interface{}
+}
+
+var errWrongArgs = errors.New("ERR wrong number of arguments")
+
+// NewServer starts listening on a random local port, the server is closed by Close
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: listener,
+		data:     make(map[string]entry),
+		scripts:  make(map[string]func(s *Server, keys []string, args []string) interface{}),
+	}
+	s.scripts[scriptHash(redis.CompareAndSetLua)] = compareAndSet
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// FastForward moves the clock of the server, keys with passed expiration are removed
+func (s *Server) FastForward(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.offset += d
+}
+
+// Get returns the value of key, false if it doesn't exist or is expired
+func (s *Server) Get(key string) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	e, ok := s.get(key)
+	return e.value, ok
+}
+
+// Set saves the key without expiration
+func (s *Server) Set(key string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data[key] = entry{value: value}
+}
+
+// TTL returns remaining time of key, 0 if it doesn't expire or doesn't exist
+func (s *Server) TTL(key string) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	e, ok := s.get(key)
+	if !ok || e.expiresAt.IsZero() {
+		return 0
+	}
+	return e.expiresAt.Sub(s.now())
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+// get must be called with lock held
+func (s *Server) get(key string) (entry, bool) {
+	e, ok := s.data[key]
+	if ok && !e.expiresAt.IsZero() && !e.expiresAt.After(s.now()) {
+		delete(s.data, key)
+		return entry{}, false
+	}
+	return e, ok
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		writeReply(writer, s.exec(args))
+		if reader.Buffered() == 0 {
+			if err = writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// exec runs the command, the result is written as reply
+func (s *Server) exec(args []string) interface{} {
+	if len(args) == 0 {
+		return errors.New("ERR empty command")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+	switch name {
+	case "PING":
+		return simpleString("PONG")
+	case "CLIENT", "SELECT":
+		return simpleString("OK")
+	case "GET":
+		if len(args) != 1 {
+			return errWrongArgs
+		}
+		if e, ok := s.get(args[0]); ok {
+			return e.value
+		}
+		return nil
+	case "GETDEL":
+		if len(args) != 1 {
+			return errWrongArgs
+		}
+		e, ok := s.get(args[0])
+		if !ok {
+			return nil
+		}
+		delete(s.data, args[0])
+		return e.value
+	case "SET":
+		return s.set(args)
+	case "SETNX":
+		if len(args) != 2 {
+			return errWrongArgs
+		}
+		if _, ok := s.get(args[0]); ok {
+			return int64(0)
+		}
+		s.data[args[0]] = entry{value: args[1]}
+		return int64(1)
+	case "DEL":
+		removed := int64(0)
+		for _, key := range args {
+			if _, ok := s.get(key); ok {
+				delete(s.data, key)
+				removed++
+			}
+		}
+		return removed
+	case "EXISTS":
+		count := int64(0)
+		for _, key := range args {
+			if _, ok := s.get(key); ok {
+				count++
+			}
+		}
+		return count
+	case "EVALSHA", "EVAL":
+		if len(args) < 2 {
+			return errWrongArgs
+		}
+		hash := strings.ToLower(args[0])
+		if name == "EVAL" {
+			hash = scriptHash(args[0])
+		}
+		script, ok := s.scripts[hash]
+		if !ok {
+			if name == "EVALSHA" {
+				return errors.New("NOSCRIPT No matching script")
+			}
+			return errors.New("ERR script is not supported by mockredis")
+		}
+		numKeys, err := strconv.Atoi(args[1])
+		if err != nil || numKeys < 0 || numKeys > len(args)-2 {
+			return errors.New("ERR invalid number of keys")
+		}
+		return script(s, args[2:2+numKeys], args[2+numKeys:])
+	default:
+		// HELLO is rejected too, so clients fall back to RESP2
+		return fmt.Errorf("ERR unknown command '%v'", strings.ToLower(name))
+	}
+}
+
+// set supports EX, PX, NX, XX, KEEPTTL and GET options
+func (s *Server) set(args []string) interface{} {
+	if len(args) < 2 {
+		return errWrongArgs
+	}
+	key, value := args[0], args[1]
+	var expiresAt time.Time
+	nx, xx, keepTtl, get := false, false, false, false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return errors.New("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errors.New("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.ToUpper(args[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			expiresAt = s.now().Add(time.Duration(n) * unit)
+			i++
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTtl = true
+		case "GET":
+			get = true
+		default:
+			return errors.New("ERR syntax error")
+		}
+	}
+
+	prev, exists := s.get(key)
+	var reply interface{} = simpleString("OK")
+	if get {
+		reply = nil
+		if exists {
+			reply = prev.value
+		}
+	}
+	if (nx && exists) || (xx && !exists) {
+		if get {
+			return reply
+		}
+		return nil
+	}
+	if keepTtl && exists {
+		expiresAt = prev.expiresAt
+	}
+	s.data[key] = entry{value: value, expiresAt: expiresAt}
+	return reply
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// compareAndSet is the go equivalent of redis.CompareAndSetLua
+func compareAndSet(s *Server, keys []string, args []string) interface{} {
+	if len(keys) != 1 || len(args) != 2 {
+		return errWrongArgs
+	}
+	e, ok := s.get(keys[0])
+	if !ok || e.value != args[0] {
+		return int64(0)
+	}
+	s.data[keys[0]] = entry{value: args[1], expiresAt: e.expiresAt}
+	return int64(1)
+}
+
+func scriptHash(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+//------------------------------------------
+//------------------------------------------
+
+type simpleString string
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// inline command
+		return strings.Fields(line), nil
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, err = readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, errors.New("expected bulk string")
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(writer *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		writer.WriteString("$-1\r\n")
+	case simpleString:
+		writer.WriteString("+" + string(v) + "\r\n")
+	case error:
+		writer.WriteString("-" + v.Error() + "\r\n")
+	case int64:
+		writer.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case string:
+		writer.WriteString("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+	case []string:
+		writer.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, item := range v {
+			writeReply(writer, item)
+		}
+	}
+}
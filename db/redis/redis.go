@@ -37,3 +37,105 @@ func SetRedis(ctx context.Context, key string, value interface{}, duration time.
 	err := redisClient.Set(ctx, key, value, duration).Err()
 	return err
 }
+
+func GetDelRedis(ctx context.Context, key string) (string, error) {
+	val, err := redisClient.GetDel(ctx, key).Result()
+	return val, err
+}
+
+func DelRedis(ctx context.Context, keys ...string) error {
+	err := redisClient.Del(ctx, keys...).Err()
+	return err
+}
+
+// DelRedisByPrefix removes all the keys starting with prefix, keys are found with SCAN so redis is not blocked
+func DelRedisByPrefix(ctx context.Context, prefix string) error {
+	iter := redisClient.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	keys := make([]string, 0)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return redisClient.Del(ctx, keys...).Err()
+}
+
+// IncrRedis increments the counter, expire is only set when the key is created so the window doesn't slide
+func IncrRedis(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	pipe := redisClient.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	ttl := pipe.TTL(ctx, key)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if ttl.Val() < 0 {
+		err = redisClient.Expire(ctx, key, expire).Err()
+	}
+	return incr.Val(), err
+}
+
+func TTLRedis(ctx context.Context, key string) (time.Duration, error) {
+	val, err := redisClient.TTL(ctx, key).Result()
+	return val, err
+}
+
+func ZAddRedis(ctx context.Context, key string, score float64, member string) error {
+	err := redisClient.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+	return err
+}
+
+func ZRemRedis(ctx context.Context, key string, members ...interface{}) error {
+	err := redisClient.ZRem(ctx, key, members...).Err()
+	return err
+}
+
+// ZRangeByMinScoreRedis removes the members with score lower than minScore and returns the others
+func ZRangeByMinScoreRedis(ctx context.Context, key string, minScore float64) ([]string, error) {
+	pipe := redisClient.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%v", minScore))
+	members := pipe.ZRange(ctx, key, 0, -1)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return members.Val(), nil
+}
+
+// CompareAndSetLua replaces the value only if it's not changed, ttl of the key is kept.
+// it's exported for mockredis, that runs the scripts of this package without lua
+const CompareAndSetLua = `
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		redis.call("SET", KEYS[1], ARGV[2], "KEEPTTL")
+		return 1
+	end
+	return 0`
+
+var compareAndSetScript = redis.NewScript(CompareAndSetLua)
+
+// CompareAndSetRedis sets the key to value only if its current value is oldValue, returns false if it's changed or removed
+func CompareAndSetRedis(ctx context.Context, key string, oldValue string, value string) (bool, error) {
+	val, err := compareAndSetScript.Run(ctx, redisClient, []string{key}, oldValue, value).Int()
+	return val == 1, err
+}
+
+// SetNXRedis sets the key only if it doesn't exist, returns false if it exists
+func SetNXRedis(ctx context.Context, key string, value interface{}, duration time.Duration) (bool, error) {
+	val, err := redisClient.SetNX(ctx, key, value, duration).Result()
+	return val, err
+}
+
+func SAddRedis(ctx context.Context, key string, members ...interface{}) error {
+	err := redisClient.SAdd(ctx, key, members...).Err()
+	return err
+}
+
+// SPopNRedis removes and returns up to count random members of the set
+func SPopNRedis(ctx context.Context, key string, count int64) ([]string, error) {
+	val, err := redisClient.SPopN(ctx, key, count).Result()
+	return val, err
+}
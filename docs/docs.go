@@ -42,18 +42,3806 @@ const docTemplate = `{
                 }
             }
         },
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Public keys that verify access tokens, match the kid header of the token.\nKeys are published before they sign tokens and removed when retired, cache it for a few minutes.",
+                "tags": [
+                    "System"
+                ],
+                "summary": "Json Web Key Set",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/util.Jwks"
+                        }
+                    }
+                }
+            }
+        },
+        "/storage/:bucketName/:fileName": {
+            "get": {
+                "description": "serve files of local storage, private files need the signature from signed url",
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Get File",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "bucket name",
+                        "name": "bucketName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "file name",
+                        "name": "fileName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "signed url expire time",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signed url signature",
+                        "name": "signature",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "upload file to local storage with url from presigned upload, the headers returned with url must be sent",
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Put File",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "bucket name",
+                        "name": "bucketName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "file name",
+                        "name": "fileName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "signed url expire time",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "signed content type",
+                        "name": "contentType",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "signed file size",
+                        "name": "size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "signed base64 sha256 of the file",
+                        "name": "checksum",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signed url signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/auditLogs": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Search security events of all users, newest first.",
+                "tags": [
+                    "Admin-AuditLogs"
+                ],
+                "summary": "Search Audit Logs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "actorId",
+                        "name": "actorId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "targetId",
+                        "name": "targetId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ip",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "from date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "to date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit, max 100",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.AuditLog"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/auditLogs/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the filtered security events as csv or json file, newest first.",
+                "tags": [
+                    "Admin-AuditLogs"
+                ],
+                "summary": "Export Audit Logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default) or json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "actorId",
+                        "name": "actorId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "targetId",
+                        "name": "targetId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ip",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "from date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "to date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit, max 10000",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/lockedAccounts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return accounts that are locked because of failed login attempts, each lock only applies to logins from its network.",
+                "tags": [
+                    "Admin-Users"
+                ],
+                "summary": "Locked Accounts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.LoginLock"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/permissions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return all the permissions.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Permissions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Permission"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new permission, it should be checked in the code to have any effect.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Create Permission",
+                "parameters": [
+                    {
+                        "description": "permission data",
+                        "name": "permission",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Permission"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/permissions/:permissionId": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update name and description of permission.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Update Permission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "permissionId",
+                        "name": "permissionId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "permission data",
+                        "name": "permission",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PermissionReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the permission from all the roles and delete it.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Delete Permission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "permissionId",
+                        "name": "permissionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return all the roles with their permissions.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Roles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.RoleWithPermissions"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new role without permissions, permissions are set with /v1/admin/roles/:roleId/permissions.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Create Role",
+                "parameters": [
+                    {
+                        "description": "role data",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RoleReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Role"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/roles/:roleId": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update data of role. default roles cannot be renamed and main admin role cannot be edited.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Update Role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "roleId",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "role data",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RoleReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the role, users that had the role need a new token to use their other roles.\ndefault roles cannot be removed.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Delete Role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "roleId",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/roles/:roleId/permissions": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the permissions of role, empty list removes all of them. main admin role cannot be edited.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Set Role Permissions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "roleId",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "permission ids",
+                        "name": "permissions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RolePermissionsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
         "/v1/admin/status": {
             "get": {
-                "description": "Return status of server resources and services",
+                "description": "Return status of server resources and services",
+                "tags": [
+                    "Admin-Status"
+                ],
+                "summary": "Server Status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Status"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/storage/orphanMedia": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Dry-run report of files in media-file and profile-image buckets that have no db record.\nRemovable files are older than the grace period and will be removed by the periodic cleanup.",
+                "tags": [
+                    "Admin-Status"
+                ],
+                "summary": "Orphan Media Report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OrphanMediaReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/unlockAccount/:userId": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the locks and failed login attempts of account from all networks.",
+                "tags": [
+                    "Admin-Users"
+                ],
+                "summary": "Unlock Account",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Search users by userId or by start of username or email, results are ordered by userId.",
+                "tags": [
+                    "Admin-Users"
+                ],
+                "summary": "Search Users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "userId, username or email",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.AdminUserDataModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/users/:userId": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return data of user with suspension state, sessions, roles, bots and counts.",
+                "tags": [
+                    "Admin-Users"
+                ],
+                "summary": "User Details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AdminUserDetailsRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/users/:userId/forceLogout": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove all the sessions of user and close the websocket connections.",
+                "tags": [
+                    "Admin-Users"
+                ],
+                "summary": "Force Logout User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/users/:userId/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return roles of the user.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "User Roles",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Role"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/users/:userId/roles/:roleId": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Assign the role to user, user needs a new token (GetToken) to use the permissions of the new role.\nmain admin role cannot be assigned.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Assign Role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "roleId",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke the role from user, tokens of user that are generated before it are rejected on admin routes.\nmain admin role cannot be revoked.",
+                "tags": [
+                    "Admin-Roles"
+                ],
+                "summary": "Revoke Role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "roleId",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/users/:userId/suspend": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Suspend user for a period or ban permanently. suspended user cannot login or use the api and websocket,\nlive websocket connections are closed. ban also logs out all the sessions. main admin cannot be suspended.",
+                "tags": [
+                    "Admin-Users"
+                ],
+                "summary": "Suspend User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "suspension data",
+                        "name": "suspension",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SuspendUserReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserSuspension"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/users/:userId/unsuspend": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lift the suspension or ban of user.",
+                "tags": [
+                    "Admin-Users"
+                ],
+                "summary": "Lift Suspension",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/UpdateUserFavoriteGenres/:genres": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "maximum number of genres is 6, (error code 409).",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Update Favorite Genres",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Array of String joined by '-', Example: action-sci_fi-drama",
+                        "name": "genres",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/activeSessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return users current session and other active sections.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Active Sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ActiveSessionRes"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/apiTokens": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return not revoked api tokens of the user, newest first. expired tokens are included.",
+                "tags": [
+                    "User-ApiToken"
+                ],
+                "summary": "Api Tokens",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ApiToken"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a personal api token for scripts and integrations, it's sent as ` + "`" + `Authorization: Bearer \u003ctoken\u003e` + "`" + ` and doesn't need refreshToken.\ntoken only works on the routes that accept one of its scopes (notifications:read, messages:send, follows:manage).\nthe plain token is returned once and cannot be seen again. each user can have 20 active tokens.\nneeds the password in body, or a fresh two-factor code when two-factor authentication is enabled.\nall tokens are revoked when password changes or all sessions are logged out.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-ApiToken"
+                ],
+                "summary": "Create Api Token",
+                "parameters": [
+                    {
+                        "description": "token body",
+                        "name": "tokenBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateApiTokenReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "totp code or recovery code, required when two-factor is enabled",
+                        "name": "twoFactorCode",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateApiTokenRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/apiTokens/:tokenId": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke the api token, requests with it are rejected immediately",
+                "tags": [
+                    "User-ApiToken"
+                ],
+                "summary": "Revoke Api Token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the token",
+                        "name": "tokenId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/auditLogs": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return security events of the user (logins, token refreshes, password and email changes, admin actions on the account), newest first.",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Audit Logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "from date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "to date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit, max 100",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.AuditLog"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/changeEmail": {
+            "post": {
+                "description": "send a confirmation link to the new email and a notice with a revert link to the current email.\nemail is changed when the confirmation link is opened, the link expires in 1 hour.\nthe revert link expires in 7 days, another change cannot be requested until then.\nmaybe email goes to spam folder.\nlimited to 6 call per minute\nneeds a fresh two-factor code when two-factor authentication is enabled",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Change Email",
+                "parameters": [
+                    {
+                        "description": "new email",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ChangeEmailReq"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "totp code or recovery code, required when two-factor is enabled",
+                        "name": "twoFactorCode",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/changeEmail/confirm/:userId/:token": {
+            "get": {
+                "description": "confirm link created on server and sent to the new email, email of account is replaced with the new one.\nlimited to 6 call per minute",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Confirm Email Change (Internal Usage)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "email change token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/changeEmail/revert/:userId/:token": {
+            "get": {
+                "description": "revert link created on server and sent to the old email, cancels the change or restores the old email.\nall the active sessions get removed and their refreshTokens get blacklisted, user need to login again.\nlimited to 6 call per minute",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Revert Email Change (Internal Usage)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "email revert token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/dataExport": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return the last exports of the user and their status, newest first.",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Data Exports",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.DataExport"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Start exporting all data of the user (profile, settings, sessions, follows, chats, media, notifications, bots and movie interactions).\na zip of json files is created in background and its download link is sent by email, the link expires in 7 days.\nonly one export can be in progress, and it can be requested once per day.\nlimited to 2 call per minute",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Request Data Export",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.DataExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/dataExport/download/:userId/:token": {
+            "get": {
+                "description": "download link of the export that is sent to user by email, redirects to a short-lived url of the zip file.\nlimited to 2 call per minute",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Download Data Export (Internal Usage)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "data export token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/deleteAccount": {
+            "delete": {
+                "description": "remove user account. email the user with a link, if user open it, account gets removed.\naccount can be restored by login until the grace period of deletion ends.\nthe link expires in 10 minutes.\nmaybe email goes to spam folder.\nlimited to 2 call per minute\nneeds a fresh two-factor code when two-factor authentication is enabled",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Delete Account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "totp code or recovery code, required when two-factor is enabled",
+                        "name": "twoFactorCode",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/deleteAccount/:userId/:token": {
+            "get": {
+                "description": "remove account link created on server and send to user by email, if user click this link the account will start to remove.\naccount is deactivated and hidden from other users, login in the grace period (ACCOUNT_DELETION_GRACE_DAYS) restores it, otherwise its data is removed after that.\nlimited to 2 call per minute",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Delete Account (Internal Usage)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "delete account verify token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/devices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return devices that user has logged in with. login from a device that is not in this list\nor from a new location sends an alert email and security notification.\ntrusted devices don't trigger the new location alert.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Known Devices",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.UserDeviceDataModel"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/devices/trust/:deviceKey/:trusted": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Mark device as trusted or untrusted, logins from trusted devices in new locations don't send alert.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Trust Device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "deviceKey from devices list",
+                        "name": "deviceKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "trusted",
+                        "name": "trusted",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/editUserProfile": {
+            "post": {
+                "description": "Edit profile data.\nemail cannot be changed here, use /v1/user/changeEmail",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Edit Profile",
+                "parameters": [
+                    {
+                        "description": "update fields",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.EditProfileReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/follow/:followId": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add followId user to your following list.\nif the user has private account a follow request is sent instead, message of response is 'Follow request sent'.",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Follow User",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id on the user want to follow",
+                        "name": "followId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followRequests/:skip/:limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get incoming follow requests of the user, newest first",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Follow Requests",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.FollowRequestDataModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followRequests/approve/:requesterId": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Accept follow request of requesterId, the requester becomes a follower and gets notified",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Approve Follow Request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the user that sent the request",
+                        "name": "requesterId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followRequests/cancel/:followId": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the follow request that is sent to followId",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Cancel Follow Request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the user that the request is sent to",
+                        "name": "followId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followRequests/decline/:requesterId": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove follow request of requesterId",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Decline Follow Request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the user that sent the request",
+                        "name": "requesterId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followSuggestions/:skip/:limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return people you may know, scored by followings of your followings, common favorite genres and common followed/liked movies.\nsuggestions are computed in background, the first call of a new user may return empty list, try again after a minute.",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Follow Suggestions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.FollowSuggestionDataModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followSuggestions/dismiss/:userId": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove userId from your follow suggestions, it will not be suggested again",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Dismiss Follow Suggestion",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of the suggested user",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followers/:userId/:skip/:limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get user followers, followers of private accounts are only visible to their followers",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Followers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.FollowUserDataModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/followings/:userId/:skip/:limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get user followings, followings of private accounts are only visible to their followers",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Followings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.FollowUserDataModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/forceLogout/:deviceId": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Logout selected device",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Force Logout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "unique id of the device",
+                        "name": "deviceId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/forceLogoutAll": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "force logout all session except current session\nneeds a fresh two-factor code when two-factor authentication is enabled",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Force Logout All",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "totp code or recovery code, required when two-factor is enabled",
+                        "name": "twoFactorCode",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/forgetPassword": {
+            "post": {
+                "description": "send an email with a reset password token, the token expires in 30 minutes and can be used once.\nresponse is the same when account doesn't exist.\nmaybe email goes to spam folder.\nlimited to 6 call per minute, also a new email is not sent for an account in 2 minutes after the last one.",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Forget Password",
+                "parameters": [
+                    {
+                        "description": "account email",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ForgetPasswordReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/getToken": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get new Tokens, also return ` + "`" + `refreshToken` + "`" + `\nrefreshToken is rotated, using an old refreshToken again revokes the session of that device and the user gets notified by email",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Get Token",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "return refreshToken in response body instead of saving in cookie",
+                        "name": "noCookie",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "also return profile images, slower response",
+                        "name": "profileImages",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Device Info",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.DeviceInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserViewModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/login": {
+            "post": {
+                "description": "Login with provided credentials\nif account has two-factor authentication, response has 'twoFactorRequired' and 'challengeToken' without tokens,\nlogin must be completed with /v1/user/login/twoFactor in 5 minutes\nlogin from a new device or location sends an alert, send 'deviceInfo.fingerprint' for better device detection\nfailed logins slow down the next attempts (429), and too many of them lock the account (423) until\nthe unlock link from email is opened. devices with fingerprint that have logged in before can still login.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "return refreshToken in response body instead of saving in cookie",
+                        "name": "noCookie",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "User object",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LoginViewModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserViewModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "423": {
+                        "description": "Locked",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/login/magicLink": {
+            "post": {
+                "description": "login with the token from the magic link deep link or the page of web link, token can be used once.\nbody can be json or form, the page of web link sends it as form.\nfingerprint is required if the link is requested with 'deviceInfo.fingerprint'.\nif account has two-factor authentication, response has 'twoFactorRequired' and 'challengeToken' like /v1/user/login.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Login Magic Link",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "return refreshToken in response body instead of saving in cookie",
+                        "name": "noCookie",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "token and fingerprint",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MagicLinkLoginReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserViewModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "423": {
+                        "description": "Locked",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/login/magicLink/:token": {
+            "get": {
+                "description": "web link of the magic link email, returns a page that asks the user to confirm the login, token is not used here.\nconfirming sends the token to /v1/user/login/magicLink and saves refreshToken in cookie, the page also has the app deep link if configured.\nlinks requested with 'deviceInfo.fingerprint' can't be used from the page.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Open Magic Link (Internal Usage)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "magic link token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "html page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/login/twoFactor": {
+            "post": {
+                "description": "second step of login for accounts with two-factor authentication.\nchallengeToken is returned from /v1/user/login, it can be tried 5 times in 5 minutes.\ncode is the 6 digit code from authenticator app or one of the recovery codes.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Login Two-Factor",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "return refreshToken in response body instead of saving in cookie",
+                        "name": "noCookie",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "challenge token and code",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactorLoginReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserViewModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "423": {
+                        "description": "Locked",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/loginHistory/:skip/:limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return logins of user, newest first. newDevice and newLocation show logins that sent alert.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Login History",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.LoginHistoryDataModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/logout": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Logout user, return accessToken as empty string and also reset/remove refreshToken cookie if use in browser\nin other environments reset refreshToken from client after successful logout.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/magicLink": {
+            "post": {
+                "description": "send an email with a single-use sign-in link that expires in 10 minutes, response is the same when account doesn't exist.\nthe email has a web link and a deep link for the app (if configured), the session is created for the given deviceInfo.\nif 'deviceInfo.fingerprint' is provided, only the same device can use the link with /v1/user/login/magicLink.\nlimited to 6 call per minute, also a new email is not sent for an account in 1 minute after the last one.",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Send Magic Link",
+                "parameters": [
+                    {
+                        "description": "account email and device info",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MagicLinkReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/media/confirmUpload": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "verify the file uploaded with presigned url and share it in chat",
+                "tags": [
+                    "User-Chat"
+                ],
+                "summary": "Confirm Upload",
+                "parameters": [
+                    {
+                        "description": "upload data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ConfirmUploadReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MediaFile"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/media/presignedUpload": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get a presigned url to upload media file directly to storage, call confirmUpload after uploading.\nthe returned headers must be sent with the upload request, they include the sha256 checksum of the file and storage rejects content that doesn't match it.",
+                "tags": [
+                    "User-Chat"
+                ],
+                "summary": "Presigned Upload",
+                "parameters": [
+                    {
+                        "description": "file data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PresignedUploadReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PresignedUploadRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/media/upload": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "upload and share media files in chats",
+                "tags": [
+                    "User-Chat"
+                ],
+                "summary": "Upload File",
+                "parameters": [
+                    {
+                        "description": "upload file data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UploadMediaReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MediaFile"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/mutualFollowers/:userId/:skip/:limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get your followings that follow the user, they are only visible to followers of private accounts",
+                "tags": [
+                    "User-Follow"
+                ],
+                "summary": "Mutual Followers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.FollowUserDataModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/notifications/:skip/:limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get user followers/followings events",
+                "tags": [
+                    "User-Notifications"
+                ],
+                "summary": "Follow events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "entityTypeId, ignore filter when value is 0",
+                        "name": "entityTypeId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "status, ignore filter when value is 0, 1 means saved, 2 means seen",
+                        "name": "status",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "autoUpdateStatus",
+                        "name": "autoUpdateStatus",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.NotificationDataModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/notifications/batchUpdateStatus/:id/:entityTypeId/:status": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "update the status of notifications",
+                "tags": [
+                    "User-Notifications"
+                ],
+                "summary": "Notification Status update",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "notificationId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "type of notification, ignore filter when value is 0",
+                        "name": "entityTypeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "new value of status, 1 means saved, 2 means seen",
+                        "name": "status",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/oidc/:provider/link": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "like /v1/user/oidc/:provider/start but the provider account is linked to the logged-in user instead of login.\naccount email doesn't need to match the provider email. a user can have one linked account of each provider.\nthe callback (or /v1/user/oidc/login) must be sent with refreshToken of the same session, otherwise it's rejected with 403.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Link Provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "device info and redirect uri",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OidcStartReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OidcStartRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/oidc/:provider/start": {
+            "post": {
+                "description": "returns the url of provider login page, open it in browser. 'state' is valid for 10 minutes.\nby default provider redirects to /v1/user/oidc/callback which creates the session and saves refreshToken in cookie.\napps can set 'redirectUri' to the app deeplink (or an allowed origin) and send the code and state to /v1/user/oidc/login.\nlimited to 6 call per minute",
                 "tags": [
-                    "Admin-Status"
+                    "User-Auth"
+                ],
+                "summary": "Start Provider Login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "device info and redirect uri",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OidcStartReq"
+                        }
+                    }
                 ],
-                "summary": "Server Status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Status"
+                            "$ref": "#/definitions/model.OidcStartRes"
                         }
                     },
                     "400": {
@@ -61,28 +3849,42 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
                     }
                 }
             }
         },
-        "/v1/user/UpdateUserFavoriteGenres/:genres": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "maximum number of genres is 6, (error code 409).",
+        "/v1/user/oidc/callback": {
+            "get": {
+                "description": "default redirect uri of providers, login and save refreshToken in cookie.\nlimited to 6 call per minute",
                 "tags": [
-                    "User"
+                    "User-Auth"
                 ],
-                "summary": "Update Favorite Genres",
+                "summary": "Provider Callback (Internal Usage)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Array of String joined by '-', Example: action-sci_fi-drama",
-                        "name": "genres",
-                        "in": "path",
+                        "description": "authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "state",
+                        "name": "state",
+                        "in": "query",
                         "required": true
                     }
                 ],
@@ -90,7 +3892,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
+                            "$ref": "#/definitions/model.UserViewModel"
                         }
                     },
                     "400": {
@@ -99,8 +3901,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -111,6 +3919,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -120,27 +3934,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/activeSessions": {
+        "/v1/user/oidc/identities": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Return users current session and other active sections.",
+                "description": "Return login providers that are linked to the account",
                 "tags": [
                     "User-Auth"
                 ],
-                "summary": "Active Sessions",
+                "summary": "Linked Providers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.ActiveSessionRes"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.UserIdentityDataModel"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -154,13 +3971,27 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/deleteAccount": {
+        "/v1/user/oidc/identities/:provider": {
             "delete": {
-                "description": "remove user account. email the user with a link, if user open it, account gets removed.\nthe link expires in 10 minutes.\nmaybe email goes to spam folder.\nlimited to 2 call per minute",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove linked login provider, account can still login with password or magic link",
                 "tags": [
-                    "User"
+                    "User-Auth"
+                ],
+                "summary": "Unlink Provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Delete Account",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -168,8 +3999,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseOKModel"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -189,25 +4020,167 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/deleteAccount/:userId/:token": {
+        "/v1/user/oidc/login": {
+            "post": {
+                "description": "login with the code and state that provider sent to 'redirectUri', state can be used once.\non first login, account with the same verified email is linked, or a new account is created.\nif account has two-factor authentication, response has 'twoFactorRequired' and 'challengeToken' like /v1/user/login.\nif login is started by /v1/user/oidc/:provider/link, the provider is linked and response has no data.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Login Provider",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "return refreshToken in response body instead of saving in cookie",
+                        "name": "noCookie",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "code and state",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OidcLoginReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserViewModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/oidc/providers": {
             "get": {
-                "description": "remove account link created on server and send to user by email, if user click this link the account will start to remove.\nlimited to 2 call per minute",
+                "description": "names of OpenID Connect providers that can be used for login",
                 "tags": [
-                    "User"
+                    "User-Auth"
                 ],
-                "summary": "Delete Account (Internal Usage)",
+                "summary": "Login Providers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OidcProvidersRes"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/pairing/:code": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return the device that requested the pairing code (model, os, location), show it before approving.\nalso available with websocket action 'device-pairing-info'.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Device Pairing Info",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "userId",
-                        "name": "userId",
+                        "type": "string",
+                        "description": "pairing code",
+                        "name": "code",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.DevicePairingInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/pairing/answer/:code/:approve": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Approve or reject the pairing code, on approve the new device is logged-in to this account.\nalso available with websocket action 'device-pairing'.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Approve Device Pairing",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "delete account verify token",
-                        "name": "token",
+                        "description": "pairing code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "approve or reject",
+                        "name": "approve",
                         "in": "path",
                         "required": true
                     }
@@ -216,11 +4189,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
+                            "$ref": "#/definitions/model.DevicePairingInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -231,6 +4210,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -240,21 +4225,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/editUserProfile": {
+        "/v1/user/pairing/complete": {
             "post": {
-                "description": "Edit profile data.",
+                "description": "create session of the new device after the pairing is approved, can be called once.\nlimited to 6 call per minute",
                 "tags": [
-                    "User"
+                    "User-Auth"
                 ],
-                "summary": "Edit Profile",
+                "summary": "Complete Device Pairing",
                 "parameters": [
                     {
-                        "description": "update fields",
+                        "type": "boolean",
+                        "description": "return refreshToken in response body instead of saving in cookie",
+                        "name": "noCookie",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "pairing code and token",
                         "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.EditProfileReq"
+                            "$ref": "#/definitions/model.DevicePairingCompleteReq"
                         }
                     }
                 ],
@@ -262,7 +4254,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
+                            "$ref": "#/definitions/model.UserViewModel"
                         }
                     },
                     "400": {
@@ -277,6 +4269,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -298,32 +4296,74 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/follow/:followId": {
+        "/v1/user/pairing/start": {
             "post": {
-                "security": [
+                "description": "login on a new device (tv, desktop) by approving it from a logged-in device. returns a pairing code that expires in 5 minutes.\nshow 'qrData' as qr code (and the code for typing), then wait for approval with /v1/user/pairing/wait/:code.\nkeep 'token' secret, it's needed to wait and to receive the session.\nlimited to 6 call per minute",
+                "tags": [
+                    "User-Auth"
+                ],
+                "summary": "Start Device Pairing",
+                "parameters": [
                     {
-                        "BearerAuth": []
+                        "description": "info of the new device",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.DevicePairingStartReq"
+                        }
                     }
                 ],
-                "description": "Add followId user to your following list",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.DevicePairingStartRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/pairing/wait/:code": {
+            "get": {
+                "description": "server-sent events stream for the new device, sends 'devicePairing' event with model.DevicePairingInfo when status changes.\nstream ends when status is 'approved' (call /v1/user/pairing/complete), 'rejected' or 'expired'.",
                 "tags": [
-                    "User-Follow"
+                    "User-Auth"
                 ],
-                "summary": "Follow User",
+                "summary": "Wait Device Pairing",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "id on the user want to follow",
-                        "name": "followId",
+                        "type": "string",
+                        "description": "pairing code",
+                        "name": "code",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "pairing token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
+                            "$ref": "#/definitions/model.DevicePairingInfo"
                         }
                     },
                     "400": {
@@ -332,14 +4372,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -353,37 +4393,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/followers/:userId/:skip/:limit": {
-            "get": {
+        "/v1/user/privateAccount/:enabled": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "get user followers",
+                "description": "Enable or disable private account. followers, followings and profile details of private accounts are hidden from non-followers\nand new follows become follow requests. pending requests are accepted when private account is disabled.",
                 "tags": [
                     "User-Follow"
                 ],
-                "summary": "Followers",
+                "summary": "Private Account",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "id of user",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "skip",
-                        "name": "skip",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "limit",
-                        "name": "limit",
+                        "type": "boolean",
+                        "description": "enable private account",
+                        "name": "enabled",
                         "in": "path",
                         "required": true
                     }
@@ -392,7 +4418,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.FollowUserDataModel"
+                            "$ref": "#/definitions/response.ResponseOKModel"
                         }
                     },
                     "400": {
@@ -401,6 +4427,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -410,50 +4442,83 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/followings/:userId/:skip/:limit": {
+        "/v1/user/profile": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "get user followings",
+                "description": "Return users profile data. if dont provide userId, return current user profile\nprofile details (bio, genres, mbti, lastSeen, followers counts, ...) of private accounts are hidden from non-followers\nprofile of other users has followsYou and a preview of mutual followers (your followings that follow the user)",
                 "tags": [
-                    "User-Follow"
+                    "User"
                 ],
-                "summary": "Followings",
+                "summary": "Profile Data",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of user",
+                        "description": "userId",
                         "name": "userId",
-                        "in": "path",
-                        "required": true
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "skip",
-                        "name": "skip",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "loadSettings",
+                        "name": "loadSettings",
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "limit",
-                        "name": "limit",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "loadFollowersCount",
+                        "name": "loadFollowersCount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "loadDevice",
+                        "name": "loadDevice",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "loadProfileImages",
+                        "name": "loadProfileImages",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "loadComputedFavoriteGenres",
+                        "name": "loadComputedFavoriteGenres",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "loadRoles",
+                        "name": "loadRoles",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "loadRolesWithPermissions",
+                        "name": "loadRolesWithPermissions",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "loadTorrentUsage",
+                        "name": "loadTorrentUsage",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.FollowUserDataModel"
+                            "$ref": "#/definitions/model.UserProfileRes"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -467,23 +4532,18 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/forceLogout/:deviceId": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Logout selected device",
+        "/v1/user/removeProfileImage/:fileName": {
+            "delete": {
+                "description": "Returns new profileImages array",
                 "tags": [
-                    "User-Auth"
+                    "User"
                 ],
-                "summary": "Force Logout",
+                "summary": "Remove Profile Image",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "unique id of the device",
-                        "name": "deviceId",
+                        "description": "file name of profile image",
+                        "name": "fileName",
                         "in": "path",
                         "required": true
                     }
@@ -492,7 +4552,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ProfileImageDataModel"
+                            }
                         }
                     },
                     "400": {
@@ -501,18 +4564,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
-                        }
-                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -528,39 +4579,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/forceLogoutAll": {
-            "put": {
-                "security": [
+        "/v1/user/resetPassword": {
+            "post": {
+                "description": "set new password with the token from reset password email.\nall the active sessions get removed and their refreshTokens get blacklisted, user need to login again.\nlimited to 6 call per minute",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Reset Password",
+                "parameters": [
                     {
-                        "BearerAuth": []
+                        "description": "token and new password",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ResetPasswordReq"
+                        }
                     }
                 ],
-                "description": "force logout all session except current session",
-                "tags": [
-                    "User-Auth"
-                ],
-                "summary": "Force Logout All",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
+                            "$ref": "#/definitions/response.ResponseOKModel"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -580,56 +4625,53 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/getToken": {
-            "put": {
+        "/v1/user/roles_and_permissions": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get new Tokens, also return ` + "`" + `refreshToken` + "`" + `",
+                "description": "Return role and permission of user",
                 "tags": [
-                    "User-Auth"
+                    "User"
                 ],
-                "summary": "Get Token",
+                "summary": "Role Data",
                 "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "return refreshToken in response body instead of saving in cookie",
-                        "name": "noCookie",
+                        "type": "integer",
+                        "description": "userId",
+                        "name": "userId",
                         "in": "query"
                     },
                     {
                         "type": "boolean",
-                        "description": "also return profile images, slower response",
-                        "name": "profileImages",
+                        "description": "loadRoles",
+                        "name": "loadRoles",
                         "in": "query"
                     },
                     {
-                        "description": "Device Info",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.DeviceInfo"
-                        }
+                        "type": "boolean",
+                        "description": "loadRolesWithPermissions",
+                        "name": "loadRolesWithPermissions",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserViewModel"
+                            "$ref": "#/definitions/model.UserRolePermissionRes"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -637,36 +4679,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/login": {
-            "post": {
-                "description": "Login with provided credentials",
+        "/v1/user/search": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Search users by start of username and publicName, queries with 3 or more characters also match similar names.\nmutual follows come first, then followings, then prefix matches, then the most similar ones.\nbanned and suspended users are not returned.",
                 "tags": [
-                    "User-Auth"
+                    "User-Follow"
                 ],
-                "summary": "Login user",
+                "summary": "Search Users",
                 "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "return refreshToken in response body instead of saving in cookie",
-                        "name": "noCookie",
+                        "type": "string",
+                        "description": "username or publicName",
+                        "name": "query",
                         "in": "query",
                         "required": true
                     },
                     {
-                        "description": "User object",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.LoginViewModel"
-                        }
+                        "type": "integer",
+                        "description": "skip",
+                        "name": "skip",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserViewModel"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.UserSearchDataModel"
+                            }
                         }
                     },
                     "400": {
@@ -674,25 +4729,6 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
-                    }
-                }
-            }
-        },
-        "/v1/user/logout": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Logout user, return accessToken as empty string and also reset/remove refreshToken cookie if use in browser\nin other environments reset refreshToken from client after successful logout.",
-                "tags": [
-                    "User-Auth"
-                ],
-                "summary": "Logout",
-                "responses": {
-                    "200": {
-                        "description": "OK"
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -700,8 +4736,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -709,50 +4745,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/media/upload": {
-            "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "upload and share media files in chats",
+        "/v1/user/sendVerifyEmail": {
+            "get": {
+                "description": "send an email with an activation link. the link will expire after 6 hour.\nmaybe email goes to spam folder.\nlimited to 2 call per minute",
                 "tags": [
-                    "User-Chat"
-                ],
-                "summary": "Upload File",
-                "parameters": [
-                    {
-                        "description": "upload file data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.UploadMediaReq"
-                        }
-                    }
+                    "User"
                 ],
+                "summary": "Send Verify Email",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.MediaFile"
+                            "$ref": "#/definitions/response.ResponseOKModel"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -760,69 +4780,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/notifications/:skip/:limit": {
-            "get": {
+        "/v1/user/setNotifToken": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "get user followers/followings events",
+                "description": "send device token as Notification token",
                 "tags": [
                     "User-Notifications"
                 ],
-                "summary": "Follow events",
+                "summary": "Notification Token",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "skip",
-                        "name": "skip",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "limit",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "notifToken",
+                        "name": "notifToken",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "entityTypeId, ignore filter when value is 0",
-                        "name": "entityTypeId",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "status, ignore filter when value is 0, 1 means saved, 2 means seen",
-                        "name": "status",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "autoUpdateStatus",
-                        "name": "autoUpdateStatus",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.NotificationDataModel"
-                        }
+                        "description": "OK"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -836,62 +4826,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/notifications/batchUpdateStatus/:id/:entityTypeId/:status": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "update the status of notifications",
+        "/v1/user/signup": {
+            "post": {
+                "description": "Register a new user with the provided credentials\nDevice detection can be improved on client side with adding 'deviceInfo.fingerprint'",
                 "tags": [
-                    "User-Notifications"
+                    "User-Auth"
                 ],
-                "summary": "Notification Status update",
+                "summary": "Register a new user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "notificationId",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "type of notification, ignore filter when value is 0",
-                        "name": "entityTypeId",
-                        "in": "path",
+                        "type": "boolean",
+                        "description": "return refreshToken in response body instead of saving in cookie",
+                        "name": "noCookie",
+                        "in": "query",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "new value of status, 1 means saved, 2 means seen",
-                        "name": "status",
-                        "in": "path",
-                        "required": true
+                        "description": "User object",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RegisterViewModel"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
+                            "$ref": "#/definitions/model.UserViewModel"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -899,79 +4867,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/profile": {
-            "get": {
+        "/v1/user/twoFactor/confirm": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Return users profile data. if dont provide userId, return current user profile",
+                "description": "enable two-factor authentication with a code from authenticator app.\nreturns one-time recovery codes, they are only shown once.\nlimited to 6 call per minute",
                 "tags": [
-                    "User"
+                    "User-TwoFactor"
                 ],
-                "summary": "Profile Data",
+                "summary": "Confirm Two-Factor",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "userId",
-                        "name": "userId",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadSettings",
-                        "name": "loadSettings",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadFollowersCount",
-                        "name": "loadFollowersCount",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadDevice",
-                        "name": "loadDevice",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadProfileImages",
-                        "name": "loadProfileImages",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadComputedFavoriteGenres",
-                        "name": "loadComputedFavoriteGenres",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadRoles",
-                        "name": "loadRoles",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadRolesWithPermissions",
-                        "name": "loadRolesWithPermissions",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadTorrentUsage",
-                        "name": "loadTorrentUsage",
-                        "in": "query"
+                        "description": "totp code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactorCodeReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserProfileRes"
+                            "$ref": "#/definitions/model.TwoFactorRecoveryCodesRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
                     "404": {
@@ -980,6 +4915,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -989,30 +4930,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/removeProfileImage/:fileName": {
-            "delete": {
-                "description": "Returns new profileImages array",
+        "/v1/user/twoFactor/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "disable two-factor authentication, needs password and a totp code or recovery code.\nlimited to 6 call per minute",
                 "tags": [
-                    "User"
+                    "User-TwoFactor"
                 ],
-                "summary": "Remove Profile Image",
+                "summary": "Disable Two-Factor",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "file name of profile image",
-                        "name": "fileName",
-                        "in": "path",
-                        "required": true
+                        "description": "password and code",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.DisableTwoFactorReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.ProfileImageDataModel"
-                            }
+                            "$ref": "#/definitions/response.ResponseOKModel"
                         }
                     },
                     "400": {
@@ -1021,12 +4966,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1036,43 +4993,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/roles_and_permissions": {
-            "get": {
+        "/v1/user/twoFactor/recoveryCodes": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Return role and permission of user",
+                "description": "replace the two-factor recovery codes with new ones, needs a totp code or recovery code.\nlimited to 6 call per minute",
                 "tags": [
-                    "User"
+                    "User-TwoFactor"
                 ],
-                "summary": "Role Data",
+                "summary": "Regenerate Recovery Codes",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "userId",
-                        "name": "userId",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadRoles",
-                        "name": "loadRoles",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "loadRolesWithPermissions",
-                        "name": "loadRolesWithPermissions",
-                        "in": "query"
+                        "description": "totp code or recovery code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TwoFactorCodeReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UserRolePermissionRes"
+                            "$ref": "#/definitions/model.TwoFactorRecoveryCodesRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
                     "404": {
@@ -1081,6 +5041,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1090,18 +5056,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/sendVerifyEmail": {
-            "get": {
-                "description": "send an email with an activation link. the link will expire after 6 hour.\nmaybe email goes to spam folder.\nlimited to 2 call per minute",
+        "/v1/user/twoFactor/setup": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "start two-factor authentication enrollment, returns totp secret and provisioning uri (otpauth://) to show as qr code.\ntwo-factor is enabled after confirming a code with /v1/user/twoFactor/confirm",
                 "tags": [
-                    "User"
+                    "User-TwoFactor"
                 ],
-                "summary": "Send Verify Email",
+                "summary": "Setup Two-Factor",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseOKModel"
+                            "$ref": "#/definitions/model.TwoFactorSetupRes"
                         }
                     },
                     "404": {
@@ -1125,39 +5096,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/user/setNotifToken": {
-            "put": {
+        "/v1/user/unfollow/:followId": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "send device token as Notification token",
+                "description": "Remove followId user from users following list",
                 "tags": [
-                    "User-Notifications"
+                    "User-Follow"
                 ],
-                "summary": "Notification Token",
+                "summary": "UnFollow User",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "notifToken",
-                        "name": "notifToken",
+                        "type": "integer",
+                        "description": "id on the user want to unfollow",
+                        "name": "followId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
-                    },
-                    "401": {
-                        "description": "Unauthorized",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
+                            "$ref": "#/definitions/response.ResponseOKModel"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
@@ -1167,68 +5135,35 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
-                    }
-                }
-            }
-        },
-        "/v1/user/signup": {
-            "post": {
-                "description": "Register a new user with the provided credentials\nDevice detection can be improved on client side with adding 'deviceInfo.fingerprint'",
-                "tags": [
-                    "User-Auth"
-                ],
-                "summary": "Register a new user",
-                "parameters": [
-                    {
-                        "type": "boolean",
-                        "description": "return refreshToken in response body instead of saving in cookie",
-                        "name": "noCookie",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "description": "User object",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.RegisterViewModel"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.UserViewModel"
-                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/response.ResponseErrorModel"
-                        }
-                    }
-                }
-            }
-        },
-        "/v1/user/unfollow/:followId": {
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
                     }
-                ],
-                "description": "Remove followId user from users following list",
+                }
+            }
+        },
+        "/v1/user/unlockAccount/:userId/:token": {
+            "get": {
+                "description": "unlock account that is locked because of failed login attempts. the link is sent to user email on lock.\nlimited to 6 call per minute",
                 "tags": [
-                    "User-Follow"
+                    "User-Auth"
                 ],
-                "summary": "UnFollow User",
+                "summary": "Unlock Account (Internal Usage)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id on the user want to unfollow",
-                        "name": "followId",
+                        "description": "userId",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "unlock token",
+                        "name": "token",
                         "in": "path",
                         "required": true
                     }
@@ -1263,7 +5198,7 @@ const docTemplate = `{
         },
         "/v1/user/updatePassword": {
             "put": {
-                "description": "Update User Password.",
+                "description": "Update User Password.\nneeds a fresh two-factor code when two-factor authentication is enabled",
                 "tags": [
                     "User"
                 ],
@@ -1277,6 +5212,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.UpdatePasswordReq"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "totp code or recovery code, required when two-factor is enabled",
+                        "name": "twoFactorCode",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -1292,6 +5233,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -1579,6 +5526,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.ResponseErrorModel"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
                     }
                 }
             }
@@ -1704,6 +5657,63 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/v1/ws/singleChat/send": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "send user-to-user message without websocket connection, roomId must be -1.\nmessage is queued and saved in background, result is sent to the websocket connections of the sender.\ncan be called with personal api token that has scope (messages:send)",
+                "tags": [
+                    "User-Websocket"
+                ],
+                "summary": "Send Message",
+                "parameters": [
+                    {
+                        "description": "message",
+                        "name": "messageBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.NewMessage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseOKModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ResponseErrorModel"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -1734,13 +5744,16 @@ const docTemplate = `{
                 "new-follow-notification",
                 "new-message-notification",
                 "movie-notification",
+                "security-notification",
                 "update-profile-images",
                 "update-profile",
                 "single-chats-list",
                 "single-chat-messages",
                 "notification-settings",
                 "user-status",
-                "user-status-isTyping"
+                "user-status-isTyping",
+                "device-pairing-info",
+                "device-pairing"
             ],
             "x-enum-varnames": [
                 "MessageReadAction",
@@ -1752,13 +5765,16 @@ const docTemplate = `{
                 "FollowNotifAction",
                 "NewMessageNotifAction",
                 "MovieNotifAction",
+                "SecurityNotifAction",
                 "UpdateProfileImagesAction",
                 "UpdateProfileAction",
                 "SingleChatsListAction",
                 "SingleChatMessagesAction",
                 "NotificationSettingsAction",
                 "UserStatusAction",
-                "UserIsTypingAction"
+                "UserIsTypingAction",
+                "DevicePairingInfoAction",
+                "DevicePairingAction"
             ]
         },
         "model.ActiveSessionDataModel": {
@@ -1767,10 +5783,284 @@ const docTemplate = `{
                 "appName": {
                     "type": "string"
                 },
-                "appVersion": {
+                "appVersion": {
+                    "type": "string"
+                },
+                "deviceId": {
+                    "type": "string"
+                },
+                "deviceModel": {
+                    "type": "string"
+                },
+                "deviceOs": {
+                    "type": "string"
+                },
+                "ipLocation": {
+                    "description": "NotifToken   string    ` + "`" + `gorm:\"column:notifToken;\" json:\"-\"` + "`" + `",
+                    "type": "string"
+                },
+                "lastUseDate": {
+                    "type": "string"
+                },
+                "loginDate": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ActiveSessionRes": {
+            "type": "object",
+            "properties": {
+                "activeSessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ActiveSessionDataModel"
+                    }
+                },
+                "thisDevice": {
+                    "$ref": "#/definitions/model.ActiveSessionDataModel"
+                }
+            }
+        },
+        "model.AdminUserCounts": {
+            "type": "object",
+            "properties": {
+                "activeSessions": {
+                    "type": "integer"
+                },
+                "bots": {
+                    "type": "integer"
+                },
+                "followers": {
+                    "type": "integer"
+                },
+                "followings": {
+                    "type": "integer"
+                },
+                "profileImages": {
+                    "type": "integer"
+                },
+                "sentMessages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.AdminUserDataModel": {
+            "type": "object",
+            "properties": {
+                "banned": {
+                    "type": "boolean"
+                },
+                "deletionDate": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "emailVerified": {
+                    "type": "boolean"
+                },
+                "lastSeenDate": {
+                    "type": "string"
+                },
+                "publicName": {
+                    "type": "string"
+                },
+                "rawUsername": {
+                    "type": "string"
+                },
+                "registrationDate": {
+                    "type": "string"
+                },
+                "suspendedBy": {
+                    "type": "integer"
+                },
+                "suspendedUntil": {
+                    "type": "integer"
+                },
+                "suspensionReason": {
+                    "type": "string"
+                },
+                "twoFactorEnabled": {
+                    "type": "boolean"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AdminUserDetailsRes": {
+            "type": "object",
+            "properties": {
+                "activeSessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ActiveSessionDataModel"
+                    }
+                },
+                "bots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.UserBotDataModel"
+                    }
+                },
+                "counts": {
+                    "$ref": "#/definitions/model.AdminUserCounts"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Role"
+                    }
+                },
+                "user": {
+                    "$ref": "#/definitions/model.AdminUserDataModel"
+                }
+            }
+        },
+        "model.ApiToken": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "null: never expires",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "lastUsedIp": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tokenPrefix": {
+                    "description": "first characters of the token, helps the user to recognize it",
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.AuditAction": {
+            "type": "string",
+            "enum": [
+                "signup",
+                "login",
+                "login_failed",
+                "token_refresh",
+                "logout",
+                "force_logout",
+                "password_change",
+                "password_reset",
+                "email_change_request",
+                "email_change",
+                "email_change_revert",
+                "account_delete",
+                "account_restore",
+                "account_remove",
+                "role_create",
+                "role_update",
+                "role_delete",
+                "role_permissions",
+                "permission_create",
+                "permission_update",
+                "permission_delete",
+                "user_role_add",
+                "user_role_remove",
+                "user_suspend",
+                "user_unsuspend",
+                "account_unlock",
+                "data_export",
+                "data_export_download",
+                "api_token_create",
+                "api_token_revoke",
+                "identity_link",
+                "identity_unlink",
+                "two_factor_enable",
+                "two_factor_disable",
+                "device_pairing"
+            ],
+            "x-enum-comments": {
+                "AuditAccountRemove": "data is removed after the grace period of account_delete",
+                "AuditDevicePairing": "new device is approved by a logged-in device"
+            },
+            "x-enum-varnames": [
+                "AuditSignup",
+                "AuditLogin",
+                "AuditLoginFailed",
+                "AuditTokenRefresh",
+                "AuditLogout",
+                "AuditForceLogout",
+                "AuditPasswordChange",
+                "AuditPasswordReset",
+                "AuditEmailChangeRequest",
+                "AuditEmailChange",
+                "AuditEmailChangeRevert",
+                "AuditAccountDelete",
+                "AuditAccountRestore",
+                "AuditAccountRemove",
+                "AuditRoleCreate",
+                "AuditRoleUpdate",
+                "AuditRoleDelete",
+                "AuditRolePermissions",
+                "AuditPermissionCreate",
+                "AuditPermissionUpdate",
+                "AuditPermissionDelete",
+                "AuditUserRoleAdd",
+                "AuditUserRoleRemove",
+                "AuditUserSuspend",
+                "AuditUserUnsuspend",
+                "AuditAccountUnlock",
+                "AuditDataExport",
+                "AuditDataExportDownload",
+                "AuditApiTokenCreate",
+                "AuditApiTokenRevoke",
+                "AuditIdentityLink",
+                "AuditIdentityUnlink",
+                "AuditTwoFactorEnable",
+                "AuditTwoFactorDisable",
+                "AuditDevicePairing"
+            ]
+        },
+        "model.AuditLog": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/model.AuditAction"
+                },
+                "actorId": {
+                    "description": "0: not authenticated",
+                    "type": "integer"
+                },
+                "appName": {
+                    "type": "string"
+                },
+                "appVersion": {
+                    "type": "string"
+                },
+                "date": {
                     "type": "string"
                 },
-                "deviceId": {
+                "details": {
                     "type": "string"
                 },
                 "deviceModel": {
@@ -1779,29 +6069,29 @@ const docTemplate = `{
                 "deviceOs": {
                     "type": "string"
                 },
-                "ipLocation": {
-                    "description": "NotifToken   string    ` + "`" + `gorm:\"column:notifToken;\" json:\"-\"` + "`" + `",
-                    "type": "string"
+                "id": {
+                    "type": "integer"
                 },
-                "lastUseDate": {
+                "ip": {
                     "type": "string"
                 },
-                "loginDate": {
+                "ipLocation": {
                     "type": "string"
+                },
+                "targetId": {
+                    "description": "0: not a user (roles, permissions)",
+                    "type": "integer"
                 }
             }
         },
-        "model.ActiveSessionRes": {
+        "model.ChangeEmailReq": {
             "type": "object",
+            "required": [
+                "email"
+            ],
             "properties": {
-                "activeSessions": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.ActiveSessionDataModel"
-                    }
-                },
-                "thisDevice": {
-                    "$ref": "#/definitions/model.ActiveSessionDataModel"
+                "email": {
+                    "type": "string"
                 }
             }
         },
@@ -1862,6 +6152,14 @@ const docTemplate = `{
                         }
                     ]
                 },
+                "devicePairingReq": {
+                    "description": "action is DevicePairingInfoAction or DevicePairingAction",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DevicePairingReq"
+                        }
+                    ]
+                },
                 "messageRead": {
                     "description": "action is MessageReadAction",
                     "allOf": [
@@ -1905,6 +6203,151 @@ const docTemplate = `{
                 }
             }
         },
+        "model.ConfirmUploadReq": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "receiverId": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "roomId": {
+                    "description": "value -1 means its user-to-user message",
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "uploadId": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CreateApiTokenReq": {
+            "type": "object",
+            "properties": {
+                "expiresInDays": {
+                    "description": "0: never expires",
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "description": "required when two-factor is not enabled",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "notifications:read",
+                            "messages:send",
+                            "follows:manage"
+                        ]
+                    }
+                }
+            }
+        },
+        "model.CreateApiTokenRes": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "null: never expires",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "lastUsedIp": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "description": "plain token, it cannot be seen again",
+                    "type": "string"
+                },
+                "tokenPrefix": {
+                    "description": "first characters of the token, helps the user to recognize it",
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.DataExport": {
+            "type": "object",
+            "properties": {
+                "completeDate": {
+                    "type": "string"
+                },
+                "errorMessage": {
+                    "type": "string"
+                },
+                "expireDate": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "requestDate": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "startDate": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.DataExportStatus"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.DataExportStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "processing",
+                "ready",
+                "failed",
+                "expired"
+            ],
+            "x-enum-varnames": [
+                "DataExportPending",
+                "DataExportProcessing",
+                "DataExportReady",
+                "DataExportFailed",
+                "DataExportExpired"
+            ]
+        },
         "model.DeviceInfo": {
             "type": "object",
             "required": [
@@ -1935,6 +6378,120 @@ const docTemplate = `{
                 }
             }
         },
+        "model.DevicePairingCompleteReq": {
+            "type": "object",
+            "required": [
+                "code",
+                "token"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.DevicePairingInfo": {
+            "type": "object",
+            "properties": {
+                "appName": {
+                    "type": "string"
+                },
+                "appVersion": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "deviceModel": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "integer"
+                },
+                "ipLocation": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.DevicePairingStatus"
+                }
+            }
+        },
+        "model.DevicePairingReq": {
+            "type": "object",
+            "properties": {
+                "approve": {
+                    "description": "only used in DevicePairingAction",
+                    "type": "boolean"
+                },
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.DevicePairingStartReq": {
+            "type": "object",
+            "properties": {
+                "deviceInfo": {
+                    "$ref": "#/definitions/model.DeviceInfo"
+                }
+            }
+        },
+        "model.DevicePairingStartRes": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "integer"
+                },
+                "qrData": {
+                    "description": "QrData is the content of qr code that is shown on the new device",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Token is secret of the new device, it's needed to wait for approval and to receive the session",
+                    "type": "string"
+                }
+            }
+        },
+        "model.DevicePairingStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "approved",
+                "rejected",
+                "expired"
+            ],
+            "x-enum-varnames": [
+                "DevicePairingPending",
+                "DevicePairingApproved",
+                "DevicePairingRejected",
+                "DevicePairingExpired"
+            ]
+        },
+        "model.DisableTwoFactorReq": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "description": "6 digit totp code or a recovery code",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
         "model.DownloadLinksSettings": {
             "type": "object",
             "properties": {
@@ -1965,34 +6522,92 @@ const docTemplate = `{
                 "bio": {
                     "type": "string"
                 },
-                "email": {
+                "mbtiType": {
+                    "$ref": "#/definitions/model.MbtiType"
+                },
+                "publicName": {
+                    "type": "string"
+                },
+                "username": {
+                    "description": "format: (?i)^[a-z|\\d_-]+$",
+                    "type": "string"
+                }
+            }
+        },
+        "model.FollowListProfileImageDataModel": {
+            "type": "object",
+            "properties": {
+                "blurHash": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "thumbnail": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.FollowRequestDataModel": {
+            "type": "object",
+            "properties": {
+                "bio": {
                     "type": "string"
                 },
-                "mbtiType": {
-                    "$ref": "#/definitions/model.MbtiType"
+                "profileImages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FollowListProfileImageDataModel"
+                    }
                 },
                 "publicName": {
                     "type": "string"
                 },
+                "rawUsername": {
+                    "type": "string"
+                },
+                "requestDate": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                },
                 "username": {
-                    "description": "format: (?i)^[a-z|\\d_-]+$",
                     "type": "string"
                 }
             }
         },
-        "model.FollowListProfileImageDataModel": {
+        "model.FollowSuggestionDataModel": {
             "type": "object",
             "properties": {
-                "blurHash": {
-                    "type": "string"
+                "commonGenres": {
+                    "type": "integer"
                 },
-                "size": {
+                "commonMovies": {
                     "type": "integer"
                 },
-                "thumbnail": {
+                "mutualFollows": {
+                    "type": "integer"
+                },
+                "profileImages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FollowListProfileImageDataModel"
+                    }
+                },
+                "publicName": {
                     "type": "string"
                 },
-                "url": {
+                "rawUsername": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "username": {
                     "type": "string"
                 }
             }
@@ -2023,6 +6638,17 @@ const docTemplate = `{
                 }
             }
         },
+        "model.ForgetPasswordReq": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
         "model.GetSingleChatListReq": {
             "type": "object",
             "properties": {
@@ -2087,6 +6713,71 @@ const docTemplate = `{
                 }
             }
         },
+        "model.LoginHistoryDataModel": {
+            "type": "object",
+            "properties": {
+                "appName": {
+                    "type": "string"
+                },
+                "appVersion": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "deviceKey": {
+                    "type": "string"
+                },
+                "deviceModel": {
+                    "type": "string"
+                },
+                "deviceOs": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ipLocation": {
+                    "type": "string"
+                },
+                "newDevice": {
+                    "type": "boolean"
+                },
+                "newLocation": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.LoginLock": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "failedAttempts": {
+                    "type": "integer"
+                },
+                "lastIpLocation": {
+                    "type": "string"
+                },
+                "lockedAt": {
+                    "type": "string"
+                },
+                "network": {
+                    "description": "ipv4 /24 or ipv6 /64 of the failed logins",
+                    "type": "string"
+                },
+                "unlockTokenHash": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
         "model.LoginViewModel": {
             "type": "object",
             "properties": {
@@ -2102,6 +6793,35 @@ const docTemplate = `{
                 }
             }
         },
+        "model.MagicLinkLoginReq": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "fingerprint": {
+                    "description": "required if the link is requested with fingerprint",
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MagicLinkReq": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "deviceInfo": {
+                    "$ref": "#/definitions/model.DeviceInfo"
+                },
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
         "model.MbtiType": {
             "type": "string",
             "enum": [
@@ -2368,22 +7088,192 @@ const docTemplate = `{
                 }
             }
         },
-        "model.Permission": {
+        "model.OidcLoginReq": {
+            "type": "object",
+            "required": [
+                "code",
+                "state"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.OidcProvidersRes": {
+            "type": "object",
+            "properties": {
+                "providers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.OidcStartReq": {
+            "type": "object",
+            "properties": {
+                "deviceInfo": {
+                    "$ref": "#/definitions/model.DeviceInfo"
+                },
+                "redirectUri": {
+                    "description": "RedirectUri is the uri registered in provider, default is the callback of this server",
+                    "type": "string"
+                }
+            }
+        },
+        "model.OidcStartRes": {
+            "type": "object",
+            "properties": {
+                "authUrl": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.OrphanBucketReport": {
+            "type": "object",
+            "properties": {
+                "bucketName": {
+                    "type": "string"
+                },
+                "errorMessage": {
+                    "type": "string"
+                },
+                "orphanCount": {
+                    "type": "integer"
+                },
+                "orphanFiles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.OrphanMediaFile"
+                    }
+                },
+                "orphanSize": {
+                    "type": "integer"
+                },
+                "removedCount": {
+                    "type": "integer"
+                },
+                "totalFiles": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.OrphanMediaFile": {
+            "type": "object",
+            "properties": {
+                "fileName": {
+                    "type": "string"
+                },
+                "lastModified": {
+                    "type": "string"
+                },
+                "removable": {
+                    "description": "Removable is true when the file is older than the grace period, newer files may belong to an unfinished upload",
+                    "type": "boolean"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.OrphanMediaReport": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.OrphanBucketReport"
+                    }
+                },
+                "date": {
+                    "type": "string"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "gracePeriodHours": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Permission": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PermissionReq": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "format: ^[a-z0-9_]+$",
+                    "type": "string"
+                }
+            }
+        },
+        "model.PresignedUploadReq": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string"
+                },
+                "fileName": {
+                    "type": "string"
+                },
+                "sha256": {
+                    "description": "hex sha256 of the file, storage rejects the upload if the content doesn't match it",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "model.PresignedUploadRes": {
             "type": "object",
             "properties": {
-                "createdAt": {
+                "expiresAt": {
                     "type": "string"
                 },
-                "description": {
-                    "type": "string"
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "id": {
-                    "type": "integer"
+                "method": {
+                    "type": "string"
                 },
-                "name": {
+                "uploadId": {
                     "type": "string"
                 },
-                "updatedAt": {
+                "url": {
                     "type": "string"
                 }
             }
@@ -2501,6 +7391,28 @@ const docTemplate = `{
                 }
             }
         },
+        "model.ResetPasswordReq": {
+            "type": "object",
+            "required": [
+                "token",
+                "userId"
+            ],
+            "properties": {
+                "confirmPassword": {
+                    "type": "string"
+                },
+                "newPassword": {
+                    "description": "contain one number, one uppercase letter, cannot have space",
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.Role": {
             "type": "object",
             "properties": {
@@ -2542,6 +7454,38 @@ const docTemplate = `{
                 }
             }
         },
+        "model.RolePermissionsReq": {
+            "type": "object",
+            "properties": {
+                "permissionIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "model.RoleReq": {
+            "type": "object",
+            "properties": {
+                "botsNotification": {
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "format: ^[a-z0-9_]+$",
+                    "type": "string"
+                },
+                "torrentLeachLimitGb": {
+                    "type": "integer"
+                },
+                "torrentSearchLimit": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.RoleToPermission": {
             "type": "object",
             "properties": {
@@ -2616,6 +7560,14 @@ const docTemplate = `{
                         "$ref": "#/definitions/model.ChatsCompressedDataModel"
                     }
                 },
+                "devicePairing": {
+                    "description": "action is DevicePairingInfoAction or DevicePairingAction",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DevicePairingInfo"
+                        }
+                    ]
+                },
                 "editProfile": {
                     "description": "action is UpdateProfileAction",
                     "allOf": [
@@ -2690,7 +7642,9 @@ const docTemplate = `{
                 4,
                 5,
                 6,
-                7
+                7,
+                8,
+                9
             ],
             "x-enum-varnames": [
                 "FinishedListSpinOffSequel",
@@ -2699,9 +7653,28 @@ const docTemplate = `{
                 "FollowMovieSubtitle",
                 "FutureList",
                 "FutureListSerialSeasonEnd",
-                "FutureListSubtitle"
+                "FutureListSubtitle",
+                "NewFollowRequest",
+                "FollowRequestAccepted"
             ]
         },
+        "model.SuspendUserReq": {
+            "type": "object",
+            "properties": {
+                "durationHours": {
+                    "description": "ignored when permanent is true",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "permanent": {
+                    "description": "ban the user",
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
         "model.TaskInfo": {
             "type": "object",
             "properties": {
@@ -2753,6 +7726,56 @@ const docTemplate = `{
                 }
             }
         },
+        "model.TwoFactorCodeReq": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "6 digit totp code or a recovery code",
+                    "type": "string"
+                }
+            }
+        },
+        "model.TwoFactorLoginReq": {
+            "type": "object",
+            "required": [
+                "challengeToken",
+                "code"
+            ],
+            "properties": {
+                "challengeToken": {
+                    "type": "string"
+                },
+                "code": {
+                    "description": "6 digit totp code or a recovery code",
+                    "type": "string"
+                }
+            }
+        },
+        "model.TwoFactorRecoveryCodesRes": {
+            "type": "object",
+            "properties": {
+                "recoveryCodes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.TwoFactorSetupRes": {
+            "type": "object",
+            "properties": {
+                "provisioningUri": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
         "model.UpdatePasswordReq": {
             "type": "object",
             "required": [
@@ -2788,6 +7811,75 @@ const docTemplate = `{
                 }
             }
         },
+        "model.UserBotDataModel": {
+            "type": "object",
+            "properties": {
+                "botId": {
+                    "type": "string"
+                },
+                "chatId": {
+                    "type": "string"
+                },
+                "notification": {
+                    "type": "boolean"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UserDeviceDataModel": {
+            "type": "object",
+            "properties": {
+                "appName": {
+                    "type": "string"
+                },
+                "deviceId": {
+                    "type": "string"
+                },
+                "deviceKey": {
+                    "type": "string"
+                },
+                "deviceModel": {
+                    "type": "string"
+                },
+                "deviceOs": {
+                    "type": "string"
+                },
+                "firstLoginDate": {
+                    "type": "string"
+                },
+                "lastIpLocation": {
+                    "type": "string"
+                },
+                "lastLoginDate": {
+                    "type": "string"
+                },
+                "trusted": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.UserIdentityDataModel": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "lastLoginDate": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                }
+            }
+        },
         "model.UserProfileRes": {
             "type": "object",
             "properties": {
@@ -2830,21 +7922,43 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "followRequested": {
+                    "type": "boolean"
+                },
                 "followersCount": {
                     "type": "integer"
                 },
                 "followingsCount": {
                     "type": "integer"
                 },
+                "followsYou": {
+                    "type": "boolean"
+                },
+                "isFollowing": {
+                    "type": "boolean"
+                },
                 "lastSeenDate": {
                     "type": "string"
                 },
                 "mbtiType": {
                     "$ref": "#/definitions/model.MbtiType"
                 },
+                "mutualFollowersPreview": {
+                    "description": "followings of viewer that follow the user",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FollowUserDataModel"
+                    }
+                },
                 "notificationSettings": {
                     "$ref": "#/definitions/model.NotificationSettings"
                 },
+                "pendingEmail": {
+                    "type": "string"
+                },
+                "privateAccount": {
+                    "type": "boolean"
+                },
                 "profileImages": {
                     "type": "array",
                     "items": {
@@ -2897,6 +8011,36 @@ const docTemplate = `{
                 }
             }
         },
+        "model.UserSearchDataModel": {
+            "type": "object",
+            "properties": {
+                "blurHash": {
+                    "type": "string"
+                },
+                "followsYou": {
+                    "type": "boolean"
+                },
+                "isFollowing": {
+                    "type": "boolean"
+                },
+                "publicName": {
+                    "type": "string"
+                },
+                "rawUsername": {
+                    "type": "string"
+                },
+                "thumbnail": {
+                    "description": "thumbnail of the newest profile image",
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
         "model.UserSettingsRes": {
             "type": "object",
             "properties": {
@@ -2959,6 +8103,27 @@ const docTemplate = `{
                 "UserStatusStopTyping"
             ]
         },
+        "model.UserSuspension": {
+            "type": "object",
+            "properties": {
+                "banned": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "suspendedBy": {
+                    "type": "integer"
+                },
+                "until": {
+                    "description": "unix milli, 0 when banned",
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
         "model.UserToRole": {
             "type": "object",
             "properties": {
@@ -2987,6 +8152,9 @@ const docTemplate = `{
         "model.UserViewModel": {
             "type": "object",
             "properties": {
+                "challengeToken": {
+                    "type": "string"
+                },
                 "email": {
                     "type": "string"
                 },
@@ -3011,6 +8179,10 @@ const docTemplate = `{
                 "token": {
                     "$ref": "#/definitions/model.TokenViewModel"
                 },
+                "twoFactorRequired": {
+                    "description": "set when account has two-factor authentication, Token is empty and login must be completed with ChallengeToken",
+                    "type": "boolean"
+                },
                 "userId": {
                     "type": "integer"
                 },
@@ -3038,6 +8210,46 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "util.Jwk": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "util.Jwks": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/util.Jwk"
+                    }
+                }
+            }
         }
     },
     "securityDefinitions": {
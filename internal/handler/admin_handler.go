@@ -2,13 +2,36 @@ package handler
 
 import (
 	"downloader_gochat/internal/service"
+	"downloader_gochat/model"
 	"downloader_gochat/pkg/response"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type IAdminHandler interface {
 	GetServerStatus(c *fiber.Ctx) error
+	GetOrphanMediaReport(c *fiber.Ctx) error
+	GetLockedAccounts(c *fiber.Ctx) error
+	UnlockAccount(c *fiber.Ctx) error
+	GetRoles(c *fiber.Ctx) error
+	CreateRole(c *fiber.Ctx) error
+	UpdateRole(c *fiber.Ctx) error
+	DeleteRole(c *fiber.Ctx) error
+	SetRolePermissions(c *fiber.Ctx) error
+	GetPermissions(c *fiber.Ctx) error
+	CreatePermission(c *fiber.Ctx) error
+	UpdatePermission(c *fiber.Ctx) error
+	DeletePermission(c *fiber.Ctx) error
+	GetUserRoles(c *fiber.Ctx) error
+	AddUserRole(c *fiber.Ctx) error
+	RemoveUserRole(c *fiber.Ctx) error
+	SearchUsers(c *fiber.Ctx) error
+	GetUserDetails(c *fiber.Ctx) error
+	SuspendUser(c *fiber.Ctx) error
+	UnsuspendUser(c *fiber.Ctx) error
+	ForceLogoutUser(c *fiber.Ctx) error
 }
 
 type AdminHandler struct {
@@ -37,3 +60,624 @@ func (a *AdminHandler) GetServerStatus(c *fiber.Ctx) error {
 
 	return response.ResponseOKWithData(c, result)
 }
+
+// GetOrphanMediaReport godoc
+//
+//	@Summary		Orphan Media Report
+//	@Description	Dry-run report of files in media-file and profile-image buckets that have no db record.
+//	@Description	Removable files are older than the grace period and will be removed by the periodic cleanup.
+//	@Tags			Admin-Status
+//	@Success		200	{object}	model.OrphanMediaReport
+//	@Failure		400	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/storage/orphanMedia [get]
+func (a *AdminHandler) GetOrphanMediaReport(c *fiber.Ctx) error {
+	result := a.adminService.GetOrphanMediaReport()
+
+	return response.ResponseOKWithData(c, result)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetLockedAccounts godoc
+//
+//	@Summary		Locked Accounts
+//	@Description	Return accounts that are locked because of failed login attempts, each lock only applies to logins from its network.
+//	@Tags			Admin-Users
+//	@Success		200	{object}	[]model.LoginLock
+//	@Failure		500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/lockedAccounts [get]
+func (a *AdminHandler) GetLockedAccounts(c *fiber.Ctx) error {
+	result, err := a.adminService.GetLockedAccounts()
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// UnlockAccount godoc
+//
+//	@Summary		Unlock Account
+//	@Description	Remove the locks and failed login attempts of account from all networks.
+//	@Tags			Admin-Users
+//	@Param			userId		path		integer	true	"userId"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/unlockAccount/:userId [put]
+func (a *AdminHandler) UnlockAccount(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.UnlockAccount(int64(userId), service.NewAuditContext(c))
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// SearchUsers godoc
+//
+//	@Summary		Search Users
+//	@Description	Search users by userId or by start of username or email, results are ordered by userId.
+//	@Tags			Admin-Users
+//	@Param			query	query		string	false	"userId, username or email"
+//	@Param			skip	query		integer	true	"skip"
+//	@Param			limit	query		integer	true	"limit"
+//	@Success		200		{object}	[]model.AdminUserDataModel
+//	@Failure		400,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users [get]
+func (a *AdminHandler) SearchUsers(c *fiber.Ctx) error {
+	var params model.AdminSearchUsersReq
+	err := c.QueryParser(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	validation := params.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	result, err := a.adminService.SearchUsers(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// GetUserDetails godoc
+//
+//	@Summary		User Details
+//	@Description	Return data of user with suspension state, sessions, roles, bots and counts.
+//	@Tags			Admin-Users
+//	@Param			userId			path		integer	true	"userId"
+//	@Success		200				{object}	model.AdminUserDetailsRes
+//	@Failure		400,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users/:userId [get]
+func (a *AdminHandler) GetUserDetails(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	result, err := a.adminService.GetUserDetails(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// SuspendUser godoc
+//
+//	@Summary		Suspend User
+//	@Description	Suspend user for a period or ban permanently. suspended user cannot login or use the api and websocket,
+//	@Description	live websocket connections are closed. ban also logs out all the sessions. main admin cannot be suspended.
+//	@Tags			Admin-Users
+//	@Param			userId				path		integer					true	"userId"
+//	@Param			suspension			body		model.SuspendUserReq	true	"suspension data"
+//	@Success		200					{object}	model.UserSuspension
+//	@Failure		400,403,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users/:userId/suspend [put]
+func (a *AdminHandler) SuspendUser(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+	var suspendReq model.SuspendUserReq
+	err = c.BodyParser(&suspendReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := suspendReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	result, err := a.adminService.SuspendUser(int64(userId), &suspendReq, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.SelfSuspended || err.Error() == response.MainAdminSuspended {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// UnsuspendUser godoc
+//
+//	@Summary		Lift Suspension
+//	@Description	Lift the suspension or ban of user.
+//	@Tags			Admin-Users
+//	@Param			userId			path		integer	true	"userId"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users/:userId/unsuspend [put]
+func (a *AdminHandler) UnsuspendUser(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.UnsuspendUser(int64(userId), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// ForceLogoutUser godoc
+//
+//	@Summary		Force Logout User
+//	@Description	Remove all the sessions of user and close the websocket connections.
+//	@Tags			Admin-Users
+//	@Param			userId		path		integer	true	"userId"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users/:userId/forceLogout [put]
+func (a *AdminHandler) ForceLogoutUser(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.ForceLogoutUser(int64(userId), service.NewAuditContext(c))
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetRoles godoc
+//
+//	@Summary		Roles
+//	@Description	Return all the roles with their permissions.
+//	@Tags			Admin-Roles
+//	@Success		200	{object}	[]model.RoleWithPermissions
+//	@Failure		500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/roles [get]
+func (a *AdminHandler) GetRoles(c *fiber.Ctx) error {
+	result, err := a.adminService.GetRoles()
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// CreateRole godoc
+//
+//	@Summary		Create Role
+//	@Description	Create a new role without permissions, permissions are set with /v1/admin/roles/:roleId/permissions.
+//	@Tags			Admin-Roles
+//	@Param			role			body		model.RoleReq	true	"role data"
+//	@Success		200				{object}	model.Role
+//	@Failure		400,409,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/roles [post]
+func (a *AdminHandler) CreateRole(c *fiber.Ctx) error {
+	var roleReq model.RoleReq
+	err := c.BodyParser(&roleReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := roleReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	result, err := a.adminService.CreateRole(&roleReq, service.NewAuditContext(c))
+	if err != nil {
+		if err.Error() == response.RoleAlreadyExist {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// UpdateRole godoc
+//
+//	@Summary		Update Role
+//	@Description	Update data of role. default roles cannot be renamed and main admin role cannot be edited.
+//	@Tags			Admin-Roles
+//	@Param			roleId				path		integer			true	"roleId"
+//	@Param			role				body		model.RoleReq	true	"role data"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/roles/:roleId [put]
+func (a *AdminHandler) UpdateRole(c *fiber.Ctx) error {
+	roleId, err := c.ParamsInt("roleId", -1)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if roleId < 0 {
+		return response.ResponseError(c, "roleId cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+	var roleReq model.RoleReq
+	err = c.BodyParser(&roleReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := roleReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.UpdateRole(int64(roleId), &roleReq, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.RoleNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.RoleAlreadyExist {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		} else if err.Error() == response.DefaultRoleChanged || err.Error() == response.MainAdminRoleChanged {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// DeleteRole godoc
+//
+//	@Summary		Delete Role
+//	@Description	Remove the role, users that had the role need a new token to use their other roles.
+//	@Description	default roles cannot be removed.
+//	@Tags			Admin-Roles
+//	@Param			roleId				path		integer	true	"roleId"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/roles/:roleId [delete]
+func (a *AdminHandler) DeleteRole(c *fiber.Ctx) error {
+	roleId, err := c.ParamsInt("roleId", -1)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if roleId < 0 {
+		return response.ResponseError(c, "roleId cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.DeleteRole(int64(roleId), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.RoleNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.DefaultRoleChanged {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// SetRolePermissions godoc
+//
+//	@Summary		Set Role Permissions
+//	@Description	Replace the permissions of role, empty list removes all of them. main admin role cannot be edited.
+//	@Tags			Admin-Roles
+//	@Param			roleId				path		integer						true	"roleId"
+//	@Param			permissions			body		model.RolePermissionsReq	true	"permission ids"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/roles/:roleId/permissions [put]
+func (a *AdminHandler) SetRolePermissions(c *fiber.Ctx) error {
+	roleId, err := c.ParamsInt("roleId", -1)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if roleId < 0 {
+		return response.ResponseError(c, "roleId cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+	var permissionsReq model.RolePermissionsReq
+	err = c.BodyParser(&permissionsReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := permissionsReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.SetRolePermissions(int64(roleId), permissionsReq.PermissionIds, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.RoleNotFound+" or "+response.PermissionNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.MainAdminRoleChanged {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetPermissions godoc
+//
+//	@Summary		Permissions
+//	@Description	Return all the permissions.
+//	@Tags			Admin-Roles
+//	@Success		200	{object}	[]model.Permission
+//	@Failure		500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/permissions [get]
+func (a *AdminHandler) GetPermissions(c *fiber.Ctx) error {
+	result, err := a.adminService.GetPermissions()
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// CreatePermission godoc
+//
+//	@Summary		Create Permission
+//	@Description	Create a new permission, it should be checked in the code to have any effect.
+//	@Tags			Admin-Roles
+//	@Param			permission		body		model.PermissionReq	true	"permission data"
+//	@Success		200				{object}	model.Permission
+//	@Failure		400,409,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/permissions [post]
+func (a *AdminHandler) CreatePermission(c *fiber.Ctx) error {
+	var permissionReq model.PermissionReq
+	err := c.BodyParser(&permissionReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := permissionReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	result, err := a.adminService.CreatePermission(&permissionReq, service.NewAuditContext(c))
+	if err != nil {
+		if err.Error() == response.PermissionAlreadyExist {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// UpdatePermission godoc
+//
+//	@Summary		Update Permission
+//	@Description	Update name and description of permission.
+//	@Tags			Admin-Roles
+//	@Param			permissionId		path		integer				true	"permissionId"
+//	@Param			permission			body		model.PermissionReq	true	"permission data"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/permissions/:permissionId [put]
+func (a *AdminHandler) UpdatePermission(c *fiber.Ctx) error {
+	permissionId, err := c.ParamsInt("permissionId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if permissionId < 1 {
+		return response.ResponseError(c, "permissionId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+	var permissionReq model.PermissionReq
+	err = c.BodyParser(&permissionReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := permissionReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.UpdatePermission(int64(permissionId), &permissionReq, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.PermissionNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.PermissionAlreadyExist {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		} else if err.Error() == response.ProtectedPermissionUsed {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// DeletePermission godoc
+//
+//	@Summary		Delete Permission
+//	@Description	Remove the permission from all the roles and delete it.
+//	@Tags			Admin-Roles
+//	@Param			permissionId		path		integer	true	"permissionId"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/permissions/:permissionId [delete]
+func (a *AdminHandler) DeletePermission(c *fiber.Ctx) error {
+	permissionId, err := c.ParamsInt("permissionId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if permissionId < 1 {
+		return response.ResponseError(c, "permissionId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	err = a.adminService.DeletePermission(int64(permissionId), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.PermissionNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.ProtectedPermissionUsed {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetUserRoles godoc
+//
+//	@Summary		User Roles
+//	@Description	Return roles of the user.
+//	@Tags			Admin-Roles
+//	@Param			userId		path		integer	true	"userId"
+//	@Success		200			{object}	[]model.Role
+//	@Failure		400,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users/:userId/roles [get]
+func (a *AdminHandler) GetUserRoles(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	result, err := a.adminService.GetUserRoles(int64(userId))
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// AddUserRole godoc
+//
+//	@Summary		Assign Role
+//	@Description	Assign the role to user, user needs a new token (GetToken) to use the permissions of the new role.
+//	@Description	main admin role cannot be assigned.
+//	@Tags			Admin-Roles
+//	@Param			userId				path		integer	true	"userId"
+//	@Param			roleId				path		integer	true	"roleId"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users/:userId/roles/:roleId [put]
+func (a *AdminHandler) AddUserRole(c *fiber.Ctx) error {
+	return a.changeUserRole(c, true)
+}
+
+// RemoveUserRole godoc
+//
+//	@Summary		Revoke Role
+//	@Description	Revoke the role from user, tokens of user that are generated before it are rejected on admin routes.
+//	@Description	main admin role cannot be revoked.
+//	@Tags			Admin-Roles
+//	@Param			userId				path		integer	true	"userId"
+//	@Param			roleId				path		integer	true	"roleId"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/users/:userId/roles/:roleId [delete]
+func (a *AdminHandler) RemoveUserRole(c *fiber.Ctx) error {
+	return a.changeUserRole(c, false)
+}
+
+func (a *AdminHandler) changeUserRole(c *fiber.Ctx, add bool) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+	roleId, err := c.ParamsInt("roleId", -1)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if roleId < 0 {
+		return response.ResponseError(c, "roleId cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+
+	if add {
+		err = a.adminService.AddUserRole(int64(userId), int64(roleId), service.NewAuditContext(c))
+	} else {
+		err = a.adminService.RemoveUserRole(int64(userId), int64(roleId), service.NewAuditContext(c))
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound+" or "+response.RoleNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.MainAdminRoleChanged {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
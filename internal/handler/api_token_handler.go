@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"downloader_gochat/internal/service"
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type IApiTokenHandler interface {
+	CreateApiToken(c *fiber.Ctx) error
+	GetApiTokens(c *fiber.Ctx) error
+	RevokeApiToken(c *fiber.Ctx) error
+}
+
+type ApiTokenHandler struct {
+	apiTokenService service.IApiTokenService
+}
+
+func NewApiTokenHandler(apiTokenService service.IApiTokenService) *ApiTokenHandler {
+	return &ApiTokenHandler{
+		apiTokenService: apiTokenService,
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// CreateApiToken godoc
+//
+//	@Summary		Create Api Token
+//	@Description	Create a personal api token for scripts and integrations, it's sent as `Authorization: Bearer <token>` and doesn't need refreshToken.
+//	@Description	token only works on the routes that accept one of its scopes (notifications:read, messages:send, follows:manage).
+//	@Description	the plain token is returned once and cannot be seen again. each user can have 20 active tokens.
+//	@Description	needs the password in body, or a fresh two-factor code when two-factor authentication is enabled.
+//	@Description	all tokens are revoked when password changes or all sessions are logged out.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-ApiToken
+//	@Param			tokenBody		body		model.CreateApiTokenReq	true	"token body"
+//	@Param			twoFactorCode	header		string					false	"totp code or recovery code, required when two-factor is enabled"
+//	@Success		200				{object}	model.CreateApiTokenRes
+//	@Failure		400,401,403,404,429,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/apiTokens [post]
+func (h *ApiTokenHandler) CreateApiToken(c *fiber.Ctx) error {
+	var params model.CreateApiTokenReq
+	err := c.BodyParser(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	validation := params.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.apiTokenService.CreateApiToken(jwtUserData.UserId, &params, c.Get("twoFactorCode", ""), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.ExceedApiTokens || err.Error() == response.PassNotMatch ||
+			err.Error() == response.TwoFactorCodeRequired || err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// GetApiTokens godoc
+//
+//	@Summary		Api Tokens
+//	@Description	Return not revoked api tokens of the user, newest first. expired tokens are included.
+//	@Tags			User-ApiToken
+//	@Success		200		{object}	[]model.ApiToken
+//	@Failure		401,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/apiTokens [get]
+func (h *ApiTokenHandler) GetApiTokens(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.apiTokenService.GetApiTokens(jwtUserData.UserId)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// RevokeApiToken godoc
+//
+//	@Summary		Revoke Api Token
+//	@Description	Revoke the api token, requests with it are rejected immediately
+//	@Tags			User-ApiToken
+//	@Param			tokenId			path		integer	true	"id of the token"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/apiTokens/:tokenId [delete]
+func (h *ApiTokenHandler) RevokeApiToken(c *fiber.Ctx) error {
+	tokenId, err := c.ParamsInt("tokenId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if tokenId < 1 {
+		return response.ResponseError(c, "tokenId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.apiTokenService.RevokeApiToken(jwtUserData.UserId, int64(tokenId), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.ApiTokenNotFound, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"downloader_gochat/internal/service"
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IAuditLogHandler interface {
+	GetUserAuditLogs(c *fiber.Ctx) error
+	SearchAuditLogs(c *fiber.Ctx) error
+	ExportAuditLogs(c *fiber.Ctx) error
+}
+
+type AuditLogHandler struct {
+	auditLogService service.IAuditLogService
+}
+
+func NewAuditLogHandler(auditLogService service.IAuditLogService) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditLogService: auditLogService,
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+const (
+	auditLogPageLimit   = 100
+	auditLogExportLimit = 10000
+)
+
+// GetUserAuditLogs godoc
+//
+//	@Summary		Audit Logs
+//	@Description	Return security events of the user (logins, token refreshes, password and email changes, admin actions on the account), newest first.
+//	@Tags			User
+//	@Param			action	query		string	false	"action"
+//	@Param			from	query		string	false	"from date"
+//	@Param			to		query		string	false	"to date"
+//	@Param			skip	query		integer	true	"skip"
+//	@Param			limit	query		integer	true	"limit, max 100"
+//	@Success		200		{object}	[]model.AuditLog
+//	@Failure		400,401	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/auditLogs [get]
+func (h *AuditLogHandler) GetUserAuditLogs(c *fiber.Ctx) error {
+	var params model.AuditLogReq
+	err := c.QueryParser(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	validation := params.Validate(auditLogPageLimit)
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	params.UserId = jwtUserData.UserId
+	params.ActorId = 0
+	params.TargetId = 0
+	params.Ip = ""
+
+	result, err := h.auditLogService.GetAuditLogs(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// SearchAuditLogs godoc
+//
+//	@Summary		Search Audit Logs
+//	@Description	Search security events of all users, newest first.
+//	@Tags			Admin-AuditLogs
+//	@Param			actorId		query		integer	false	"actorId"
+//	@Param			targetId	query		integer	false	"targetId"
+//	@Param			action		query		string	false	"action"
+//	@Param			ip			query		string	false	"ip"
+//	@Param			from		query		string	false	"from date"
+//	@Param			to			query		string	false	"to date"
+//	@Param			skip		query		integer	true	"skip"
+//	@Param			limit		query		integer	true	"limit, max 100"
+//	@Success		200			{object}	[]model.AuditLog
+//	@Failure		400,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/auditLogs [get]
+func (h *AuditLogHandler) SearchAuditLogs(c *fiber.Ctx) error {
+	var params model.AuditLogReq
+	err := c.QueryParser(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	validation := params.Validate(auditLogPageLimit)
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+	params.UserId = 0
+
+	result, err := h.auditLogService.GetAuditLogs(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// ExportAuditLogs godoc
+//
+//	@Summary		Export Audit Logs
+//	@Description	Download the filtered security events as csv or json file, newest first.
+//	@Tags			Admin-AuditLogs
+//	@Param			format		query		string	false	"csv (default) or json"
+//	@Param			actorId		query		integer	false	"actorId"
+//	@Param			targetId	query		integer	false	"targetId"
+//	@Param			action		query		string	false	"action"
+//	@Param			ip			query		string	false	"ip"
+//	@Param			from		query		string	false	"from date"
+//	@Param			to			query		string	false	"to date"
+//	@Param			skip		query		integer	true	"skip"
+//	@Param			limit		query		integer	true	"limit, max 10000"
+//	@Success		200			{file}		file
+//	@Failure		400,500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/admin/auditLogs/export [get]
+func (h *AuditLogHandler) ExportAuditLogs(c *fiber.Ctx) error {
+	format := c.Query("format", "csv")
+	if format != "csv" && format != "json" {
+		return response.ResponseError(c, "format must be csv or json", fiber.StatusBadRequest)
+	}
+	var params model.AuditLogReq
+	err := c.QueryParser(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	validation := params.Validate(auditLogExportLimit)
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+	params.UserId = 0
+
+	result, err := h.auditLogService.GetAuditLogs(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	c.Attachment("auditLogs." + format)
+	if format == "json" {
+		return c.JSON(result)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	w := csv.NewWriter(c)
+	_ = w.Write([]string{"id", "date", "action", "actorId", "targetId", "ip", "ipLocation",
+		"appName", "appVersion", "deviceOs", "deviceModel", "details"})
+	for _, l := range result {
+		_ = w.Write([]string{
+			strconv.FormatInt(l.Id, 10),
+			l.Date.Format(time.RFC3339),
+			string(l.Action),
+			strconv.FormatInt(l.ActorId, 10),
+			strconv.FormatInt(l.TargetId, 10),
+			l.Ip,
+			l.IpLocation,
+			l.AppName,
+			l.AppVersion,
+			l.DeviceOs,
+			l.DeviceModel,
+			l.Details,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"downloader_gochat/internal/service"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type IDataExportHandler interface {
+	RequestDataExport(c *fiber.Ctx) error
+	GetDataExports(c *fiber.Ctx) error
+	DownloadDataExport(c *fiber.Ctx) error
+}
+
+type DataExportHandler struct {
+	dataExportService service.IDataExportService
+}
+
+func NewDataExportHandler(dataExportService service.IDataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		dataExportService: dataExportService,
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// RequestDataExport godoc
+//
+//	@Summary		Request Data Export
+//	@Description	Start exporting all data of the user (profile, settings, sessions, follows, chats, media, notifications, bots and movie interactions).
+//	@Description	a zip of json files is created in background and its download link is sent by email, the link expires in 7 days.
+//	@Description	only one export can be in progress, and it can be requested once per day.
+//	@Description	limited to 2 call per minute
+//	@Tags			User
+//	@Success		200			{object}	model.DataExport
+//	@Failure		401,409,429	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/dataExport [post]
+func (h *DataExportHandler) RequestDataExport(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.dataExportService.RequestDataExport(jwtUserData.UserId, service.NewAuditContext(c))
+	if err != nil {
+		if err.Error() == response.DataExportInProgress {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		} else if err.Error() == response.DataExportRequestedRecently {
+			return response.ResponseError(c, err.Error(), fiber.StatusTooManyRequests)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// GetDataExports godoc
+//
+//	@Summary		Data Exports
+//	@Description	Return the last exports of the user and their status, newest first.
+//	@Tags			User
+//	@Success		200		{object}	[]model.DataExport
+//	@Failure		401,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/dataExport [get]
+func (h *DataExportHandler) GetDataExports(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.dataExportService.GetDataExports(jwtUserData.UserId)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// DownloadDataExport godoc
+//
+//	@Summary		Download Data Export (Internal Usage)
+//	@Description	download link of the export that is sent to user by email, redirects to a short-lived url of the zip file.
+//	@Description	limited to 2 call per minute
+//	@Tags			User
+//	@Param			userId			path		integer	true	"userId"
+//	@Param			token			path		string	true	"data export token"
+//	@Success		302
+//	@Failure		400,404,410,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/dataExport/download/:userId/:token [get]
+func (h *DataExportHandler) DownloadDataExport(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+	token := c.Params("token", "")
+	if token == "" {
+		return response.ResponseError(c, "token cannot be empty", fiber.StatusBadRequest)
+	}
+
+	fileUrl, err := h.dataExportService.GetDataExportDownloadUrl(int64(userId), token, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.InvalidToken, fiber.StatusNotFound)
+		} else if err.Error() == response.DataExportExpired {
+			return response.ResponseError(c, err.Error(), fiber.StatusGone)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return c.Redirect(fileUrl, fiber.StatusFound)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"downloader_gochat/internal/service"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type IFollowSuggestionHandler interface {
+	GetFollowSuggestions(c *fiber.Ctx) error
+	DismissFollowSuggestion(c *fiber.Ctx) error
+}
+
+type FollowSuggestionHandler struct {
+	followSuggestionService service.IFollowSuggestionService
+}
+
+func NewFollowSuggestionHandler(followSuggestionService service.IFollowSuggestionService) *FollowSuggestionHandler {
+	return &FollowSuggestionHandler{
+		followSuggestionService: followSuggestionService,
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetFollowSuggestions godoc
+//
+//	@Summary		Follow Suggestions
+//	@Description	Return people you may know, scored by followings of your followings, common favorite genres and common followed/liked movies.
+//	@Description	suggestions are computed in background, the first call of a new user may return empty list, try again after a minute.
+//	@Tags			User-Follow
+//	@Param			skip		path		integer	true	"skip"
+//	@Param			limit		path		integer	true	"limit"
+//	@Success		200			{object}	[]model.FollowSuggestionDataModel
+//	@Failure		400,401,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/followSuggestions/:skip/:limit [get]
+func (h *FollowSuggestionHandler) GetFollowSuggestions(c *fiber.Ctx) error {
+	skip, err := c.ParamsInt("skip", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if skip < 0 {
+		return response.ResponseError(c, "skip cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+	limit, err := c.ParamsInt("limit", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if limit < 1 {
+		return response.ResponseError(c, "limit cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.followSuggestionService.GetFollowSuggestions(jwtUserData.UserId, skip, limit)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// DismissFollowSuggestion godoc
+//
+//	@Summary		Dismiss Follow Suggestion
+//	@Description	Remove userId from your follow suggestions, it will not be suggested again
+//	@Tags			User-Follow
+//	@Param			userId			path		integer	true	"id of the suggested user"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/followSuggestions/dismiss/:userId [put]
+func (h *FollowSuggestionHandler) DismissFollowSuggestion(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	if int64(userId) == jwtUserData.UserId {
+		return response.ResponseError(c, "userId cannot be your own id", fiber.StatusBadRequest)
+	}
+	err = h.followSuggestionService.DismissFollowSuggestion(jwtUserData.UserId, int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
@@ -17,6 +17,8 @@ import (
 
 type IMediaHandler interface {
 	UploadFile(c *fiber.Ctx) error
+	CreatePresignedUpload(c *fiber.Ctx) error
+	ConfirmUpload(c *fiber.Ctx) error
 }
 
 type MediaHandler struct {
@@ -60,18 +62,111 @@ func (m *MediaHandler) UploadFile(c *fiber.Ctx) error {
 
 	contentType := file.Header["Content-Type"][0]
 
-	//---------------------------------------------
+	if errorMessage := checkMediaFileLimits(file.Filename, contentType, file.Size); errorMessage != "" {
+		return response.ResponseError(c, errorMessage, fiber.StatusBadRequest)
+	}
+
+	buffer, err := file.Open()
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	defer buffer.Close()
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := m.mediaService.UploadFile(jwtUserData.UserId, &req, contentType, file.Size, file.Filename, buffer)
+	if err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return response.ResponseError(c, "Receiver User Not Found", fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// CreatePresignedUpload godoc
+//
+//	@Summary		Presigned Upload
+//	@Description	get a presigned url to upload media file directly to storage, call confirmUpload after uploading.
+//	@Description	the returned headers must be sent with the upload request, they include the sha256 checksum of the file and storage rejects content that doesn't match it.
+//	@Tags			User-Chat
+//	@Param			user		body		model.PresignedUploadReq	true	"file data"
+//	@Success		200			{object}	model.PresignedUploadRes
+//	@Failure		400,401		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/media/presignedUpload [post]
+func (m *MediaHandler) CreatePresignedUpload(c *fiber.Ctx) error {
+	var req model.PresignedUploadReq
+	err := c.BodyParser(&req)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := req.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+	if errorMessage := checkMediaFileLimits(req.FileName, req.ContentType, req.Size); errorMessage != "" {
+		return response.ResponseError(c, errorMessage, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := m.mediaService.CreatePresignedUpload(jwtUserData.UserId, &req)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// ConfirmUpload godoc
+//
+//	@Summary		Confirm Upload
+//	@Description	verify the file uploaded with presigned url and share it in chat
+//	@Tags			User-Chat
+//	@Param			user		body		model.ConfirmUploadReq	true	"upload data"
+//	@Success		200			{object}	model.MediaFile
+//	@Failure		400,401,404	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/media/confirmUpload [post]
+func (m *MediaHandler) ConfirmUpload(c *fiber.Ctx) error {
+	var req model.ConfirmUploadReq
+	err := c.BodyParser(&req)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := req.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := m.mediaService.ConfirmUpload(jwtUserData.UserId, &req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return response.ResponseError(c, "Receiver User Not Found", fiber.StatusNotFound)
+		} else if err.Error() == response.UploadNotFound {
+			return response.ResponseError(c, response.UploadNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.InvalidUploadedFile {
+			return response.ResponseError(c, response.InvalidUploadedFile, fiber.StatusBadRequest)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func checkMediaFileLimits(fileName string, contentType string, fileSize int64) string {
 	dbconfig := configs.GetDbConfigs()
-	if file.Size == 0 {
-		return response.ResponseError(c, "File is empty", fiber.StatusBadRequest)
+	if fileSize == 0 {
+		return "File is empty"
 	}
 
-	if file.Size > dbconfig.MediaFileSizeLimit*1024*1024 {
-		return response.ResponseError(c, fmt.Sprintf("File size exceeds the limit (%vmb)", dbconfig.MediaFileSizeLimit), fiber.StatusBadRequest)
+	if fileSize > dbconfig.MediaFileSizeLimit*1024*1024 {
+		return fmt.Sprintf("File size exceeds the limit (%vmb)", dbconfig.MediaFileSizeLimit)
 	}
 
 	allowedExts := strings.Split(dbconfig.MediaFileExtensionLimit, ",")
-	ext := filepath.Ext(file.Filename)
+	ext := filepath.Ext(fileName)
 	validExtension := false
 	for _, allowedExt := range allowedExts {
 		if ext == "."+strings.TrimSpace(allowedExt) {
@@ -80,9 +175,13 @@ func (m *MediaHandler) UploadFile(c *fiber.Ctx) error {
 		}
 	}
 	if !validExtension {
-		return response.ResponseError(c, "Invalid file extension", fiber.StatusBadRequest)
+		return "Invalid file extension"
 	}
-	ext = strings.Split(contentType, "/")[1]
+	contentTypeParts := strings.Split(contentType, "/")
+	if len(contentTypeParts) < 2 {
+		return "Invalid file extension"
+	}
+	ext = contentTypeParts[1]
 	validExtension = false
 	for _, allowedExt := range allowedExts {
 		if ext == strings.TrimSpace(allowedExt) {
@@ -91,23 +190,7 @@ func (m *MediaHandler) UploadFile(c *fiber.Ctx) error {
 		}
 	}
 	if !validExtension {
-		return response.ResponseError(c, "Invalid file extension", fiber.StatusBadRequest)
-	}
-	//---------------------------------------------
-
-	buffer, err := file.Open()
-	if err != nil {
-		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
-	}
-	defer buffer.Close()
-
-	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	result, err := m.mediaService.UploadFile(jwtUserData.UserId, &req, contentType, file.Size, file.Filename, buffer)
-	if err != nil {
-		if errors.Is(err, gorm.ErrForeignKeyViolated) {
-			return response.ResponseError(c, "Receiver User Not Found", fiber.StatusNotFound)
-		}
-		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+		return "Invalid file extension"
 	}
-	return response.ResponseOKWithData(c, result)
+	return ""
 }
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"downloader_gochat/cloudStorage"
+	"downloader_gochat/pkg/response"
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IStorageHandler interface {
+	GetFile(c *fiber.Ctx) error
+	PutFile(c *fiber.Ctx) error
+}
+
+// StorageHandler serves the objects of cloudStorage.LocalStorage, only registered when local storage is used
+type StorageHandler struct {
+	localStorage *cloudStorage.LocalStorage
+}
+
+func NewStorageHandler(localStorage *cloudStorage.LocalStorage) *StorageHandler {
+	return &StorageHandler{
+		localStorage: localStorage,
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetFile godoc
+//
+//	@Summary		Get File
+//	@Description	serve files of local storage, private files need the signature from signed url
+//	@Tags			Storage
+//	@Param			bucketName	path		string	true	"bucket name"
+//	@Param			fileName	path		string	true	"file name"
+//	@Param			expires		query		integer	false	"signed url expire time"
+//	@Param			signature	query		string	false	"signed url signature"
+//	@Success		200			{file}		binary
+//	@Failure		403,404		{object}	response.ResponseErrorModel
+//	@Router			/storage/:bucketName/:fileName [get]
+func (s *StorageHandler) GetFile(c *fiber.Ctx) error {
+	bucketName := c.Params("bucketName")
+	fileName := c.Params("fileName")
+
+	if s.localStorage.IsPrivateBucket(bucketName) {
+		err := s.localStorage.VerifySignature(fiber.MethodGet, bucketName, fileName, c.Query("expires"), "", "", "", c.Query("signature"))
+		if err != nil {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+	}
+
+	filePath, err := s.localStorage.FilePath(bucketName, fileName)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if _, err = os.Stat(filePath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return response.ResponseError(c, "File not found", fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	c.Set(fiber.HeaderContentType, s.localStorage.ContentType(bucketName, fileName))
+	return c.SendFile(filePath)
+}
+
+// PutFile godoc
+//
+//	@Summary		Put File
+//	@Description	upload file to local storage with url from presigned upload, the headers returned with url must be sent
+//	@Tags			Storage
+//	@Param			bucketName	path		string	true	"bucket name"
+//	@Param			fileName	path		string	true	"file name"
+//	@Param			expires		query		integer	true	"signed url expire time"
+//	@Param			contentType	query		string	true	"signed content type"
+//	@Param			size		query		integer	true	"signed file size"
+//	@Param			checksum	query		string	false	"signed base64 sha256 of the file"
+//	@Param			signature	query		string	true	"signed url signature"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,403		{object}	response.ResponseErrorModel
+//	@Router			/storage/:bucketName/:fileName [put]
+func (s *StorageHandler) PutFile(c *fiber.Ctx) error {
+	bucketName := c.Params("bucketName")
+	fileName := c.Params("fileName")
+	contentType := c.Query("contentType")
+	size := c.Query("size")
+	checksum := c.Query("checksum")
+
+	err := s.localStorage.VerifySignature(fiber.MethodPut, bucketName, fileName, c.Query("expires"), contentType, size, checksum, c.Query("signature"))
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+	}
+	if c.Get(fiber.HeaderContentType) != contentType {
+		return response.ResponseError(c, "Content-Type does not match the signed value", fiber.StatusBadRequest)
+	}
+	body := c.Body()
+	if strconv.Itoa(len(body)) != size {
+		return response.ResponseError(c, "Content-Length does not match the signed value", fiber.StatusBadRequest)
+	}
+
+	err = s.localStorage.SaveFile(bucketName, fileName, contentType, checksum, bytes.NewReader(body))
+	if err != nil {
+		if errors.Is(err, cloudStorage.LocalStorageChecksumMismatch) {
+			return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOK(c, "")
+}
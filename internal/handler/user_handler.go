@@ -1,15 +1,20 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
 	"downloader_gochat/configs"
 	"downloader_gochat/internal/service"
 	"downloader_gochat/model"
 	"downloader_gochat/pkg/response"
 	"downloader_gochat/util"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +25,23 @@ import (
 type IUserHandler interface {
 	RegisterUser(c *fiber.Ctx) error
 	Login(c *fiber.Ctx) error
+	LoginTwoFactor(c *fiber.Ctx) error
+	UnlockAccount(c *fiber.Ctx) error
+	SendMagicLink(c *fiber.Ctx) error
+	LoginMagicLink(c *fiber.Ctx) error
+	OpenMagicLink(c *fiber.Ctx) error
+	GetOidcProviders(c *fiber.Ctx) error
+	StartOidcLogin(c *fiber.Ctx) error
+	LoginOidc(c *fiber.Ctx) error
+	OidcCallback(c *fiber.Ctx) error
+	LinkOidcProvider(c *fiber.Ctx) error
+	GetUserIdentities(c *fiber.Ctx) error
+	UnlinkUserIdentity(c *fiber.Ctx) error
+	StartDevicePairing(c *fiber.Ctx) error
+	WaitDevicePairing(c *fiber.Ctx) error
+	CompleteDevicePairing(c *fiber.Ctx) error
+	GetDevicePairing(c *fiber.Ctx) error
+	AnswerDevicePairing(c *fiber.Ctx) error
 	GetToken(c *fiber.Ctx) error
 	LogOut(c *fiber.Ctx) error
 	ForceLogoutDevice(c *fiber.Ctx) error
@@ -29,18 +51,37 @@ type IUserHandler interface {
 	UnFollowUser(c *fiber.Ctx) error
 	GetUserFollowers(c *fiber.Ctx) error
 	GetUserFollowings(c *fiber.Ctx) error
+	GetMutualFollowers(c *fiber.Ctx) error
+	SetPrivateAccount(c *fiber.Ctx) error
+	GetFollowRequests(c *fiber.Ctx) error
+	ApproveFollowRequest(c *fiber.Ctx) error
+	DeclineFollowRequest(c *fiber.Ctx) error
+	CancelFollowRequest(c *fiber.Ctx) error
+	SearchUsers(c *fiber.Ctx) error
 	GetUserNotifications(c *fiber.Ctx) error
 	GetUserSettings(c *fiber.Ctx) error
 	UpdateUserSettings(c *fiber.Ctx) error
 	UpdateUserFavoriteGenres(c *fiber.Ctx) error
 	GetActiveSessions(c *fiber.Ctx) error
+	GetUserDevices(c *fiber.Ctx) error
+	UpdateDeviceTrusted(c *fiber.Ctx) error
+	GetLoginHistory(c *fiber.Ctx) error
 	GetUserProfile(c *fiber.Ctx) error
 	EditUserProfile(c *fiber.Ctx) error
+	ChangeEmail(c *fiber.Ctx) error
+	ConfirmEmailChange(c *fiber.Ctx) error
+	RevertEmailChange(c *fiber.Ctx) error
 	UpdateUserPassword(c *fiber.Ctx) error
+	ForgetPassword(c *fiber.Ctx) error
+	ResetPassword(c *fiber.Ctx) error
 	SendVerifyEmail(c *fiber.Ctx) error
 	VerifyEmail(c *fiber.Ctx) error
 	SendDeleteAccount(c *fiber.Ctx) error
 	DeleteUserAccount(c *fiber.Ctx) error
+	SetupTwoFactor(c *fiber.Ctx) error
+	ConfirmTwoFactor(c *fiber.Ctx) error
+	DisableTwoFactor(c *fiber.Ctx) error
+	RegenerateTwoFactorRecoveryCodes(c *fiber.Ctx) error
 	UploadProfileImage(c *fiber.Ctx) error
 	RemoveProfileImage(c *fiber.Ctx) error
 }
@@ -103,22 +144,7 @@ func (h *UserHandler) RegisterUser(c *fiber.Ctx) error {
 	}
 
 	if !noCookie {
-		cookieDomain := "." + configs.GetConfigs().Domain
-		if cookieDomain == ".localhost" {
-			cookieDomain = ""
-		}
-		c.Cookie(&fiber.Cookie{
-			Name:        "refreshToken",
-			Value:       result.Token.RefreshToken,
-			Path:        "/",
-			Expires:     time.Now().Add(time.Duration(configs.GetConfigs().RefreshTokenExpireDay) * 24 * time.Hour),
-			Secure:      true,
-			HTTPOnly:    true,
-			Domain:      cookieDomain,
-			SameSite:    "none",
-			SessionOnly: false,
-		})
-		result.Token.RefreshToken = ""
+		setAuthCookies(c, &result.Token)
 	}
 
 	return response.ResponseCreated(c, result)
@@ -128,11 +154,16 @@ func (h *UserHandler) RegisterUser(c *fiber.Ctx) error {
 //
 //	@Summary		Login user
 //	@Description	Login with provided credentials
+//	@Description	if account has two-factor authentication, response has 'twoFactorRequired' and 'challengeToken' without tokens,
+//	@Description	login must be completed with /v1/user/login/twoFactor in 5 minutes
+//	@Description	login from a new device or location sends an alert, send 'deviceInfo.fingerprint' for better device detection
+//	@Description	failed logins slow down the next attempts (429), and too many of them lock the account (423) until
+//	@Description	the unlock link from email is opened. devices with fingerprint that have logged in before can still login.
 //	@Tags			User-Auth
 //	@Param			noCookie	query		bool					true	"return refreshToken in response body instead of saving in cookie"
 //	@Param			user		body		model.LoginViewModel	true	"User object"
 //	@Success		200			{object}	model.UserViewModel
-//	@Failure		400			{object}	response.ResponseErrorModel
+//	@Failure		400,401,403,404,423,429,500	{object}	response.ResponseErrorModel
 //	@Router			/v1/user/login [post]
 func (h *UserHandler) Login(c *fiber.Ctx) error {
 	var loginVM model.LoginViewModel
@@ -156,29 +187,85 @@ func (h *UserHandler) Login(c *fiber.Ctx) error {
 
 	result, err := h.userService.LoginUser(&loginVM, ip)
 	if err != nil {
+		if isSuspensionError(err) {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		if err.Error() == response.UserPassNotMatch {
+			return response.ResponseError(c, err.Error(), fiber.StatusUnauthorized)
+		}
+		if err.Error() == response.TooManyLoginAttempts {
+			return response.ResponseError(c, err.Error(), fiber.StatusTooManyRequests)
+		}
+		if err.Error() == response.AccountLocked {
+			return response.ResponseError(c, err.Error(), fiber.StatusLocked)
+		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 	if result == nil {
 		return response.ResponseError(c, "Cannot find user", fiber.StatusNotFound)
 	}
+	if result.TwoFactorRequired {
+		return response.ResponseOKWithData(c, result)
+	}
+
+	if !noCookie {
+		setAuthCookies(c, &result.Token)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// LoginTwoFactor godoc
+//
+//	@Summary		Login Two-Factor
+//	@Description	second step of login for accounts with two-factor authentication.
+//	@Description	challengeToken is returned from /v1/user/login, it can be tried 5 times in 5 minutes.
+//	@Description	code is the 6 digit code from authenticator app or one of the recovery codes.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			noCookie	query		bool					true	"return refreshToken in response body instead of saving in cookie"
+//	@Param			user		body		model.TwoFactorLoginReq	true	"challenge token and code"
+//	@Success		200			{object}	model.UserViewModel
+//	@Failure		400,401,403,423,429,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/login/twoFactor [post]
+func (h *UserHandler) LoginTwoFactor(c *fiber.Ctx) error {
+	var loginReq model.TwoFactorLoginReq
+	err := c.BodyParser(&loginReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	noCookie := c.QueryBool("noCookie", false)
+
+	validation := loginReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	ip := c.IP()
+	ips := c.IPs()
+	if len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	result, err := h.userService.LoginTwoFactor(&loginReq, ip)
+	if err != nil {
+		if isSuspensionError(err) {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		if err.Error() == response.InvalidToken || err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusUnauthorized)
+		}
+		if err.Error() == response.TooManyLoginAttempts {
+			return response.ResponseError(c, err.Error(), fiber.StatusTooManyRequests)
+		}
+		if err.Error() == response.AccountLocked {
+			return response.ResponseError(c, err.Error(), fiber.StatusLocked)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
 
 	if !noCookie {
-		cookieDomain := "." + configs.GetConfigs().Domain
-		if cookieDomain == ".localhost" {
-			cookieDomain = ""
-		}
-		c.Cookie(&fiber.Cookie{
-			Name:        "refreshToken",
-			Value:       result.Token.RefreshToken,
-			Path:        "/",
-			Expires:     time.Now().Add(time.Duration(configs.GetConfigs().RefreshTokenExpireDay) * 24 * time.Hour),
-			Secure:      true,
-			HTTPOnly:    true,
-			Domain:      cookieDomain,
-			SameSite:    "none",
-			SessionOnly: false,
-		})
-		result.Token.RefreshToken = ""
+		setAuthCookies(c, &result.Token)
 	}
 
 	return response.ResponseOKWithData(c, result)
@@ -188,12 +275,13 @@ func (h *UserHandler) Login(c *fiber.Ctx) error {
 //
 //	@Summary		Get Token
 //	@Description	Get new Tokens, also return `refreshToken`
+//	@Description	refreshToken is rotated, using an old refreshToken again revokes the session of that device and the user gets notified by email
 //	@Tags			User-Auth
 //	@Param			noCookie		query		bool				false	"return refreshToken in response body instead of saving in cookie"
 //	@Param			profileImages	query		bool				false	"also return profile images, slower response"
 //	@Param			user			body		model.DeviceInfo	true	"Device Info"
 //	@Success		200				{object}	model.UserViewModel
-//	@Failure		400,401			{object}	response.ResponseErrorModel
+//	@Failure		400,401,403		{object}	response.ResponseErrorModel
 //	@Security		BearerAuth
 //	@Router			/v1/user/getToken [put]
 func (h *UserHandler) GetToken(c *fiber.Ctx) error {
@@ -222,27 +310,16 @@ func (h *UserHandler) GetToken(c *fiber.Ctx) error {
 	result, token, err := h.userService.GetToken(&deviceInfo, refreshToken, jwtUserData, addProfileImages, ip)
 
 	if !noCookie && token != nil {
-		cookieDomain := "." + configs.GetConfigs().Domain
-		if cookieDomain == ".localhost" {
-			cookieDomain = ""
-		}
-		c.Cookie(&fiber.Cookie{
-			Name:        "refreshToken",
-			Value:       token.RefreshToken,
-			Path:        "/",
-			Expires:     time.Now().Add(time.Duration(configs.GetConfigs().RefreshTokenExpireDay) * 24 * time.Hour),
-			Secure:      true,
-			HTTPOnly:    true,
-			Domain:      cookieDomain,
-			SameSite:    "none",
-			SessionOnly: false,
-		})
+		setRefreshTokenCookie(c, token.RefreshToken)
 		if result != nil {
 			result.Token.RefreshToken = ""
 		}
 	}
 
 	if err != nil {
+		if isSuspensionError(err) {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 	if result == nil {
@@ -274,22 +351,7 @@ func (h *UserHandler) LogOut(c *fiber.Ctx) error {
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 
-	cookieDomain := "." + configs.GetConfigs().Domain
-	if cookieDomain == ".localhost" {
-		cookieDomain = ""
-	}
-	c.Cookie(&fiber.Cookie{
-		Name:        "refreshToken",
-		Value:       "",
-		Path:        "/",
-		MaxAge:      -1,
-		Expires:     time.Now(),
-		Secure:      true,
-		HTTPOnly:    true,
-		Domain:      cookieDomain,
-		SameSite:    "none",
-		SessionOnly: false,
-	})
+	removeAuthCookies(c)
 
 	return response.ResponseOK(c, "")
 }
@@ -327,7 +389,9 @@ func (h *UserHandler) ForceLogoutDevice(c *fiber.Ctx) error {
 //
 //	@Summary		Force Logout All
 //	@Description	force logout all session except current session
+//	@Description	needs a fresh two-factor code when two-factor authentication is enabled
 //	@Tags			User-Auth
+//	@Param			twoFactorCode		header		string	false	"totp code or recovery code, required when two-factor is enabled"
 //	@Success		200					{object}	response.ResponseOKModel
 //	@Failure		400,401,403,404,500	{object}	response.ResponseErrorModel
 //	@Security		BearerAuth
@@ -335,11 +399,13 @@ func (h *UserHandler) ForceLogoutDevice(c *fiber.Ctx) error {
 func (h *UserHandler) ForceLogoutAll(c *fiber.Ctx) error {
 	refreshToken := c.Locals("refreshToken").(string)
 	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	err := h.userService.ForceLogoutAll(c, jwtUserData, refreshToken)
+	err := h.userService.ForceLogoutAll(c, jwtUserData, refreshToken, c.Get("twoFactorCode", ""))
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return response.ResponseError(c, "Cannot find device", fiber.StatusNotFound)
+		} else if err.Error() == response.TwoFactorCodeRequired || err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
 		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
@@ -383,7 +449,8 @@ func (h *UserHandler) SetNotifToken(c *fiber.Ctx) error {
 // FollowUser godoc
 //
 //	@Summary		Follow User
-//	@Description	Add followId user to your following list
+//	@Description	Add followId user to your following list.
+//	@Description	if the user has private account a follow request is sent instead, message of response is 'Follow request sent'.
 //	@Tags			User-Follow
 //	@Param			followId		path		integer	true	"id on the user want to follow"
 //	@Success		200				{object}	response.ResponseOKModel
@@ -400,16 +467,21 @@ func (h *UserHandler) FollowUser(c *fiber.Ctx) error {
 	}
 
 	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	err = h.userService.FollowUser(jwtUserData, int64(followId))
+	requested, err := h.userService.FollowUser(jwtUserData, int64(followId))
 	if err != nil {
-		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) || errors.Is(err, gorm.ErrRecordNotFound) {
 			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
 		} else if err.Error() == "duplicated key not allowed" {
 			return response.ResponseError(c, response.AlreadyFollowed, fiber.StatusConflict)
+		} else if err.Error() == response.FollowRequestAlreadySent {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
 		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 
+	if requested {
+		return response.ResponseOK(c, "Follow request sent")
+	}
 	return response.ResponseOK(c, "")
 }
 
@@ -447,13 +519,13 @@ func (h *UserHandler) UnFollowUser(c *fiber.Ctx) error {
 // GetUserFollowers godoc
 //
 //	@Summary		Followers
-//	@Description	get user followers
+//	@Description	get user followers, followers of private accounts are only visible to their followers
 //	@Tags			User-Follow
-//	@Param			userId	path		integer	true	"id of user"
-//	@Param			skip	path		integer	true	"skip"
-//	@Param			limit	path		integer	true	"limit"
-//	@Success		200		{object}	model.FollowUserDataModel
-//	@Failure		400,500	{object}	response.ResponseErrorModel
+//	@Param			userId		path		integer	true	"id of user"
+//	@Param			skip		path		integer	true	"skip"
+//	@Param			limit		path		integer	true	"limit"
+//	@Success		200			{object}	model.FollowUserDataModel
+//	@Failure		400,403,500	{object}	response.ResponseErrorModel
 //	@Security		BearerAuth
 //	@Router			/v1/user/followers/:userId/:skip/:limit [get]
 func (h *UserHandler) GetUserFollowers(c *fiber.Ctx) error {
@@ -479,8 +551,12 @@ func (h *UserHandler) GetUserFollowers(c *fiber.Ctx) error {
 		return response.ResponseError(c, "limit cannot be smaller than 1", fiber.StatusBadRequest)
 	}
 
-	result, err := h.userService.GetUserFollowers(int64(userId), skip, limit)
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.GetUserFollowers(jwtUserData.UserId, int64(userId), skip, limit)
 	if err != nil {
+		if err.Error() == response.PrivateAccount {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 	return response.ResponseOKWithData(c, result)
@@ -489,13 +565,13 @@ func (h *UserHandler) GetUserFollowers(c *fiber.Ctx) error {
 // GetUserFollowings godoc
 //
 //	@Summary		Followings
-//	@Description	get user followings
+//	@Description	get user followings, followings of private accounts are only visible to their followers
 //	@Tags			User-Follow
-//	@Param			userId	path		integer	true	"id of user"
-//	@Param			skip	path		integer	true	"skip"
-//	@Param			limit	path		integer	true	"limit"
-//	@Success		200		{object}	model.FollowUserDataModel
-//	@Failure		400,500	{object}	response.ResponseErrorModel
+//	@Param			userId		path		integer	true	"id of user"
+//	@Param			skip		path		integer	true	"skip"
+//	@Param			limit		path		integer	true	"limit"
+//	@Success		200			{object}	model.FollowUserDataModel
+//	@Failure		400,403,500	{object}	response.ResponseErrorModel
 //	@Security		BearerAuth
 //	@Router			/v1/user/followings/:userId/:skip/:limit [get]
 func (h *UserHandler) GetUserFollowings(c *fiber.Ctx) error {
@@ -521,7 +597,244 @@ func (h *UserHandler) GetUserFollowings(c *fiber.Ctx) error {
 		return response.ResponseError(c, "limit cannot be smaller than 1", fiber.StatusBadRequest)
 	}
 
-	result, err := h.userService.GetUserFollowings(int64(userId), skip, limit)
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.GetUserFollowings(jwtUserData.UserId, int64(userId), skip, limit)
+	if err != nil {
+		if err.Error() == response.PrivateAccount {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// GetMutualFollowers godoc
+//
+//	@Summary		Mutual Followers
+//	@Description	get your followings that follow the user, they are only visible to followers of private accounts
+//	@Tags			User-Follow
+//	@Param			userId		path		integer	true	"id of user"
+//	@Param			skip		path		integer	true	"skip"
+//	@Param			limit		path		integer	true	"limit"
+//	@Success		200			{object}	[]model.FollowUserDataModel
+//	@Failure		400,403,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/mutualFollowers/:userId/:skip/:limit [get]
+func (h *UserHandler) GetMutualFollowers(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+	skip, err := c.ParamsInt("skip", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if skip < 0 {
+		return response.ResponseError(c, "skip cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+	limit, err := c.ParamsInt("limit", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if limit < 1 {
+		return response.ResponseError(c, "limit cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.GetMutualFollowers(jwtUserData.UserId, int64(userId), skip, limit)
+	if err != nil {
+		if err.Error() == response.PrivateAccount {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// SetPrivateAccount godoc
+//
+//	@Summary		Private Account
+//	@Description	Enable or disable private account. followers, followings and profile details of private accounts are hidden from non-followers
+//	@Description	and new follows become follow requests. pending requests are accepted when private account is disabled.
+//	@Tags			User-Follow
+//	@Param			enabled		path		bool	true	"enable private account"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,401,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/privateAccount/:enabled [put]
+func (h *UserHandler) SetPrivateAccount(c *fiber.Ctx) error {
+	enabled, err := strconv.ParseBool(c.Params("enabled", ""))
+	if err != nil {
+		return response.ResponseError(c, "enabled must be true or false", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.SetPrivateAccount(jwtUserData.UserId, enabled)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// GetFollowRequests godoc
+//
+//	@Summary		Follow Requests
+//	@Description	get incoming follow requests of the user, newest first
+//	@Tags			User-Follow
+//	@Param			skip		path		integer	true	"skip"
+//	@Param			limit		path		integer	true	"limit"
+//	@Success		200			{object}	[]model.FollowRequestDataModel
+//	@Failure		400,401,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/followRequests/:skip/:limit [get]
+func (h *UserHandler) GetFollowRequests(c *fiber.Ctx) error {
+	skip, err := c.ParamsInt("skip", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if skip < 0 {
+		return response.ResponseError(c, "skip cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+	limit, err := c.ParamsInt("limit", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if limit < 1 {
+		return response.ResponseError(c, "limit cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.GetFollowRequests(jwtUserData.UserId, skip, limit)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// ApproveFollowRequest godoc
+//
+//	@Summary		Approve Follow Request
+//	@Description	Accept follow request of requesterId, the requester becomes a follower and gets notified
+//	@Tags			User-Follow
+//	@Param			requesterId		path		integer	true	"id of the user that sent the request"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/followRequests/approve/:requesterId [put]
+func (h *UserHandler) ApproveFollowRequest(c *fiber.Ctx) error {
+	requesterId, err := c.ParamsInt("requesterId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if requesterId < 1 {
+		return response.ResponseError(c, "requesterId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.ApproveFollowRequest(jwtUserData.UserId, int64(requesterId))
+	if err != nil {
+		if err.Error() == response.FollowRequestNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// DeclineFollowRequest godoc
+//
+//	@Summary		Decline Follow Request
+//	@Description	Remove follow request of requesterId
+//	@Tags			User-Follow
+//	@Param			requesterId		path		integer	true	"id of the user that sent the request"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/followRequests/decline/:requesterId [delete]
+func (h *UserHandler) DeclineFollowRequest(c *fiber.Ctx) error {
+	requesterId, err := c.ParamsInt("requesterId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if requesterId < 1 {
+		return response.ResponseError(c, "requesterId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.DeclineFollowRequest(jwtUserData.UserId, int64(requesterId))
+	if err != nil {
+		if err.Error() == response.FollowRequestNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// CancelFollowRequest godoc
+//
+//	@Summary		Cancel Follow Request
+//	@Description	Remove the follow request that is sent to followId
+//	@Tags			User-Follow
+//	@Param			followId		path		integer	true	"id of the user that the request is sent to"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/followRequests/cancel/:followId [delete]
+func (h *UserHandler) CancelFollowRequest(c *fiber.Ctx) error {
+	followId, err := c.ParamsInt("followId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if followId < 1 {
+		return response.ResponseError(c, "followId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.CancelFollowRequest(jwtUserData.UserId, int64(followId))
+	if err != nil {
+		if err.Error() == response.FollowRequestNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// SearchUsers godoc
+//
+//	@Summary		Search Users
+//	@Description	Search users by start of username and publicName, queries with 3 or more characters also match similar names.
+//	@Description	mutual follows come first, then followings, then prefix matches, then the most similar ones.
+//	@Description	banned and suspended users are not returned.
+//	@Tags			User-Follow
+//	@Param			query		query		string	true	"username or publicName"
+//	@Param			skip		query		integer	true	"skip"
+//	@Param			limit		query		integer	true	"limit"
+//	@Success		200			{object}	[]model.UserSearchDataModel
+//	@Failure		400,401,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/search [get]
+func (h *UserHandler) SearchUsers(c *fiber.Ctx) error {
+	var params model.UserSearchReq
+	err := c.QueryParser(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	validation := params.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.SearchUsers(jwtUserData.UserId, &params)
 	if err != nil {
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
@@ -693,42 +1006,131 @@ func (h *UserHandler) GetActiveSessions(c *fiber.Ctx) error {
 	return response.ResponseOKWithData(c, result)
 }
 
-//------------------------------------------
-//------------------------------------------
+// GetUserDevices godoc
+//
+//	@Summary		Known Devices
+//	@Description	Return devices that user has logged in with. login from a device that is not in this list
+//	@Description	or from a new location sends an alert email and security notification.
+//	@Description	trusted devices don't trigger the new location alert.
+//	@Tags			User-Auth
+//	@Success		200		{object}	[]model.UserDeviceDataModel
+//	@Failure		500		{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/devices [get]
+func (h *UserHandler) GetUserDevices(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.GetUserDevices(jwtUserData.UserId)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
 
-// GetUserProfile godoc
+// UpdateDeviceTrusted godoc
 //
-//	@Summary		Profile Data
-//	@Description	Return users profile data. if dont provide userId, return current user profile
-//	@Tags			User
-//	@Param			userId						query		integer	false	"userId"
-//	@Param			loadSettings				query		bool	false	"loadSettings"
-//	@Param			loadFollowersCount			query		bool	false	"loadFollowersCount"
-//	@Param			loadDevice					query		bool	false	"loadDevice"
-//	@Param			loadProfileImages			query		bool	false	"loadProfileImages"
-//	@Param			loadComputedFavoriteGenres	query		bool	false	"loadComputedFavoriteGenres"
-//	@Param			loadRoles					query		bool	false	"loadRoles"
-//	@Param			loadRolesWithPermissions	query		bool	false	"loadRolesWithPermissions"
-//	@Param			loadTorrentUsage			query		bool	false	"loadTorrentUsage"
-//	@Success		200							{object}	model.UserProfileRes
-//	@Failure		404,500						{object}	response.ResponseErrorModel
+//	@Summary		Trust Device
+//	@Description	Mark device as trusted or untrusted, logins from trusted devices in new locations don't send alert.
+//	@Tags			User-Auth
+//	@Param			deviceKey	path		string	true	"deviceKey from devices list"
+//	@Param			trusted		path		bool	true	"trusted"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,404,500	{object}	response.ResponseErrorModel
 //	@Security		BearerAuth
-//	@Router			/v1/user/profile [get]
-func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
-	userId := int64(c.QueryInt("userId", 0))
-	loadSettings := c.QueryBool("loadSettings", false)
-	loadFollowersCount := c.QueryBool("loadFollowersCount", false)
-	loadDevice := c.QueryBool("loadDevice", false)
-	loadProfileImages := c.QueryBool("loadProfileImages", false)
-	loadComputedFavoriteGenres := c.QueryBool("loadComputedFavoriteGenres", false)
-	loadRoles := c.QueryBool("loadRoles", false)
-	loadRolesWithPermissions := c.QueryBool("loadRolesWithPermissions", false)
-	loadTorrentUsage := c.QueryBool("loadTorrentUsage", false)
+//	@Router			/v1/user/devices/trust/:deviceKey/:trusted [put]
+func (h *UserHandler) UpdateDeviceTrusted(c *fiber.Ctx) error {
+	deviceKey := c.Params("deviceKey", "")
+	if deviceKey == "" || deviceKey == ":deviceKey" {
+		return response.ResponseError(c, "Invalid deviceKey", fiber.StatusBadRequest)
+	}
+	trusted, err := strconv.ParseBool(c.Params("trusted", ""))
+	if err != nil {
+		return response.ResponseError(c, "Invalid trusted value", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.UpdateDeviceTrusted(jwtUserData.UserId, deviceKey, trusted)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.DeviceNotFound, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOK(c, "")
+}
+
+// GetLoginHistory godoc
+//
+//	@Summary		Login History
+//	@Description	Return logins of user, newest first. newDevice and newLocation show logins that sent alert.
+//	@Tags			User-Auth
+//	@Param			skip	path		integer	true	"skip"
+//	@Param			limit	path		integer	true	"limit"
+//	@Success		200		{object}	[]model.LoginHistoryDataModel
+//	@Failure		400,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/loginHistory/:skip/:limit [get]
+func (h *UserHandler) GetLoginHistory(c *fiber.Ctx) error {
+	skip, err := c.ParamsInt("skip", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if skip < 0 {
+		return response.ResponseError(c, "skip cannot be smaller than 0", fiber.StatusBadRequest)
+	}
+	limit, err := c.ParamsInt("limit", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if limit < 1 {
+		return response.ResponseError(c, "limit cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.GetLoginHistory(jwtUserData.UserId, skip, limit)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetUserProfile godoc
+//
+//	@Summary		Profile Data
+//	@Description	Return users profile data. if dont provide userId, return current user profile
+//	@Description	profile details (bio, genres, mbti, lastSeen, followers counts, ...) of private accounts are hidden from non-followers
+//	@Description	profile of other users has followsYou and a preview of mutual followers (your followings that follow the user)
+//	@Tags			User
+//	@Param			userId						query		integer	false	"userId"
+//	@Param			loadSettings				query		bool	false	"loadSettings"
+//	@Param			loadFollowersCount			query		bool	false	"loadFollowersCount"
+//	@Param			loadDevice					query		bool	false	"loadDevice"
+//	@Param			loadProfileImages			query		bool	false	"loadProfileImages"
+//	@Param			loadComputedFavoriteGenres	query		bool	false	"loadComputedFavoriteGenres"
+//	@Param			loadRoles					query		bool	false	"loadRoles"
+//	@Param			loadRolesWithPermissions	query		bool	false	"loadRolesWithPermissions"
+//	@Param			loadTorrentUsage			query		bool	false	"loadTorrentUsage"
+//	@Success		200							{object}	model.UserProfileRes
+//	@Failure		404,500						{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/profile [get]
+func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
+	userId := int64(c.QueryInt("userId", 0))
+	loadSettings := c.QueryBool("loadSettings", false)
+	loadFollowersCount := c.QueryBool("loadFollowersCount", false)
+	loadDevice := c.QueryBool("loadDevice", false)
+	loadProfileImages := c.QueryBool("loadProfileImages", false)
+	loadComputedFavoriteGenres := c.QueryBool("loadComputedFavoriteGenres", false)
+	loadRoles := c.QueryBool("loadRoles", false)
+	loadRolesWithPermissions := c.QueryBool("loadRolesWithPermissions", false)
+	loadTorrentUsage := c.QueryBool("loadTorrentUsage", false)
 
 	isSelfProfile := false
 	refreshToken := ""
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
 	if userId <= 0 {
-		jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
 		userId = jwtUserData.UserId
 		isSelfProfile = true
 		if loadDevice {
@@ -738,6 +1140,7 @@ func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
 
 	requestParams := model.UserProfileReq{
 		UserId:                     userId,
+		ViewerId:                   jwtUserData.UserId,
 		IsSelfProfile:              isSelfProfile,
 		LoadSettings:               loadSettings,
 		LoadFollowersCount:         loadFollowersCount,
@@ -793,124 +1196,922 @@ func (h *UserHandler) GetUserRolePermission(c *fiber.Ctx) error {
 	result, err := h.userService.GetUserRolePermission(&requestParams)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return response.ResponseError(c, response.SessionNotFound, fiber.StatusNotFound)
+			return response.ResponseError(c, response.SessionNotFound, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// EditUserProfile godoc
+//
+//	@Summary		Edit Profile
+//	@Description	Edit profile data.
+//	@Description	email cannot be changed here, use /v1/user/changeEmail
+//	@Tags			User
+//	@Param			user				body		model.EditProfileReq	true	"update fields"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,401,404,409,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/editUserProfile [post]
+func (h *UserHandler) EditUserProfile(c *fiber.Ctx) error {
+	var editFields model.EditProfileReq
+	err := c.BodyParser(&editFields)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := editFields.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.EditUserProfile(jwtUserData.UserId, &editFields)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	if result == nil {
+		return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+	}
+	if result.UserId == 0 {
+		return response.ResponseError(c, response.UsernameAlreadyExist, fiber.StatusConflict)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// ChangeEmail godoc
+//
+//	@Summary		Change Email
+//	@Description	send a confirmation link to the new email and a notice with a revert link to the current email.
+//	@Description	email is changed when the confirmation link is opened, the link expires in 1 hour.
+//	@Description	the revert link expires in 7 days, another change cannot be requested until then.
+//	@Description	maybe email goes to spam folder.
+//	@Description	limited to 6 call per minute
+//	@Description	needs a fresh two-factor code when two-factor authentication is enabled
+//	@Tags			User
+//	@Param			user				body		model.ChangeEmailReq	true	"new email"
+//	@Param			twoFactorCode		header		string					false	"totp code or recovery code, required when two-factor is enabled"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/changeEmail [post]
+func (h *UserHandler) ChangeEmail(c *fiber.Ctx) error {
+	var changeReq model.ChangeEmailReq
+	err := c.BodyParser(&changeReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := changeReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.RequestEmailChange(jwtUserData.UserId, changeReq.Email, c.Get("twoFactorCode", ""), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.TwoFactorCodeRequired || err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		} else if err.Error() == response.EmailAlreadyExist || err.Error() == response.EmailNotChanged || err.Error() == response.EmailChangedRecently {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// ConfirmEmailChange godoc
+//
+//	@Summary		Confirm Email Change (Internal Usage)
+//	@Description	confirm link created on server and sent to the new email, email of account is replaced with the new one.
+//	@Description	limited to 6 call per minute
+//	@Tags			User
+//	@Param			userId			path		integer	true	"userId"
+//	@Param			token			path		string	true	"email change token"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,404,409,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/changeEmail/confirm/:userId/:token [get]
+func (h *UserHandler) ConfirmEmailChange(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+	token := c.Params("token", "")
+	if token == "" {
+		return response.ResponseError(c, "token cannot be empty", fiber.StatusBadRequest)
+	}
+
+	err = h.userService.ConfirmEmailChange(int64(userId), token, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.InvalidToken, fiber.StatusNotFound)
+		} else if err.Error() == response.EmailAlreadyExist {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "email changed")
+}
+
+// RevertEmailChange godoc
+//
+//	@Summary		Revert Email Change (Internal Usage)
+//	@Description	revert link created on server and sent to the old email, cancels the change or restores the old email.
+//	@Description	all the active sessions get removed and their refreshTokens get blacklisted, user need to login again.
+//	@Description	limited to 6 call per minute
+//	@Tags			User
+//	@Param			userId			path		integer	true	"userId"
+//	@Param			token			path		string	true	"email revert token"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		400,404,409,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/changeEmail/revert/:userId/:token [get]
+func (h *UserHandler) RevertEmailChange(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+	token := c.Params("token", "")
+	if token == "" {
+		return response.ResponseError(c, "token cannot be empty", fiber.StatusBadRequest)
+	}
+
+	err = h.userService.RevertEmailChange(int64(userId), token, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.InvalidToken, fiber.StatusNotFound)
+		} else if err.Error() == response.EmailAlreadyExist {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "email change reverted")
+}
+
+// UpdateUserPassword godoc
+//
+//	@Summary		Update Password
+//	@Description	Update User Password.
+//	@Description	needs a fresh two-factor code when two-factor authentication is enabled
+//	@Tags			User
+//	@Param			passwords			body		model.UpdatePasswordReq	true	"old/new passwords"
+//	@Param			twoFactorCode		header		string					false	"totp code or recovery code, required when two-factor is enabled"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/updatePassword [put]
+func (h *UserHandler) UpdateUserPassword(c *fiber.Ctx) error {
+	var passwords model.UpdatePasswordReq
+	err := c.BodyParser(&passwords)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := passwords.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.UpdateUserPassword(jwtUserData.UserId, &passwords, c.Get("twoFactorCode", ""), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.OldPassNotMatch {
+			return response.ResponseError(c, response.OldPassNotMatch, fiber.StatusConflict)
+		} else if err.Error() == response.TwoFactorCodeRequired || err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// ForgetPassword godoc
+//
+//	@Summary		Forget Password
+//	@Description	send an email with a reset password token, the token expires in 30 minutes and can be used once.
+//	@Description	response is the same when account doesn't exist.
+//	@Description	maybe email goes to spam folder.
+//	@Description	limited to 6 call per minute, also a new email is not sent for an account in 2 minutes after the last one.
+//	@Tags			User
+//	@Param			user	body		model.ForgetPasswordReq	true	"account email"
+//	@Success		200		{object}	response.ResponseOKModel
+//	@Failure		400,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/forgetPassword [post]
+func (h *UserHandler) ForgetPassword(c *fiber.Ctx) error {
+	var forgetReq model.ForgetPasswordReq
+	err := c.BodyParser(&forgetReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := forgetReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	err = h.userService.SendResetPassword(forgetReq.Email)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// ResetPassword godoc
+//
+//	@Summary		Reset Password
+//	@Description	set new password with the token from reset password email.
+//	@Description	all the active sessions get removed and their refreshTokens get blacklisted, user need to login again.
+//	@Description	limited to 6 call per minute
+//	@Tags			User
+//	@Param			user		body		model.ResetPasswordReq	true	"token and new password"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,404,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/resetPassword [post]
+func (h *UserHandler) ResetPassword(c *fiber.Ctx) error {
+	var resetReq model.ResetPasswordReq
+	err := c.BodyParser(&resetReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := resetReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	err = h.userService.ResetPassword(&resetReq, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.InvalidToken, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// SendVerifyEmail godoc
+//
+//	@Summary		Send Verify Email
+//	@Description	send an email with an activation link. the link will expire after 6 hour.
+//	@Description	maybe email goes to spam folder.
+//	@Description	limited to 2 call per minute
+//	@Tags			User
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		404,409,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/sendVerifyEmail [get]
+func (h *UserHandler) SendVerifyEmail(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err := h.userService.SendVerifyEmail(jwtUserData.UserId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// VerifyEmail godoc
+//
+//	@Summary		Verify Email (Internal Usage)
+//	@Description	verify given email token. create activation link on server side.
+//	@Description	limited to 2 call per minute
+//	@Tags			User
+//	@Param			userId		path		integer	true	"userId"
+//	@Param			token		path		string	true	"email verify token"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,404,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/verifyEmail/:userId/:token [get]
+func (h *UserHandler) VerifyEmail(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId", 0)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	if userId < 1 {
+		return response.ResponseError(c, "userId cannot be smaller than 1", fiber.StatusBadRequest)
+	}
+
+	token := c.Params("token", "")
+	if token == "" {
+		return response.ResponseError(c, "token cannot be empty", fiber.StatusBadRequest)
+	}
+
+	err = h.userService.VerifyEmail(int64(userId), token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.InvalidToken, fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "email verified")
+}
+
+// SendMagicLink godoc
+//
+//	@Summary		Send Magic Link
+//	@Description	send an email with a single-use sign-in link that expires in 10 minutes, response is the same when account doesn't exist.
+//	@Description	the email has a web link and a deep link for the app (if configured), the session is created for the given deviceInfo.
+//	@Description	if 'deviceInfo.fingerprint' is provided, only the same device can use the link with /v1/user/login/magicLink.
+//	@Description	limited to 6 call per minute, also a new email is not sent for an account in 1 minute after the last one.
+//	@Tags			User-Auth
+//	@Param			user	body		model.MagicLinkReq	true	"account email and device info"
+//	@Success		200		{object}	response.ResponseOKModel
+//	@Failure		400,429,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/magicLink [post]
+func (h *UserHandler) SendMagicLink(c *fiber.Ctx) error {
+	var linkReq model.MagicLinkReq
+	err := c.BodyParser(&linkReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := linkReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	ip := c.IP()
+	ips := c.IPs()
+	if len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	err = h.userService.SendMagicLink(&linkReq, ip)
+	if err != nil {
+		if err.Error() == response.TooManyLoginAttempts {
+			return response.ResponseError(c, err.Error(), fiber.StatusTooManyRequests)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOK(c, "")
+}
+
+// LoginMagicLink godoc
+//
+//	@Summary		Login Magic Link
+//	@Description	login with the token from the magic link deep link or the page of web link, token can be used once.
+//	@Description	body can be json or form, the page of web link sends it as form.
+//	@Description	fingerprint is required if the link is requested with 'deviceInfo.fingerprint'.
+//	@Description	if account has two-factor authentication, response has 'twoFactorRequired' and 'challengeToken' like /v1/user/login.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			noCookie	query		bool					true	"return refreshToken in response body instead of saving in cookie"
+//	@Param			user		body		model.MagicLinkLoginReq	true	"token and fingerprint"
+//	@Success		200			{object}	model.UserViewModel
+//	@Failure		400,401,403,423,429,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/login/magicLink [post]
+func (h *UserHandler) LoginMagicLink(c *fiber.Ctx) error {
+	var loginReq model.MagicLinkLoginReq
+	err := c.BodyParser(&loginReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := loginReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	return h.loginMagicLink(c, &loginReq, c.QueryBool("noCookie", false))
+}
+
+// OpenMagicLink godoc
+//
+//	@Summary		Open Magic Link (Internal Usage)
+//	@Description	web link of the magic link email, returns a page that asks the user to confirm the login, token is not used here.
+//	@Description	confirming sends the token to /v1/user/login/magicLink and saves refreshToken in cookie, the page also has the app deep link if configured.
+//	@Description	links requested with 'deviceInfo.fingerprint' can't be used from the page.
+//	@Tags			User-Auth
+//	@Param			token	path		string	true	"magic link token"
+//	@Success		200		{string}	string	"html page"
+//	@Failure		400		{object}	response.ResponseErrorModel
+//	@Router			/v1/user/login/magicLink/:token [get]
+func (h *UserHandler) OpenMagicLink(c *fiber.Ctx) error {
+	loginReq := model.MagicLinkLoginReq{Token: c.Params("token", "")}
+	validation := loginReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	// opening the link must not login, mail scanners and link previews open it before the user
+	pageData := map[string]string{
+		"LoginUrl": configs.GetConfigs().ServerAddress + "/v1/user/login/magicLink",
+		"Token":    loginReq.Token,
+	}
+	if appDeepLink := configs.GetConfigs().AppDeepLink; appDeepLink != "" {
+		pageData["DeepLink"] = strings.TrimSuffix(appDeepLink, "/") + "/magicLink/" + loginReq.Token
+	}
+	var page bytes.Buffer
+	err := magicLinkPage.Execute(&page, pageData)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("Referrer-Policy", "no-referrer")
+	c.Type("html", "utf-8")
+	return c.Send(page.Bytes())
+}
+
+var magicLinkPage = template.Must(template.New("magicLink").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>Sign in</title>
+</head>
+<body>
+	<form method="post" action="{{.LoginUrl}}">
+		<input type="hidden" name="token" value="{{.Token}}">
+		<button type="submit">Sign in</button>
+	</form>
+	{{if .DeepLink}}<p><a href="{{.DeepLink}}">Open in app</a></p>{{end}}
+</body>
+</html>`))
+
+func (h *UserHandler) loginMagicLink(c *fiber.Ctx, loginReq *model.MagicLinkLoginReq, noCookie bool) error {
+	ip := c.IP()
+	ips := c.IPs()
+	if len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	result, err := h.userService.LoginMagicLink(loginReq, ip)
+	if err != nil {
+		if isSuspensionError(err) {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		if err.Error() == response.InvalidToken {
+			return response.ResponseError(c, err.Error(), fiber.StatusUnauthorized)
+		}
+		if err.Error() == response.TooManyLoginAttempts {
+			return response.ResponseError(c, err.Error(), fiber.StatusTooManyRequests)
+		}
+		if err.Error() == response.AccountLocked {
+			return response.ResponseError(c, err.Error(), fiber.StatusLocked)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	if result.TwoFactorRequired {
+		return response.ResponseOKWithData(c, result)
+	}
+
+	if !noCookie {
+		setAuthCookies(c, &result.Token)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// GetOidcProviders godoc
+//
+//	@Summary		Login Providers
+//	@Description	names of OpenID Connect providers that can be used for login
+//	@Tags			User-Auth
+//	@Success		200		{object}	model.OidcProvidersRes
+//	@Router			/v1/user/oidc/providers [get]
+func (h *UserHandler) GetOidcProviders(c *fiber.Ctx) error {
+	return response.ResponseOKWithData(c, h.userService.GetOidcProviders())
+}
+
+// StartOidcLogin godoc
+//
+//	@Summary		Start Provider Login
+//	@Description	returns the url of provider login page, open it in browser. 'state' is valid for 10 minutes.
+//	@Description	by default provider redirects to /v1/user/oidc/callback which creates the session and saves refreshToken in cookie.
+//	@Description	apps can set 'redirectUri' to the app deeplink (or an allowed origin) and send the code and state to /v1/user/oidc/login.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			provider	path		string				true	"provider name"
+//	@Param			user		body		model.OidcStartReq	true	"device info and redirect uri"
+//	@Success		200			{object}	model.OidcStartRes
+//	@Failure		400,404,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/oidc/:provider/start [post]
+func (h *UserHandler) StartOidcLogin(c *fiber.Ctx) error {
+	return h.startOidcLogin(c, 0)
+}
+
+// LinkOidcProvider godoc
+//
+//	@Summary		Link Provider
+//	@Description	like /v1/user/oidc/:provider/start but the provider account is linked to the logged-in user instead of login.
+//	@Description	account email doesn't need to match the provider email. a user can have one linked account of each provider.
+//	@Description	the callback (or /v1/user/oidc/login) must be sent with refreshToken of the same session, otherwise it's rejected with 403.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			provider	path		string				true	"provider name"
+//	@Param			user		body		model.OidcStartReq	true	"device info and redirect uri"
+//	@Success		200			{object}	model.OidcStartRes
+//	@Failure		400,401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/oidc/:provider/link [post]
+func (h *UserHandler) LinkOidcProvider(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	return h.startOidcLogin(c, jwtUserData.UserId)
+}
+
+func (h *UserHandler) startOidcLogin(c *fiber.Ctx, linkUserId int64) error {
+	var startReq model.OidcStartReq
+	err := c.BodyParser(&startReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := startReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	refreshToken := ""
+	if linkUserId != 0 {
+		refreshToken, _ = c.Locals("refreshToken").(string)
+	}
+	result, err := h.userService.StartOidcLogin(c.Params("provider", ""), &startReq, linkUserId, refreshToken)
+	if err != nil {
+		if err.Error() == response.ProviderNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		if err.Error() == response.InvalidRefreshToken {
+			return response.ResponseError(c, err.Error(), fiber.StatusUnauthorized)
+		}
+		if err.Error() == response.InvalidRedirectUri {
+			return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// LoginOidc godoc
+//
+//	@Summary		Login Provider
+//	@Description	login with the code and state that provider sent to 'redirectUri', state can be used once.
+//	@Description	on first login, account with the same verified email is linked, or a new account is created.
+//	@Description	if account has two-factor authentication, response has 'twoFactorRequired' and 'challengeToken' like /v1/user/login.
+//	@Description	if login is started by /v1/user/oidc/:provider/link, the provider is linked and response has no data.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			noCookie	query		bool				true	"return refreshToken in response body instead of saving in cookie"
+//	@Param			user		body		model.OidcLoginReq	true	"code and state"
+//	@Success		200			{object}	model.UserViewModel
+//	@Failure		400,401,403,409,429,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/oidc/login [post]
+func (h *UserHandler) LoginOidc(c *fiber.Ctx) error {
+	var loginReq model.OidcLoginReq
+	err := c.BodyParser(&loginReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := loginReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	return h.loginOidc(c, &loginReq, c.QueryBool("noCookie", false))
+}
+
+// OidcCallback godoc
+//
+//	@Summary		Provider Callback (Internal Usage)
+//	@Description	default redirect uri of providers, login and save refreshToken in cookie.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			code		query		string	true	"authorization code"
+//	@Param			state		query		string	true	"state"
+//	@Success		200			{object}	model.UserViewModel
+//	@Failure		400,401,403,409,429,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/oidc/callback [get]
+func (h *UserHandler) OidcCallback(c *fiber.Ctx) error {
+	if providerError := c.Query("error", ""); providerError != "" {
+		return response.ResponseError(c, response.ProviderLoginFailed+": "+providerError, fiber.StatusUnauthorized)
+	}
+	loginReq := model.OidcLoginReq{
+		State: c.Query("state", ""),
+		Code:  c.Query("code", ""),
+	}
+	validation := loginReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	return h.loginOidc(c, &loginReq, false)
+}
+
+func (h *UserHandler) loginOidc(c *fiber.Ctx, loginReq *model.OidcLoginReq, noCookie bool) error {
+	ip := c.IP()
+	ips := c.IPs()
+	if len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	result, err := h.userService.LoginOidc(loginReq, ip, getRequestRefreshToken(c))
+	if err != nil {
+		if isSuspensionError(err) {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		switch err.Error() {
+		case response.InvalidToken, response.ProviderLoginFailed:
+			return response.ResponseError(c, err.Error(), fiber.StatusUnauthorized)
+		case response.ProviderEmailNotVerified, response.LinkSessionMismatch:
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		case response.EmailNotVerified, response.IdentityAlreadyLinked:
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		case response.ProviderNotFound, response.UserNotFound:
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		case response.TooManyLoginAttempts:
+			return response.ResponseError(c, err.Error(), fiber.StatusTooManyRequests)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	if result == nil {
+		// provider is linked to the account
+		return response.ResponseOK(c, "provider linked")
+	}
+	if result.TwoFactorRequired {
+		return response.ResponseOKWithData(c, result)
+	}
+
+	if !noCookie {
+		setAuthCookies(c, &result.Token)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// GetUserIdentities godoc
+//
+//	@Summary		Linked Providers
+//	@Description	Return login providers that are linked to the account
+//	@Tags			User-Auth
+//	@Success		200		{object}	[]model.UserIdentityDataModel
+//	@Failure		401,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/oidc/identities [get]
+func (h *UserHandler) GetUserIdentities(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.GetUserIdentities(jwtUserData.UserId)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
+
+// UnlinkUserIdentity godoc
+//
+//	@Summary		Unlink Provider
+//	@Description	Remove linked login provider, account can still login with password or magic link
+//	@Tags			User-Auth
+//	@Param			provider	path		string	true	"provider name"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/oidc/identities/:provider [delete]
+func (h *UserHandler) UnlinkUserIdentity(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err := h.userService.UnlinkUserIdentity(jwtUserData.UserId, c.Params("provider", ""), service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.IdentityNotFound, fiber.StatusNotFound)
 		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
-	return response.ResponseOKWithData(c, result)
+	return response.ResponseOK(c, "")
 }
 
-// EditUserProfile godoc
+// StartDevicePairing godoc
 //
-//	@Summary		Edit Profile
-//	@Description	Edit profile data.
-//	@Tags			User
-//	@Param			user				body		model.EditProfileReq	true	"update fields"
-//	@Success		200					{object}	response.ResponseOKModel
-//	@Failure		400,401,404,409,500	{object}	response.ResponseErrorModel
-//	@Router			/v1/user/editUserProfile [post]
-func (h *UserHandler) EditUserProfile(c *fiber.Ctx) error {
-	var editFields model.EditProfileReq
-	err := c.BodyParser(&editFields)
+//	@Summary		Start Device Pairing
+//	@Description	login on a new device (tv, desktop) by approving it from a logged-in device. returns a pairing code that expires in 5 minutes.
+//	@Description	show 'qrData' as qr code (and the code for typing), then wait for approval with /v1/user/pairing/wait/:code.
+//	@Description	keep 'token' secret, it's needed to wait and to receive the session.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			user	body		model.DevicePairingStartReq	true	"info of the new device"
+//	@Success		200		{object}	model.DevicePairingStartRes
+//	@Failure		400,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/pairing/start [post]
+func (h *UserHandler) StartDevicePairing(c *fiber.Ctx) error {
+	var startReq model.DevicePairingStartReq
+	err := c.BodyParser(&startReq)
 	if err != nil {
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
-	validation := editFields.Validate()
+	validation := startReq.Validate()
 	if len(validation) > 0 {
 		return response.ResponseError(c, validation, fiber.StatusBadRequest)
 	}
 
-	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	result, err := h.userService.EditUserProfile(jwtUserData.UserId, &editFields)
+	ip := c.IP()
+	ips := c.IPs()
+	if len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	result, err := h.userService.StartDevicePairing(&startReq, ip)
 	if err != nil {
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
-	if result == nil {
-		return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
-	}
-	if result.UserId == 0 {
-		if result.Username == strings.ToLower(editFields.Username) {
-			return response.ResponseError(c, response.UsernameAlreadyExist, fiber.StatusConflict)
-		} else {
-			return response.ResponseError(c, response.EmailAlreadyExist, fiber.StatusConflict)
+	return response.ResponseOKWithData(c, result)
+}
+
+// WaitDevicePairing godoc
+//
+//	@Summary		Wait Device Pairing
+//	@Description	server-sent events stream for the new device, sends 'devicePairing' event with model.DevicePairingInfo when status changes.
+//	@Description	stream ends when status is 'approved' (call /v1/user/pairing/complete), 'rejected' or 'expired'.
+//	@Tags			User-Auth
+//	@Param			code		path		string	true	"pairing code"
+//	@Param			token		query		string	true	"pairing token"
+//	@Success		200			{object}	model.DevicePairingInfo
+//	@Failure		400,401,404,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/pairing/wait/:code [get]
+func (h *UserHandler) WaitDevicePairing(c *fiber.Ctx) error {
+	code := strings.ToUpper(c.Params("code", ""))
+	token := c.Query("token", "")
+	if code == "" || token == "" {
+		return response.ResponseError(c, "code and token cannot be empty", fiber.StatusBadRequest)
+	}
+
+	pairingInfo, err := h.userService.GetDevicePairingStatus(code, token)
+	if err != nil {
+		if err.Error() == response.DevicePairingNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		if err.Error() == response.InvalidToken {
+			return response.ResponseError(c, err.Error(), fiber.StatusUnauthorized)
 		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 
-	return response.ResponseOK(c, "")
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		var lastStatus model.DevicePairingStatus
+		for i := 0; ; i++ {
+			if pairingInfo.Status != lastStatus {
+				lastStatus = pairingInfo.Status
+				data, _ := json.Marshal(pairingInfo)
+				_, _ = fmt.Fprintf(w, "event: devicePairing\ndata: %s\n\n", data)
+			} else if i%15 == 0 {
+				// keep the connection open through proxies
+				_, _ = w.WriteString(": ping\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				// client disconnected
+				return
+			}
+			if lastStatus != model.DevicePairingPending {
+				return
+			}
+
+			<-ticker.C
+			pairingInfo, err = h.userService.GetDevicePairingStatus(code, token)
+			if err != nil {
+				pairingInfo = &model.DevicePairingInfo{Code: code, Status: model.DevicePairingExpired}
+			}
+		}
+	})
+	return nil
 }
 
-// UpdateUserPassword godoc
+// CompleteDevicePairing godoc
 //
-//	@Summary		Update Password
-//	@Description	Update User Password.
-//	@Tags			User
-//	@Param			passwords		body		model.UpdatePasswordReq	true	"old/new passwords"
-//	@Success		200				{object}	response.ResponseOKModel
-//	@Failure		400,404,409,500	{object}	response.ResponseErrorModel
-//	@Router			/v1/user/updatePassword [put]
-func (h *UserHandler) UpdateUserPassword(c *fiber.Ctx) error {
-	var passwords model.UpdatePasswordReq
-	err := c.BodyParser(&passwords)
+//	@Summary		Complete Device Pairing
+//	@Description	create session of the new device after the pairing is approved, can be called once.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			noCookie	query		bool							true	"return refreshToken in response body instead of saving in cookie"
+//	@Param			user		body		model.DevicePairingCompleteReq	true	"pairing code and token"
+//	@Success		200			{object}	model.UserViewModel
+//	@Failure		400,401,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Router			/v1/user/pairing/complete [post]
+func (h *UserHandler) CompleteDevicePairing(c *fiber.Ctx) error {
+	var completeReq model.DevicePairingCompleteReq
+	err := c.BodyParser(&completeReq)
 	if err != nil {
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
-	validation := passwords.Validate()
+	validation := completeReq.Validate()
 	if len(validation) > 0 {
 		return response.ResponseError(c, validation, fiber.StatusBadRequest)
 	}
 
-	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	err = h.userService.UpdateUserPassword(jwtUserData.UserId, &passwords)
+	ip := c.IP()
+	ips := c.IPs()
+	if len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	result, err := h.userService.CompleteDevicePairing(&completeReq, ip)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
-		} else if err.Error() == response.OldPassNotMatch {
-			return response.ResponseError(c, response.OldPassNotMatch, fiber.StatusConflict)
+		if isSuspensionError(err) {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		switch err.Error() {
+		case response.InvalidToken:
+			return response.ResponseError(c, err.Error(), fiber.StatusUnauthorized)
+		case response.DevicePairingRejected:
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		case response.DevicePairingNotFound, response.UserNotFound:
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		case response.DevicePairingPending:
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
 		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 
-	return response.ResponseOK(c, "")
+	if !c.QueryBool("noCookie", false) {
+		setAuthCookies(c, &result.Token)
+	}
+
+	return response.ResponseOKWithData(c, result)
 }
 
-//------------------------------------------
-//------------------------------------------
+// GetDevicePairing godoc
+//
+//	@Summary		Device Pairing Info
+//	@Description	Return the device that requested the pairing code (model, os, location), show it before approving.
+//	@Description	also available with websocket action 'device-pairing-info'.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			code		path		string	true	"pairing code"
+//	@Success		200			{object}	model.DevicePairingInfo
+//	@Failure		401,404,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/pairing/:code [get]
+func (h *UserHandler) GetDevicePairing(c *fiber.Ctx) error {
+	result, err := h.userService.GetDevicePairing(strings.ToUpper(c.Params("code", "")))
+	if err != nil {
+		if err.Error() == response.DevicePairingNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOKWithData(c, result)
+}
 
-// SendVerifyEmail godoc
+// AnswerDevicePairing godoc
 //
-//	@Summary		Send Verify Email
-//	@Description	send an email with an activation link. the link will expire after 6 hour.
-//	@Description	maybe email goes to spam folder.
-//	@Description	limited to 2 call per minute
-//	@Tags			User
-//	@Success		200			{object}	response.ResponseOKModel
-//	@Failure		404,409,500	{object}	response.ResponseErrorModel
-//	@Router			/v1/user/sendVerifyEmail [get]
-func (h *UserHandler) SendVerifyEmail(c *fiber.Ctx) error {
+//	@Summary		Approve Device Pairing
+//	@Description	Approve or reject the pairing code, on approve the new device is logged-in to this account.
+//	@Description	also available with websocket action 'device-pairing'.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
+//	@Param			code		path		string	true	"pairing code"
+//	@Param			approve		path		bool	true	"approve or reject"
+//	@Success		200			{object}	model.DevicePairingInfo
+//	@Failure		400,401,404,409,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/pairing/answer/:code/:approve [put]
+func (h *UserHandler) AnswerDevicePairing(c *fiber.Ctx) error {
+	approve, err := strconv.ParseBool(c.Params("approve", ""))
+	if err != nil {
+		return response.ResponseError(c, "Invalid approve value", fiber.StatusBadRequest)
+	}
+
 	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	err := h.userService.SendVerifyEmail(jwtUserData.UserId)
+	result, err := h.userService.AnswerDevicePairing(jwtUserData.UserId, strings.ToUpper(c.Params("code", "")), approve, service.NewAuditContext(c))
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		if err.Error() == response.DevicePairingNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		if err.Error() == response.DevicePairingAnswered {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
 		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
-
-	return response.ResponseOK(c, "")
+	return response.ResponseOKWithData(c, result)
 }
 
-// VerifyEmail godoc
+// UnlockAccount godoc
 //
-//	@Summary		Verify Email (Internal Usage)
-//	@Description	verify given email token. create activation link on server side.
-//	@Description	limited to 2 call per minute
-//	@Tags			User
+//	@Summary		Unlock Account (Internal Usage)
+//	@Description	unlock account that is locked because of failed login attempts. the link is sent to user email on lock.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-Auth
 //	@Param			userId		path		integer	true	"userId"
-//	@Param			token		path		string	true	"email verify token"
+//	@Param			token		path		string	true	"unlock token"
 //	@Success		200			{object}	response.ResponseOKModel
 //	@Failure		400,404,500	{object}	response.ResponseErrorModel
-//	@Router			/v1/user/verifyEmail/:userId/:token [get]
-func (h *UserHandler) VerifyEmail(c *fiber.Ctx) error {
+//	@Router			/v1/user/unlockAccount/:userId/:token [get]
+func (h *UserHandler) UnlockAccount(c *fiber.Ctx) error {
 	userId, err := c.ParamsInt("userId", 0)
 	if err != nil {
 		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
@@ -924,34 +2125,39 @@ func (h *UserHandler) VerifyEmail(c *fiber.Ctx) error {
 		return response.ResponseError(c, "token cannot be empty", fiber.StatusBadRequest)
 	}
 
-	err = h.userService.VerifyEmail(int64(userId), token)
+	err = h.userService.UnlockAccount(int64(userId), token)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err.Error() == response.InvalidToken {
 			return response.ResponseError(c, response.InvalidToken, fiber.StatusNotFound)
 		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 
-	return response.ResponseOK(c, "email verified")
+	return response.ResponseOK(c, "account unlocked")
 }
 
 // SendDeleteAccount godoc
 //
 //	@Summary		Delete Account
 //	@Description	remove user account. email the user with a link, if user open it, account gets removed.
+//	@Description	account can be restored by login until the grace period of deletion ends.
 //	@Description	the link expires in 10 minutes.
 //	@Description	maybe email goes to spam folder.
 //	@Description	limited to 2 call per minute
+//	@Description	needs a fresh two-factor code when two-factor authentication is enabled
 //	@Tags			User
-//	@Success		200			{object}	response.ResponseOKModel
-//	@Failure		403,404,500	{object}	response.ResponseErrorModel
+//	@Param			twoFactorCode	header		string	false	"totp code or recovery code, required when two-factor is enabled"
+//	@Success		200				{object}	response.ResponseOKModel
+//	@Failure		403,404,500		{object}	response.ResponseErrorModel
 //	@Router			/v1/user/deleteAccount [delete]
 func (h *UserHandler) SendDeleteAccount(c *fiber.Ctx) error {
 	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	err := h.userService.SendDeleteAccount(jwtUserData.UserId)
+	err := h.userService.SendDeleteAccount(jwtUserData.UserId, c.Get("twoFactorCode", ""))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.TwoFactorCodeRequired || err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
 		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
@@ -963,6 +2169,7 @@ func (h *UserHandler) SendDeleteAccount(c *fiber.Ctx) error {
 //
 //	@Summary		Delete Account (Internal Usage)
 //	@Description	remove account link created on server and send to user by email, if user click this link the account will start to remove.
+//	@Description	account is deactivated and hidden from other users, login in the grace period (ACCOUNT_DELETION_GRACE_DAYS) restores it, otherwise its data is removed after that.
 //	@Description	limited to 2 call per minute
 //	@Tags			User
 //	@Param			userId		path		integer	true	"userId"
@@ -983,7 +2190,7 @@ func (h *UserHandler) DeleteUserAccount(c *fiber.Ctx) error {
 		return response.ResponseError(c, "token cannot be empty", fiber.StatusBadRequest)
 	}
 
-	err = h.userService.DeleteUserAccount(int64(userId), token)
+	err = h.userService.DeleteUserAccount(int64(userId), token, service.NewAuditContext(c))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return response.ResponseError(c, response.InvalidToken, fiber.StatusNotFound)
@@ -991,26 +2198,154 @@ func (h *UserHandler) DeleteUserAccount(c *fiber.Ctx) error {
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 
-	cookieDomain := "." + configs.GetConfigs().Domain
-	if cookieDomain == ".localhost" {
-		cookieDomain = ""
+	removeAuthCookies(c)
+
+	return response.ResponseOK(c, "")
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// SetupTwoFactor godoc
+//
+//	@Summary		Setup Two-Factor
+//	@Description	start two-factor authentication enrollment, returns totp secret and provisioning uri (otpauth://) to show as qr code.
+//	@Description	two-factor is enabled after confirming a code with /v1/user/twoFactor/confirm
+//	@Tags			User-TwoFactor
+//	@Success		200			{object}	model.TwoFactorSetupRes
+//	@Failure		404,409,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/twoFactor/setup [post]
+func (h *UserHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.SetupTwoFactor(jwtUserData.UserId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.TwoFactorAlreadyEnabled {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// ConfirmTwoFactor godoc
+//
+//	@Summary		Confirm Two-Factor
+//	@Description	enable two-factor authentication with a code from authenticator app.
+//	@Description	returns one-time recovery codes, they are only shown once.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-TwoFactor
+//	@Param			code				body		model.TwoFactorCodeReq	true	"totp code"
+//	@Success		200					{object}	model.TwoFactorRecoveryCodesRes
+//	@Failure		400,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/twoFactor/confirm [post]
+func (h *UserHandler) ConfirmTwoFactor(c *fiber.Ctx) error {
+	var codeReq model.TwoFactorCodeReq
+	err := c.BodyParser(&codeReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := codeReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.ConfirmTwoFactor(jwtUserData.UserId, codeReq.Code, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.TwoFactorAlreadyEnabled || err.Error() == response.TwoFactorNotEnabled {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		} else if err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
+// DisableTwoFactor godoc
+//
+//	@Summary		Disable Two-Factor
+//	@Description	disable two-factor authentication, needs password and a totp code or recovery code.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-TwoFactor
+//	@Param			user				body		model.DisableTwoFactorReq	true	"password and code"
+//	@Success		200					{object}	response.ResponseOKModel
+//	@Failure		400,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/twoFactor/disable [post]
+func (h *UserHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	var disableReq model.DisableTwoFactorReq
+	err := c.BodyParser(&disableReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := disableReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = h.userService.DisableTwoFactor(jwtUserData.UserId, &disableReq, service.NewAuditContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.TwoFactorNotEnabled {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		} else if err.Error() == response.PassNotMatch || err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
-	c.Cookie(&fiber.Cookie{
-		Name:        "refreshToken",
-		Value:       "",
-		Path:        "/",
-		MaxAge:      -1,
-		Expires:     time.Now(),
-		Secure:      true,
-		HTTPOnly:    true,
-		Domain:      cookieDomain,
-		SameSite:    "none",
-		SessionOnly: false,
-	})
 
 	return response.ResponseOK(c, "")
 }
 
+// RegenerateTwoFactorRecoveryCodes godoc
+//
+//	@Summary		Regenerate Recovery Codes
+//	@Description	replace the two-factor recovery codes with new ones, needs a totp code or recovery code.
+//	@Description	limited to 6 call per minute
+//	@Tags			User-TwoFactor
+//	@Param			code				body		model.TwoFactorCodeReq	true	"totp code or recovery code"
+//	@Success		200					{object}	model.TwoFactorRecoveryCodesRes
+//	@Failure		400,403,404,409,500	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/user/twoFactor/recoveryCodes [post]
+func (h *UserHandler) RegenerateTwoFactorRecoveryCodes(c *fiber.Ctx) error {
+	var codeReq model.TwoFactorCodeReq
+	err := c.BodyParser(&codeReq)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	validation := codeReq.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	result, err := h.userService.RegenerateTwoFactorRecoveryCodes(jwtUserData.UserId, codeReq.Code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.ResponseError(c, response.UserNotFound, fiber.StatusNotFound)
+		} else if err.Error() == response.TwoFactorNotEnabled {
+			return response.ResponseError(c, err.Error(), fiber.StatusConflict)
+		} else if err.Error() == response.InvalidTwoFactorCode {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	return response.ResponseOKWithData(c, result)
+}
+
 //------------------------------------------
 //------------------------------------------
 
@@ -1116,3 +2451,67 @@ func (h *UserHandler) RemoveProfileImage(c *fiber.Ctx) error {
 
 	return response.ResponseOKWithData(c, images)
 }
+
+//------------------------------------------
+//------------------------------------------
+
+// setAuthCookies saves refreshToken in cookie and removes it from the response
+func setAuthCookies(c *fiber.Ctx, token *model.TokenViewModel) {
+	setRefreshTokenCookie(c, token.RefreshToken)
+	token.RefreshToken = ""
+}
+
+func setRefreshTokenCookie(c *fiber.Ctx, refreshToken string) {
+	c.Cookie(&fiber.Cookie{
+		Name:        "refreshToken",
+		Value:       refreshToken,
+		Path:        "/",
+		Expires:     time.Now().Add(time.Duration(configs.GetConfigs().RefreshTokenExpireDay) * 24 * time.Hour),
+		Secure:      true,
+		HTTPOnly:    true,
+		Domain:      getCookieDomain(),
+		SameSite:    "none",
+		SessionOnly: false,
+	})
+}
+
+func removeAuthCookies(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:        "refreshToken",
+		Value:       "",
+		Path:        "/",
+		MaxAge:      -1,
+		Expires:     time.Now(),
+		Secure:      true,
+		HTTPOnly:    true,
+		Domain:      getCookieDomain(),
+		SameSite:    "none",
+		SessionOnly: false,
+	})
+}
+
+// getRequestRefreshToken reads refreshToken from cookie or header like AuthMiddleware, for routes that don't require login
+func getRequestRefreshToken(c *fiber.Ctx) string {
+	refreshToken := c.Cookies("refreshToken", "")
+	if refreshToken == "" {
+		refreshToken = c.Get("refreshtoken", "")
+		if refreshToken == "" {
+			refreshToken = c.Get("refreshToken", "")
+		}
+	}
+	return refreshToken
+}
+
+func getCookieDomain() string {
+	cookieDomain := "." + configs.GetConfigs().Domain
+	if cookieDomain == ".localhost" {
+		cookieDomain = ""
+	}
+	return cookieDomain
+}
+
+// isSuspensionError reports whether login or connection is blocked by suspension of the user, it's responded with 403
+func isSuspensionError(err error) bool {
+	var suspension *model.UserSuspension
+	return errors.As(err, &suspension)
+}
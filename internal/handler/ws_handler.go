@@ -13,6 +13,7 @@ type IWsHandler interface {
 	AddClient(c *fiber.Ctx) error
 	GetSingleChatMessages(c *fiber.Ctx) error
 	GetSingleChatList(c *fiber.Ctx) error
+	SendSingleChatMessage(c *fiber.Ctx) error
 }
 
 type WsHandler struct {
@@ -36,7 +37,7 @@ func NewWsHandler(wsService service.IWsService) *WsHandler {
 //	@Param			deviceId	path		string				true	"unique id of the device"
 //	@Param			messageBody	body		model.ClientMessage	true	"types of bodies can be handled in server"
 //	@Success		200			{object}	model.ServerResultMessage
-//	@Failure		400			{object}	response.ResponseErrorModel
+//	@Failure		400,403		{object}	response.ResponseErrorModel
 //	@Router			/v1/ws/addClient/:deviceId [get]
 func (w *WsHandler) AddClient(c *fiber.Ctx) error {
 	deviceId := c.Params("deviceId", "")
@@ -44,9 +45,17 @@ func (w *WsHandler) AddClient(c *fiber.Ctx) error {
 		return response.ResponseError(c, response.InvalidDeviceId, fiber.StatusBadRequest)
 	}
 
+	ip := c.IP()
+	if ips := c.IPs(); len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
 	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
-	err := w.wsService.AddClient(c.Context(), jwtUserData.UserId, jwtUserData.Username, deviceId)
+	err := w.wsService.AddClient(c.Context(), jwtUserData.UserId, jwtUserData.Username, deviceId, ip)
 	if err != nil {
+		if isSuspensionError(err) {
+			return response.ResponseError(c, err.Error(), fiber.StatusForbidden)
+		}
 		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
 	}
 
@@ -120,3 +129,40 @@ func (w *WsHandler) GetSingleChatList(c *fiber.Ctx) error {
 	}
 	return response.ResponseOKWithData(c, messages)
 }
+
+// SendSingleChatMessage godoc
+//
+//	@Summary		Send Message
+//	@Description	send user-to-user message without websocket connection, roomId must be -1.
+//	@Description	message is queued and saved in background, result is sent to the websocket connections of the sender.
+//	@Description	can be called with personal api token that has scope (messages:send)
+//	@Tags			User-Websocket
+//	@Param			messageBody	body		model.NewMessage	true	"message"
+//	@Success		200			{object}	response.ResponseOKModel
+//	@Failure		400,401,403,404	{object}	response.ResponseErrorModel
+//	@Security		BearerAuth
+//	@Router			/v1/ws/singleChat/send [post]
+func (w *WsHandler) SendSingleChatMessage(c *fiber.Ctx) error {
+	var params model.NewMessage
+	err := c.BodyParser(&params)
+	if err != nil {
+		return response.ResponseError(c, err.Error(), fiber.StatusBadRequest)
+	}
+	validation := params.Validate()
+	if len(validation) > 0 {
+		return response.ResponseError(c, validation, fiber.StatusBadRequest)
+	}
+	if params.RoomId != -1 {
+		return response.ResponseError(c, "only user-to-user messages (roomId: -1) can be sent", fiber.StatusBadRequest)
+	}
+
+	jwtUserData := c.Locals("jwtUserData").(*util.MyJwtClaims)
+	err = w.wsService.SendSingleChatMessage(jwtUserData.UserId, jwtUserData.Username, &params)
+	if err != nil {
+		if err.Error() == response.UserNotFound {
+			return response.ResponseError(c, err.Error(), fiber.StatusNotFound)
+		}
+		return response.ResponseError(c, err.Error(), fiber.StatusInternalServerError)
+	}
+	return response.ResponseOK(c, "message is queued")
+}
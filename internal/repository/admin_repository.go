@@ -1,11 +1,38 @@
 package repository
 
 import (
+	"downloader_gochat/model"
+	"strconv"
+	"strings"
+	"time"
+
 	"go.mongodb.org/mongo-driver/mongo"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type IAdminRepository interface {
+	GetMediaFileUrls() ([]string, error)
+	GetProfileImageUrls() ([]string, error)
+	GetRoles() ([]model.RoleWithPermissions, error)
+	CreateRole(role *model.Role) error
+	UpdateRole(roleId int64, updateFields map[string]interface{}) error
+	DeleteRole(roleId int64) ([]int64, error)
+	SetRolePermissions(roleId int64, permissionIds []int64) error
+	GetPermissions() ([]model.Permission, error)
+	GetPermission(permissionId int64) (*model.Permission, error)
+	CreatePermission(permission *model.Permission) error
+	UpdatePermission(permissionId int64, updateFields map[string]interface{}) error
+	DeletePermission(permissionId int64) error
+	AddUserRole(userId int64, roleId int64) error
+	RemoveUserRole(userId int64, roleId int64) error
+	SearchUsers(query string, skip int, limit int) ([]model.AdminUserDataModel, error)
+	GetUser(userId int64) (*model.AdminUserDataModel, error)
+	GetUserCounts(userId int64) (*model.AdminUserCounts, error)
+	GetUserBots(userId int64) ([]model.UserBotDataModel, error)
+	SetUserSuspension(suspension *model.UserSuspension) error
+	RemoveUserSuspension(userId int64) error
+	RemoveUserSessions(userId int64) ([]model.ActiveSession, error)
 }
 
 type AdminRepository struct {
@@ -19,3 +46,383 @@ func NewAdminRepository(db *gorm.DB, mongodb *mongo.Database) *AdminRepository {
 
 //------------------------------------------
 //------------------------------------------
+
+// GetMediaFileUrls returns urls of all media files and the file names of the dedup blobs
+func (a *AdminRepository) GetMediaFileUrls() ([]string, error) {
+	var urls []string
+	err := a.db.Model(&model.MediaFile{}).Distinct("url").Pluck("url", &urls).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var blobFileNames []string
+	err = a.db.Model(&model.MediaBlob{}).Pluck("\"fileName\"", &blobFileNames).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return append(urls, blobFileNames...), nil
+}
+
+func (a *AdminRepository) GetProfileImageUrls() ([]string, error) {
+	var urls []string
+	err := a.db.Model(&model.ProfileImage{}).Pluck("url", &urls).Error
+	return urls, err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (a *AdminRepository) GetRoles() ([]model.RoleWithPermissions, error) {
+	var roles []model.Role
+	err := a.db.Model(&model.Role{}).Order("id").Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type resType struct {
+		RoleId int64 `gorm:"column:roleId"`
+		model.Permission
+	}
+	var res []resType
+	err = a.db.
+		Raw("SELECT \"RoleToPermission\".\"roleId\", \"Permission\".* FROM \"Permission\" " +
+			"JOIN \"RoleToPermission\" ON \"RoleToPermission\".\"permissionId\" = \"Permission\".id " +
+			"ORDER BY \"Permission\".id").
+		Scan(&res).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.RoleWithPermissions, len(roles))
+	for i, r := range roles {
+		result[i] = model.RoleWithPermissions{
+			Id:                  r.Id,
+			Name:                r.Name,
+			Description:         r.Description,
+			TorrentLeachLimitGb: r.TorrentLeachLimitGb,
+			TorrentSearchLimit:  r.TorrentSearchLimit,
+			BotsNotification:    r.BotsNotification,
+			CreatedAt:           r.CreatedAt,
+			UpdatedAt:           r.UpdatedAt,
+			Permissions:         []model.Permission{},
+		}
+		for _, item := range res {
+			if item.RoleId == r.Id {
+				result[i].Permissions = append(result[i].Permissions, item.Permission)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (a *AdminRepository) CreateRole(role *model.Role) error {
+	return a.db.Create(role).Error
+}
+
+func (a *AdminRepository) UpdateRole(roleId int64, updateFields map[string]interface{}) error {
+	res := a.db.
+		Model(&model.Role{}).
+		Where("id = ?", roleId).
+		Limit(1).
+		Updates(updateFields)
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteRole removes the role and its relations, returns the users that had the role
+func (a *AdminRepository) DeleteRole(roleId int64) ([]int64, error) {
+	var userIds []int64
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.UserToRole{}).Where("\"roleId\" = ?", roleId).Pluck("\"userId\"", &userIds).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("\"roleId\" = ?", roleId).Delete(&model.UserToRole{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("\"roleId\" = ?", roleId).Delete(&model.RoleToPermission{}).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Where("id = ?", roleId).Delete(&model.Role{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+
+	return userIds, err
+}
+
+// SetRolePermissions replaces the permissions of role, returns gorm.ErrRecordNotFound if role or any of the permissions doesn't exist
+func (a *AdminRepository) SetRolePermissions(roleId int64, permissionIds []int64) error {
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Model(&model.Role{}).
+			Where("id = ?", roleId).
+			Limit(1).
+			UpdateColumn("updatedAt", time.Now().UTC())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if len(permissionIds) > 0 {
+			var count int64
+			err := tx.Model(&model.Permission{}).Where("id IN ?", permissionIds).Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count != int64(len(permissionIds)) {
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		err := tx.Where("\"roleId\" = ?", roleId).Delete(&model.RoleToPermission{}).Error
+		if err != nil {
+			return err
+		}
+		if len(permissionIds) == 0 {
+			return nil
+		}
+
+		rolePermissions := make([]model.RoleToPermission, len(permissionIds))
+		for i, id := range permissionIds {
+			rolePermissions[i] = model.RoleToPermission{RoleId: roleId, PermissionId: id}
+		}
+		return tx.Create(&rolePermissions).Error
+	})
+
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (a *AdminRepository) GetPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := a.db.Model(&model.Permission{}).Order("id").Find(&permissions).Error
+	return permissions, err
+}
+
+func (a *AdminRepository) GetPermission(permissionId int64) (*model.Permission, error) {
+	var permission model.Permission
+	err := a.db.Where("id = ?", permissionId).Limit(1).Find(&permission).Error
+	if err != nil {
+		return nil, err
+	}
+	if permission.Id == 0 {
+		return nil, nil
+	}
+	return &permission, nil
+}
+
+func (a *AdminRepository) CreatePermission(permission *model.Permission) error {
+	return a.db.Create(permission).Error
+}
+
+func (a *AdminRepository) UpdatePermission(permissionId int64, updateFields map[string]interface{}) error {
+	res := a.db.
+		Model(&model.Permission{}).
+		Where("id = ?", permissionId).
+		Limit(1).
+		Updates(updateFields)
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (a *AdminRepository) DeletePermission(permissionId int64) error {
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("\"permissionId\" = ?", permissionId).Delete(&model.RoleToPermission{}).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Where("id = ?", permissionId).Delete(&model.Permission{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// AddUserRole returns gorm.ErrRecordNotFound if user or role doesn't exist, does nothing if user already has the role
+func (a *AdminRepository) AddUserRole(userId int64, roleId int64) error {
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.User{}).Where("\"userId\" = ?", userId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		err = tx.Model(&model.Role{}).Where("id = ?", roleId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.UserToRole{UserId: userId, RoleId: roleId}).
+			Error
+	})
+
+	return err
+}
+
+func (a *AdminRepository) RemoveUserRole(userId int64, roleId int64) error {
+	res := a.db.
+		Where("\"userId\" = ? AND \"roleId\" = ?", userId, roleId).
+		Delete(&model.UserToRole{})
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+// SearchUsers matches the userId when query is numeric, also matches the start of username and email
+func (a *AdminRepository) SearchUsers(query string, skip int, limit int) ([]model.AdminUserDataModel, error) {
+	db := a.db.Model(&model.AdminUserDataModel{})
+	if query != "" {
+		prefix := likeEscaper.Replace(query) + "%"
+		if userId, err := strconv.ParseInt(query, 10, 64); err == nil {
+			db = db.Where("\"userId\" = ? OR username LIKE ? OR email LIKE ?", userId, prefix, prefix)
+		} else {
+			db = db.Where("username LIKE ? OR email LIKE ?", prefix, prefix)
+		}
+	}
+
+	var result []model.AdminUserDataModel
+	err := db.
+		Order("\"userId\"").
+		Offset(skip).
+		Limit(limit).
+		Find(&result).
+		Error
+	return result, err
+}
+
+// GetUser returns nil if user doesn't exist
+func (a *AdminRepository) GetUser(userId int64) (*model.AdminUserDataModel, error) {
+	var result model.AdminUserDataModel
+	err := a.db.
+		Model(&model.AdminUserDataModel{}).
+		Where("\"userId\" = ?", userId).
+		Limit(1).
+		Find(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if result.UserId == 0 {
+		return nil, nil
+	}
+	return &result, nil
+}
+
+func (a *AdminRepository) GetUserCounts(userId int64) (*model.AdminUserCounts, error) {
+	var result model.AdminUserCounts
+	err := a.db.
+		Raw(`SELECT
+			(SELECT "followersCount" FROM "User" WHERE "userId" = ?) AS followers,
+			(SELECT "followingsCount" FROM "User" WHERE "userId" = ?) AS followings,
+			(SELECT count(*) FROM "ProfileImage" WHERE "userId" = ?) AS "profileImages",
+			(SELECT count(*) FROM "ActiveSession" WHERE "userId" = ?) AS "activeSessions",
+			(SELECT count(*) FROM "UserBot" WHERE "userId" = ?) AS bots,
+			(SELECT count(*) FROM "Message" WHERE "creatorId" = ?) AS "sentMessages"`,
+			userId, userId, userId, userId, userId, userId).
+		Scan(&result).
+		Error
+	return &result, err
+}
+
+// GetUserBots returns all the bots of user, including the ones with disabled notification
+func (a *AdminRepository) GetUserBots(userId int64) ([]model.UserBotDataModel, error) {
+	var result []model.UserBotDataModel
+	err := a.db.
+		Model(&model.UserBotDataModel{}).
+		Where("\"userId\" = ?", userId).
+		Find(&result).
+		Error
+	return result, err
+}
+
+// SetUserSuspension returns gorm.ErrRecordNotFound if user doesn't exist
+func (a *AdminRepository) SetUserSuspension(suspension *model.UserSuspension) error {
+	res := a.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ?", suspension.UserId).
+		Updates(map[string]interface{}{
+			"banned":           suspension.Banned,
+			"suspendedUntil":   suspension.Until,
+			"suspensionReason": suspension.Reason,
+			"suspendedBy":      suspension.SuspendedBy,
+		})
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RemoveUserSuspension returns gorm.ErrRecordNotFound if user doesn't exist
+func (a *AdminRepository) RemoveUserSuspension(userId int64) error {
+	return a.SetUserSuspension(&model.UserSuspension{UserId: userId})
+}
+
+// RemoveUserSessions removes all the sessions of user and returns them
+func (a *AdminRepository) RemoveUserSessions(userId int64) ([]model.ActiveSession, error) {
+	var result []model.ActiveSession
+	err := a.db.
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "refreshToken"}, {Name: "notifToken"}, {Name: "deviceId"}}}).
+		Where("\"userId\" = ?", userId).
+		Delete(&result).
+		Error
+	return result, err
+}
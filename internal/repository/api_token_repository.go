@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"downloader_gochat/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IApiTokenRepository interface {
+	AddApiToken(apiToken *model.ApiToken) error
+	CountActiveApiTokens(userId int64) (int64, error)
+	GetApiTokens(userId int64) ([]model.ApiToken, error)
+	RevokeApiToken(userId int64, tokenId int64) (string, error)
+	RevokeAllApiTokens(userId int64) ([]string, error)
+	UseApiToken(tokenHash string, ip string) (*model.ApiTokenAuthData, error)
+}
+
+type ApiTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewApiTokenRepository(db *gorm.DB) *ApiTokenRepository {
+	return &ApiTokenRepository{db: db}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (r *ApiTokenRepository) AddApiToken(apiToken *model.ApiToken) error {
+	return r.db.Create(apiToken).Error
+}
+
+func (r *ApiTokenRepository) CountActiveApiTokens(userId int64) (int64, error) {
+	var count int64
+	err := r.db.
+		Model(&model.ApiToken{}).
+		Where("\"userId\" = ? AND \"revokedAt\" IS NULL AND (\"expiresAt\" IS NULL OR \"expiresAt\" > ?)", userId, time.Now().UTC()).
+		Count(&count).
+		Error
+	return count, err
+}
+
+// GetApiTokens returns not revoked tokens of the user, expired ones included, newest first
+func (r *ApiTokenRepository) GetApiTokens(userId int64) ([]model.ApiToken, error) {
+	var result []model.ApiToken
+	err := r.db.
+		Where("\"userId\" = ? AND \"revokedAt\" IS NULL", userId).
+		Order("id desc").
+		Find(&result).
+		Error
+	return result, err
+}
+
+// RevokeApiToken returns hash of the revoked token, returns gorm.ErrRecordNotFound if token doesn't exist or is already revoked
+func (r *ApiTokenRepository) RevokeApiToken(userId int64, tokenId int64) (string, error) {
+	var result []model.ApiToken
+	err := r.db.
+		Model(&result).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "tokenHash"}}}).
+		Where("id = ? AND \"userId\" = ? AND \"revokedAt\" IS NULL", tokenId, userId).
+		UpdateColumn("revokedAt", time.Now().UTC()).
+		Error
+	if err != nil {
+		return "", err
+	}
+	if len(result) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return result[0].TokenHash, nil
+}
+
+// RevokeAllApiTokens returns hashes of the revoked tokens, expired tokens are revoked too
+func (r *ApiTokenRepository) RevokeAllApiTokens(userId int64) ([]string, error) {
+	var result []model.ApiToken
+	err := r.db.
+		Model(&result).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "tokenHash"}}}).
+		Where("\"userId\" = ? AND \"revokedAt\" IS NULL", userId).
+		UpdateColumn("revokedAt", time.Now().UTC()).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(result))
+	for i := range result {
+		hashes[i] = result[i].TokenHash
+	}
+	return hashes, nil
+}
+
+// UseApiToken updates last usage of the token and returns the data needed for authorizing the request,
+// returns nil if token doesn't exist, is revoked, is expired or its user is pending deletion
+func (r *ApiTokenRepository) UseApiToken(tokenHash string, ip string) (*model.ApiTokenAuthData, error) {
+	var result model.ApiTokenAuthData
+	now := time.Now().UTC()
+	err := r.db.
+		Raw(`UPDATE "ApiToken" t SET "lastUsedAt" = @now, "lastUsedIp" = @ip
+			FROM "User" u
+			WHERE t."tokenHash" = @hash AND t."revokedAt" IS NULL AND (t."expiresAt" IS NULL OR t."expiresAt" > @now)
+				AND u."userId" = t."userId" AND u."deletionDate" = 0
+			RETURNING t.id, t."userId", u.username, t.scopes, t."expiresAt",
+				ARRAY(SELECT "roleId" FROM "UserToRole" WHERE "userId" = t."userId") AS "roleIds"`,
+			map[string]interface{}{
+				"now":  now,
+				"ip":   ip,
+				"hash": tokenHash,
+			}).
+		Scan(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if result.Id == 0 {
+		return nil, nil
+	}
+	return &result, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"downloader_gochat/model"
+
+	"gorm.io/gorm"
+)
+
+type IAuditRepository interface {
+	AddAuditLogs(auditLogs []model.AuditLog) error
+	GetAuditLogs(params *model.AuditLogReq) ([]model.AuditLog, error)
+	GetSessionDevice(refreshToken string) (*model.ActiveSessionDataModel, error)
+}
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// AddAuditLogs only inserts, the table doesn't allow update and delete
+func (r *AuditRepository) AddAuditLogs(auditLogs []model.AuditLog) error {
+	return r.db.CreateInBatches(auditLogs, 100).Error
+}
+
+// GetAuditLogs returns newest logs first, when params.UserId is set only logs that target the user are returned
+func (r *AuditRepository) GetAuditLogs(params *model.AuditLogReq) ([]model.AuditLog, error) {
+	db := r.db.Model(&model.AuditLog{})
+	if params.UserId > 0 {
+		db = db.Where("\"targetId\" = ?", params.UserId)
+	}
+	if params.ActorId > 0 {
+		db = db.Where("\"actorId\" = ?", params.ActorId)
+	}
+	if params.TargetId > 0 {
+		db = db.Where("\"targetId\" = ?", params.TargetId)
+	}
+	if params.Action != "" {
+		db = db.Where("action = ?", params.Action)
+	}
+	if params.Ip != "" {
+		db = db.Where("ip = ?", params.Ip)
+	}
+	if !params.From.IsZero() {
+		db = db.Where("date >= ?", params.From.UTC())
+	}
+	if !params.To.IsZero() {
+		db = db.Where("date <= ?", params.To.UTC())
+	}
+
+	var result []model.AuditLog
+	err := db.
+		Order("date desc, id desc").
+		Offset(params.Skip).
+		Limit(params.Limit).
+		Find(&result).
+		Error
+	return result, err
+}
+
+// GetSessionDevice returns nil if session doesn't exist
+func (r *AuditRepository) GetSessionDevice(refreshToken string) (*model.ActiveSessionDataModel, error) {
+	var result model.ActiveSessionDataModel
+	err := r.db.
+		Model(&model.ActiveSessionDataModel{}).
+		Where("\"refreshToken\" = ?", refreshToken).
+		Limit(1).
+		Find(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if result.UserId == 0 {
+		return nil, nil
+	}
+	return &result, nil
+}
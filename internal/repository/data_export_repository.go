@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"downloader_gochat/model"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IDataExportRepository interface {
+	AddDataExport(dataExport *model.DataExport) error
+	GetDataExports(userId int64, limit int) ([]model.DataExport, error)
+	GetDataExportByToken(userId int64, token string) (*model.DataExport, error)
+	ClaimPendingDataExport() (*model.DataExport, error)
+	SaveDataExportResult(dataExport *model.DataExport) error
+	FailStaleDataExports(startedBefore time.Time) error
+	GetExpiredDataExports(limit int) ([]model.DataExport, error)
+	SetDataExportExpired(id int64) error
+	GetUserExportSections() []string
+	GetUserExportSectionRows(userId int64, name string, skip int, limit int) ([]map[string]interface{}, error)
+}
+
+type DataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// AddDataExport returns gorm.ErrDuplicatedKey if user has an export in progress
+func (r *DataExportRepository) AddDataExport(dataExport *model.DataExport) error {
+	return r.db.Create(dataExport).Error
+}
+
+// GetDataExports returns newest exports first
+func (r *DataExportRepository) GetDataExports(userId int64, limit int) ([]model.DataExport, error) {
+	var result []model.DataExport
+	err := r.db.
+		Where("\"userId\" = ?", userId).
+		Order("id desc").
+		Limit(limit).
+		Find(&result).
+		Error
+	return result, err
+}
+
+func (r *DataExportRepository) GetDataExportByToken(userId int64, token string) (*model.DataExport, error) {
+	var result model.DataExport
+	err := r.db.
+		Where("\"userId\" = ? AND token = ? AND token != ''", userId, token).
+		Limit(1).
+		Find(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if result.Id == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &result, nil
+}
+
+// ClaimPendingDataExport marks the oldest pending export as processing and returns it, returns nil if there is none.
+// rows that are locked by other servers are skipped
+func (r *DataExportRepository) ClaimPendingDataExport() (*model.DataExport, error) {
+	var result model.DataExport
+	err := r.db.
+		Raw(`UPDATE "DataExport" SET status = ?, "startDate" = ?
+			WHERE id = (SELECT id FROM "DataExport" WHERE status = ? ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED)
+			RETURNING *`,
+			model.DataExportProcessing, time.Now().UTC(), model.DataExportPending).
+		Scan(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if result.Id == 0 {
+		return nil, nil
+	}
+	return &result, nil
+}
+
+func (r *DataExportRepository) SaveDataExportResult(dataExport *model.DataExport) error {
+	return r.db.
+		Model(&model.DataExport{}).
+		Where("id = ?", dataExport.Id).
+		Updates(map[string]interface{}{
+			"status":       dataExport.Status,
+			"completeDate": dataExport.CompleteDate,
+			"expireDate":   dataExport.ExpireDate,
+			"fileName":     dataExport.FileName,
+			"size":         dataExport.Size,
+			"token":        dataExport.Token,
+			"errorMessage": dataExport.ErrorMessage,
+		}).
+		Error
+}
+
+// FailStaleDataExports fails the exports that their server is stopped while processing them
+func (r *DataExportRepository) FailStaleDataExports(startedBefore time.Time) error {
+	return r.db.
+		Model(&model.DataExport{}).
+		Where("status = ? AND \"startDate\" < ?", model.DataExportProcessing, startedBefore.UTC()).
+		Updates(map[string]interface{}{
+			"status":       model.DataExportFailed,
+			"errorMessage": "export is not completed in time",
+		}).
+		Error
+}
+
+func (r *DataExportRepository) GetExpiredDataExports(limit int) ([]model.DataExport, error) {
+	var result []model.DataExport
+	err := r.db.
+		Where("status = ? AND \"expireDate\" <= ?", model.DataExportReady, time.Now().UTC()).
+		Order("id").
+		Limit(limit).
+		Find(&result).
+		Error
+	return result, err
+}
+
+func (r *DataExportRepository) SetDataExportExpired(id int64) error {
+	return r.db.
+		Model(&model.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status": model.DataExportExpired,
+			"token":  "",
+		}).
+		Error
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// userExportColumns skips password, tokens and secrets of the user
+var userExportColumns = []string{
+	"userId", "username", "rawUsername", "publicName", "email", "emailVerified", "bio", "defaultProfile",
+	"favoriteGenres", "mbtiType", "registrationDate", "lastSeenDate", "twoFactorEnabled",
+	"banned", "suspendedUntil", "suspensionReason", "deletionDate", "privateAccount",
+}
+
+// apiTokenExportColumns skips hash of the tokens
+var apiTokenExportColumns = []string{
+	"id", "name", "tokenPrefix", "scopes", "createdAt", "expiresAt", "lastUsedAt", "lastUsedIp", "revokedAt",
+}
+
+var sessionExportColumns = []string{
+	"deviceId", "appName", "appVersion", "deviceModel", "deviceOs", "ipLocation", "loginDate", "lastUseDate",
+}
+
+// userExportSection is a table that has data of the user, columns are exported with their db names
+type userExportSection struct {
+	name    string
+	table   interface{}
+	columns []string
+	where   string
+	order   string
+}
+
+var userExportSections = []userExportSection{
+	{"profile", &model.User{}, userExportColumns, "\"userId\" = ?", ""},
+	{"profile_images", &model.ProfileImage{}, nil, "\"userId\" = ?", "\"addDate\""},
+	{"computed_favorite_genres", &model.ComputedFavoriteGenres{}, nil, "\"userId\" = ?", ""},
+	{"movie_settings", &model.MovieSettings{}, nil, "\"userId\" = ?", ""},
+	{"notification_settings", &model.NotificationSettings{}, nil, "\"userId\" = ?", ""},
+	{"download_links_settings", &model.DownloadLinksSettings{}, nil, "\"userId\" = ?", ""},
+	{"torrent_usage", &model.UserTorrent{}, nil, "\"userId\" = ?", ""},
+	{"active_sessions", &model.ActiveSession{}, sessionExportColumns, "\"userId\" = ?", "\"loginDate\""},
+	{"devices", &model.UserDevice{}, nil, "\"userId\" = ?", "\"firstLoginDate\""},
+	{"login_history", &model.LoginHistory{}, nil, "\"userId\" = ?", "date"},
+	{"linked_identities", &model.UserIdentity{}, nil, "\"userId\" = ?", "\"createdAt\""},
+	{"api_tokens", &model.ApiToken{}, apiTokenExportColumns, "\"userId\" = ?", "id"},
+	{"followers", &model.Follow{}, nil, "\"followingId\" = ?", "\"addDate\""},
+	{"followings", &model.Follow{}, nil, "\"followerId\" = ?", "\"addDate\""},
+	{"follow_requests", &model.FollowRequest{}, nil, "\"requesterId\" = ? OR \"targetId\" = ?", "date"},
+	{"dismissed_follow_suggestions", &model.FollowSuggestionDismiss{}, nil, "\"userId\" = ?", "date"},
+	{"chats", &model.Room{}, nil, "\"creatorId\" = ? OR \"receiverId\" = ?", "\"roomId\""},
+	{"messages", &model.Message{}, nil, "\"creatorId\" = ? OR \"receiverId\" = ?", "date"},
+	{"message_media", &model.MediaFile{}, nil,
+		"\"messageId\" IN (SELECT id FROM \"Message\" WHERE \"creatorId\" = ? OR \"receiverId\" = ?)", "date"},
+	{"notifications", &model.Notification{}, nil, "\"receiverId\" = ? OR \"creatorId\" = ?", "date"},
+	{"bots", &model.UserBot{}, nil, "\"userId\" = ?", ""},
+	{"liked_movies", &model.LikeDislikeMovie{}, nil, "\"userId\" = ?", "date"},
+	{"watched_movies", &model.WatchedMovie{}, nil, "\"userId\" = ?", "date"},
+	{"followed_movies", &model.FollowMovie{}, nil, "\"userId\" = ?", "date"},
+	{"watch_list_groups", &model.WatchListGroup{}, nil, "\"userId\" = ?", "date"},
+	{"watch_list_movies", &model.WatchListMovie{}, nil, "\"userId\" = ?", "date"},
+	{"collections", &model.UserCollection{}, nil, "\"userId\" = ?", "date"},
+	{"collection_movies", &model.UserCollectionMovie{}, nil, "\"userId\" = ?", "date"},
+	{"liked_staff", &model.LikeDislikeStaff{}, nil, "\"userId\" = ?", "date"},
+	{"followed_staff", &model.FollowStaff{}, nil, "\"userId\" = ?", "date"},
+	{"liked_characters", &model.LikeDislikeCharacter{}, nil, "\"userId\" = ?", "date"},
+	{"favorite_characters", &model.FavoriteCharacter{}, nil, "\"userId\" = ?", "date"},
+	{"security_events", &model.AuditLog{}, nil, "\"targetId\" = ?", "date"},
+}
+
+// GetUserExportSections returns the names of the sections in the order of the archive
+func (r *DataExportRepository) GetUserExportSections() []string {
+	names := make([]string, 0, len(userExportSections))
+	for _, s := range userExportSections {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// GetUserExportSectionRows returns a batch of the rows of section, rows are read until a batch is smaller than limit
+func (r *DataExportRepository) GetUserExportSectionRows(userId int64, name string, skip int, limit int) ([]map[string]interface{}, error) {
+	index := slices.IndexFunc(userExportSections, func(s userExportSection) bool { return s.name == name })
+	if index == -1 {
+		return nil, fmt.Errorf("unknown data export section: %v", name)
+	}
+	s := userExportSections[index]
+
+	args := make([]interface{}, strings.Count(s.where, "?"))
+	for i := range args {
+		args[i] = userId
+	}
+
+	query := r.db.Model(s.table).Where(s.where, args...)
+	if len(s.columns) > 0 {
+		query = query.Select(s.columns)
+	}
+	if s.order != "" {
+		query = query.Order(s.order)
+	}
+	// keeps the order of rows with the same date stable between the batches
+	query = query.Order("ctid")
+
+	rows := make([]map[string]interface{}, 0)
+	err := query.Offset(skip).Limit(limit).Find(&rows).Error
+	return rows, err
+}
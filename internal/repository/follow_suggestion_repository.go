@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"downloader_gochat/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IFollowSuggestionRepository interface {
+	ComputeFollowSuggestions(userId int64, limit int) ([]model.FollowSuggestion, error)
+	GetActiveUserIds(seenAfter time.Time, afterUserId int64, limit int) ([]int64, error)
+	AddFollowSuggestionDismiss(userId int64, suggestedId int64) error
+	GetFollowSuggestionUsers(viewerId int64, userIds []int64) ([]model.FollowSuggestionDataModel, error)
+}
+
+type FollowSuggestionRepository struct {
+	db *gorm.DB
+}
+
+func NewFollowSuggestionRepository(db *gorm.DB) *FollowSuggestionRepository {
+	return &FollowSuggestionRepository{db: db}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+const (
+	followSuggestionCandidates   = 300
+	followSuggestionMovieSample  = 100
+	followSuggestionGenreSample  = 5
+	followSuggestionMovieFanOut  = 200 // users that are read for each movie of the user
+	followSuggestionMutualWeight = 3
+	followSuggestionMovieWeight  = 2
+	followSuggestionGenreWeight  = 1
+	followSuggestionActiveCutOff = 30 * 24 * time.Hour
+)
+
+// ComputeFollowSuggestions scores candidates from followings of the user's followings, users that followed or liked
+// the same movies and users with the same favorite genres. every source and the users of each movie are limited,
+// so the cost doesn't grow with the number of users. newer users are read first on popular movies.
+// followed, requested, dismissed, banned, suspended and pending deletion users are skipped
+func (r *FollowSuggestionRepository) ComputeFollowSuggestions(userId int64, limit int) ([]model.FollowSuggestion, error) {
+	queryStr := `WITH following AS (
+			SELECT "followingId" AS id FROM "Follow" WHERE "followerId" = @user
+		), my_movies AS (
+			(SELECT "movieId" FROM "FollowMovie" WHERE "userId" = @user ORDER BY date DESC LIMIT @movieSample)
+			UNION
+			(SELECT "movieId" FROM "LikeDislikeMovie" WHERE "userId" = @user AND type = @like ORDER BY date DESC LIMIT @movieSample)
+		), my_genres AS (
+			(SELECT genre FROM "ComputedFavoriteGenres" WHERE "userId" = @user ORDER BY percent DESC LIMIT @genreSample)
+			UNION
+			SELECT unnest("favoriteGenres") FROM "User" WHERE "userId" = @user
+		), fof AS (
+			SELECT f."followingId" AS id, count(*) AS c FROM "Follow" f JOIN following ON f."followerId" = following.id
+			GROUP BY f."followingId" ORDER BY c DESC LIMIT @candidates
+		), movie_match AS (
+			SELECT m."userId" AS id, count(DISTINCT m."movieId") AS c FROM my_movies mm CROSS JOIN LATERAL (
+				(SELECT "userId", "movieId" FROM "FollowMovie" WHERE "movieId" = mm."movieId"
+					ORDER BY "userId" DESC LIMIT @movieFanOut)
+				UNION ALL
+				(SELECT "userId", "movieId" FROM "LikeDislikeMovie" WHERE "movieId" = mm."movieId" AND type = @like
+					ORDER BY "userId" DESC LIMIT @movieFanOut)
+			) m
+			GROUP BY m."userId" ORDER BY c DESC, m."userId" DESC LIMIT @candidates
+		), genre_candidates AS (
+			SELECT "userId" AS id FROM "ComputedFavoriteGenres" WHERE genre IN (SELECT genre FROM my_genres)
+			GROUP BY "userId" ORDER BY count(*) DESC, sum(percent) DESC, "userId" DESC LIMIT @candidates
+		), candidates AS (
+			SELECT id FROM fof UNION SELECT id FROM movie_match UNION SELECT id FROM genre_candidates
+		)
+		SELECT * FROM (
+			SELECT s.*, (s."mutualFollows" * @mutualWeight + s."commonMovies" * @movieWeight + s."commonGenres" * @genreWeight) AS score
+			FROM (
+				SELECT c.id AS "userId",
+					coalesce(fof.c, 0) AS "mutualFollows",
+					coalesce(mm.c, 0) AS "commonMovies",
+					(SELECT count(*) FROM (
+						SELECT genre FROM "ComputedFavoriteGenres" WHERE "userId" = c.id
+						UNION
+						SELECT unnest(u."favoriteGenres")
+					) g WHERE g.genre IN (SELECT genre FROM my_genres)) AS "commonGenres"
+				FROM candidates c
+				JOIN "User" u ON u."userId" = c.id
+				LEFT JOIN fof ON fof.id = c.id
+				LEFT JOIN movie_match mm ON mm.id = c.id
+				WHERE c.id != @user
+					AND c.id NOT IN (SELECT id FROM following)
+					AND NOT EXISTS (SELECT 1 FROM "FollowRequest" WHERE "requesterId" = @user AND "targetId" = c.id)
+					AND NOT EXISTS (SELECT 1 FROM "FollowSuggestionDismiss" WHERE "userId" = @user AND "suggestedId" = c.id)
+					AND u."deletionDate" = 0 AND u.banned = false AND u."suspendedUntil" < @now
+					AND u."lastSeenDate" > @activeAfter
+			) s
+		) t
+		WHERE t.score > 0
+		ORDER BY t.score DESC, t."mutualFollows" DESC, t."userId"
+		LIMIT @limit`
+
+	var result []model.FollowSuggestion
+	err := r.db.
+		Raw(queryStr, map[string]interface{}{
+			"user":         userId,
+			"like":         model.LIKE,
+			"movieSample":  followSuggestionMovieSample,
+			"genreSample":  followSuggestionGenreSample,
+			"movieFanOut":  followSuggestionMovieFanOut,
+			"candidates":   followSuggestionCandidates,
+			"mutualWeight": followSuggestionMutualWeight,
+			"movieWeight":  followSuggestionMovieWeight,
+			"genreWeight":  followSuggestionGenreWeight,
+			"now":          time.Now().UnixMilli(),
+			"activeAfter":  time.Now().Add(-followSuggestionActiveCutOff).UTC(),
+			"limit":        limit,
+		}).
+		Scan(&result).
+		Error
+	return result, err
+}
+
+// GetActiveUserIds returns users that are seen after seenAfter, ordered by userId, afterUserId is used for paginating
+func (r *FollowSuggestionRepository) GetActiveUserIds(seenAfter time.Time, afterUserId int64, limit int) ([]int64, error) {
+	var result []int64
+	err := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" > ? AND \"lastSeenDate\" > ? AND \"deletionDate\" = 0", afterUserId, seenAfter.UTC()).
+		Order("\"userId\"").
+		Limit(limit).
+		Pluck("userId", &result).
+		Error
+	return result, err
+}
+
+// AddFollowSuggestionDismiss returns gorm.ErrForeignKeyViolated if suggested user doesn't exist
+func (r *FollowSuggestionRepository) AddFollowSuggestionDismiss(userId int64, suggestedId int64) error {
+	dismiss := model.FollowSuggestionDismiss{
+		UserId:      userId,
+		SuggestedId: suggestedId,
+		Date:        time.Now().UTC(),
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&dismiss).Error
+}
+
+// GetFollowSuggestionUsers returns data of the users that are still suggestable, users that are followed or requested
+// after computing the suggestions are skipped. order of the result is not same as userIds
+func (r *FollowSuggestionRepository) GetFollowSuggestionUsers(viewerId int64, userIds []int64) ([]model.FollowSuggestionDataModel, error) {
+	var result []model.FollowSuggestionDataModel
+	err := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" IN ? AND \"deletionDate\" = 0 AND banned = false AND \"suspendedUntil\" < ?", userIds, time.Now().UnixMilli()).
+		Where("NOT EXISTS (SELECT 1 FROM \"Follow\" WHERE \"followerId\" = ? AND \"followingId\" = \"User\".\"userId\")", viewerId).
+		Where("NOT EXISTS (SELECT 1 FROM \"FollowRequest\" WHERE \"requesterId\" = ? AND \"targetId\" = \"User\".\"userId\")", viewerId).
+		Preload("ProfileImages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("\"addDate\" DESC")
+		}).
+		Find(&result).
+		Error
+	return result, err
+}
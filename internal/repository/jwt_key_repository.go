@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"downloader_gochat/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IJwtKeyRepository interface {
+	GetJwtKeys() ([]model.JwtKey, error)
+	ListJwtKeys() ([]model.JwtKey, error)
+	AddJwtKey(jwtKey *model.JwtKey) error
+	ActivateJwtKey(kid string) error
+	RetireJwtKey(kid string) error
+	GetFirstJwtKeyActivation() (*time.Time, error)
+}
+
+type JwtKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewJwtKeyRepository(db *gorm.DB) *JwtKeyRepository {
+	return &JwtKeyRepository{db: db}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GetJwtKeys returns the signing and verifying keys
+func (r *JwtKeyRepository) GetJwtKeys() ([]model.JwtKey, error) {
+	var result []model.JwtKey
+	err := r.db.
+		Where("status != ?", model.JwtKeyRetired).
+		Order("\"createdAt\" desc").
+		Find(&result).Error
+	return result, err
+}
+
+func (r *JwtKeyRepository) ListJwtKeys() ([]model.JwtKey, error) {
+	var result []model.JwtKey
+	err := r.db.
+		Select("kid", "algorithm", "\"publicKey\"", "status", "\"createdAt\"", "\"updatedAt\"", "\"activatedAt\"").
+		Order("\"createdAt\" desc").
+		Find(&result).Error
+	return result, err
+}
+
+func (r *JwtKeyRepository) AddJwtKey(jwtKey *model.JwtKey) error {
+	return r.db.Create(jwtKey).Error
+}
+
+// ActivateJwtKey makes the key the only signing key, the previous signing key keeps verifying tokens until retired
+func (r *JwtKeyRepository) ActivateJwtKey(kid string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var jwtKey model.JwtKey
+		err := tx.Where("kid = ?", kid).Take(&jwtKey).Error
+		if err != nil {
+			return err
+		}
+		if jwtKey.Status == model.JwtKeyRetired {
+			return errors.New("retired key cannot be activated")
+		}
+
+		err = tx.Model(&model.JwtKey{}).
+			Where("status = ? AND kid != ?", model.JwtKeySigning, kid).
+			Updates(map[string]interface{}{
+				"status":    model.JwtKeyVerifying,
+				"updatedAt": time.Now().UTC(),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&model.JwtKey{}).
+			Where("kid = ?", kid).
+			Updates(map[string]interface{}{
+				"status":      model.JwtKeySigning,
+				"updatedAt":   time.Now().UTC(),
+				"activatedAt": gorm.Expr("coalesce(\"activatedAt\", ?)", time.Now().UTC()),
+			}).Error
+	})
+}
+
+// RetireJwtKey stops publishing the key, tokens signed with it are rejected after that
+func (r *JwtKeyRepository) RetireJwtKey(kid string) error {
+	var jwtKey model.JwtKey
+	err := r.db.Where("kid = ?", kid).Take(&jwtKey).Error
+	if err != nil {
+		return err
+	}
+	if jwtKey.Status == model.JwtKeySigning {
+		return errors.New("signing key cannot be retired, activate another key first")
+	}
+
+	return r.db.Model(&model.JwtKey{}).
+		Where("kid = ?", kid).
+		Updates(map[string]interface{}{
+			"status":     model.JwtKeyRetired,
+			"privateKey": "",
+			"updatedAt":  time.Now().UTC(),
+		}).Error
+}
+
+// GetFirstJwtKeyActivation returns the first time any key, retired ones included, started signing. returns nil if no key is activated yet
+func (r *JwtKeyRepository) GetFirstJwtKeyActivation() (*time.Time, error) {
+	var result *time.Time
+	err := r.db.
+		Model(&model.JwtKey{}).
+		Select("min(\"activatedAt\")").
+		Scan(&result).Error
+	return result, err
+}
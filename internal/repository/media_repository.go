@@ -2,13 +2,18 @@ package repository
 
 import (
 	"downloader_gochat/model"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type IMediaRepository interface {
 	SaveMediaData(mediaFile *model.MediaFile) error
+	GetAndTouchMediaBlob(hash string) (*model.MediaBlob, error)
+	SaveMediaBlob(blob *model.MediaBlob) (bool, error)
+	RemoveUnusedMediaBlobs(unusedBefore time.Time) ([]model.MediaBlob, error)
 }
 
 type MediaRepository struct {
@@ -27,3 +32,39 @@ func (m *MediaRepository) SaveMediaData(mediaFile *model.MediaFile) error {
 	err := m.db.Create(mediaFile).Error
 	return err
 }
+
+//------------------------------------------
+//------------------------------------------
+
+// GetAndTouchMediaBlob returns the blob and refreshes its updatedAt,
+// an unused blob that has been touched won't be removed by RemoveUnusedMediaBlobs until the grace period passes
+func (m *MediaRepository) GetAndTouchMediaBlob(hash string) (*model.MediaBlob, error) {
+	blob := model.MediaBlob{}
+	result := m.db.Model(&blob).
+		Clauses(clause.Returning{}).
+		Where("hash = ?", hash).
+		Update("updatedAt", time.Now().UTC())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &blob, nil
+}
+
+// SaveMediaBlob returns false if another blob with the same hash already exists
+func (m *MediaRepository) SaveMediaBlob(blob *model.MediaBlob) (bool, error) {
+	result := m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(blob)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (m *MediaRepository) RemoveUnusedMediaBlobs(unusedBefore time.Time) ([]model.MediaBlob, error) {
+	var rows []model.MediaBlob
+	err := m.db.
+		Clauses(clause.Returning{}).
+		Where("\"refCount\" <= 0 AND \"updatedAt\" < ?", unusedBefore).
+		Delete(&rows).
+		Error
+	return rows, err
+}
@@ -18,6 +18,7 @@ type INotificationRepository interface {
 	GetBatchUserMetaDataWithImage(ids []int64) ([]model.UserMetaWithImageDataModel, error)
 	BatchUpdateNotificationStatusByDate(date time.Time, receiverId int64, entityTypeId int, status int) error
 	BatchUpdateNotificationStatusById(receiverId int64, nid int64, entityTypeId int, status int) error
+	IsUserPendingDeletion(userId int64) (bool, error)
 }
 
 type NotificationRepository struct {
@@ -57,6 +58,8 @@ func (n *NotificationRepository) GetUserNotifications(userId int64, skip int, li
 	if status > 0 {
 		queryStr = queryStr + " AND status = @status"
 	}
+	// notifications of accounts that are pending deletion are hidden until they are restored
+	queryStr = queryStr + " AND \"creatorId\" NOT IN (SELECT \"userId\" FROM \"User\" WHERE \"deletionDate\" > 0)"
 	err := n.db.Where(queryStr, map[string]interface{}{
 		"receiverid":   userId,
 		"entitytypeid": entityTypeId,
@@ -190,3 +193,14 @@ func (n *NotificationRepository) GetBatchUserMetaDataWithImage(ids []int64) ([]m
 
 	return userDataModel, nil
 }
+
+// IsUserPendingDeletion returns false if user doesn't exist
+func (n *NotificationRepository) IsUserPendingDeletion(userId int64) (bool, error) {
+	var count int64
+	err := n.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"deletionDate\" > 0", userId).
+		Count(&count).
+		Error
+	return count > 0, err
+}
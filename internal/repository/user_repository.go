@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"go.mongodb.org/mongo-driver/mongo"
 	"gorm.io/gorm"
@@ -20,12 +22,28 @@ type IUserRepository interface {
 	GetUserByUsernameEmailAndUserId(userId int64, username string, email string) (*model.UserDataModel, error)
 	GetUserProfile(requestParams *model.UserProfileReq) (*model.UserProfileRes, error)
 	GetUserMetaData(id int64) (*model.UserDataModel, error)
+	GetUserSuspension(userId int64) (*model.UserSuspension, error)
 	EditUserProfile(userId int64, editFields *model.EditProfileReq, updateFields map[string]interface{}) error
 	UpdateUserPassword(userId int64, passwords *model.UpdatePasswordReq) error
 	SaveUserEmailToken(userId int64, token string, expire int64) error
 	SaveDeleteAccountToken(userId int64, token string, expire int64) error
 	VerifyUserEmailToken(userId int64, token string) error
 	VerifyDeleteAccountToken(userId int64, token string) error
+	SaveResetPasswordToken(userId int64, token string, expire int64, lastTokenExpireBefore int64) (bool, error)
+	ResetPasswordWithToken(userId int64, token string, hashedPassword string) ([]model.ActiveSession, error)
+	SaveEmailChangeTokens(userId int64, newEmail string, changeToken string, changeExpire int64, revertToken string, revertExpire int64) (bool, error)
+	ConfirmEmailChangeToken(userId int64, token string) error
+	RevertEmailChangeToken(userId int64, token string) ([]model.ActiveSession, error)
+	GetUserTwoFactor(userId int64) (*model.UserTwoFactorDataModel, error)
+	SaveTwoFactorSecret(userId int64, secret string) error
+	EnableTwoFactor(userId int64, step int64, recoveryCodeHashes []string) error
+	DisableTwoFactor(userId int64) error
+	UpdateTwoFactorLastStep(userId int64, step int64) (bool, error)
+	UseTwoFactorRecoveryCode(userId int64, codeHash string) (bool, error)
+	ReplaceTwoFactorRecoveryCodes(userId int64, codeHashes []string) error
+	ScheduleUserDeletion(userId int64, deletionDate int64) ([]model.ActiveSession, error)
+	RestoreUserDeletion(userId int64) (bool, error)
+	ClaimUsersToDelete(deletionDateBefore int64, claimUntil int64, limit int) ([]int64, error)
 	DeleteUserAndRelatedData(userId int64) error
 	GetProfileImagesCount(userId int64) (int64, error)
 	GetProfileImages(userId int64) (*[]model.ProfileImageDataModel, error)
@@ -36,6 +54,7 @@ type IUserRepository interface {
 	UpdateSession(device *model.DeviceInfo, deviceId string, userId int64, refreshToken string, ipLocation string) (bool, error)
 	UpdateSessionRefreshToken(device *model.DeviceInfo, userId int64, refreshToken string, prevRefreshToken string, ipLocation string) (*model.ActiveSession, error)
 	UpdateSessionNotifToken(userId int64, refreshToken string, notifToken string) error
+	RemoveSessionByTokenFamily(userId int64, deviceId string, tokenFamily string) (*model.ActiveSession, error)
 	GetUserActiveSessions(userId int64) ([]model.ActiveSession, error)
 	RemoveSession(userId int64, prevRefreshToken string) error
 	RemoveSessions(userId int64, prevRefreshTokens []string) error
@@ -45,6 +64,16 @@ type IUserRepository interface {
 	RemoveUserFollow(userId int64, followId int64) error
 	GetUserFollowers(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
 	GetUserFollowings(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
+	IsFollowing(userId int64, followId int64) (bool, error)
+	GetFollowRelation(viewerId int64, userId int64) (*model.FollowRelation, error)
+	GetMutualFollowers(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
+	IsPrivateAccount(userId int64) (bool, error)
+	SetPrivateAccount(userId int64, privateAccount bool) ([]int64, error)
+	AddFollowRequest(userId int64, targetId int64) error
+	RemoveFollowRequest(requesterId int64, targetId int64) error
+	AcceptFollowRequest(requesterId int64, targetId int64) error
+	GetFollowRequests(userId int64, skip int, limit int) ([]model.FollowRequestDataModel, error)
+	SearchUsers(viewerId int64, query string, skip int, limit int) ([]model.UserSearchDataModel, error)
 	GetUserMetaDataAndNotificationSettings(id int64, imageLimit int) (*model.UserMetaWithNotificationSettings, error)
 	GetUserDownloadLinkSettings(userId int64) (*model.DownloadLinksSettings, error)
 	GetUserNotificationSettings(userId int64) (*model.NotificationSettings, error)
@@ -54,6 +83,16 @@ type IUserRepository interface {
 	UpdateUserMovieSettings(userId int64, settings model.MovieSettings) error
 	UpdateUserFavoriteGenres(userId int64, genresArray []string) error
 	GetActiveSessions(userId int64) ([]model.ActiveSessionDataModel, error)
+	AddLoginHistory(userId int64, deviceKey string, deviceId string, device *model.DeviceInfo, ipLocation string) (*model.LoginHistory, error)
+	GetUserDevices(userId int64) ([]model.UserDeviceDataModel, error)
+	IsKnownUserDevice(userId int64, deviceKey string) (bool, error)
+	UpdateUserDeviceTrusted(userId int64, deviceKey string, trusted bool) error
+	GetLoginHistory(userId int64, skip int, limit int) ([]model.LoginHistoryDataModel, error)
+	GetUserIdentity(provider string, subject string) (*model.UserIdentity, error)
+	GetUserIdentities(userId int64) ([]model.UserIdentityDataModel, error)
+	AddUserIdentity(identity *model.UserIdentity) error
+	UpdateUserIdentityLogin(id int64, email string) error
+	RemoveUserIdentity(userId int64, provider string) error
 	GetUserBots(userId int64) ([]model.UserBotDataModel, error)
 	GetBotData(botId string) (*model.Bot, error)
 	GetUserRoles(userId int64) ([]model.Role, error)
@@ -206,6 +245,10 @@ func (r *UserRepository) GetUserProfile(requestParams *model.UserProfileReq) (*m
 	query := r.db.Model(&model.User{}).
 		Where("\"userId\" = ?", requestParams.UserId).
 		Limit(1)
+	if !requestParams.IsSelfProfile {
+		// accounts that are pending deletion are hidden
+		query = query.Where("\"deletionDate\" = 0")
+	}
 
 	if requestParams.LoadProfileImages {
 		query = query.Preload("ProfileImages", func(db *gorm.DB) *gorm.DB {
@@ -257,27 +300,14 @@ func (r *UserRepository) GetUserProfile(requestParams *model.UserProfileReq) (*m
 		}
 	}
 
-	if requestParams.LoadFollowersCount {
-		type countRes struct {
-			FollowersCount  int64 `gorm:"column:f1;"`
-			FollowingsCount int64 `gorm:"column:f2;"`
-		}
-		var res countRes
-
-		err := r.db.
-			Raw("SELECT count(1) filter ( where \"followingId\" = ? ) as f1, count(1) filter ( where \"followerId\" = ? ) as f2 FROM \"Follow\"",
-				requestParams.UserId, requestParams.UserId).
-			Scan(&res).
-			Error
-
-		if err == nil {
-			result.FollowersCount = res.FollowersCount
-			result.FollowingsCount = res.FollowingsCount
-		}
+	if !requestParams.LoadFollowersCount {
+		result.FollowersCount = 0
+		result.FollowingsCount = 0
 	}
 	if !requestParams.IsSelfProfile {
 		result.ComputedStatsLastUpdate = 0
 		result.Email = ""
+		result.PendingEmail = ""
 	}
 
 	return &result, nil
@@ -297,6 +327,33 @@ func (r *UserRepository) GetUserMetaData(id int64) (*model.UserDataModel, error)
 	return &userDataModel, nil
 }
 
+// GetUserSuspension returns nil if user is not suspended or suspension is expired
+func (r *UserRepository) GetUserSuspension(userId int64) (*model.UserSuspension, error) {
+	var result model.AdminUserDataModel
+	err := r.db.
+		Model(&model.AdminUserDataModel{}).
+		Select("\"userId\"", "banned", "\"suspendedUntil\"", "\"suspensionReason\"", "\"suspendedBy\"").
+		Where("\"userId\" = ?", userId).
+		Limit(1).
+		Find(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	suspension := model.UserSuspension{
+		UserId:      result.UserId,
+		Banned:      result.Banned,
+		Until:       result.SuspendedUntil,
+		Reason:      result.SuspensionReason,
+		SuspendedBy: result.SuspendedBy,
+	}
+	if result.UserId == 0 || !suspension.IsActive() {
+		return nil, nil
+	}
+	return &suspension, nil
+}
+
 func (r *UserRepository) EditUserProfile(userId int64, editFields *model.EditProfileReq, updateFields map[string]interface{}) error {
 	res := r.db.
 		Model(&model.User{}).
@@ -411,9 +468,331 @@ func (r *UserRepository) VerifyDeleteAccountToken(userId int64, token string) er
 	return nil
 }
 
+// SaveResetPasswordToken returns false if the previous token has an expire time after lastTokenExpireBefore,
+// used to limit the number of reset emails per account
+func (r *UserRepository) SaveResetPasswordToken(userId int64, token string, expire int64, lastTokenExpireBefore int64) (bool, error) {
+	res := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"resetPasswordToken_expire\" <= ?", userId, lastTokenExpireBefore).
+		Limit(1).
+		Updates(map[string]interface{}{
+			"resetPasswordToken":        token,
+			"resetPasswordToken_expire": expire,
+		})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// ResetPasswordWithToken consumes the token, updates the password and removes all the sessions of user,
+// returns the removed sessions
+func (r *UserRepository) ResetPasswordWithToken(userId int64, token string, hashedPassword string) ([]model.ActiveSession, error) {
+	var sessions []model.ActiveSession
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Model(&model.User{}).
+			Where("\"userId\" = ? AND \"resetPasswordToken\" = ? AND \"resetPasswordToken_expire\" >= ? ",
+				userId, token, time.Now().UnixMilli()).
+			Limit(1).
+			Updates(map[string]interface{}{
+				"password":                  hashedPassword,
+				"resetPasswordToken":        "",
+				"resetPasswordToken_expire": 0,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "refreshToken"}, {Name: "notifToken"}}}).
+			Where("\"userId\" = ?", userId).
+			Delete(&sessions).
+			Error
+	})
+
+	return sessions, err
+}
+
+// SaveEmailChangeTokens starts a new email change, returns false if the revert link of a confirmed change is not expired yet,
+// otherwise the new change could invalidate the revert link of the previous owner
+func (r *UserRepository) SaveEmailChangeTokens(userId int64, newEmail string, changeToken string, changeExpire int64, revertToken string, revertExpire int64) (bool, error) {
+	res := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND NOT (\"previousEmail\" != '' AND \"emailRevertToken_expire\" >= ?)", userId, time.Now().UnixMilli()).
+		Limit(1).
+		Updates(map[string]interface{}{
+			"pendingEmail":            newEmail,
+			"previousEmail":           "",
+			"emailChangeToken":        changeToken,
+			"emailChangeToken_expire": changeExpire,
+			"emailRevertToken":        revertToken,
+			"emailRevertToken_expire": revertExpire,
+		})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// ConfirmEmailChangeToken replaces the email with the pending one, old email is kept for the revert link.
+// tokens sent to the old email are not valid anymore
+func (r *UserRepository) ConfirmEmailChangeToken(userId int64, token string) error {
+	res := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"pendingEmail\" != '' AND \"emailChangeToken\" = ? AND \"emailChangeToken_expire\" >= ? ",
+			userId, token, time.Now().UnixMilli()).
+		Limit(1).
+		Updates(map[string]interface{}{
+			"email":                     gorm.Expr("\"pendingEmail\""),
+			"previousEmail":             gorm.Expr("email"),
+			"pendingEmail":              "",
+			"emailChangeToken":          "",
+			"emailChangeToken_expire":   0,
+			"emailVerified":             true,
+			"emailVerifyToken":          "",
+			"emailVerifyToken_expire":   0,
+			"resetPasswordToken":        "",
+			"resetPasswordToken_expire": 0,
+		})
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevertEmailChangeToken cancels the pending change or restores the previous email if the change is confirmed,
+// all the sessions of user are removed. returns the removed sessions
+func (r *UserRepository) RevertEmailChangeToken(userId int64, token string) ([]model.ActiveSession, error) {
+	var sessions []model.ActiveSession
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Model(&model.User{}).
+			Where("\"userId\" = ? AND \"emailRevertToken\" = ? AND \"emailRevertToken_expire\" >= ? ",
+				userId, token, time.Now().UnixMilli()).
+			Limit(1).
+			Updates(map[string]interface{}{
+				"email":                     gorm.Expr("CASE WHEN \"previousEmail\" != '' THEN \"previousEmail\" ELSE email END"),
+				"emailVerified":             gorm.Expr("CASE WHEN \"previousEmail\" != '' THEN true ELSE \"emailVerified\" END"),
+				"previousEmail":             "",
+				"pendingEmail":              "",
+				"emailChangeToken":          "",
+				"emailChangeToken_expire":   0,
+				"emailRevertToken":          "",
+				"emailRevertToken_expire":   0,
+				"resetPasswordToken":        "",
+				"resetPasswordToken_expire": 0,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "refreshToken"}, {Name: "notifToken"}}}).
+			Where("\"userId\" = ?", userId).
+			Delete(&sessions).
+			Error
+	})
+
+	return sessions, err
+}
+
 //------------------------------------------
 //------------------------------------------
 
+func (r *UserRepository) GetUserTwoFactor(userId int64) (*model.UserTwoFactorDataModel, error) {
+	var result model.UserTwoFactorDataModel
+	err := r.db.
+		Where("\"userId\" = ?", userId).
+		Limit(1).
+		Find(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if result.UserId == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &result, nil
+}
+
+// SaveTwoFactorSecret saves the secret of pending enrollment, does nothing if two-factor is already enabled
+func (r *UserRepository) SaveTwoFactorSecret(userId int64, secret string) error {
+	res := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"twoFactorEnabled\" = false", userId).
+		Limit(1).
+		Updates(map[string]interface{}{
+			"twoFactorSecret":   secret,
+			"twoFactorLastStep": 0,
+		})
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) EnableTwoFactor(userId int64, step int64, recoveryCodeHashes []string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Model(&model.User{}).
+			Where("\"userId\" = ? AND \"twoFactorEnabled\" = false AND \"twoFactorSecret\" != '' AND \"twoFactorLastStep\" < ?", userId, step).
+			Limit(1).
+			Updates(map[string]interface{}{
+				"twoFactorEnabled":  true,
+				"twoFactorLastStep": step,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return replaceTwoFactorRecoveryCodes(tx, userId, recoveryCodeHashes)
+	})
+
+	return err
+}
+
+func (r *UserRepository) DisableTwoFactor(userId int64) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&model.User{}).
+			Where("\"userId\" = ?", userId).
+			Limit(1).
+			Updates(map[string]interface{}{
+				"twoFactorEnabled":  false,
+				"twoFactorSecret":   "",
+				"twoFactorLastStep": 0,
+			}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.
+			Where("\"userId\" = ?", userId).
+			Delete(&model.TwoFactorRecoveryCode{}).
+			Error
+	})
+
+	return err
+}
+
+// UpdateTwoFactorLastStep returns false if a code of this step or a later one has been used before
+func (r *UserRepository) UpdateTwoFactorLastStep(userId int64, step int64) (bool, error) {
+	res := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"twoFactorLastStep\" < ?", userId, step).
+		Limit(1).
+		UpdateColumn("twoFactorLastStep", step)
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// UseTwoFactorRecoveryCode removes the recovery code, returns false if it doesn't exist
+func (r *UserRepository) UseTwoFactorRecoveryCode(userId int64, codeHash string) (bool, error) {
+	res := r.db.
+		Where("\"userId\" = ? AND \"codeHash\" = ?", userId, codeHash).
+		Delete(&model.TwoFactorRecoveryCode{})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *UserRepository) ReplaceTwoFactorRecoveryCodes(userId int64, codeHashes []string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceTwoFactorRecoveryCodes(tx, userId, codeHashes)
+	})
+
+	return err
+}
+
+func replaceTwoFactorRecoveryCodes(tx *gorm.DB, userId int64, codeHashes []string) error {
+	err := tx.
+		Where("\"userId\" = ?", userId).
+		Delete(&model.TwoFactorRecoveryCode{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	codes := make([]model.TwoFactorRecoveryCode, len(codeHashes))
+	for i := range codeHashes {
+		codes[i] = model.TwoFactorRecoveryCode{
+			UserId:    userId,
+			CodeHash:  codeHashes[i],
+			CreatedAt: time.Now().UTC(),
+		}
+	}
+	return tx.Create(&codes).Error
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// ScheduleUserDeletion marks the account as pending deletion and removes its sessions,
+// account is hidden from other users until it's restored or removed at deletionDate
+func (r *UserRepository) ScheduleUserDeletion(userId int64, deletionDate int64) ([]model.ActiveSession, error) {
+	var sessions []model.ActiveSession
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Model(&model.User{}).
+			Where("\"userId\" = ?", userId).
+			Limit(1).
+			Update("deletionDate", deletionDate)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "refreshToken"}, {Name: "notifToken"}}}).
+			Where("\"userId\" = ?", userId).
+			Delete(&sessions).
+			Error
+	})
+
+	return sessions, err
+}
+
+// RestoreUserDeletion returns false if the account wasn't pending deletion
+func (r *UserRepository) RestoreUserDeletion(userId int64) (bool, error) {
+	res := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"deletionDate\" > 0", userId).
+		Limit(1).
+		Update("deletionDate", 0)
+	return res.RowsAffected > 0, res.Error
+}
+
+// ClaimUsersToDelete moves deletionDate of the accounts to claimUntil and returns them,
+// rows that are locked by other servers are skipped. accounts that are not removed are claimed again after claimUntil
+func (r *UserRepository) ClaimUsersToDelete(deletionDateBefore int64, claimUntil int64, limit int) ([]int64, error) {
+	var result []int64
+	err := r.db.
+		Raw(`UPDATE "User" SET "deletionDate" = ?
+			WHERE "userId" IN (SELECT "userId" FROM "User" WHERE "deletionDate" > 0 AND "deletionDate" <= ?
+				ORDER BY "deletionDate" LIMIT ? FOR UPDATE SKIP LOCKED)
+			RETURNING "userId"`,
+			claimUntil, deletionDateBefore, limit).
+		Scan(&result).
+		Error
+	return result, err
+}
+
 func (r *UserRepository) DeleteUserAndRelatedData(userId int64) error {
 	err := r.db.Transaction(func(tx *gorm.DB) error {
 		//handle movie counts decrement
@@ -783,6 +1162,7 @@ func (r *UserRepository) AddSession(device *model.DeviceInfo, deviceId string, u
 		DeviceOs:     device.Os,
 		NotifToken:   device.NotifToken,
 		IpLocation:   ipLocation,
+		TokenFamily:  uuid.NewString(),
 	}
 	err := r.db.Create(&newDevice).Error
 	if err != nil {
@@ -804,12 +1184,14 @@ func (r *UserRepository) UpdateSession(device *model.DeviceInfo, deviceId string
 		NotifToken:   device.NotifToken,
 		IpLocation:   ipLocation,
 		LoginDate:    now,
+		TokenFamily:  uuid.NewString(),
 	}
 
+	// login starts a new token family
 	err := r.db.Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "userId"}, {Name: "deviceId"}},
 		DoUpdates: clause.AssignmentColumns([]string{
-			"appName", "appVersion", "deviceOs", "deviceModel", "ipLocation", "lastUseDate", "refreshToken"}),
+			"appName", "appVersion", "deviceOs", "deviceModel", "ipLocation", "lastUseDate", "refreshToken", "tokenFamily"}),
 	}).Create(&newDevice).Error
 
 	if err != nil {
@@ -845,6 +1227,23 @@ func (r *UserRepository) UpdateSessionRefreshToken(device *model.DeviceInfo, use
 	return &activeSession, nil
 }
 
+// RemoveSessionByTokenFamily returns nil if the session is already removed or the device has logged in again
+func (r *UserRepository) RemoveSessionByTokenFamily(userId int64, deviceId string, tokenFamily string) (*model.ActiveSession, error) {
+	var result []model.ActiveSession
+	err := r.db.
+		Clauses(clause.Returning{}).
+		Where("\"userId\" = ? AND \"deviceId\" = ? AND \"tokenFamily\" = ?", userId, deviceId, tokenFamily).
+		Delete(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
 func (r *UserRepository) UpdateSessionNotifToken(userId int64, refreshToken string, notifToken string) error {
 	activeSession := model.ActiveSession{}
 	result := r.db.Model(&activeSession).
@@ -945,13 +1344,27 @@ func (r *UserRepository) RemoveAllAuthSession(userId int64, refreshToken string)
 //------------------------------------------
 
 func (r *UserRepository) AddUserFollow(userId int64, followId int64) error {
+	var pendingDeletion int64
+	err := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"deletionDate\" > 0", followId).
+		Count(&pendingDeletion).
+		Error
+	if err != nil {
+		return err
+	}
+	if pendingDeletion > 0 {
+		// account is pending deletion, same as not existing
+		return gorm.ErrForeignKeyViolated
+	}
+
 	follow := model.Follow{
 		FollowerId:  userId,
 		FollowingId: followId,
 		AddDate:     time.Now().UTC(),
 	}
 
-	err := r.db.Create(&follow).Error
+	err = r.db.Create(&follow).Error
 	return err
 }
 
@@ -963,6 +1376,7 @@ func (r *UserRepository) RemoveUserFollow(userId int64, followId int64) error {
 func (r *UserRepository) GetUserFollowers(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
 	var result []model.FollowUserDataModel
 	err := r.db.Model(&model.User{}).Joins("join \"Follow\" on \"userId\" = \"followerId\" AND \"followingId\" = ? ", userId).
+		Where("\"deletionDate\" = 0").
 		Order("\"addDate\" desc").
 		Offset(skip).
 		Limit(limit).
@@ -977,6 +1391,7 @@ func (r *UserRepository) GetUserFollowers(userId int64, skip int, limit int) ([]
 func (r *UserRepository) GetUserFollowings(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
 	var result []model.FollowUserDataModel
 	err := r.db.Model(&model.User{}).Joins("join \"Follow\" on \"userId\" = \"followingId\" AND \"followerId\" = ? ", userId).
+		Where("\"deletionDate\" = 0").
 		Order("\"addDate\" desc").
 		Offset(skip).
 		Limit(limit).
@@ -988,6 +1403,218 @@ func (r *UserRepository) GetUserFollowings(userId int64, skip int, limit int) ([
 	return result, err
 }
 
+func (r *UserRepository) IsFollowing(userId int64, followId int64) (bool, error) {
+	var count int64
+	err := r.db.
+		Model(&model.Follow{}).
+		Where("\"followerId\" = ? AND \"followingId\" = ?", userId, followId).
+		Count(&count).
+		Error
+	return count > 0, err
+}
+
+func (r *UserRepository) GetFollowRelation(viewerId int64, userId int64) (*model.FollowRelation, error) {
+	var result model.FollowRelation
+	err := r.db.
+		Raw(`SELECT
+			EXISTS(SELECT 1 FROM "Follow" WHERE "followerId" = @viewer AND "followingId" = @user) AS "isFollowing",
+			EXISTS(SELECT 1 FROM "Follow" WHERE "followerId" = @user AND "followingId" = @viewer) AS "followsYou",
+			EXISTS(SELECT 1 FROM "FollowRequest" WHERE "requesterId" = @viewer AND "targetId" = @user) AS "followRequested"`,
+			map[string]interface{}{
+				"viewer": viewerId,
+				"user":   userId,
+			}).
+		Scan(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetMutualFollowers returns followings of viewer that follow the user, newest follows first
+func (r *UserRepository) GetMutualFollowers(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
+	var result []model.FollowUserDataModel
+	err := r.db.Model(&model.User{}).
+		Joins("join \"Follow\" f1 on f1.\"followerId\" = ? AND f1.\"followingId\" = \"userId\"", viewerId).
+		Joins("join \"Follow\" f2 on f2.\"followerId\" = \"userId\" AND f2.\"followingId\" = ?", userId).
+		Where("\"deletionDate\" = 0").
+		Order("f2.\"addDate\" desc").
+		Offset(skip).
+		Limit(limit).
+		Preload("ProfileImages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("\"addDate\" DESC")
+		}).
+		Find(&result).Error
+
+	return result, err
+}
+
+// IsPrivateAccount returns gorm.ErrRecordNotFound if user doesn't exist or is pending deletion
+func (r *UserRepository) IsPrivateAccount(userId int64) (bool, error) {
+	var result []bool
+	err := r.db.
+		Model(&model.User{}).
+		Where("\"userId\" = ? AND \"deletionDate\" = 0", userId).
+		Limit(1).
+		Pluck("privateAccount", &result).
+		Error
+	if err != nil {
+		return false, err
+	}
+	if len(result) == 0 {
+		return false, gorm.ErrRecordNotFound
+	}
+	return result[0], nil
+}
+
+// SetPrivateAccount updates the setting, when account becomes public the pending follow requests are accepted.
+// returns id of the users that their requests are accepted
+func (r *UserRepository) SetPrivateAccount(userId int64, privateAccount bool) ([]int64, error) {
+	var accepted []int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&model.User{}).
+			Where("\"userId\" = ?", userId).
+			UpdateColumn("privateAccount", privateAccount).
+			Error
+		if err != nil || privateAccount {
+			return err
+		}
+
+		var requests []model.FollowRequest
+		err = tx.
+			Clauses(clause.Returning{}).
+			Where("\"targetId\" = ?", userId).
+			Delete(&requests).
+			Error
+		if err != nil || len(requests) == 0 {
+			return err
+		}
+
+		follows := make([]model.Follow, len(requests))
+		for i := range requests {
+			follows[i] = model.Follow{
+				FollowerId:  requests[i].RequesterId,
+				FollowingId: userId,
+				AddDate:     time.Now().UTC(),
+			}
+			accepted = append(accepted, requests[i].RequesterId)
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&follows).Error
+	})
+	return accepted, err
+}
+
+// AddFollowRequest returns gorm.ErrDuplicatedKey if the request already exists
+func (r *UserRepository) AddFollowRequest(userId int64, targetId int64) error {
+	request := model.FollowRequest{
+		RequesterId: userId,
+		TargetId:    targetId,
+		Date:        time.Now().UTC(),
+	}
+	return r.db.Create(&request).Error
+}
+
+// RemoveFollowRequest returns gorm.ErrRecordNotFound if the request doesn't exist
+func (r *UserRepository) RemoveFollowRequest(requesterId int64, targetId int64) error {
+	res := r.db.
+		Where("\"requesterId\" = ? AND \"targetId\" = ?", requesterId, targetId).
+		Delete(&model.FollowRequest{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptFollowRequest moves the request to Follow, returns gorm.ErrRecordNotFound if the request doesn't exist
+func (r *UserRepository) AcceptFollowRequest(requesterId int64, targetId int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Where("\"requesterId\" = ? AND \"targetId\" = ?", requesterId, targetId).
+			Delete(&model.FollowRequest{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		follow := model.Follow{
+			FollowerId:  requesterId,
+			FollowingId: targetId,
+			AddDate:     time.Now().UTC(),
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error
+	})
+}
+
+// GetFollowRequests returns incoming requests of the user, newest first
+func (r *UserRepository) GetFollowRequests(userId int64, skip int, limit int) ([]model.FollowRequestDataModel, error) {
+	var result []model.FollowRequestDataModel
+	err := r.db.Model(&model.User{}).
+		Select("\"User\".*, \"FollowRequest\".date").
+		Joins("join \"FollowRequest\" on \"userId\" = \"requesterId\" AND \"targetId\" = ? ", userId).
+		Where("\"deletionDate\" = 0").
+		Order("\"FollowRequest\".date desc").
+		Offset(skip).
+		Limit(limit).
+		Preload("ProfileImages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("\"addDate\" DESC")
+		}).
+		Find(&result).Error
+
+	return result, err
+}
+
+// SearchUsers matches the start of username and publicName, queries with UserSearchMinFuzzyLength or more characters
+// are also matched by trigram similarity. rawUsername is covered by username, it's the lowercase of it.
+// mutual follows come first, then followings, then prefix matches, then the most similar ones.
+// banned, suspended and pending deletion users are skipped
+func (r *UserRepository) SearchUsers(viewerId int64, query string, skip int, limit int) ([]model.UserSearchDataModel, error) {
+	matchQuery := "u.username LIKE @prefix OR lower(u.\"publicName\") LIKE @prefix"
+	if utf8.RuneCountInString(query) >= model.UserSearchMinFuzzyLength {
+		matchQuery += " OR u.username % @query OR u.\"publicName\" % @query"
+	}
+
+	// profile image is joined after pagination, so it's only read for the returned rows
+	queryStr := `SELECT s.*, pi.thumbnail, pi."blurHash" FROM (
+			SELECT u."userId", u.username, u."rawUsername", u."publicName",
+				f1."followerId" IS NOT NULL AS "isFollowing", f2."followerId" IS NOT NULL AS "followsYou",
+				(u.username LIKE @prefix OR lower(u."publicName") LIKE @prefix) AS "prefixMatch",
+				greatest(similarity(u.username, @query), similarity(u."publicName", @query)) AS similarity
+			FROM "User" u
+			LEFT JOIN "Follow" f1 ON f1."followerId" = @viewer AND f1."followingId" = u."userId"
+			LEFT JOIN "Follow" f2 ON f2."followerId" = u."userId" AND f2."followingId" = @viewer
+			WHERE (` + matchQuery + `)
+				AND u."userId" != @viewer AND u."deletionDate" = 0 AND u.banned = false AND u."suspendedUntil" < @now
+			ORDER BY (f1."followerId" IS NOT NULL AND f2."followerId" IS NOT NULL) DESC, f1."followerId" IS NOT NULL DESC,
+				"prefixMatch" DESC, similarity DESC, u."userId"
+			OFFSET @skip LIMIT @limit
+		) s
+		LEFT JOIN LATERAL (
+			SELECT thumbnail, "blurHash" FROM "ProfileImage" WHERE "userId" = s."userId" ORDER BY "addDate" DESC LIMIT 1
+		) pi ON true
+		ORDER BY ("isFollowing" AND "followsYou") DESC, "isFollowing" DESC, "prefixMatch" DESC, similarity DESC, s."userId"`
+
+	var result []model.UserSearchDataModel
+	err := r.db.
+		Raw(queryStr, map[string]interface{}{
+			"prefix": likeEscaper.Replace(query) + "%",
+			"query":  query,
+			"viewer": viewerId,
+			"now":    time.Now().UnixMilli(),
+			"skip":   skip,
+			"limit":  limit,
+		}).
+		Scan(&result).
+		Error
+	return result, err
+}
+
 func (r *UserRepository) GetUserMetaDataAndNotificationSettings(id int64, imageLimit int) (*model.UserMetaWithNotificationSettings, error) {
 	var result model.UserMetaWithNotificationSettings
 	err := r.db.
@@ -1132,6 +1759,192 @@ func (r *UserRepository) GetActiveSessions(userId int64) ([]model.ActiveSessionD
 //------------------------------------------
 //------------------------------------------
 
+// AddLoginHistory records the login and marks it as new device or new location compared to previous logins.
+// the first recorded login of user (signup or first login after this was added) is never marked
+func (r *UserRepository) AddLoginHistory(userId int64, deviceKey string, deviceId string, device *model.DeviceInfo, ipLocation string) (*model.LoginHistory, error) {
+	now := time.Now().UTC()
+	loginHistory := model.LoginHistory{
+		UserId:      userId,
+		DeviceKey:   deviceKey,
+		DeviceId:    deviceId,
+		AppName:     device.AppName,
+		AppVersion:  device.AppVersion,
+		DeviceModel: device.DeviceModel,
+		DeviceOs:    device.Os,
+		IpLocation:  ipLocation,
+		Date:        now,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var hasHistory bool
+		err := tx.Raw("SELECT EXISTS(SELECT 1 FROM \"LoginHistory\" WHERE \"userId\" = ?)", userId).Scan(&hasHistory).Error
+		if err != nil {
+			return err
+		}
+
+		if hasHistory {
+			var userDevice []model.UserDevice
+			err = tx.
+				Where("\"userId\" = ? AND \"deviceKey\" = ?", userId, deviceKey).
+				Limit(1).
+				Find(&userDevice).Error
+			if err != nil {
+				return err
+			}
+			loginHistory.NewDevice = len(userDevice) == 0
+
+			// trusted devices can move between locations without alert
+			if ipLocation != "" && (len(userDevice) == 0 || !userDevice[0].Trusted) {
+				var seenLocation bool
+				err = tx.Raw("SELECT EXISTS(SELECT 1 FROM \"LoginHistory\" WHERE \"userId\" = ? AND \"ipLocation\" = ?)", userId, ipLocation).
+					Scan(&seenLocation).Error
+				if err != nil {
+					return err
+				}
+				loginHistory.NewLocation = !seenLocation
+			}
+		}
+
+		userDevice := model.UserDevice{
+			UserId:         userId,
+			DeviceKey:      deviceKey,
+			DeviceId:       deviceId,
+			AppName:        device.AppName,
+			DeviceModel:    device.DeviceModel,
+			DeviceOs:       device.Os,
+			LastIpLocation: ipLocation,
+			FirstLoginDate: now,
+			LastLoginDate:  now,
+		}
+		err = tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "userId"}, {Name: "deviceKey"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"deviceId", "appName", "deviceModel", "deviceOs", "lastIpLocation", "lastLoginDate"}),
+		}).Create(&userDevice).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&loginHistory).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return &loginHistory, nil
+}
+
+func (r *UserRepository) GetUserDevices(userId int64) ([]model.UserDeviceDataModel, error) {
+	var result []model.UserDeviceDataModel
+	err := r.db.
+		Model(&model.UserDeviceDataModel{}).
+		Where("\"userId\" = ?", userId).
+		Order("\"lastLoginDate\" desc").
+		Find(&result).
+		Error
+	return result, err
+}
+
+func (r *UserRepository) IsKnownUserDevice(userId int64, deviceKey string) (bool, error) {
+	var count int64
+	err := r.db.
+		Model(&model.UserDevice{}).
+		Where("\"userId\" = ? AND \"deviceKey\" = ?", userId, deviceKey).
+		Count(&count).
+		Error
+	return count > 0, err
+}
+
+func (r *UserRepository) UpdateUserDeviceTrusted(userId int64, deviceKey string, trusted bool) error {
+	result := r.db.
+		Model(&model.UserDevice{}).
+		Where("\"userId\" = ? AND \"deviceKey\" = ?", userId, deviceKey).
+		UpdateColumn("trusted", trusted)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) GetLoginHistory(userId int64, skip int, limit int) ([]model.LoginHistoryDataModel, error) {
+	var result []model.LoginHistoryDataModel
+	err := r.db.
+		Model(&model.LoginHistoryDataModel{}).
+		Where("\"userId\" = ?", userId).
+		Order("date desc").
+		Offset(skip).
+		Limit(limit).
+		Find(&result).
+		Error
+	return result, err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (r *UserRepository) GetUserIdentity(provider string, subject string) (*model.UserIdentity, error) {
+	var result model.UserIdentity
+	err := r.db.
+		Model(&model.UserIdentity{}).
+		Where("provider = ? AND subject = ?", provider, subject).
+		Limit(1).
+		Find(&result).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if result.Id == 0 {
+		return nil, nil
+	}
+	return &result, nil
+}
+
+func (r *UserRepository) GetUserIdentities(userId int64) ([]model.UserIdentityDataModel, error) {
+	var result []model.UserIdentityDataModel
+	err := r.db.
+		Model(&model.UserIdentityDataModel{}).
+		Where("\"userId\" = ?", userId).
+		Order("\"createdAt\" asc").
+		Find(&result).
+		Error
+	return result, err
+}
+
+func (r *UserRepository) AddUserIdentity(identity *model.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *UserRepository) UpdateUserIdentityLogin(id int64, email string) error {
+	err := r.db.
+		Model(&model.UserIdentity{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"email":         email,
+			"lastLoginDate": time.Now().UTC(),
+		}).
+		Error
+	return err
+}
+
+func (r *UserRepository) RemoveUserIdentity(userId int64, provider string) error {
+	result := r.db.
+		Where("\"userId\" = ? AND provider = ?", userId, provider).
+		Delete(&model.UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
 func (r *UserRepository) GetUserBots(userId int64) ([]model.UserBotDataModel, error) {
 	var result []model.UserBotDataModel
 	err := r.db.
@@ -16,6 +16,7 @@ import (
 type IWsRepository interface {
 	GetReceiverUser(userId int64) (*model.UserDataModel, error)
 	SaveMessage(message *model.ReceiveNewMessage) (int64, error)
+	SaveMessageWithMedia(message *model.ReceiveNewMessage, mediaFile *model.MediaFile) (int64, error)
 	UpdateMessageState(mid int64, creatorId int64, receiverId int64, state int) (*model.MessageDataModel, error)
 	BatchUpdateMessageState(mid int64, roomId int64, creatorId int64, receiverId int64, state int) error
 	UpdateUserReceivedMessageTime(userId int64) error
@@ -38,9 +39,10 @@ func NewWsRepository(db *gorm.DB, mongodb *mongo.Database) *WsRepository {
 //------------------------------------------
 //------------------------------------------
 
+// GetReceiverUser returns nil if user doesn't exist or is pending deletion
 func (w *WsRepository) GetReceiverUser(userId int64) (*model.UserDataModel, error) {
 	var userDataModel model.UserDataModel
-	err := w.db.Where("\"userId\" = ?", userId).
+	err := w.db.Where("\"userId\" = ? AND \"deletionDate\" = 0", userId).
 		Model(&model.User{}).
 		Limit(1).
 		Find(&userDataModel).
@@ -51,6 +53,9 @@ func (w *WsRepository) GetReceiverUser(userId int64) (*model.UserDataModel, erro
 		}
 		return nil, err
 	}
+	if userDataModel.UserId == 0 {
+		return nil, nil
+	}
 
 	return &userDataModel, nil
 }
@@ -59,6 +64,32 @@ func (w *WsRepository) GetReceiverUser(userId int64) (*model.UserDataModel, erro
 //------------------------------------------
 
 func (w *WsRepository) SaveMessage(message *model.ReceiveNewMessage) (int64, error) {
+	m := newMessageRow(message)
+	err := w.db.Create(&m).Error
+	if err != nil {
+		return -1, err
+	}
+	return m.Id, nil
+}
+
+// SaveMessageWithMedia saves the message and its media in one transaction, a failed save leaves no message without media
+func (w *WsRepository) SaveMessageWithMedia(message *model.ReceiveNewMessage, mediaFile *model.MediaFile) (int64, error) {
+	m := newMessageRow(message)
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&m).Error
+		if err != nil {
+			return err
+		}
+		mediaFile.MessageId = m.Id
+		return tx.Create(mediaFile).Error
+	})
+	if err != nil {
+		return -1, err
+	}
+	return m.Id, nil
+}
+
+func newMessageRow(message *model.ReceiveNewMessage) model.Message {
 	m := model.Message{
 		CreatorId:  message.UserId,
 		ReceiverId: message.ReceiverId,
@@ -70,11 +101,7 @@ func (w *WsRepository) SaveMessage(message *model.ReceiveNewMessage) (int64, err
 	if *m.RoomId == -1 {
 		m.RoomId = nil
 	}
-	err := w.db.Create(&m).Error
-	if err != nil {
-		return -1, err
-	}
-	return m.Id, nil
+	return m
 }
 
 func (w *WsRepository) UpdateMessageState(mid int64, creatorId int64, receiverId int64, state int) (*model.MessageDataModel, error) {
@@ -228,14 +255,14 @@ func (w *WsRepository) GetSingleChatList(params *model.GetSingleChatListReq) ([]
 	//        offset 0
 	//        LIMIT 2
 	//    ) as t_limited ON t_limited.state = 1 and t_limited."roomId" IS NULL
-	//) join "User" on t_limited."creatorId" = "User"."userId" left join "MediaFile" on t_limited.id = "MediaFile"."messageId";
+	//) join "User" on t_limited."creatorId" = "User"."userId" and "User"."deletionDate" = 0 left join "MediaFile" on t_limited.id = "MediaFile"."messageId";
 
 	queryStr := "SELECT t_limited.*, \"User\".\"publicName\", \"User\".username, \"User\".\"userId\", \"User\".\"lastSeenDate\", \"MediaFile\".* " +
 		"FROM ( ( SELECT DISTINCT \"creatorId\" FROM \"Message\" offset @chatskip limit @chatlimit) as t_groups " +
 		"JOIN LATERAL (SELECT * FROM \"Message\" t_all WHERE t_all.\"creatorId\" = t_groups.\"creatorId\" and " +
 		" (t_all.\"receiverId\" = @receiverid OR t_groups.\"creatorId\" = @receiverid) " +
 		"ORDER BY t_all.date desc Offset @messageskip LIMIT @messagelimit) " +
-		"as t_limited ON t_limited.state = @messagestate AND t_limited.\"roomId\" IS NULL) JOIN \"User\" ON t_limited.\"creatorId\" = \"User\".\"userId\" LEFT JOIN \"MediaFile\" ON t_limited.id = \"MediaFile\".\"messageId\";"
+		"as t_limited ON t_limited.state = @messagestate AND t_limited.\"roomId\" IS NULL) JOIN \"User\" ON t_limited.\"creatorId\" = \"User\".\"userId\" AND \"User\".\"deletionDate\" = 0 LEFT JOIN \"MediaFile\" ON t_limited.id = \"MediaFile\".\"messageId\";"
 	if params.MessageState == 0 {
 		queryStr = strings.Replace(queryStr, "t_limited.state = @messagestate AND ", "", 1)
 	}
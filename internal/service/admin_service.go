@@ -2,14 +2,44 @@ package service
 
 import (
 	"downloader_gochat/cloudStorage"
+	"downloader_gochat/configs"
 	"downloader_gochat/internal/repository"
 	"downloader_gochat/model"
+	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/response"
 	"downloader_gochat/rabbitmq"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type IAdminService interface {
 	GetServerStatus() *model.Status
+	GetOrphanMediaReport() *model.OrphanMediaReport
+	GetLockedAccounts() ([]model.LoginLock, error)
+	UnlockAccount(userId int64, auditCtx *model.AuditContext) error
+	GetRoles() ([]model.RoleWithPermissions, error)
+	CreateRole(roleReq *model.RoleReq, auditCtx *model.AuditContext) (*model.Role, error)
+	UpdateRole(roleId int64, roleReq *model.RoleReq, auditCtx *model.AuditContext) error
+	DeleteRole(roleId int64, auditCtx *model.AuditContext) error
+	SetRolePermissions(roleId int64, permissionIds []int64, auditCtx *model.AuditContext) error
+	GetPermissions() ([]model.Permission, error)
+	CreatePermission(permissionReq *model.PermissionReq, auditCtx *model.AuditContext) (*model.Permission, error)
+	UpdatePermission(permissionId int64, permissionReq *model.PermissionReq, auditCtx *model.AuditContext) error
+	DeletePermission(permissionId int64, auditCtx *model.AuditContext) error
+	GetUserRoles(userId int64) ([]model.Role, error)
+	AddUserRole(userId int64, roleId int64, auditCtx *model.AuditContext) error
+	RemoveUserRole(userId int64, roleId int64, auditCtx *model.AuditContext) error
+	SearchUsers(searchReq *model.AdminSearchUsersReq) ([]model.AdminUserDataModel, error)
+	GetUserDetails(userId int64) (*model.AdminUserDetailsRes, error)
+	SuspendUser(userId int64, suspendReq *model.SuspendUserReq, auditCtx *model.AuditContext) (*model.UserSuspension, error)
+	UnsuspendUser(userId int64, auditCtx *model.AuditContext) error
+	ForceLogoutUser(userId int64, auditCtx *model.AuditContext) error
 }
 
 type AdminService struct {
@@ -34,6 +64,7 @@ func NewAdminService(userRepo repository.IUserRepository, adminRepo repository.I
 	}
 
 	AdminSvc = svc
+	go svc.removeOrphanMediaJob()
 
 	return svc
 }
@@ -46,3 +77,467 @@ var AdminSvc *AdminService
 func (a *AdminService) GetServerStatus() *model.Status {
 	return a.status
 }
+
+//------------------------------------------
+//------------------------------------------
+
+func (a *AdminService) GetLockedAccounts() ([]model.LoginLock, error) {
+	keys, err := getLockedAccountKeysCache(0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.LoginLock, 0, len(keys))
+	for _, key := range keys {
+		lock, err := getLoginLockCacheByKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if lock != nil {
+			lock.UnlockTokenHash = ""
+			result = append(result, *lock)
+		}
+	}
+	return result, nil
+}
+
+func (a *AdminService) UnlockAccount(userId int64, auditCtx *model.AuditContext) error {
+	err := removeLoginLocks(userId)
+	if err != nil {
+		return err
+	}
+	addAuditLog(model.AuditAccountUnlock, auditCtx, userId, "")
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// roles and permissions are shared with the main server. every change removes the cached permissions of roleIds,
+// when roles of a user change, tokens generated before it are rejected by CheckUserPermission so the user gets new roleIds with GetToken
+
+func (a *AdminService) GetRoles() ([]model.RoleWithPermissions, error) {
+	return a.adminRepo.GetRoles()
+}
+
+func (a *AdminService) CreateRole(roleReq *model.RoleReq, auditCtx *model.AuditContext) (*model.Role, error) {
+	role := model.Role{
+		Name:                roleReq.Name,
+		Description:         roleReq.Description,
+		TorrentLeachLimitGb: roleReq.TorrentLeachLimitGb,
+		TorrentSearchLimit:  roleReq.TorrentSearchLimit,
+		BotsNotification:    roleReq.BotsNotification,
+		CreatedAt:           time.Now().UTC(),
+		UpdatedAt:           time.Now().UTC(),
+	}
+	err := a.adminRepo.CreateRole(&role)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errors.New(response.RoleAlreadyExist)
+		}
+		return nil, err
+	}
+	addAuditLog(model.AuditRoleCreate, auditCtx, 0, fmt.Sprintf("roleId: %v, name: %v", role.Id, role.Name))
+	return &role, nil
+}
+
+func (a *AdminService) UpdateRole(roleId int64, roleReq *model.RoleReq, auditCtx *model.AuditContext) error {
+	if roleId == int64(model.MainAdmin) {
+		return errors.New(response.MainAdminRoleChanged)
+	}
+	if name, ok := model.DefaultRoles[model.DefaultRoleId(roleId)]; ok && string(name) != roleReq.Name {
+		return errors.New(response.DefaultRoleChanged)
+	}
+
+	err := a.adminRepo.UpdateRole(roleId, map[string]interface{}{
+		"name":                roleReq.Name,
+		"description":         roleReq.Description,
+		"torrentLeachLimitGb": roleReq.TorrentLeachLimitGb,
+		"torrentSearchLimit":  roleReq.TorrentSearchLimit,
+		"botsNotification":    roleReq.BotsNotification,
+		"updatedAt":           time.Now().UTC(),
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errors.New(response.RoleAlreadyExist)
+		}
+		return err
+	}
+	addAuditLog(model.AuditRoleUpdate, auditCtx, 0, fmt.Sprintf("roleId: %v, name: %v", roleId, roleReq.Name))
+	return nil
+}
+
+func (a *AdminService) DeleteRole(roleId int64, auditCtx *model.AuditContext) error {
+	if _, ok := model.DefaultRoles[model.DefaultRoleId(roleId)]; ok {
+		return errors.New(response.DefaultRoleChanged)
+	}
+
+	userIds, err := a.adminRepo.DeleteRole(roleId)
+	if err != nil {
+		return err
+	}
+	_ = removeRolePermissionsCache()
+	_ = setUserRolesUpdatedCache(userIds)
+	addAuditLog(model.AuditRoleDelete, auditCtx, 0, fmt.Sprintf("roleId: %v, users: %v", roleId, len(userIds)))
+	return nil
+}
+
+func (a *AdminService) SetRolePermissions(roleId int64, permissionIds []int64, auditCtx *model.AuditContext) error {
+	if roleId == int64(model.MainAdmin) {
+		return errors.New(response.MainAdminRoleChanged)
+	}
+
+	err := a.adminRepo.SetRolePermissions(roleId, permissionIds)
+	if err != nil {
+		return err
+	}
+	_ = removeRolePermissionsCache()
+	addAuditLog(model.AuditRolePermissions, auditCtx, 0, fmt.Sprintf("roleId: %v, permissionIds: %v", roleId, permissionIds))
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (a *AdminService) GetPermissions() ([]model.Permission, error) {
+	return a.adminRepo.GetPermissions()
+}
+
+func (a *AdminService) CreatePermission(permissionReq *model.PermissionReq, auditCtx *model.AuditContext) (*model.Permission, error) {
+	permission := model.Permission{
+		Name:        permissionReq.Name,
+		Description: permissionReq.Description,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	err := a.adminRepo.CreatePermission(&permission)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errors.New(response.PermissionAlreadyExist)
+		}
+		return nil, err
+	}
+	addAuditLog(model.AuditPermissionCreate, auditCtx, 0, fmt.Sprintf("permissionId: %v, name: %v", permission.Id, permission.Name))
+	return &permission, nil
+}
+
+func (a *AdminService) UpdatePermission(permissionId int64, permissionReq *model.PermissionReq, auditCtx *model.AuditContext) error {
+	permission, err := a.adminRepo.GetPermission(permissionId)
+	if err != nil {
+		return err
+	}
+	if permission == nil {
+		return gorm.ErrRecordNotFound
+	}
+	if permission.Name == model.ManageRolesPermission && permissionReq.Name != model.ManageRolesPermission {
+		return errors.New(response.ProtectedPermissionUsed)
+	}
+
+	err = a.adminRepo.UpdatePermission(permissionId, map[string]interface{}{
+		"name":        permissionReq.Name,
+		"description": permissionReq.Description,
+		"updatedAt":   time.Now().UTC(),
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errors.New(response.PermissionAlreadyExist)
+		}
+		return err
+	}
+	_ = removeRolePermissionsCache()
+	addAuditLog(model.AuditPermissionUpdate, auditCtx, 0, fmt.Sprintf("permissionId: %v, name: %v -> %v", permissionId, permission.Name, permissionReq.Name))
+	return nil
+}
+
+func (a *AdminService) DeletePermission(permissionId int64, auditCtx *model.AuditContext) error {
+	permission, err := a.adminRepo.GetPermission(permissionId)
+	if err != nil {
+		return err
+	}
+	if permission == nil {
+		return gorm.ErrRecordNotFound
+	}
+	if permission.Name == model.ManageRolesPermission {
+		return errors.New(response.ProtectedPermissionUsed)
+	}
+
+	err = a.adminRepo.DeletePermission(permissionId)
+	if err != nil {
+		return err
+	}
+	_ = removeRolePermissionsCache()
+	addAuditLog(model.AuditPermissionDelete, auditCtx, 0, fmt.Sprintf("permissionId: %v, name: %v", permissionId, permission.Name))
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (a *AdminService) GetUserRoles(userId int64) ([]model.Role, error) {
+	return a.userRepo.GetUserRoles(userId)
+}
+
+func (a *AdminService) AddUserRole(userId int64, roleId int64, auditCtx *model.AuditContext) error {
+	if roleId == int64(model.MainAdmin) {
+		return errors.New(response.MainAdminRoleChanged)
+	}
+
+	err := a.adminRepo.AddUserRole(userId, roleId)
+	if err != nil {
+		return err
+	}
+	_ = setUserRolesUpdatedCache([]int64{userId})
+	addAuditLog(model.AuditUserRoleAdd, auditCtx, userId, fmt.Sprintf("roleId: %v", roleId))
+	return nil
+}
+
+func (a *AdminService) RemoveUserRole(userId int64, roleId int64, auditCtx *model.AuditContext) error {
+	if roleId == int64(model.MainAdmin) {
+		return errors.New(response.MainAdminRoleChanged)
+	}
+
+	err := a.adminRepo.RemoveUserRole(userId, roleId)
+	if err != nil {
+		return err
+	}
+	_ = setUserRolesUpdatedCache([]int64{userId})
+	addAuditLog(model.AuditUserRoleRemove, auditCtx, userId, fmt.Sprintf("roleId: %v", roleId))
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// suspended users are rejected by AuthMiddleware and websocket using the cached suspension, login and getToken check the db.
+// sessions are kept on suspension so user can continue after it expires or is lifted, ban also logs out all the sessions
+
+func (a *AdminService) SearchUsers(searchReq *model.AdminSearchUsersReq) ([]model.AdminUserDataModel, error) {
+	return a.adminRepo.SearchUsers(searchReq.Query, searchReq.Skip, searchReq.Limit)
+}
+
+func (a *AdminService) GetUserDetails(userId int64) (*model.AdminUserDetailsRes, error) {
+	user, err := a.adminRepo.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	roles, err := a.userRepo.GetUserRoles(userId)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := a.userRepo.GetActiveSessions(userId)
+	if err != nil {
+		return nil, err
+	}
+	bots, err := a.adminRepo.GetUserBots(userId)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := a.adminRepo.GetUserCounts(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AdminUserDetailsRes{
+		User:           *user,
+		Roles:          roles,
+		ActiveSessions: sessions,
+		Bots:           bots,
+		Counts:         *counts,
+	}, nil
+}
+
+func (a *AdminService) SuspendUser(userId int64, suspendReq *model.SuspendUserReq, auditCtx *model.AuditContext) (*model.UserSuspension, error) {
+	if auditCtx.ActorId == userId {
+		return nil, errors.New(response.SelfSuspended)
+	}
+	roles, err := a.userRepo.GetUserRoles(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roles {
+		if r.Id == int64(model.MainAdmin) {
+			return nil, errors.New(response.MainAdminSuspended)
+		}
+	}
+
+	suspension := model.UserSuspension{
+		UserId:      userId,
+		Banned:      suspendReq.Permanent,
+		Until:       0,
+		Reason:      suspendReq.Reason,
+		SuspendedBy: auditCtx.ActorId,
+	}
+	if !suspendReq.Permanent {
+		suspension.Until = time.Now().Add(time.Duration(suspendReq.DurationHours) * time.Hour).UnixMilli()
+	}
+	err = a.adminRepo.SetUserSuspension(&suspension)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setUserSuspensionCache(&suspension)
+	if err != nil {
+		return nil, err
+	}
+	addAuditLog(model.AuditUserSuspend, auditCtx, userId, suspension.Error())
+	if suspension.Banned {
+		err = a.ForceLogoutUser(userId, auditCtx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = revokeAllApiTokens(userId, "account is suspended", auditCtx)
+		if err != nil {
+			return nil, err
+		}
+		closeDeviceConnections(userId, "", "account is suspended")
+	}
+	return &suspension, nil
+}
+
+func (a *AdminService) UnsuspendUser(userId int64, auditCtx *model.AuditContext) error {
+	err := a.adminRepo.RemoveUserSuspension(userId)
+	if err != nil {
+		return err
+	}
+	addAuditLog(model.AuditUserUnsuspend, auditCtx, userId, "")
+	return removeUserSuspensionCache(userId)
+}
+
+// ForceLogoutUser removes all the sessions of user, blacklists their refreshTokens, revokes their api tokens and closes the websocket connections
+func (a *AdminService) ForceLogoutUser(userId int64, auditCtx *model.AuditContext) error {
+	sessions, err := a.adminRepo.RemoveUserSessions(userId)
+	if err != nil {
+		return err
+	}
+	err = revokeAllApiTokens(userId, "logged out by admin", auditCtx)
+	if err != nil {
+		return err
+	}
+
+	refreshTokenExpireDay := configs.GetConfigs().RefreshTokenExpireDay
+	for i := range sessions {
+		_ = setJwtDataCache(sessions[i].RefreshToken, "forceLogout", time.Duration(refreshTokenExpireDay)*24*time.Hour)
+		if sessions[i].NotifToken != "" {
+			_ = removeNotifTokenFromCachedUserData(userId, sessions[i].NotifToken)
+		}
+	}
+
+	closeDeviceConnections(userId, "", "logged out by admin")
+	addAuditLog(model.AuditForceLogout, auditCtx, userId, fmt.Sprintf("all sessions (%v)", len(sessions)))
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+const orphanMediaCheckInterval = 6 * time.Hour
+
+// GetOrphanMediaReport returns the files that removeOrphanMediaJob would remove, nothing is removed
+func (a *AdminService) GetOrphanMediaReport() *model.OrphanMediaReport {
+	return a.reconcileOrphanMedia(true)
+}
+
+func (a *AdminService) removeOrphanMediaJob() {
+	ticker := time.NewTicker(orphanMediaCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		report := a.reconcileOrphanMedia(false)
+		for _, b := range report.Buckets {
+			if b.RemovedCount > 0 || b.ErrorMessage != "" {
+				errorMessage := fmt.Sprintf("Orphan media of bucket (%v): found %v, removed %v, error: %v",
+					b.BucketName, b.OrphanCount, b.RemovedCount, b.ErrorMessage)
+				errorHandler.SaveError(errorMessage, nil)
+			}
+		}
+	}
+}
+
+// reconcileOrphanMedia compares the bucket listings with MediaFile/ProfileImage rows,
+// orphan files older than the grace period are removed if dryRun is false
+func (a *AdminService) reconcileOrphanMedia(dryRun bool) *model.OrphanMediaReport {
+	graceHours := configs.GetConfigs().OrphanMediaGraceHours
+	report := &model.OrphanMediaReport{
+		DryRun:           dryRun,
+		GracePeriodHours: graceHours,
+		Date:             time.Now().UTC(),
+		Buckets:          make([]model.OrphanBucketReport, 0),
+	}
+
+	buckets := []struct {
+		name    string
+		getUrls func() ([]string, error)
+	}{
+		{cloudStorage.MediaFileBucketName, a.adminRepo.GetMediaFileUrls},
+		{cloudStorage.ProfileImageBucketName, a.adminRepo.GetProfileImageUrls},
+	}
+	for _, b := range buckets {
+		bucketReport := model.OrphanBucketReport{
+			BucketName:  b.name,
+			OrphanFiles: make([]model.OrphanMediaFile, 0),
+		}
+
+		// list the bucket before reading the db, files uploaded in between are newer than the grace period
+		files, err := a.cloudStorage.ListFiles(b.name)
+		if err != nil {
+			bucketReport.ErrorMessage = err.Error()
+			report.Buckets = append(report.Buckets, bucketReport)
+			continue
+		}
+		urls, err := b.getUrls()
+		if err != nil {
+			bucketReport.ErrorMessage = err.Error()
+			report.Buckets = append(report.Buckets, bucketReport)
+			continue
+		}
+
+		usedFiles := make(map[string]struct{}, len(urls))
+		for _, u := range urls {
+			fileName := path.Base(strings.Split(u, "?")[0])
+			if unescaped, err := url.PathUnescape(fileName); err == nil {
+				fileName = unescaped
+			}
+			usedFiles[fileName] = struct{}{}
+		}
+
+		bucketReport.TotalFiles = len(files)
+		graceTime := time.Now().Add(-time.Duration(graceHours) * time.Hour)
+		for _, f := range files {
+			if f.Key == nil {
+				continue
+			}
+			if _, ok := usedFiles[*f.Key]; ok {
+				continue
+			}
+			orphan := model.OrphanMediaFile{
+				FileName: *f.Key,
+			}
+			if f.Size != nil {
+				orphan.Size = *f.Size
+			}
+			if f.LastModified != nil {
+				orphan.LastModified = *f.LastModified
+				orphan.Removable = f.LastModified.Before(graceTime)
+			}
+			bucketReport.OrphanCount++
+			bucketReport.OrphanSize += orphan.Size
+			if orphan.Removable && !dryRun {
+				err = a.cloudStorage.RemoveFile(b.name, orphan.FileName)
+				if err != nil {
+					bucketReport.ErrorMessage = err.Error()
+				} else {
+					bucketReport.RemovedCount++
+				}
+			}
+			bucketReport.OrphanFiles = append(bucketReport.OrphanFiles, orphan)
+		}
+
+		report.Buckets = append(report.Buckets, bucketReport)
+	}
+
+	return report
+}
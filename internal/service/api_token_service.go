@@ -0,0 +1,160 @@
+package service
+
+import (
+	"downloader_gochat/internal/repository"
+	"downloader_gochat/model"
+	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type IApiTokenService interface {
+	CreateApiToken(userId int64, params *model.CreateApiTokenReq, twoFactorCode string, auditCtx *model.AuditContext) (*model.CreateApiTokenRes, error)
+	GetApiTokens(userId int64) ([]model.ApiToken, error)
+	RevokeApiToken(userId int64, tokenId int64, auditCtx *model.AuditContext) error
+}
+
+type ApiTokenService struct {
+	apiTokenRepo repository.IApiTokenRepository
+}
+
+func NewApiTokenService(apiTokenRepo repository.IApiTokenRepository) *ApiTokenService {
+	svc := &ApiTokenService{
+		apiTokenRepo: apiTokenRepo,
+	}
+
+	apiTokenSvc = svc
+
+	return svc
+}
+
+var apiTokenSvc *ApiTokenService
+
+//------------------------------------------
+//------------------------------------------
+
+const (
+	// apiTokenCacheExpire also limits how often lastUsedAt of a token is updated
+	apiTokenCacheExpire  = time.Minute
+	apiTokenPrefixLength = len(model.ApiTokenPrefix) + 8
+)
+
+// CreateApiToken returns the plain token once, only its hash is saved.
+// needs the password of the user, or a two-factor code when two-factor is enabled
+func (s *ApiTokenService) CreateApiToken(userId int64, params *model.CreateApiTokenReq, twoFactorCode string, auditCtx *model.AuditContext) (*model.CreateApiTokenRes, error) {
+	err := userSvc.checkPasswordOrSecondFactor(userId, params.Password, twoFactorCode)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.apiTokenRepo.CountActiveApiTokens(userId)
+	if err != nil {
+		return nil, err
+	}
+	if count >= model.ApiTokenMaxActive {
+		return nil, errors.New(response.ExceedApiTokens)
+	}
+
+	token, err := util.CreateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	token = model.ApiTokenPrefix + token
+
+	apiToken := model.ApiToken{
+		UserId:      userId,
+		Name:        params.Name,
+		TokenHash:   util.HashToken(token),
+		TokenPrefix: token[:apiTokenPrefixLength],
+		Scopes:      params.Scopes,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if params.ExpiresInDays > 0 {
+		expiresAt := apiToken.CreatedAt.Add(time.Duration(params.ExpiresInDays) * 24 * time.Hour)
+		apiToken.ExpiresAt = &expiresAt
+	}
+	err = s.apiTokenRepo.AddApiToken(&apiToken)
+	if err != nil {
+		return nil, err
+	}
+	addAuditLog(model.AuditApiTokenCreate, auditCtx, userId,
+		fmt.Sprintf("tokenId: %v, name: %v, scopes: %v", apiToken.Id, apiToken.Name, strings.Join(apiToken.Scopes, ",")))
+
+	return &model.CreateApiTokenRes{ApiToken: apiToken, Token: token}, nil
+}
+
+func (s *ApiTokenService) GetApiTokens(userId int64) ([]model.ApiToken, error) {
+	return s.apiTokenRepo.GetApiTokens(userId)
+}
+
+// RevokeApiToken returns gorm.ErrRecordNotFound if token doesn't exist or is already revoked
+func (s *ApiTokenService) RevokeApiToken(userId int64, tokenId int64, auditCtx *model.AuditContext) error {
+	tokenHash, err := s.apiTokenRepo.RevokeApiToken(userId, tokenId)
+	if err != nil {
+		return err
+	}
+	_ = removeApiTokenCache(tokenHash)
+	addAuditLog(model.AuditApiTokenRevoke, auditCtx, userId, fmt.Sprintf("tokenId: %v", tokenId))
+	return nil
+}
+
+// revokeAllApiTokens revokes every token of the user and removes them from cache,
+// used when password changes or sessions of the user are removed
+func revokeAllApiTokens(userId int64, reason string, auditCtx *model.AuditContext) error {
+	if apiTokenSvc == nil {
+		return nil
+	}
+	tokenHashes, err := apiTokenSvc.apiTokenRepo.RevokeAllApiTokens(userId)
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on revoking api tokens of user %v: %v", userId, err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	if len(tokenHashes) == 0 {
+		return nil
+	}
+	err = removeApiTokenCache(tokenHashes...)
+	addAuditLog(model.AuditApiTokenRevoke, auditCtx, userId, fmt.Sprintf("all tokens (%v), %v", len(tokenHashes), reason))
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// AuthenticateApiToken is used by AuthMiddleware, returns nil if the token is invalid, revoked or expired.
+// returned claims have the scopes of the token, lastUsedAt is updated when the token is not in cache
+func AuthenticateApiToken(token string, ip string) (*util.MyJwtClaims, error) {
+	if apiTokenSvc == nil || !strings.HasPrefix(token, model.ApiTokenPrefix) {
+		return nil, nil
+	}
+
+	tokenHash := util.HashToken(token)
+	authData, err := getApiTokenCache(tokenHash)
+	if err != nil || authData == nil {
+		authData, err = apiTokenSvc.apiTokenRepo.UseApiToken(tokenHash, ip)
+		if err != nil || authData == nil {
+			return nil, err
+		}
+		_ = setApiTokenCache(tokenHash, authData, apiTokenCacheExpire)
+	}
+	if authData.ExpiresAt != nil && authData.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+
+	claims := &util.MyJwtClaims{
+		UserId:         authData.UserId,
+		Username:       authData.Username,
+		RoleIds:        authData.RoleIds,
+		GeneratedAt:    time.Now().UnixMilli(),
+		ApiTokenId:     authData.Id,
+		ApiTokenScopes: authData.Scopes,
+	}
+	if authData.ExpiresAt != nil {
+		claims.ExpiresAt = authData.ExpiresAt.UnixMilli()
+	}
+	return claims, nil
+}
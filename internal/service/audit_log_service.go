@@ -0,0 +1,207 @@
+package service
+
+import (
+	"downloader_gochat/internal/repository"
+	"downloader_gochat/model"
+	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/geoip"
+	"downloader_gochat/util"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IAuditLogService interface {
+	GetAuditLogs(params *model.AuditLogReq) ([]model.AuditLog, error)
+}
+
+type AuditLogService struct {
+	auditRepo repository.IAuditRepository
+	auditLogs chan pendingAuditLog
+	closeLock sync.RWMutex
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+func NewAuditLogService(auditRepo repository.IAuditRepository) *AuditLogService {
+	svc := &AuditLogService{
+		auditRepo: auditRepo,
+		auditLogs: make(chan pendingAuditLog, auditLogBufferSize),
+	}
+
+	auditLogSvc = svc
+	svc.wg.Add(1)
+	go svc.saveAuditLogsJob()
+
+	return svc
+}
+
+var auditLogSvc *AuditLogService
+
+//------------------------------------------
+//------------------------------------------
+
+const (
+	auditLogBufferSize = 1000
+	auditLogBatchSize  = 100
+)
+
+// GetAuditLogs is used for the logs of user and search/export of admin, filters are set by the handler
+func (s *AuditLogService) GetAuditLogs(params *model.AuditLogReq) ([]model.AuditLog, error) {
+	return s.auditRepo.GetAuditLogs(params)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// NewAuditContext reads the actor, ip and refreshToken of the request, actor is 0 on public routes
+func NewAuditContext(c *fiber.Ctx) *model.AuditContext {
+	ip := c.IP()
+	ips := c.IPs()
+	if len(ips) > 0 {
+		ip = ips[len(ips)-1]
+	}
+
+	auditCtx := &model.AuditContext{Ip: ip}
+	if jwtUserData, ok := c.Locals("jwtUserData").(*util.MyJwtClaims); ok {
+		auditCtx.ActorId = jwtUserData.UserId
+	}
+	if refreshToken, ok := c.Locals("refreshToken").(string); ok {
+		auditCtx.RefreshToken = refreshToken
+	}
+	return auditCtx
+}
+
+// pendingAuditLog is enriched with ip location and device of the session before saving
+type pendingAuditLog struct {
+	auditLog     model.AuditLog
+	refreshToken string
+}
+
+// addAuditLog doesn't wait for the insert, logs are enriched and saved in batches by saveAuditLogsJob
+func addAuditLog(action model.AuditAction, auditCtx *model.AuditContext, targetId int64, details string) {
+	if auditLogSvc == nil {
+		return
+	}
+
+	pending := pendingAuditLog{
+		auditLog: model.AuditLog{
+			Date:     time.Now().UTC(),
+			Action:   action,
+			TargetId: targetId,
+			Details:  details,
+		},
+	}
+	if auditCtx != nil {
+		pending.auditLog.ActorId = auditCtx.ActorId
+		pending.auditLog.Ip = auditCtx.Ip
+		if auditCtx.DeviceInfo != nil {
+			pending.auditLog.AppName = auditCtx.DeviceInfo.AppName
+			pending.auditLog.AppVersion = auditCtx.DeviceInfo.AppVersion
+			pending.auditLog.DeviceOs = auditCtx.DeviceInfo.Os
+			pending.auditLog.DeviceModel = auditCtx.DeviceInfo.DeviceModel
+		} else {
+			pending.refreshToken = auditCtx.RefreshToken
+		}
+	}
+
+	auditLogSvc.closeLock.RLock()
+	defer auditLogSvc.closeLock.RUnlock()
+	if auditLogSvc.closed {
+		auditLogSvc.saveAuditLogs([]pendingAuditLog{pending})
+		return
+	}
+	select {
+	case auditLogSvc.auditLogs <- pending:
+	default:
+		// buffer is full, don't lose the log and don't block the request
+		auditLogSvc.wg.Add(1)
+		go func() {
+			defer auditLogSvc.wg.Done()
+			auditLogSvc.saveAuditLogs([]pendingAuditLog{pending})
+		}()
+	}
+}
+
+// resolveAuditDevice reads the device from the session of refreshToken when request doesn't have it,
+// it's called before removing the session on logout
+func resolveAuditDevice(auditCtx *model.AuditContext) {
+	if auditLogSvc == nil || auditCtx.DeviceInfo != nil || auditCtx.RefreshToken == "" {
+		return
+	}
+	session, err := auditLogSvc.auditRepo.GetSessionDevice(auditCtx.RefreshToken)
+	if err == nil && session != nil {
+		auditCtx.DeviceInfo = &model.DeviceInfo{
+			AppName:     session.AppName,
+			AppVersion:  session.AppVersion,
+			Os:          session.DeviceOs,
+			DeviceModel: session.DeviceModel,
+		}
+	}
+}
+
+// Close saves the logs that are in the buffer, it's called on shutdown after the server stops accepting requests
+func (s *AuditLogService) Close() {
+	s.closeLock.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.auditLogs)
+	}
+	s.closeLock.Unlock()
+	s.wg.Wait()
+}
+
+func (s *AuditLogService) saveAuditLogsJob() {
+	defer s.wg.Done()
+	batch := make([]pendingAuditLog, 0, auditLogBatchSize)
+	for pending := range s.auditLogs {
+		batch = append(batch, pending)
+	loop:
+		for len(batch) < auditLogBatchSize {
+			select {
+			case next, ok := <-s.auditLogs:
+				if !ok {
+					break loop
+				}
+				batch = append(batch, next)
+			default:
+				break loop
+			}
+		}
+		s.saveAuditLogs(batch)
+		batch = batch[:0]
+	}
+}
+
+func (s *AuditLogService) saveAuditLogs(pendingLogs []pendingAuditLog) {
+	auditLogs := make([]model.AuditLog, 0, len(pendingLogs))
+	sessionDevices := make(map[string]*model.ActiveSessionDataModel)
+	for _, pending := range pendingLogs {
+		auditLog := pending.auditLog
+		if auditLog.Ip != "" {
+			auditLog.IpLocation = geoip.GetRequestLocation(auditLog.Ip)
+		}
+		if pending.refreshToken != "" {
+			session, ok := sessionDevices[pending.refreshToken]
+			if !ok {
+				session, _ = s.auditRepo.GetSessionDevice(pending.refreshToken)
+				sessionDevices[pending.refreshToken] = session
+			}
+			if session != nil {
+				auditLog.AppName = session.AppName
+				auditLog.AppVersion = session.AppVersion
+				auditLog.DeviceOs = session.DeviceOs
+				auditLog.DeviceModel = session.DeviceModel
+			}
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	err := s.auditRepo.AddAuditLogs(auditLogs)
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on saving audit logs: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"downloader_gochat/configs"
 	"downloader_gochat/db/redis"
 	"downloader_gochat/model"
 	errorHandler "downloader_gochat/pkg/error"
@@ -31,11 +32,30 @@ type ICacheService interface {
 }
 
 const (
-	jwtDataCachePrefix         = "jwtKey:"
-	userDataCachePrefix        = "user:"
-	movieDataCachePrefix       = "movie:"
-	botDataCachePrefix         = "bot:"
-	rolePermissionsCachePrefix = "roleIds:"
+	jwtDataCachePrefix          = "jwtKey:"
+	userDataCachePrefix         = "user:"
+	movieDataCachePrefix        = "movie:"
+	botDataCachePrefix          = "bot:"
+	rolePermissionsCachePrefix  = "roleIds:"
+	rolesUpdatedPrefix          = "rolesUpdated:"
+	userSuspensionPrefix        = "userSuspension:"
+	mediaUploadCachePrefix      = "mediaUpload:"
+	twoFactorChallengePrefix    = "twoFactorChallenge:"
+	rotatedRefreshTokenPrefix   = "rotatedRefreshToken:"
+	loginAttemptIpPrefix        = "loginAttempt:ip:"
+	loginAttemptUserPrefix      = "loginAttempt:user:"
+	loginAttemptUserIpPrefix    = "loginAttempt:userIp:"
+	loginBackoffPrefix          = "loginBackoff:"
+	loginLockPrefix             = "loginLock:"
+	lockedAccountsKey           = "lockedAccounts"
+	magicLinkPrefix             = "magicLink:"
+	magicLinkSentPrefix         = "magicLinkSent:"
+	oidcStatePrefix             = "oidcState:"
+	devicePairingPrefix         = "devicePairing:"
+	followSuggestionsPrefix     = "followSuggestions:"
+	followSuggestionsQueueKey   = "followSuggestionsQueue"
+	followSuggestionsRefreshKey = "followSuggestionsRefresh"
+	apiTokenPrefix              = "apiToken:"
 )
 
 //------------------------------------------
@@ -92,6 +112,112 @@ func SetRolePermissionsCache(roleIds []int64, permissions []string, duration tim
 	return err
 }
 
+// removeRolePermissionsCache removes the permissions of all the role combinations, called when roles or permissions change
+func removeRolePermissionsCache() error {
+	err := redis.DelRedisByPrefix(context.Background(), rolePermissionsCachePrefix)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on removing permissions: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+// GetUserRolesUpdatedCache returns the time (unix milli) that roles of user changed, tokens generated before it have stale roleIds
+func GetUserRolesUpdatedCache(userId int64) (int64, error) {
+	result, err := redis.GetRedis(context.Background(), rolesUpdatedPrefix+strconv.FormatInt(userId, 10))
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(result, 10, 64)
+}
+
+// setUserRolesUpdatedCache is kept until the access tokens generated before now expire
+func setUserRolesUpdatedCache(userIds []int64) error {
+	duration := time.Duration(configs.GetConfigs().AccessTokenExpireHour) * time.Hour
+	now := time.Now().UnixMilli()
+	for _, id := range userIds {
+		err := redis.SetRedis(context.Background(), rolesUpdatedPrefix+strconv.FormatInt(id, 10), now, duration)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Redis Error on saving roles update time: %v", err)
+			errorHandler.SaveError(errorMessage, err)
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// getUserSuspensionCache returns nil on cache miss, users that are not suspended have an inactive suspension in cache
+func getUserSuspensionCache(userId int64) (*model.UserSuspension, error) {
+	result, err := redis.GetRedis(context.Background(), userSuspensionPrefix+strconv.FormatInt(userId, 10))
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.UserSuspension
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+// setUserSuspensionCache is kept until the suspension expires, ban has no expire time
+func setUserSuspensionCache(suspension *model.UserSuspension) error {
+	jsonData, err := json.Marshal(suspension)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving user suspension: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	duration := time.Duration(0)
+	if !suspension.Banned {
+		duration = time.Until(time.UnixMilli(suspension.Until))
+		if duration <= 0 {
+			return nil
+		}
+	}
+	err = redis.SetRedis(context.Background(), userSuspensionPrefix+strconv.FormatInt(suspension.UserId, 10), jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving user suspension: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+// setUserNotSuspendedCache keeps api and websocket from reading the db on each request,
+// it doesn't overwrite a suspension that is set after reading the db
+func setUserNotSuspendedCache(userId int64) error {
+	jsonData, err := json.Marshal(model.UserSuspension{UserId: userId})
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving user suspension: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	_, err = redis.SetNXRedis(context.Background(), userSuspensionPrefix+strconv.FormatInt(userId, 10), jsonData, userNotSuspendedCacheExpire)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving user suspension: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+func removeUserSuspensionCache(userId int64) error {
+	err := redis.DelRedis(context.Background(), userSuspensionPrefix+strconv.FormatInt(userId, 10))
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on removing user suspension: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
 //------------------------------------------
 //------------------------------------------
 
@@ -174,6 +300,21 @@ func updateProfileDataOfCachedUserData(userId int64, username string, publicName
 	return err
 }
 
+func updateEmailOfCachedUserData(userId int64, email string) error {
+	cacheData, err := getCachedUserData(userId)
+	if err != nil {
+		return err
+	}
+	if cacheData == nil {
+		return nil
+	}
+
+	cacheData.Email = email
+	err = setUserDataCache(userId, cacheData)
+
+	return err
+}
+
 //------------------------------------------
 //------------------------------------------
 
@@ -366,6 +507,462 @@ func setBotDataCache(botId string, botData *model.Bot) error {
 //------------------------------------------
 //------------------------------------------
 
+func getAndRemovePendingMediaUploadCache(uploadId string) (*model.PendingMediaUpload, error) {
+	result, err := redis.GetDelRedis(context.Background(), mediaUploadCachePrefix+uploadId)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.PendingMediaUpload
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+func setPendingMediaUploadCache(uploadId string, uploadData *model.PendingMediaUpload, duration time.Duration) error {
+	jsonData, err := json.Marshal(uploadData)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving pending upload: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	err = redis.SetRedis(context.Background(), mediaUploadCachePrefix+uploadId, jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving pending upload: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func getAndRemoveTwoFactorChallengeCache(key string) (*model.TwoFactorChallenge, error) {
+	result, err := redis.GetDelRedis(context.Background(), twoFactorChallengePrefix+key)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.TwoFactorChallenge
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+func setTwoFactorChallengeCache(key string, challenge *model.TwoFactorChallenge, duration time.Duration) error {
+	jsonData, err := json.Marshal(challenge)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving two-factor challenge: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	err = redis.SetRedis(context.Background(), twoFactorChallengePrefix+key, jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving two-factor challenge: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func getRotatedRefreshTokenCache(key string) (*model.RotatedRefreshToken, error) {
+	result, err := redis.GetRedis(context.Background(), rotatedRefreshTokenPrefix+key)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.RotatedRefreshToken
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+func setRotatedRefreshTokenCache(key string, rotatedToken *model.RotatedRefreshToken, duration time.Duration) error {
+	jsonData, err := json.Marshal(rotatedToken)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving rotated refresh token: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	err = redis.SetRedis(context.Background(), rotatedRefreshTokenPrefix+key, jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving rotated refresh token: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// login locks are per account and source network, key of lock and member of lockedAccounts is "userId:network"
+
+func loginLockKey(userId int64, network string) string {
+	return strconv.FormatInt(userId, 10) + ":" + network
+}
+
+func getLoginLockCache(userId int64, network string) (*model.LoginLock, error) {
+	return getLoginLockCacheByKey(loginLockKey(userId, network))
+}
+
+func getLoginLockCacheByKey(key string) (*model.LoginLock, error) {
+	result, err := redis.GetRedis(context.Background(), loginLockPrefix+key)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.LoginLock
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+// setLoginLockCache also adds the lock to lockedAccounts sorted set, score is the expire time of lock
+func setLoginLockCache(lock *model.LoginLock) error {
+	jsonData, err := json.Marshal(lock)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving login lock: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	key := loginLockKey(lock.UserId, lock.Network)
+	err = redis.SetRedis(context.Background(), loginLockPrefix+key, jsonData, time.Until(lock.ExpiresAt))
+	if err == nil {
+		err = redis.ZAddRedis(context.Background(), lockedAccountsKey, float64(lock.ExpiresAt.Unix()), key)
+	}
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving login lock: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+// removeLoginLockCache removes the lock and failed attempts of user from the network
+func removeLoginLockCache(userId int64, network string) error {
+	return removeLoginLockCacheByKey(loginLockKey(userId, network))
+}
+
+func removeLoginLockCacheByKey(key string) error {
+	err := redis.DelRedis(context.Background(), loginLockPrefix+key, loginAttemptUserPrefix+key)
+	if err == nil {
+		err = redis.ZRemRedis(context.Background(), lockedAccountsKey, key)
+	}
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on removing login lock: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+// getLockedAccountKeysCache returns keys of the active locks, userId 0 returns the locks of all users
+func getLockedAccountKeysCache(userId int64) ([]string, error) {
+	members, err := redis.ZRangeByMinScoreRedis(context.Background(), lockedAccountsKey, float64(time.Now().Unix()))
+	if err != nil {
+		return nil, err
+	}
+	if userId == 0 {
+		return members, nil
+	}
+	prefix := strconv.FormatInt(userId, 10) + ":"
+	result := make([]string, 0)
+	for _, m := range members {
+		if strings.HasPrefix(m, prefix) {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func getAndRemoveMagicLinkCache(key string) (*model.MagicLink, error) {
+	result, err := redis.GetDelRedis(context.Background(), magicLinkPrefix+key)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.MagicLink
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+func setMagicLinkCache(key string, magicLink *model.MagicLink, duration time.Duration) error {
+	jsonData, err := json.Marshal(magicLink)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving magic link: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	err = redis.SetRedis(context.Background(), magicLinkPrefix+key, jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving magic link: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+// setMagicLinkSentCache returns false if a link is sent to the user in the duration
+func setMagicLinkSentCache(userId int64, duration time.Duration) (bool, error) {
+	return redis.SetNXRedis(context.Background(), magicLinkSentPrefix+strconv.FormatInt(userId, 10), 1, duration)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func getAndRemoveOidcStateCache(state string) (*model.OidcState, error) {
+	result, err := redis.GetDelRedis(context.Background(), oidcStatePrefix+state)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.OidcState
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+func setOidcStateCache(state string, oidcState *model.OidcState, duration time.Duration) error {
+	jsonData, err := json.Marshal(oidcState)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving oidc state: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	err = redis.SetRedis(context.Background(), oidcStatePrefix+state, jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving oidc state: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func getDevicePairingCache(code string) (*model.DevicePairing, error) {
+	result, err := redis.GetRedis(context.Background(), devicePairingPrefix+code)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.DevicePairing
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+func getAndRemoveDevicePairingCache(code string) (*model.DevicePairing, error) {
+	result, err := redis.GetDelRedis(context.Background(), devicePairingPrefix+code)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.DevicePairing
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+// setDevicePairingCache saves the pairing until its expiration, returns false if the code is already used
+func setDevicePairingCache(pairing *model.DevicePairing, onlyNew bool) (bool, error) {
+	jsonData, err := json.Marshal(pairing)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving device pairing: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return false, err
+	}
+	duration := time.Until(time.UnixMilli(pairing.ExpiresAt))
+	if duration <= 0 {
+		return false, nil
+	}
+	if onlyNew {
+		ok, err := redis.SetNXRedis(context.Background(), devicePairingPrefix+pairing.Code, jsonData, duration)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Redis Error on saving device pairing: %v", err)
+			errorHandler.SaveError(errorMessage, err)
+		}
+		return ok, err
+	}
+	err = redis.SetRedis(context.Background(), devicePairingPrefix+pairing.Code, jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving device pairing: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// replaceDevicePairingCache saves the pairing only if the cached one is still prevPairing,
+// returns false if it's changed by another request, removed or expired
+func replaceDevicePairingCache(prevPairing *model.DevicePairing, pairing *model.DevicePairing) (bool, error) {
+	prevJsonData, err := json.Marshal(prevPairing)
+	if err != nil {
+		return false, err
+	}
+	jsonData, err := json.Marshal(pairing)
+	if err != nil {
+		return false, err
+	}
+	ok, err := redis.CompareAndSetRedis(context.Background(), devicePairingPrefix+pairing.Code, string(prevJsonData), string(jsonData))
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving device pairing: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return ok, err
+}
+
+// getFollowSuggestionsCache returns nil if suggestions of the user are not computed yet
+func getFollowSuggestionsCache(userId int64) ([]model.FollowSuggestion, error) {
+	result, err := redis.GetRedis(context.Background(), followSuggestionsPrefix+strconv.FormatInt(userId, 10))
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		jsonData := make([]model.FollowSuggestion, 0)
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return jsonData, nil
+	}
+	return nil, nil
+}
+
+func setFollowSuggestionsCache(userId int64, suggestions []model.FollowSuggestion, duration time.Duration) error {
+	if suggestions == nil {
+		suggestions = make([]model.FollowSuggestion, 0)
+	}
+	jsonData, err := json.Marshal(suggestions)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving follow suggestions: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	err = redis.SetRedis(context.Background(), followSuggestionsPrefix+strconv.FormatInt(userId, 10), jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving follow suggestions: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+// addFollowSuggestionsQueueCache queues the users for computing suggestions by the background job of any server
+func addFollowSuggestionsQueueCache(userIds ...int64) error {
+	members := make([]interface{}, len(userIds))
+	for i, id := range userIds {
+		members[i] = strconv.FormatInt(id, 10)
+	}
+	return redis.SAddRedis(context.Background(), followSuggestionsQueueKey, members...)
+}
+
+// setFollowSuggestionsRefreshCache returns true only for one server in each refresh interval
+func setFollowSuggestionsRefreshCache(duration time.Duration) (bool, error) {
+	return redis.SetNXRedis(context.Background(), followSuggestionsRefreshKey, time.Now().UnixMilli(), duration)
+}
+
+func popFollowSuggestionsQueueCache(count int64) ([]int64, error) {
+	members, err := redis.SPopNRedis(context.Background(), followSuggestionsQueueKey, count)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	result := make([]int64, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseInt(m, 10, 64); err == nil {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func getApiTokenCache(tokenHash string) (*model.ApiTokenAuthData, error) {
+	result, err := redis.GetRedis(context.Background(), apiTokenPrefix+tokenHash)
+	if err != nil && err.Error() != "redis: nil" {
+		return nil, err
+	}
+	if result != "" {
+		var jsonData model.ApiTokenAuthData
+		err = json.Unmarshal([]byte(result), &jsonData)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonData, nil
+	}
+	return nil, nil
+}
+
+func setApiTokenCache(tokenHash string, authData *model.ApiTokenAuthData, duration time.Duration) error {
+	jsonData, err := json.Marshal(authData)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving api token: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return err
+	}
+	err = redis.SetRedis(context.Background(), apiTokenPrefix+tokenHash, jsonData, duration)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on saving api token: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+func removeApiTokenCache(tokenHashes ...string) error {
+	if len(tokenHashes) == 0 {
+		return nil
+	}
+	keys := make([]string, len(tokenHashes))
+	for i, h := range tokenHashes {
+		keys[i] = apiTokenPrefix + h
+	}
+	err := redis.DelRedis(context.Background(), keys...)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Redis Error on removing api token: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	return err
+}
+
+//------------------------------------------
+//------------------------------------------
+
 func int64SliceToString(nums []int64, delimiter string) string {
 	// Create a string slice to hold the converted numbers
 	strNums := make([]string, len(nums))
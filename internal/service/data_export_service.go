@@ -0,0 +1,393 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"downloader_gochat/cloudStorage"
+	"downloader_gochat/configs"
+	"downloader_gochat/internal/repository"
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/email"
+	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/rabbitmq"
+	"downloader_gochat/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
+)
+
+type IDataExportService interface {
+	RequestDataExport(userId int64, auditCtx *model.AuditContext) (*model.DataExport, error)
+	GetDataExports(userId int64) ([]model.DataExport, error)
+	GetDataExportDownloadUrl(userId int64, token string, auditCtx *model.AuditContext) (string, error)
+}
+
+type DataExportService struct {
+	dataExportRepo repository.IDataExportRepository
+	userRepo       repository.IUserRepository
+	movieRepo      repository.IMovieRepository
+	rabbitmq       rabbitmq.RabbitMQ
+	cloudStorage   cloudStorage.IS3Storage
+	newExport      chan struct{}
+}
+
+func NewDataExportService(dataExportRepo repository.IDataExportRepository, userRepo repository.IUserRepository,
+	movieRepo repository.IMovieRepository, rabbit rabbitmq.RabbitMQ, cloudStorage cloudStorage.IS3Storage) *DataExportService {
+	svc := &DataExportService{
+		dataExportRepo: dataExportRepo,
+		userRepo:       userRepo,
+		movieRepo:      movieRepo,
+		rabbitmq:       rabbit,
+		cloudStorage:   cloudStorage,
+		newExport:      make(chan struct{}, 1),
+	}
+
+	go svc.dataExportJob()
+
+	return svc
+}
+
+//------------------------------------------
+//------------------------------------------
+
+const (
+	dataExportExpire            = 7 * 24 * time.Hour
+	dataExportRequestInterval   = 24 * time.Hour
+	dataExportMaxDuration       = time.Hour
+	dataExportCheckInterval     = time.Minute
+	dataExportDownloadUrlExpire = 10 * time.Minute
+	dataExportListLimit         = 10
+	dataExportMovieBatchSize    = 500
+	dataExportRowBatchSize      = 1000
+)
+
+// RequestDataExport adds a pending export, the archive is created by dataExportJob and its link is sent by email.
+// only one export can be in progress and a new one can be requested once per dataExportRequestInterval
+func (s *DataExportService) RequestDataExport(userId int64, auditCtx *model.AuditContext) (*model.DataExport, error) {
+	lastExports, err := s.dataExportRepo.GetDataExports(userId, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(lastExports) > 0 {
+		last := lastExports[0]
+		if last.Status == model.DataExportPending || last.Status == model.DataExportProcessing {
+			return nil, errors.New(response.DataExportInProgress)
+		}
+		if time.Since(last.RequestDate) < dataExportRequestInterval {
+			return nil, errors.New(response.DataExportRequestedRecently)
+		}
+	}
+
+	dataExport := &model.DataExport{
+		UserId:      userId,
+		Status:      model.DataExportPending,
+		RequestDate: time.Now().UTC(),
+	}
+	err = s.dataExportRepo.AddDataExport(dataExport)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errors.New(response.DataExportInProgress)
+		}
+		return nil, err
+	}
+	addAuditLog(model.AuditDataExport, auditCtx, userId, "")
+
+	// wake up the job, it's already notified if the channel is full
+	select {
+	case s.newExport <- struct{}{}:
+	default:
+	}
+
+	return dataExport, nil
+}
+
+func (s *DataExportService) GetDataExports(userId int64) ([]model.DataExport, error) {
+	return s.dataExportRepo.GetDataExports(userId, dataExportListLimit)
+}
+
+// GetDataExportDownloadUrl returns a short-lived url of the archive, the token is the one sent by email
+func (s *DataExportService) GetDataExportDownloadUrl(userId int64, token string, auditCtx *model.AuditContext) (string, error) {
+	dataExport, err := s.dataExportRepo.GetDataExportByToken(userId, token)
+	if err != nil {
+		return "", err
+	}
+	if !dataExport.IsDownloadable() {
+		return "", errors.New(response.DataExportExpired)
+	}
+
+	fileUrl, err := s.cloudStorage.PresignGetFile(cloudStorage.DataExportBucketName, dataExport.FileName, dataExportDownloadUrlExpire)
+	if err != nil {
+		return "", err
+	}
+	auditCtx.ActorId = userId
+	addAuditLog(model.AuditDataExportDownload, auditCtx, userId, "")
+	return fileUrl, nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (s *DataExportService) dataExportJob() {
+	ticker := time.NewTicker(dataExportCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := s.dataExportRepo.FailStaleDataExports(time.Now().Add(-dataExportMaxDuration))
+			if err != nil {
+				errorMessage := fmt.Sprintf("error on failing stale data exports: %v", err)
+				errorHandler.SaveError(errorMessage, err)
+			}
+			s.removeExpiredDataExports()
+		case <-s.newExport:
+		}
+
+		for {
+			dataExport, err := s.dataExportRepo.ClaimPendingDataExport()
+			if err != nil {
+				errorMessage := fmt.Sprintf("error on getting pending data export: %v", err)
+				errorHandler.SaveError(errorMessage, err)
+				break
+			}
+			if dataExport == nil {
+				break
+			}
+			s.processDataExport(dataExport)
+		}
+	}
+}
+
+func (s *DataExportService) processDataExport(dataExport *model.DataExport) {
+	userData, err := s.userRepo.GetUserMetaData(dataExport.UserId)
+	if err == nil {
+		err = s.createDataExportArchive(dataExport)
+	}
+	if err != nil {
+		dataExport.Status = model.DataExportFailed
+		dataExport.ErrorMessage = "export failed, try again later"
+		errorMessage := fmt.Sprintf("error on creating data export of user (%v): %v", dataExport.UserId, err)
+		errorHandler.SaveError(errorMessage, err)
+		if err = s.dataExportRepo.SaveDataExportResult(dataExport); err != nil {
+			errorHandler.SaveError("error on saving failed data export", err)
+		}
+		return
+	}
+
+	err = s.dataExportRepo.SaveDataExportResult(dataExport)
+	if err != nil {
+		_ = s.cloudStorage.RemoveFile(cloudStorage.DataExportBucketName, dataExport.FileName)
+		errorMessage := fmt.Sprintf("error on saving data export of user (%v): %v", dataExport.UserId, err)
+		errorHandler.SaveError(errorMessage, err)
+		return
+	}
+
+	//-----------------------------------
+	downloadUrl := fmt.Sprintf("%v/v1/user/dataExport/download/%v/%v",
+		configs.GetConfigs().ServerAddress, dataExport.UserId, dataExport.Token)
+	queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+	queueConf.Expiration = strconv.FormatInt(dataExportExpire.Milliseconds(), 10)
+	emailData := email.EmailQueueData{
+		Type:        email.DataExportReady,
+		UserId:      userData.UserId,
+		RawUsername: userData.Username,
+		Email:       userData.Email,
+		Token:       dataExport.Token,
+		Host:        "",
+		Url:         downloadUrl,
+		DeviceInfo:  nil,
+		IpLocation:  "",
+	}
+	s.rabbitmq.Publish(context.TODO(), emailData, queueConf, userData.UserId)
+	//-----------------------------------
+}
+
+// createDataExportArchive writes every section of user data as a json file into a zip and uploads it,
+// rows are read in batches and written directly into the zip. the result is set on dataExport
+func (s *DataExportService) createDataExportArchive(dataExport *model.DataExport) error {
+	tempFile, err := os.CreateTemp("", "dataExport-*.zip")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	zipWriter := zip.NewWriter(tempFile)
+	movieIds := make([]string, 0)
+	seenMovieIds := make(map[string]struct{})
+	for _, name := range s.dataExportRepo.GetUserExportSections() {
+		w, err := zipWriter.Create(name + ".json")
+		if err != nil {
+			return err
+		}
+		err = s.writeDataExportSection(w, dataExport.UserId, name, func(row map[string]interface{}) {
+			movieId, ok := row["movieId"].(string)
+			if !ok || !primitive.IsValidObjectID(movieId) {
+				return
+			}
+			if _, ok = seenMovieIds[movieId]; !ok {
+				seenMovieIds[movieId] = struct{}{}
+				movieIds = append(movieIds, movieId)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := zipWriter.Create("movies.json")
+	if err != nil {
+		return err
+	}
+	if err = s.writeExportMovies(w, movieIds); err != nil {
+		return err
+	}
+	if err = zipWriter.Close(); err != nil {
+		return err
+	}
+
+	size, err := tempFile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err = tempFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("%v-%v.zip", dataExport.UserId, uuid.NewString())
+	_, err = s.cloudStorage.UploadLargeFile(cloudStorage.DataExportBucketName, fileName, tempFile)
+	if err != nil {
+		return err
+	}
+
+	hashToken, err := util.HashPassword(uuid.NewString())
+	if err != nil {
+		_ = s.cloudStorage.RemoveFile(cloudStorage.DataExportBucketName, fileName)
+		return err
+	}
+	now := time.Now().UTC()
+	expireDate := now.Add(dataExportExpire)
+	dataExport.Status = model.DataExportReady
+	dataExport.CompleteDate = &now
+	dataExport.ExpireDate = &expireDate
+	dataExport.FileName = fileName
+	dataExport.Size = size
+	dataExport.Token = strings.ReplaceAll(hashToken, "/", "")
+	return nil
+}
+
+// writeDataExportSection writes the rows of section as a json array, profile is written as an object.
+// onRow is called for each row, it's used to collect the movies
+func (s *DataExportService) writeDataExportSection(w io.Writer, userId int64, name string, onRow func(row map[string]interface{})) error {
+	if name == "profile" {
+		rows, err := s.dataExportRepo.GetUserExportSectionRows(userId, name, 0, 1)
+		if err != nil {
+			return err
+		}
+		var profile interface{} = rows
+		if len(rows) > 0 {
+			profile = rows[0]
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(profile)
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	count := 0
+	for {
+		rows, err := s.dataExportRepo.GetUserExportSectionRows(userId, name, count, dataExportRowBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			onRow(row)
+			if err = writeJsonArrayItem(w, count, row); err != nil {
+				return err
+			}
+			count++
+		}
+		if len(rows) < dataExportRowBatchSize {
+			break
+		}
+	}
+	return writeJsonArrayEnd(w, count)
+}
+
+// writeExportMovies writes title, type and year of the movies that user has interacted with, from mongodb
+func (s *DataExportService) writeExportMovies(w io.Writer, movieIds []string) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	count := 0
+	for i := 0; i < len(movieIds); i += dataExportMovieBatchSize {
+		movies, err := s.movieRepo.GetBatchMovieBriefData(movieIds[i:min(i+dataExportMovieBatchSize, len(movieIds))])
+		if err != nil {
+			return err
+		}
+		for _, movie := range movies {
+			if err = writeJsonArrayItem(w, count, movie); err != nil {
+				return err
+			}
+			count++
+		}
+	}
+	return writeJsonArrayEnd(w, count)
+}
+
+// writeJsonArrayItem writes an indented item of the array that is started with '['
+func writeJsonArrayItem(w io.Writer, index int, item interface{}) error {
+	data, err := json.MarshalIndent(item, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := "\n  "
+	if index > 0 {
+		separator = ",\n  "
+	}
+	if _, err = io.WriteString(w, separator); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func writeJsonArrayEnd(w io.Writer, count int) error {
+	end := "]\n"
+	if count > 0 {
+		end = "\n]\n"
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
+
+func (s *DataExportService) removeExpiredDataExports() {
+	dataExports, err := s.dataExportRepo.GetExpiredDataExports(100)
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on getting expired data exports: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return
+	}
+	for _, e := range dataExports {
+		err = s.cloudStorage.RemoveFile(cloudStorage.DataExportBucketName, e.FileName)
+		if err == nil {
+			err = s.dataExportRepo.SetDataExportExpired(e.Id)
+		}
+		if err != nil {
+			errorMessage := fmt.Sprintf("error on removing expired data export (%v): %v", e.Id, err)
+			errorHandler.SaveError(errorMessage, err)
+		}
+	}
+}
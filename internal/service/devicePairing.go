@@ -0,0 +1,201 @@
+package service
+
+import (
+	"crypto/rand"
+	"downloader_gochat/configs"
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/geoip"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// login on devices that typing password is hard (tv, desktop):
+//  1. new device requests a pairing code and shows it as qr code, then waits for approval (sse)
+//  2. logged-in device scans the code, sees the device model and location and approves it (rest or websocket)
+//  3. new device receives the session with its secret token
+// the pairing is kept in redis so any instance can handle each step
+
+const (
+	devicePairingExpire     = 5 * time.Minute
+	devicePairingCodeLength = 8
+	// no 0/O and 1/I, code may be typed manually
+	devicePairingCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+func (s *UserService) StartDevicePairing(startReq *model.DevicePairingStartReq, ip string) (*model.DevicePairingStartRes, error) {
+	token, err := util.CreateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	pairing := model.DevicePairing{
+		TokenHash:  util.HashToken(token),
+		DeviceInfo: startReq.DeviceInfo,
+		IpLocation: geoip.GetRequestLocation(ip),
+		Status:     model.DevicePairingPending,
+		ExpiresAt:  time.Now().Add(devicePairingExpire).UnixMilli(),
+	}
+	saved := false
+	for i := 0; i < 3 && !saved; i++ {
+		pairing.Code, err = createDevicePairingCode()
+		if err != nil {
+			return nil, err
+		}
+		saved, err = setDevicePairingCache(&pairing, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !saved {
+		return nil, errors.New(response.ServerError)
+	}
+
+	qrData := configs.GetConfigs().ServerAddress + "/v1/user/pairing/" + pairing.Code
+	if appDeepLink := configs.GetConfigs().AppDeepLink; appDeepLink != "" {
+		qrData = strings.TrimSuffix(appDeepLink, "/") + "/pairing/" + pairing.Code
+	}
+	return &model.DevicePairingStartRes{
+		Code:      pairing.Code,
+		Token:     token,
+		QrData:    qrData,
+		ExpiresAt: pairing.ExpiresAt,
+	}, nil
+}
+
+// GetDevicePairing returns the device that requested the code, it's shown to the user before approving
+func (s *UserService) GetDevicePairing(code string) (*model.DevicePairingInfo, error) {
+	return getDevicePairingInfo(code)
+}
+
+func (s *UserService) AnswerDevicePairing(userId int64, code string, approve bool, auditCtx *model.AuditContext) (*model.DevicePairingInfo, error) {
+	return answerDevicePairing(userId, code, approve, auditCtx)
+}
+
+// GetDevicePairingStatus is checked by the new device while waiting for approval
+func (s *UserService) GetDevicePairingStatus(code string, token string) (*model.DevicePairingInfo, error) {
+	pairing, err := getDevicePairingCache(code)
+	if err != nil {
+		return nil, err
+	}
+	if pairing == nil {
+		return nil, errors.New(response.DevicePairingNotFound)
+	}
+	if pairing.TokenHash != util.HashToken(token) {
+		return nil, errors.New(response.InvalidToken)
+	}
+	return pairing.GetInfo(), nil
+}
+
+// CompleteDevicePairing creates the session of the new device after approval, code can be used once.
+// two-factor is not asked, the approving device is already logged-in
+func (s *UserService) CompleteDevicePairing(completeReq *model.DevicePairingCompleteReq, ip string) (*model.UserViewModel, error) {
+	pairing, err := getDevicePairingCache(completeReq.Code)
+	if err != nil {
+		return nil, err
+	}
+	if pairing == nil {
+		return nil, errors.New(response.DevicePairingNotFound)
+	}
+	if pairing.TokenHash != util.HashToken(completeReq.Token) {
+		return nil, errors.New(response.InvalidToken)
+	}
+	if pairing.Status == model.DevicePairingPending {
+		return nil, errors.New(response.DevicePairingPending)
+	}
+
+	pairing, err = getAndRemoveDevicePairingCache(completeReq.Code)
+	if err != nil {
+		return nil, err
+	}
+	if pairing == nil || pairing.TokenHash != util.HashToken(completeReq.Token) {
+		// completed by another request
+		return nil, errors.New(response.DevicePairingNotFound)
+	}
+	if pairing.Status != model.DevicePairingApproved {
+		return nil, errors.New(response.DevicePairingRejected)
+	}
+
+	userData, err := s.userRepo.GetUserMetaData(pairing.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if userData.UserId == 0 {
+		return nil, errors.New(response.UserNotFound)
+	}
+	return s.createLoginSession(userData, &pairing.DeviceInfo, ip)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func getDevicePairingInfo(code string) (*model.DevicePairingInfo, error) {
+	pairing, err := getDevicePairingCache(code)
+	if err != nil {
+		return nil, err
+	}
+	if pairing == nil {
+		return nil, errors.New(response.DevicePairingNotFound)
+	}
+	return pairing.GetInfo(), nil
+}
+
+// answerDevicePairing is used by rest api and websocket
+func answerDevicePairing(userId int64, code string, approve bool, auditCtx *model.AuditContext) (*model.DevicePairingInfo, error) {
+	pairing, err := getDevicePairingCache(code)
+	if err != nil {
+		return nil, err
+	}
+	if pairing == nil {
+		return nil, errors.New(response.DevicePairingNotFound)
+	}
+	if pairing.Status != model.DevicePairingPending {
+		return nil, errors.New(response.DevicePairingAnswered)
+	}
+
+	answeredPairing := *pairing
+	answeredPairing.UserId = userId
+	answeredPairing.Status = model.DevicePairingRejected
+	if approve {
+		answeredPairing.Status = model.DevicePairingApproved
+	}
+	// another device of the user (or another user) may answer at the same time, only the first answer is saved
+	saved, err := replaceDevicePairingCache(pairing, &answeredPairing)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		current, err := getDevicePairingCache(code)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, errors.New(response.DevicePairingNotFound)
+		}
+		return nil, errors.New(response.DevicePairingAnswered)
+	}
+
+	if approve {
+		details := fmt.Sprintf("new device: %v %v, %v, location: %v", pairing.DeviceInfo.AppName,
+			pairing.DeviceInfo.AppVersion, pairing.DeviceInfo.DeviceModel, pairing.IpLocation)
+		addAuditLog(model.AuditDevicePairing, auditCtx, userId, details)
+	}
+	return answeredPairing.GetInfo(), nil
+}
+
+func createDevicePairingCode() (string, error) {
+	code := make([]byte, devicePairingCodeLength)
+	max := big.NewInt(int64(len(devicePairingCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = devicePairingCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
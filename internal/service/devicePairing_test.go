@@ -0,0 +1,194 @@
+package service
+
+import (
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/util"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// savePendingPairing saves a pairing like StartDevicePairing, returns its token
+func savePendingPairing(t *testing.T, code string) string {
+	t.Helper()
+	token, err := util.CreateRandomToken()
+	if err != nil {
+		t.Fatalf("CreateRandomToken: %v", err)
+	}
+	pairing := model.DevicePairing{
+		Code:       code,
+		TokenHash:  util.HashToken(token),
+		DeviceInfo: model.DeviceInfo{AppName: "tv-app", DeviceModel: "tv"},
+		Status:     model.DevicePairingPending,
+		ExpiresAt:  time.Now().Add(devicePairingExpire).UnixMilli(),
+	}
+	saved, err := setDevicePairingCache(&pairing, true)
+	if err != nil || !saved {
+		t.Fatalf("could not save pairing: %v, %v", saved, err)
+	}
+	return token
+}
+
+func TestCreateDevicePairingCode(t *testing.T) {
+	code, err := createDevicePairingCode()
+	if err != nil {
+		t.Fatalf("createDevicePairingCode: %v", err)
+	}
+	if len(code) != devicePairingCodeLength {
+		t.Errorf("code length = %v, want %v", len(code), devicePairingCodeLength)
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(devicePairingCodeAlphabet, c) {
+			t.Errorf("code %v has character %q out of alphabet", code, c)
+		}
+	}
+}
+
+func TestAnswerDevicePairing(t *testing.T) {
+	server := startMockRedis(t)
+	s := &UserService{}
+	token := savePendingPairing(t, "ABCD2345")
+
+	info, err := answerDevicePairing(1, "ABCD2345", true, nil)
+	if err != nil {
+		t.Fatalf("answerDevicePairing: %v", err)
+	}
+	if info.Status != model.DevicePairingApproved {
+		t.Errorf("status = %v, want %v", info.Status, model.DevicePairingApproved)
+	}
+
+	pairing, err := getDevicePairingCache("ABCD2345")
+	if err != nil || pairing == nil {
+		t.Fatalf("pairing is removed: %v", err)
+	}
+	if pairing.Status != model.DevicePairingApproved || pairing.UserId != 1 {
+		t.Errorf("saved pairing = %+v, want approved by user 1", pairing)
+	}
+	if ttl := server.TTL(devicePairingPrefix + "ABCD2345"); ttl <= 0 || ttl > devicePairingExpire {
+		t.Errorf("ttl of answered pairing = %v, it must be kept", ttl)
+	}
+
+	// answered once
+	_, err = answerDevicePairing(2, "ABCD2345", false, nil)
+	expectError(t, err, response.DevicePairingAnswered)
+
+	// new device sees the answer
+	info, err = s.GetDevicePairingStatus("ABCD2345", token)
+	if err != nil || info.Status != model.DevicePairingApproved {
+		t.Errorf("status = %+v, %v, want approved", info, err)
+	}
+	_, err = s.GetDevicePairingStatus("ABCD2345", "wrong-token")
+	expectError(t, err, response.InvalidToken)
+}
+
+func TestAnswerDevicePairing_Concurrent(t *testing.T) {
+	startMockRedis(t)
+	savePendingPairing(t, "CONCURR2")
+
+	const answers = 20
+	var wg sync.WaitGroup
+	results := make([]error, answers)
+	for i := 0; i < answers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// half of the devices approve and the others reject
+			_, results[i] = answerDevicePairing(int64(i+1), "CONCURR2", i%2 == 0, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, err := range results {
+		if err == nil {
+			if winner != -1 {
+				t.Fatalf("answers %v and %v are both saved", winner, i)
+			}
+			winner = i
+		} else if err.Error() != response.DevicePairingAnswered {
+			t.Errorf("answer %v: error = %v, want %v", i, err, response.DevicePairingAnswered)
+		}
+	}
+	if winner == -1 {
+		t.Fatal("no answer is saved")
+	}
+
+	pairing, err := getDevicePairingCache("CONCURR2")
+	if err != nil || pairing == nil {
+		t.Fatalf("pairing is removed: %v", err)
+	}
+	wantStatus := model.DevicePairingRejected
+	if winner%2 == 0 {
+		wantStatus = model.DevicePairingApproved
+	}
+	if pairing.UserId != int64(winner+1) || pairing.Status != wantStatus {
+		t.Errorf("saved pairing = %+v, want the answer of user %v (%v)", pairing, winner+1, wantStatus)
+	}
+}
+
+func TestReplaceDevicePairingCache_Changed(t *testing.T) {
+	startMockRedis(t)
+	savePendingPairing(t, "CHANGED2")
+	pending, _ := getDevicePairingCache("CHANGED2")
+
+	rejected := *pending
+	rejected.Status = model.DevicePairingRejected
+	rejected.UserId = 2
+	saved, err := replaceDevicePairingCache(pending, &rejected)
+	if err != nil || !saved {
+		t.Fatalf("first answer is not saved: %v, %v", saved, err)
+	}
+
+	// answer that read the pending state before the first answer
+	approved := *pending
+	approved.Status = model.DevicePairingApproved
+	approved.UserId = 1
+	saved, err = replaceDevicePairingCache(pending, &approved)
+	if err != nil || saved {
+		t.Fatalf("stale answer is saved: %v, %v", saved, err)
+	}
+	current, _ := getDevicePairingCache("CHANGED2")
+	if current.Status != model.DevicePairingRejected || current.UserId != 2 {
+		t.Errorf("pairing = %+v, want the first answer", current)
+	}
+}
+
+func TestAnswerDevicePairing_NotFound(t *testing.T) {
+	server := startMockRedis(t)
+	_, err := answerDevicePairing(1, "MISSING2", true, nil)
+	expectError(t, err, response.DevicePairingNotFound)
+
+	savePendingPairing(t, "EXPIRED2")
+	server.FastForward(devicePairingExpire + time.Second)
+	_, err = answerDevicePairing(1, "EXPIRED2", true, nil)
+	expectError(t, err, response.DevicePairingNotFound)
+
+	pending := &model.DevicePairing{Code: "EXPIRED2", Status: model.DevicePairingPending}
+	saved, err := replaceDevicePairingCache(pending, pending)
+	if err != nil || saved {
+		t.Errorf("expired pairing is replaced: %v, %v", saved, err)
+	}
+}
+
+func TestCompleteDevicePairing_NotApproved(t *testing.T) {
+	startMockRedis(t)
+	s := &UserService{}
+	token := savePendingPairing(t, "COMPLET2")
+
+	_, err := s.CompleteDevicePairing(&model.DevicePairingCompleteReq{Code: "COMPLET2", Token: "wrong-token"}, "")
+	expectError(t, err, response.InvalidToken)
+	_, err = s.CompleteDevicePairing(&model.DevicePairingCompleteReq{Code: "COMPLET2", Token: token}, "")
+	expectError(t, err, response.DevicePairingPending)
+
+	if _, err = answerDevicePairing(1, "COMPLET2", false, nil); err != nil {
+		t.Fatalf("answerDevicePairing: %v", err)
+	}
+	_, err = s.CompleteDevicePairing(&model.DevicePairingCompleteReq{Code: "COMPLET2", Token: token}, "")
+	expectError(t, err, response.DevicePairingRejected)
+
+	// code can be used once
+	_, err = s.CompleteDevicePairing(&model.DevicePairingCompleteReq{Code: "COMPLET2", Token: token}, "")
+	expectError(t, err, response.DevicePairingNotFound)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"downloader_gochat/configs"
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/email"
+	"downloader_gochat/pkg/geoip"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/rabbitmq"
+	"downloader_gochat/util"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// email is changed in two steps so a stolen session cannot take over the account recovery email:
+//  1. a confirmation link is sent to the new email and a notice with a revert link is sent to the current one
+//  2. email is replaced only after the new address is confirmed
+// the revert link stays valid for some days after the change, it restores the old email and logs out all the sessions
+
+const (
+	emailChangeTokenExpire = 1 * time.Hour
+	emailRevertTokenExpire = 7 * 24 * time.Hour
+)
+
+func (s *UserService) RequestEmailChange(userId int64, newEmail string, twoFactorCode string, auditCtx *model.AuditContext) error {
+	searchResult, err := s.userRepo.GetUserMetaData(userId)
+	if err != nil {
+		return err
+	}
+	if searchResult.UserId == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if searchResult.Email == newEmail {
+		return errors.New(response.EmailNotChanged)
+	}
+	if searchResult.TwoFactorEnabled {
+		err = s.checkSecondFactor(userId, twoFactorCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	emailOwner, err := s.userRepo.GetUserByUsernameEmail("", newEmail)
+	if err != nil {
+		return err
+	}
+	if emailOwner != nil {
+		return errors.New(response.EmailAlreadyExist)
+	}
+
+	changeToken, err := util.CreateRandomToken()
+	if err != nil {
+		return err
+	}
+	revertToken, err := util.CreateRandomToken()
+	if err != nil {
+		return err
+	}
+	changeTokenExpire := time.Now().Add(emailChangeTokenExpire).UnixMilli()
+	revertTokenExpire := time.Now().Add(emailRevertTokenExpire).UnixMilli()
+
+	saved, err := s.userRepo.SaveEmailChangeTokens(userId, newEmail, util.HashToken(changeToken), changeTokenExpire, util.HashToken(revertToken), revertTokenExpire)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return errors.New(response.EmailChangedRecently)
+	}
+	addAuditLog(model.AuditEmailChangeRequest, auditCtx, userId, "new email: "+newEmail)
+
+	//-----------------------------------
+	ipLocation := geoip.GetRequestLocation(auditCtx.Ip)
+	confirmUrl := fmt.Sprintf("%v/v1/user/changeEmail/confirm/%v/%v",
+		configs.GetConfigs().ServerAddress, userId, changeToken)
+	queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+	queueConf.Expiration = strconv.FormatInt(emailChangeTokenExpire.Milliseconds(), 10)
+	emailData := email.EmailQueueData{
+		Type:        email.ChangeEmail,
+		UserId:      searchResult.UserId,
+		RawUsername: searchResult.Username,
+		Email:       newEmail,
+		NewEmail:    newEmail,
+		Token:       "",
+		Host:        "",
+		Url:         confirmUrl,
+		DeviceInfo:  nil,
+		IpLocation:  ipLocation,
+	}
+	s.rabbitmq.Publish(context.TODO(), emailData, queueConf, userId)
+
+	if searchResult.Email != "" {
+		revertUrl := fmt.Sprintf("%v/v1/user/changeEmail/revert/%v/%v",
+			configs.GetConfigs().ServerAddress, userId, revertToken)
+		queueConf = rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+		emailData = email.EmailQueueData{
+			Type:        email.EmailChanged,
+			UserId:      searchResult.UserId,
+			RawUsername: searchResult.Username,
+			Email:       searchResult.Email,
+			NewEmail:    newEmail,
+			Token:       "",
+			Host:        "",
+			Url:         revertUrl,
+			DeviceInfo:  nil,
+			IpLocation:  ipLocation,
+		}
+		s.rabbitmq.Publish(context.TODO(), emailData, queueConf, userId)
+	}
+	//-----------------------------------
+	return nil
+}
+
+// ConfirmEmailChange is called from the link sent to the new email
+func (s *UserService) ConfirmEmailChange(userId int64, token string, auditCtx *model.AuditContext) error {
+	err := s.userRepo.ConfirmEmailChangeToken(userId, util.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// registered by another user after the request
+			return errors.New(response.EmailAlreadyExist)
+		}
+		return err
+	}
+
+	addAuditLog(model.AuditEmailChange, auditCtx, userId, "")
+	s.onEmailChanged(userId, "Email of your account is changed")
+	return nil
+}
+
+// RevertEmailChange is called from the link sent to the old email, it's assumed the account is compromised,
+// so all the sessions are removed and their refreshTokens get blacklisted
+func (s *UserService) RevertEmailChange(userId int64, token string, auditCtx *model.AuditContext) error {
+	sessions, err := s.userRepo.RevertEmailChangeToken(userId, util.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// old email is registered by another user after the change
+			return errors.New(response.EmailAlreadyExist)
+		}
+		return err
+	}
+
+	refreshTokenExpireDay := configs.GetConfigs().RefreshTokenExpireDay
+	for i := range sessions {
+		_ = setJwtDataCache(sessions[i].RefreshToken, "revertEmailChange", time.Duration(refreshTokenExpireDay)*24*time.Hour)
+		if sessions[i].NotifToken != "" {
+			_ = removeNotifTokenFromCachedUserData(userId, sessions[i].NotifToken)
+		}
+	}
+	closeDeviceConnections(userId, "", "email change is reverted")
+
+	addAuditLog(model.AuditEmailChangeRevert, auditCtx, userId, fmt.Sprintf("sessions removed: %v", len(sessions)))
+	s.onEmailChanged(userId, "Email change of your account is reverted, all the sessions are logged out")
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (s *UserService) onEmailChanged(userId int64, message string) {
+	searchResult, err := s.userRepo.GetUserMetaData(userId)
+	if err != nil || searchResult.UserId == 0 {
+		return
+	}
+	_ = updateEmailOfCachedUserData(userId, searchResult.Email)
+
+	notifQueueConf := rabbitmq.NewConfigPublish(rabbitmq.NotificationExchange, rabbitmq.NotificationBindingKey)
+	notification := model.CreateSecurityNotificationAction(userId, userId, message)
+	s.rabbitmq.Publish(context.TODO(), notification, notifQueueConf, userId)
+}
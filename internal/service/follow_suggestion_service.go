@@ -0,0 +1,176 @@
+package service
+
+import (
+	"downloader_gochat/internal/repository"
+	"downloader_gochat/model"
+	errorHandler "downloader_gochat/pkg/error"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type IFollowSuggestionService interface {
+	GetFollowSuggestions(userId int64, skip int, limit int) ([]model.FollowSuggestionDataModel, error)
+	DismissFollowSuggestion(userId int64, suggestedId int64) error
+}
+
+type FollowSuggestionService struct {
+	followSuggestionRepo repository.IFollowSuggestionRepository
+}
+
+func NewFollowSuggestionService(followSuggestionRepo repository.IFollowSuggestionRepository) *FollowSuggestionService {
+	svc := &FollowSuggestionService{
+		followSuggestionRepo: followSuggestionRepo,
+	}
+
+	go svc.followSuggestionsJob()
+
+	return svc
+}
+
+//------------------------------------------
+//------------------------------------------
+
+const (
+	followSuggestionsLimit           = 50
+	followSuggestionsCacheExpire     = 36 * time.Hour
+	followSuggestionsQueueInterval   = time.Minute
+	followSuggestionsRefreshInterval = 12 * time.Hour
+	followSuggestionsActiveDuration  = 24 * time.Hour
+	followSuggestionsBatchSize       = 100
+	// followSuggestionsQueueLimit is the max users that each server computes in followSuggestionsQueueInterval,
+	// refresh of active users is spread over time and servers
+	followSuggestionsQueueLimit = 500
+)
+
+// GetFollowSuggestions returns the cached suggestions of the user, users that are followed after computing them are skipped.
+// if suggestions are not computed yet, user is queued for the job and an empty list is returned
+func (s *FollowSuggestionService) GetFollowSuggestions(userId int64, skip int, limit int) ([]model.FollowSuggestionDataModel, error) {
+	result := make([]model.FollowSuggestionDataModel, 0)
+	suggestions, err := getFollowSuggestionsCache(userId)
+	if err != nil {
+		return nil, err
+	}
+	if suggestions == nil {
+		err = addFollowSuggestionsQueueCache(userId)
+		return result, err
+	}
+	if len(suggestions) == 0 {
+		return result, nil
+	}
+
+	userIds := make([]int64, len(suggestions))
+	for i := range suggestions {
+		userIds[i] = suggestions[i].UserId
+	}
+	users, err := s.followSuggestionRepo.GetFollowSuggestionUsers(userId, userIds)
+	if err != nil {
+		return nil, err
+	}
+	usersMap := make(map[int64]*model.FollowSuggestionDataModel, len(users))
+	for i := range users {
+		usersMap[users[i].UserId] = &users[i]
+	}
+	for _, suggestion := range suggestions {
+		if u, ok := usersMap[suggestion.UserId]; ok {
+			u.MutualFollows = suggestion.MutualFollows
+			u.CommonGenres = suggestion.CommonGenres
+			u.CommonMovies = suggestion.CommonMovies
+			result = append(result, *u)
+		}
+	}
+
+	if skip >= len(result) {
+		return make([]model.FollowSuggestionDataModel, 0), nil
+	}
+	return result[skip:min(skip+limit, len(result))], nil
+}
+
+// DismissFollowSuggestion hides the suggested user, it's also skipped on the next computes
+func (s *FollowSuggestionService) DismissFollowSuggestion(userId int64, suggestedId int64) error {
+	err := s.followSuggestionRepo.AddFollowSuggestionDismiss(userId, suggestedId)
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := getFollowSuggestionsCache(userId)
+	if err == nil && suggestions != nil {
+		suggestions = slices.DeleteFunc(suggestions, func(f model.FollowSuggestion) bool {
+			return f.UserId == suggestedId
+		})
+		_ = setFollowSuggestionsCache(userId, suggestions, followSuggestionsCacheExpire)
+	}
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// followSuggestionsJob computes suggestions of the queued users every minute,
+// active users are queued by one of the servers every followSuggestionsRefreshInterval
+func (s *FollowSuggestionService) followSuggestionsJob() {
+	ticker := time.NewTicker(followSuggestionsQueueInterval)
+	defer ticker.Stop()
+	for {
+		refresh, err := setFollowSuggestionsRefreshCache(followSuggestionsRefreshInterval)
+		if err != nil {
+			errorMessage := fmt.Sprintf("error on checking follow suggestions refresh: %v", err)
+			errorHandler.SaveError(errorMessage, err)
+		} else if refresh {
+			s.queueActiveUsersSuggestions()
+		}
+
+		for computed := 0; computed < followSuggestionsQueueLimit; {
+			userIds, err := popFollowSuggestionsQueueCache(followSuggestionsBatchSize)
+			if err != nil {
+				errorMessage := fmt.Sprintf("error on getting follow suggestions queue: %v", err)
+				errorHandler.SaveError(errorMessage, err)
+				break
+			}
+			for _, userId := range userIds {
+				s.computeFollowSuggestions(userId)
+			}
+			computed += len(userIds)
+			if len(userIds) < followSuggestionsBatchSize {
+				break
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+// queueActiveUsersSuggestions adds the active users to the queue, they are computed by all the servers
+func (s *FollowSuggestionService) queueActiveUsersSuggestions() {
+	seenAfter := time.Now().Add(-followSuggestionsActiveDuration)
+	lastUserId := int64(0)
+	for {
+		userIds, err := s.followSuggestionRepo.GetActiveUserIds(seenAfter, lastUserId, followSuggestionsBatchSize)
+		if err != nil {
+			errorMessage := fmt.Sprintf("error on getting active users for follow suggestions: %v", err)
+			errorHandler.SaveError(errorMessage, err)
+			return
+		}
+		if len(userIds) > 0 {
+			if err = addFollowSuggestionsQueueCache(userIds...); err != nil {
+				errorMessage := fmt.Sprintf("error on queueing active users for follow suggestions: %v", err)
+				errorHandler.SaveError(errorMessage, err)
+				return
+			}
+		}
+		if len(userIds) < followSuggestionsBatchSize {
+			return
+		}
+		lastUserId = userIds[len(userIds)-1]
+	}
+}
+
+func (s *FollowSuggestionService) computeFollowSuggestions(userId int64) {
+	suggestions, err := s.followSuggestionRepo.ComputeFollowSuggestions(userId, followSuggestionsLimit)
+	if err != nil {
+		errorMessage := fmt.Sprintf("error on computing follow suggestions of user (%v): %v", userId, err)
+		errorHandler.SaveError(errorMessage, err)
+		return
+	}
+	_ = setFollowSuggestionsCache(userId, suggestions, followSuggestionsCacheExpire)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"downloader_gochat/internal/repository"
+	"downloader_gochat/model"
+	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/util"
+	"fmt"
+	"time"
+)
+
+type IJwtKeyService interface {
+	LoadJwtKeys() error
+	GenerateJwtKey(algorithm string, activate bool) (string, error)
+	ActivateJwtKey(kid string) error
+	RetireJwtKey(kid string) error
+	ListJwtKeys() ([]model.JwtKey, error)
+}
+
+type JwtKeyService struct {
+	jwtKeyRepo repository.IJwtKeyRepository
+}
+
+// NewJwtKeyService only loads the keys once, api servers call StartJwtKeyReloadJob to
+// pick up keys that are generated, activated or retired from the management command
+func NewJwtKeyService(jwtKeyRepo repository.IJwtKeyRepository) *JwtKeyService {
+	return &JwtKeyService{
+		jwtKeyRepo: jwtKeyRepo,
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+const jwtKeyReloadInterval = time.Minute
+
+func (s *JwtKeyService) StartJwtKeyReloadJob() {
+	if err := s.LoadJwtKeys(); err != nil {
+		errorHandler.SaveError("error on loading jwt keys", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(jwtKeyReloadInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.LoadJwtKeys(); err != nil {
+				errorHandler.SaveError("error on reloading jwt keys", err)
+			}
+		}
+	}()
+}
+
+// LoadJwtKeys replaces the keys used for access tokens, when there is no signing key
+// tokens are signed with ACCESS_TOKEN_SECRET. after a key is activated hmac tokens are only
+// accepted for one access token lifetime, unless ALLOW_HMAC_ACCESS_TOKEN is set
+func (s *JwtKeyService) LoadJwtKeys() error {
+	jwtKeys, err := s.jwtKeyRepo.GetJwtKeys()
+	if err != nil {
+		return err
+	}
+	firstActivation, err := s.jwtKeyRepo.GetFirstJwtKeyActivation()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]util.JwtKey, 0, len(jwtKeys))
+	signingKid := ""
+	for _, k := range jwtKeys {
+		privateKey := ""
+		if k.Status == model.JwtKeySigning {
+			privateKey = k.PrivateKey
+		}
+		key, err := util.ParseJwtKey(k.Kid, k.Algorithm, privateKey, k.PublicKey)
+		if err != nil {
+			errorMessage := fmt.Sprintf("error on parsing jwt key (%v)", k.Kid)
+			errorHandler.SaveError(errorMessage, err)
+			continue
+		}
+		keys = append(keys, *key)
+		if k.Status == model.JwtKeySigning {
+			signingKid = k.Kid
+		}
+	}
+
+	util.SetJwtKeys(keys, signingKid, firstActivation)
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (s *JwtKeyService) GenerateJwtKey(algorithm string, activate bool) (string, error) {
+	kid, privatePem, publicPem, err := util.GenerateJwtKey(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.jwtKeyRepo.AddJwtKey(&model.JwtKey{
+		Kid:        kid,
+		Algorithm:  algorithm,
+		PrivateKey: privatePem,
+		PublicKey:  publicPem,
+		Status:     model.JwtKeyVerifying,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if activate {
+		if err = s.jwtKeyRepo.ActivateJwtKey(kid); err != nil {
+			return kid, err
+		}
+	}
+	return kid, nil
+}
+
+func (s *JwtKeyService) ActivateJwtKey(kid string) error {
+	return s.jwtKeyRepo.ActivateJwtKey(kid)
+}
+
+func (s *JwtKeyService) RetireJwtKey(kid string) error {
+	return s.jwtKeyRepo.RetireJwtKey(kid)
+}
+
+func (s *JwtKeyService) ListJwtKeys() ([]model.JwtKey, error) {
+	return s.jwtKeyRepo.ListJwtKeys()
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"downloader_gochat/configs"
+	"downloader_gochat/db/redis"
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/email"
+	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/geoip"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/rabbitmq"
+	"downloader_gochat/util"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// failed logins are counted in redis so limits apply across all instances.
+//   - per ip: blocks an ip that tries many accounts
+//   - per account and ip: exponential backoff, slows down guessing the password of one account
+//   - per account and network (ipv4 /24, ipv6 /64): locks the account for that network and sends unlock link,
+//     logins from other networks and devices that have logged in before (with fingerprint) are not affected,
+//     so an attacker can't lock out the owner
+// redis errors are logged and the login continues, limits must not make login unavailable
+
+const (
+	loginIpMaxFailedAttempts = 50
+	loginIpAttemptWindow     = 15 * time.Minute
+	loginBackoffFreeAttempts = 3
+	loginMaxBackoff          = 15 * time.Minute
+)
+
+// checkLoginIpAllowed is checked before searching the user, so unknown usernames are limited too
+func checkLoginIpAllowed(ip string) error {
+	result, err := redis.GetRedis(context.Background(), loginAttemptIpPrefix+ip)
+	if err != nil {
+		if err.Error() != "redis: nil" {
+			errorHandler.SaveError("Redis Error on getting login attempts", err)
+		}
+		return nil
+	}
+	if count, _ := strconv.Atoi(result); count >= loginIpMaxFailedAttempts {
+		return errors.New(response.TooManyLoginAttempts)
+	}
+	return nil
+}
+
+func (s *UserService) checkLoginAllowed(userId int64, ip string, deviceInfo *model.DeviceInfo) error {
+	ttl, err := redis.TTLRedis(context.Background(), loginBackoffPrefix+strconv.FormatInt(userId, 10)+":"+ip)
+	if err != nil {
+		errorHandler.SaveError("Redis Error on getting login backoff", err)
+	} else if ttl > 0 {
+		return errors.New(response.TooManyLoginAttempts)
+	}
+
+	lock, err := getLoginLockCache(userId, getLoginNetwork(ip))
+	if err != nil {
+		errorHandler.SaveError("Redis Error on getting login lock", err)
+		return nil
+	}
+	if lock == nil {
+		return nil
+	}
+	// device key without fingerprint is made of device info that anyone can send
+	if deviceInfo.Fingerprint != "" {
+		known, err := s.userRepo.IsKnownUserDevice(userId, getDeviceKey(deviceInfo))
+		if err != nil {
+			return err
+		}
+		if known {
+			return nil
+		}
+	}
+	return errors.New(response.AccountLocked)
+}
+
+func registerFailedLoginIp(ip string) {
+	_, err := redis.IncrRedis(context.Background(), loginAttemptIpPrefix+ip, loginIpAttemptWindow)
+	if err != nil {
+		errorHandler.SaveError("Redis Error on saving login attempt", err)
+	}
+}
+
+func (s *UserService) registerFailedLogin(userData *model.UserDataModel, deviceInfo *model.DeviceInfo, ip string, reason string) {
+	registerFailedLoginIp(ip)
+	addAuditLog(model.AuditLoginFailed, &model.AuditContext{Ip: ip, DeviceInfo: deviceInfo}, userData.UserId, reason)
+
+	ctx := context.Background()
+	lockoutDuration := time.Duration(configs.GetConfigs().LoginLockoutMinutes) * time.Minute
+	userId := strconv.FormatInt(userData.UserId, 10)
+
+	userIpAttempts, err := redis.IncrRedis(ctx, loginAttemptUserIpPrefix+userId+":"+ip, lockoutDuration)
+	if err != nil {
+		errorHandler.SaveError("Redis Error on saving login attempt", err)
+		return
+	}
+	if userIpAttempts > loginBackoffFreeAttempts {
+		backoff := loginMaxBackoff
+		if shift := userIpAttempts - loginBackoffFreeAttempts - 1; shift < 10 {
+			backoff = min(time.Second<<shift, loginMaxBackoff)
+		}
+		_ = redis.SetRedis(ctx, loginBackoffPrefix+userId+":"+ip, 1, backoff)
+	}
+
+	network := getLoginNetwork(ip)
+	userAttempts, err := redis.IncrRedis(ctx, loginAttemptUserPrefix+loginLockKey(userData.UserId, network), lockoutDuration)
+	if err != nil {
+		errorHandler.SaveError("Redis Error on saving login attempt", err)
+		return
+	}
+	// only on reaching the limit, so each network is locked once
+	if userAttempts == int64(configs.GetConfigs().LoginMaxFailedAttempts) {
+		s.lockAccount(userData, userAttempts, ip, network, lockoutDuration)
+	}
+}
+
+// lockAccount sends the unlock link on the first lock of the account, locks from other networks
+// while it's active share its token so the link removes all of them
+func (s *UserService) lockAccount(userData *model.UserDataModel, failedAttempts int64, ip string, network string, lockoutDuration time.Duration) {
+	now := time.Now().UTC()
+	ipLocation := geoip.GetRequestLocation(ip)
+	lock := model.LoginLock{
+		UserId:         userData.UserId,
+		Username:       userData.Username,
+		Network:        network,
+		FailedAttempts: failedAttempts,
+		LastIpLocation: ipLocation,
+		LockedAt:       now,
+		ExpiresAt:      now.Add(lockoutDuration),
+	}
+
+	activeLock, err := getActiveLoginLock(userData.UserId)
+	if err != nil {
+		errorHandler.SaveError("Redis Error on getting login lock", err)
+		return
+	}
+	if activeLock != nil {
+		lock.UnlockTokenHash = activeLock.UnlockTokenHash
+		_ = setLoginLockCache(&lock)
+		return
+	}
+
+	token, err := util.CreateRandomToken()
+	if err != nil {
+		errorHandler.SaveError("Error on creating unlock token", err)
+		return
+	}
+	lock.UnlockTokenHash = util.HashToken(token)
+	if err = setLoginLockCache(&lock); err != nil {
+		return
+	}
+
+	//-----------------------------------
+	unlockUrl := fmt.Sprintf("%v/v1/user/unlockAccount/%v/%v",
+		configs.GetConfigs().ServerAddress, userData.UserId, token)
+	queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+	emailData := email.EmailQueueData{
+		Type:        email.AccountLocked,
+		UserId:      userData.UserId,
+		RawUsername: userData.Username,
+		Email:       userData.Email,
+		Token:       "",
+		Host:        "",
+		Url:         unlockUrl,
+		DeviceInfo:  nil,
+		IpLocation:  ipLocation,
+	}
+	s.rabbitmq.Publish(context.TODO(), emailData, queueConf, userData.UserId)
+	//-----------------------------------
+}
+
+// clearFailedLogins is called after a successful login, the account lock of the network is removed too
+func clearFailedLogins(userId int64, ip string) {
+	id := strconv.FormatInt(userId, 10)
+	err := redis.DelRedis(context.Background(), loginAttemptUserIpPrefix+id+":"+ip, loginBackoffPrefix+id+":"+ip)
+	if err != nil {
+		errorHandler.SaveError("Redis Error on removing login attempts", err)
+	}
+	_ = removeLoginLockCache(userId, getLoginNetwork(ip))
+}
+
+// getLoginNetwork returns the /24 of ipv4 and /64 of ipv6 addresses, failed logins of an account are counted per network
+func getLoginNetwork(ip string) string {
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return ip
+	}
+	if ipv4 := parsedIp.To4(); ipv4 != nil {
+		return (&net.IPNet{IP: ipv4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsedIp.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
+
+// getActiveLoginLock returns one of the active locks of the account, nil if it's not locked
+func getActiveLoginLock(userId int64) (*model.LoginLock, error) {
+	keys, err := getLockedAccountKeysCache(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		lock, err := getLoginLockCacheByKey(key)
+		if err != nil || lock != nil {
+			return lock, err
+		}
+	}
+	return nil, nil
+}
+
+// removeLoginLocks removes the locks of the account from all networks
+func removeLoginLocks(userId int64) error {
+	keys, err := getLockedAccountKeysCache(userId)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err = removeLoginLockCacheByKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// UnlockAccount removes the locks of account from all networks, they share the token that was sent on the first lock
+func (s *UserService) UnlockAccount(userId int64, token string) error {
+	lock, err := getActiveLoginLock(userId)
+	if err != nil {
+		return err
+	}
+	if lock == nil || lock.UnlockTokenHash != util.HashToken(token) {
+		return errors.New(response.InvalidToken)
+	}
+	return removeLoginLocks(userId)
+}
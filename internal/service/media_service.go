@@ -3,19 +3,29 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"downloader_gochat/cloudStorage"
+	"downloader_gochat/configs"
 	"downloader_gochat/internal/repository"
 	"downloader_gochat/model"
 	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/response"
 	"downloader_gochat/rabbitmq"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"mime/multipart"
+	"net/http"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/buckket/go-blurhash"
 	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
@@ -27,8 +37,17 @@ import (
 
 type IMediaService interface {
 	UploadFile(userId int64, messageData *model.UploadMediaReq, contentType string, fileSize int64, fileName string, fileBuffer multipart.File) (*model.MediaFile, error)
+	CreatePresignedUpload(userId int64, uploadData *model.PresignedUploadReq) (*model.PresignedUploadRes, error)
+	ConfirmUpload(userId int64, confirmData *model.ConfirmUploadReq) (*model.MediaFile, error)
 }
 
+const (
+	presignedUploadExpire        = 15 * time.Minute
+	signedMediaUrlExpire         = 30 * time.Minute
+	unusedMediaBlobGracePeriod   = 1 * time.Hour
+	unusedMediaBlobCheckInterval = 30 * time.Minute
+)
+
 type MediaService struct {
 	mediaRepo    repository.IMediaRepository
 	userRep      repository.IUserRepository
@@ -44,13 +63,16 @@ func NewMediaService(mediaRepo repository.IMediaRepository, userRep repository.I
 		Mux:           &sync.Mutex{},
 	}
 
-	return &MediaService{
+	mediaSvc := MediaService{
 		mediaRepo:    mediaRepo,
 		userRep:      userRep,
 		wsRep:        wsRep,
 		rabbitmq:     rabbit,
 		cloudStorage: cloudStorage,
 	}
+	go mediaSvc.removeUnusedMediaBlobsJob()
+
+	return &mediaSvc
 }
 
 //------------------------------------------
@@ -72,37 +94,307 @@ func (m *MediaService) UploadFile(userId int64, messageData *model.UploadMediaRe
 		return nil, err
 	}
 
-	savingFileName := uuid.NewString() + filepath.Ext(fileName)
-	result, err := m.cloudStorage.UploadLargeFile(cloudStorage.MediaFileBucketName, savingFileName, fileBuffer)
+	hash, err := hashMediaFile(fileBuffer)
 	if err != nil {
 		return nil, err
 	}
-
-	mediaFile := model.MediaFile{
-		Id:        0,
-		MessageId: messageId,
-		Date:      time.Now().UTC(),
-		Url:       result.Location,
-		Type:      contentType,
-		Size:      fileSize,
-		Thumbnail: "",
-		BlurHash:  "",
+	blob, err := m.mediaRepo.GetAndTouchMediaBlob(hash)
+	if err != nil {
+		return nil, err
 	}
-	if strings.Contains(contentType, "image") {
-		mediaFile.Thumbnail, mediaFile.BlurHash = createThumbnailAndBlurHash(contentType, fileBuffer)
+	if blob == nil {
+		// new content, upload and process it once
+		if _, err = fileBuffer.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		savingFileName := uuid.NewString() + filepath.Ext(fileName)
+		result, err := m.cloudStorage.UploadLargeFile(cloudStorage.MediaFileBucketName, savingFileName, fileBuffer)
+		if err != nil {
+			return nil, err
+		}
+
+		blob = &model.MediaBlob{
+			Hash:     hash,
+			FileName: savingFileName,
+			Url:      result.Location,
+			Type:     contentType,
+			Size:     fileSize,
+		}
+		if strings.Contains(contentType, "image") {
+			if _, err = fileBuffer.Seek(0, io.SeekStart); err == nil {
+				blob.Thumbnail, blob.BlurHash = createThumbnailAndBlurHash(contentType, fileBuffer)
+			}
+		}
+		blob, err = m.saveMediaBlob(blob)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	mediaFile := newMediaFileFromBlob(messageId, blob)
 	err = m.mediaRepo.SaveMediaData(&mediaFile)
 	if err != nil {
 		return nil, err
 	}
 
 	newMessage.Medias = []model.MediaFile{mediaFile}
+	signMediaFileUrls(m.cloudStorage, newMessage.Medias)
+	m.sendMediaMessage(userId, &newMessage)
+
+	return &newMessage.Medias[0], err
+}
+
+func (m *MediaService) CreatePresignedUpload(userId int64, uploadData *model.PresignedUploadReq) (*model.PresignedUploadRes, error) {
+	uploadId := uuid.NewString()
+	savingFileName := uploadId + filepath.Ext(uploadData.FileName)
+	checksum, err := hexToBase64(uploadData.Sha256)
+	if err != nil {
+		return nil, err
+	}
+	// storage checks the content against the checksum, the file doesn't need to be read again for hashing
+	presigned, err := m.cloudStorage.PresignUploadFile(cloudStorage.MediaFileBucketName, savingFileName, uploadData.ContentType, uploadData.Size, checksum, presignedUploadExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string)
+	for key := range presigned.SignedHeader {
+		if !strings.EqualFold(key, "host") {
+			headers[key] = presigned.SignedHeader.Get(key)
+		}
+	}
+
+	expiresAt := time.Now().UTC().Add(presignedUploadExpire)
+	pendingUpload := model.PendingMediaUpload{
+		UserId:      userId,
+		FileName:    savingFileName,
+		Url:         strings.Split(presigned.URL, "?")[0],
+		ContentType: uploadData.ContentType,
+		Size:        uploadData.Size,
+		Hash:        uploadData.Sha256,
+		ExpiresAt:   expiresAt,
+	}
+	// keep it a bit longer than the presigned url, the client needs time to confirm after uploading
+	err = setPendingMediaUploadCache(uploadId, &pendingUpload, presignedUploadExpire+5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	result := model.PresignedUploadRes{
+		UploadId:  uploadId,
+		Url:       presigned.URL,
+		Method:    presigned.Method,
+		Headers:   headers,
+		ExpiresAt: expiresAt,
+	}
+	return &result, nil
+}
+
+// ConfirmUpload claims the pending upload, so concurrent confirms of the same upload cannot both pass.
+// the pending upload is put back on every error except an invalid file, the client can confirm again before it expires
+func (m *MediaService) ConfirmUpload(userId int64, confirmData *model.ConfirmUploadReq) (*model.MediaFile, error) {
+	pendingUpload, err := getAndRemovePendingMediaUploadCache(confirmData.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if pendingUpload == nil {
+		return nil, errors.New(response.UploadNotFound)
+	}
+	if pendingUpload.UserId != userId {
+		restorePendingMediaUpload(confirmData.UploadId, pendingUpload)
+		return nil, errors.New(response.UploadNotFound)
+	}
+
+	mediaFile, err := m.confirmPendingUpload(userId, confirmData, pendingUpload)
+	if err != nil && err.Error() != response.InvalidUploadedFile {
+		restorePendingMediaUpload(confirmData.UploadId, pendingUpload)
+	}
+	return mediaFile, err
+}
+
+func (m *MediaService) confirmPendingUpload(userId int64, confirmData *model.ConfirmUploadReq, pendingUpload *model.PendingMediaUpload) (*model.MediaFile, error) {
+	head, err := m.cloudStorage.HeadFile(cloudStorage.MediaFileBucketName, pendingUpload.FileName)
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			// not uploaded yet
+			return nil, errors.New(response.UploadNotFound)
+		}
+		return nil, err
+	}
+
+	checksum, err := hexToBase64(pendingUpload.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if head.ContentLength == nil || *head.ContentLength != pendingUpload.Size ||
+		head.ContentType == nil || *head.ContentType != pendingUpload.ContentType ||
+		head.ChecksumSHA256 == nil || *head.ChecksumSHA256 != checksum {
+		_ = m.cloudStorage.RemoveFile(cloudStorage.MediaFileBucketName, pendingUpload.FileName)
+		return nil, errors.New(response.InvalidUploadedFile)
+	}
+
+	body, err := m.cloudStorage.DownloadFile(cloudStorage.MediaFileBucketName, pendingUpload.FileName, "bytes=0-511")
+	if err != nil {
+		return nil, err
+	}
+	header, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !checkSniffedContentType(pendingUpload.ContentType, header) {
+		_ = m.cloudStorage.RemoveFile(cloudStorage.MediaFileBucketName, pendingUpload.FileName)
+		return nil, errors.New(response.InvalidUploadedFile)
+	}
+
+	// content is verified by storage against the checksum that was signed with the upload
+	hash := pendingUpload.Hash
+	blob, err := m.mediaRepo.GetAndTouchMediaBlob(hash)
+	if err != nil {
+		return nil, err
+	}
+	if blob != nil {
+		if blob.FileName != pendingUpload.FileName {
+			// same content already exists, the new object is not needed.
+			// on a retried confirm the blob may be the one saved from this upload
+			_ = m.cloudStorage.RemoveFile(cloudStorage.MediaFileBucketName, pendingUpload.FileName)
+		}
+	} else {
+		blob = &model.MediaBlob{
+			Hash:     hash,
+			FileName: pendingUpload.FileName,
+			Url:      pendingUpload.Url,
+			Type:     pendingUpload.ContentType,
+			Size:     pendingUpload.Size,
+		}
+		if strings.Contains(pendingUpload.ContentType, "image") {
+			// the only full read of the object, for thumbnail and blurHash
+			fileBody, err := m.cloudStorage.DownloadFile(cloudStorage.MediaFileBucketName, pendingUpload.FileName, "")
+			if err == nil {
+				blob.Thumbnail, blob.BlurHash = createThumbnailAndBlurHash(pendingUpload.ContentType, fileBody)
+				_ = fileBody.Close()
+			} else {
+				errorMessage := fmt.Sprintf("Error on downloading uploaded image: %v", err)
+				errorHandler.SaveError(errorMessage, err)
+			}
+		}
+		blob, err = m.saveMediaBlob(blob)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	//----------------------------------------------------
 	//----------------------------------------------------
+
+	newMessage := model.ReceiveNewMessage{
+		Uuid:       confirmData.Uuid,
+		Content:    confirmData.Content,
+		RoomId:     confirmData.RoomId,
+		ReceiverId: confirmData.ReceiverId,
+		State:      1,
+		Date:       time.Now().UTC(),
+		UserId:     userId,
+	}
+	// saved together, so a confirm that is retried after an error doesn't add a second message
+	mediaFile := newMediaFileFromBlob(0, blob)
+	messageId, err := m.wsRep.SaveMessageWithMedia(&newMessage, &mediaFile)
+	if err != nil {
+		return nil, err
+	}
+	newMessage.Id = messageId
+
+	newMessage.Medias = []model.MediaFile{mediaFile}
+	signMediaFileUrls(m.cloudStorage, newMessage.Medias)
+	m.sendMediaMessage(userId, &newMessage)
+
+	return &newMessage.Medias[0], nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// saveMediaBlob saves the new blob, if the same content is saved concurrently the existing blob is used instead
+func (m *MediaService) saveMediaBlob(blob *model.MediaBlob) (*model.MediaBlob, error) {
+	created, err := m.mediaRepo.SaveMediaBlob(blob)
+	if err != nil || created {
+		return blob, err
+	}
+
+	existing, err := m.mediaRepo.GetAndTouchMediaBlob(blob.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		// removed as unused right after the conflict
+		_, err = m.mediaRepo.SaveMediaBlob(blob)
+		return blob, err
+	}
+	_ = m.cloudStorage.RemoveFile(cloudStorage.MediaFileBucketName, blob.FileName)
+	return existing, nil
+}
+
+func restorePendingMediaUpload(uploadId string, pendingUpload *model.PendingMediaUpload) {
+	// same grace period as CreatePresignedUpload
+	if remaining := time.Until(pendingUpload.ExpiresAt) + 5*time.Minute; remaining > 0 {
+		_ = setPendingMediaUploadCache(uploadId, pendingUpload, remaining)
+	}
+}
+
+func hexToBase64(hexStr string) (string, error) {
+	b, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (m *MediaService) removeUnusedMediaBlobsJob() {
+	ticker := time.NewTicker(unusedMediaBlobCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		blobs, err := m.mediaRepo.RemoveUnusedMediaBlobs(time.Now().UTC().Add(-unusedMediaBlobGracePeriod))
+		if err != nil {
+			errorMessage := fmt.Sprintf("Error on removing unused media blobs: %v", err)
+			errorHandler.SaveError(errorMessage, err)
+			continue
+		}
+		for i := range blobs {
+			err = m.cloudStorage.RemoveFile(cloudStorage.MediaFileBucketName, blobs[i].FileName)
+			if err != nil {
+				errorMessage := fmt.Sprintf("Error on removing unused media file (%v): %v", blobs[i].FileName, err)
+				errorHandler.SaveError(errorMessage, err)
+			}
+		}
+	}
+}
+
+func newMediaFileFromBlob(messageId int64, blob *model.MediaBlob) model.MediaFile {
+	return model.MediaFile{
+		Id:        0,
+		MessageId: messageId,
+		Date:      time.Now().UTC(),
+		Url:       blob.Url,
+		Type:      blob.Type,
+		Size:      blob.Size,
+		Thumbnail: blob.Thumbnail,
+		BlurHash:  blob.BlurHash,
+		Hash:      blob.Hash,
+	}
+}
+
+func hashMediaFile(file io.Reader) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func (m *MediaService) sendMediaMessage(userId int64, newMessage *model.ReceiveNewMessage) {
 	sender, senderExist := getClientFromHub(userId)
-	cl, ok := getClientFromHub(messageData.ReceiverId)
+	cl, ok := getClientFromHub(newMessage.ReceiverId)
 	if ok {
 		// receiver is online
 		// add creator profileImage, read from cache only
@@ -114,7 +406,7 @@ func (m *MediaService) UploadFile(userId int64, messageData *model.UploadMediaRe
 			newMessage.Username = userCacheData.Username
 		}
 
-		receiveMessage := model.CreateReceiveNewMessageAction(&newMessage)
+		receiveMessage := model.CreateReceiveNewMessageAction(newMessage)
 		cl.Message <- receiveMessage
 	}
 
@@ -136,18 +428,69 @@ func (m *MediaService) UploadFile(userId int64, messageData *model.UploadMediaRe
 		ctx, _ := context.WithCancel(context.Background())
 		//defer cancel()
 		notifQueueConf := rabbitmq.NewConfigPublish(rabbitmq.NotificationExchange, rabbitmq.NotificationBindingKey)
-		notifMessage := model.CreateNewMessageNotificationAction(&newMessage)
+		notifMessage := model.CreateNewMessageNotificationAction(newMessage)
 		m.rabbitmq.Publish(ctx, notifMessage, notifQueueConf, newMessage.ReceiverId)
 	}
 	_ = m.wsRep.UpdateUserReceivedMessageTime(newMessage.ReceiverId)
+}
 
-	return &mediaFile, err
+// signMediaFileUrls replaces the stored urls with short-lived signed urls when media files are private,
+// callers must make sure the medias belong to a conversation of the requesting user
+func signMediaFileUrls(storage cloudStorage.IS3Storage, medias []model.MediaFile) {
+	if !configs.GetConfigs().PrivateMediaFiles {
+		return
+	}
+	for i := range medias {
+		medias[i].Url = signMediaFileUrl(storage, medias[i].Url)
+		if strings.HasPrefix(medias[i].Thumbnail, "http") {
+			medias[i].Thumbnail = signMediaFileUrl(storage, medias[i].Thumbnail)
+		}
+	}
+}
+
+func signMediaFileUrl(storage cloudStorage.IS3Storage, fileUrl string) string {
+	if fileUrl == "" {
+		return ""
+	}
+	fileName := path.Base(strings.Split(fileUrl, "?")[0])
+	signedUrl, err := storage.PresignGetFile(cloudStorage.MediaFileBucketName, fileName, signedMediaUrlExpire)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Error on signing media file url: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return ""
+	}
+	return signedUrl
+}
+
+// checkSniffedContentType compares the declared content-type with the one detected from the file magic bytes
+func checkSniffedContentType(contentType string, header []byte) bool {
+	declared, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if declared == "image/jpg" {
+		declared = "image/jpeg"
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(header))
+
+	if sniffed == declared {
+		return true
+	}
+	if sniffed != "application/octet-stream" {
+		return false
+	}
+	// these types always have detectable magic bytes
+	switch declared {
+	case "image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp", "application/pdf", "video/webm":
+		return false
+	}
+	return true
 }
 
 //------------------------------------------
 //------------------------------------------
 
-func createThumbnailAndBlurHash(contentType string, fileBuffer multipart.File) (string, string) {
+func createThumbnailAndBlurHash(contentType string, fileBuffer io.Reader) (string, string) {
 	AdminSvc.status.Tasks.MediaService.Mux.Lock()
 	AdminSvc.status.Tasks.MediaService.RunningCount++
 	AdminSvc.status.Tasks.MediaService.Mux.Unlock()
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestCheckSniffedContentType(t *testing.T) {
+	var (
+		png    = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+		jpeg   = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+		gif    = []byte("GIF89a\x01\x00\x01\x00")
+		webp   = []byte("RIFF\x24\x00\x00\x00WEBPVP8 ")
+		pdf    = []byte("%PDF-1.7\n")
+		mp4    = []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+		html   = []byte("<html><body>")
+		text   = []byte("hello world")
+		binary = []byte("\x00\x01\x02\x03\x04\x05\x06\x07")
+	)
+
+	tests := []struct {
+		name        string
+		contentType string
+		header      []byte
+		want        bool
+	}{
+		{"png", "image/png", png, true},
+		{"jpeg", "image/jpeg", jpeg, true},
+		{"jpg alias", "image/jpg", jpeg, true},
+		{"gif", "image/gif", gif, true},
+		{"webp", "image/webp", webp, true},
+		{"pdf", "application/pdf", pdf, true},
+		{"mp4", "video/mp4", mp4, true},
+		{"parameters are ignored", "text/plain; charset=utf-8", text, true},
+		{"png declared as jpeg", "image/jpeg", png, false},
+		{"html declared as png", "image/png", html, false},
+		{"html declared as text", "text/plain", html, false},
+		{"image without magic bytes", "image/png", binary, false},
+		{"pdf without magic bytes", "application/pdf", binary, false},
+		{"type without magic bytes", "audio/aac", binary, true},
+		{"invalid content type", "image/", png, false},
+		{"empty content type", "", png, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkSniffedContentType(tt.contentType, tt.header); got != tt.want {
+				t.Errorf("checkSniffedContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHexToBase64(t *testing.T) {
+	sum := sha256.Sum256([]byte("media file"))
+	want := base64.StdEncoding.EncodeToString(sum[:])
+
+	got, err := hexToBase64(hex.EncodeToString(sum[:]))
+	if err != nil || got != want {
+		t.Errorf("hexToBase64 = %v, %v, want %v", got, err, want)
+	}
+	// clients may send uppercase hex
+	got, err = hexToBase64("48656C6C6F")
+	if err != nil || got != "SGVsbG8=" {
+		t.Errorf("uppercase hex = %v, %v, want SGVsbG8=", got, err)
+	}
+
+	for _, invalid := range []string{"abc", "zz", "0x12"} {
+		if _, err = hexToBase64(invalid); err == nil {
+			t.Errorf("hexToBase64(%q) must fail", invalid)
+		}
+	}
+}
@@ -88,8 +88,20 @@ func NotificationConsumer(d *amqp.Delivery, extraConsumerData interface{}) {
 		return
 	}
 
+	if notifData := channelMessage.NotificationData; notifData != nil && notifData.CreatorId != 0 && notifData.CreatorId != notifData.ReceiverId {
+		// follow and message notifications of accounts that are pending deletion are dropped
+		pendingDeletion, err := notifSvc.notifRepo.IsUserPendingDeletion(notifData.CreatorId)
+		if err == nil && pendingDeletion {
+			if err = d.Ack(false); err != nil {
+				errorMessage := fmt.Sprintf("error acking [notification] message: %s", err)
+				errorHandler.SaveError(errorMessage, err)
+			}
+			return
+		}
+	}
+
 	switch channelMessage.Action {
-	case model.FollowNotifAction, model.MovieNotifAction:
+	case model.FollowNotifAction, model.MovieNotifAction, model.SecurityNotifAction:
 		// need to save the notification, show notification in app, send push-notification to followed user
 		err = notifSvc.notifRepo.SaveUserNotification(channelMessage.NotificationData)
 		if err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
@@ -289,7 +301,8 @@ func (n *NotificationService) handleNotification(notificationData *model.Notific
 		if !dbconfig.DisableBotsNotifications {
 			go n.handleMovieBotNotification(notificationData)
 		}
-	} else {
+	} else if notificationData.EntityTypeId != model.SecurityNotificationTypeId {
+		// security notifications are about the receiver's own account, message is already created
 		cacheData, _ := getCachedUserData(notificationData.CreatorId)
 		if cacheData != nil {
 			notificationData.Message = generateNotificationMessage(notificationData, cacheData.Username)
@@ -306,11 +319,20 @@ func (n *NotificationService) handleNotification(notificationData *model.Notific
 	pushNotificationTitle := ""
 	switch notificationData.EntityTypeId {
 	case model.FollowNotificationTypeId:
-		pushNotificationTitle = "New Follower"
+		switch notificationData.SubEntityTypeId {
+		case model.NewFollowRequest:
+			pushNotificationTitle = "Follow Request"
+		case model.FollowRequestAccepted:
+			pushNotificationTitle = "Follow Request Accepted"
+		default:
+			pushNotificationTitle = "New Follower"
+		}
 	case model.NewMessageNotificationTypeId:
 		pushNotificationTitle = "New Message"
 	case model.MoviesNotificationTypeId:
 		pushNotificationTitle = "Movie Update"
+	case model.SecurityNotificationTypeId:
+		pushNotificationTitle = "Security Alert"
 	}
 
 	receiverCacheData, _ := getCachedUserData(notificationData.ReceiverId)
@@ -419,8 +441,15 @@ func generateNotificationMessage(notificationData *model.NotificationDataModel,
 	message := ""
 	switch notificationData.EntityTypeId {
 	case model.FollowNotificationTypeId:
-		//new follower
-		message = fmt.Sprintf("%v Started Following You", username)
+		switch notificationData.SubEntityTypeId {
+		case model.NewFollowRequest:
+			message = fmt.Sprintf("%v Requested To Follow You", username)
+		case model.FollowRequestAccepted:
+			message = fmt.Sprintf("%v Accepted Your Follow Request", username)
+		default:
+			//new follower
+			message = fmt.Sprintf("%v Started Following You", username)
+		}
 	case model.NewMessageNotificationTypeId:
 		//new message
 		message = fmt.Sprintf("%v: %v", username, notificationData.Message)
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"downloader_gochat/configs"
+	"downloader_gochat/model"
+	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/oidc"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/rabbitmq"
+	"downloader_gochat/util"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// login with OpenID Connect providers, accounts are matched by (provider, sub) of the id token.
+// on first login the provider is linked to the account with the same email only if both sides have verified it,
+// otherwise whoever registers an email first on any side could take over the other account
+
+const (
+	oidcStateExpire    = 10 * time.Minute
+	oidcCallbackPath   = "/v1/user/oidc/callback"
+	oidcRequestTimeout = 15 * time.Second
+)
+
+var usernameInvalidChars = regexp.MustCompile("[^a-z0-9_-]+")
+
+func newOidcProviders() map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider)
+	for _, p := range configs.GetConfigs().OidcProviders {
+		providers[p.Name] = oidc.NewProvider(p.Name, p.Issuer, p.ClientId, p.ClientSecret)
+	}
+	return providers
+}
+
+func (s *UserService) GetOidcProviders() *model.OidcProvidersRes {
+	names := make([]string, 0, len(s.oidcProviders))
+	for name := range s.oidcProviders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return &model.OidcProvidersRes{Providers: names}
+}
+
+// StartOidcLogin returns the url of provider login page, linkUserId and refreshToken of the session are set when linking provider to a logged-in account
+func (s *UserService) StartOidcLogin(providerName string, startReq *model.OidcStartReq, linkUserId int64, refreshToken string) (*model.OidcStartRes, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return nil, errors.New(response.ProviderNotFound)
+	}
+
+	var linkSession *model.ActiveSession
+	if linkUserId != 0 {
+		session, err := s.getRefreshTokenSession(linkUserId, refreshToken)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, errors.New(response.InvalidRefreshToken)
+		}
+		linkSession = session
+	}
+
+	redirectUri := startReq.RedirectUri
+	if redirectUri == "" {
+		redirectUri = configs.GetConfigs().ServerAddress + oidcCallbackPath
+	} else if !isAllowedOidcRedirectUri(redirectUri) {
+		return nil, errors.New(response.InvalidRedirectUri)
+	}
+
+	state, err := util.CreateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := util.CreateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	oidcState := model.OidcState{
+		Provider:     providerName,
+		CodeVerifier: oidc.NewCodeVerifier(),
+		Nonce:        nonce,
+		RedirectUri:  redirectUri,
+		DeviceInfo:   startReq.DeviceInfo,
+		LinkUserId:   linkUserId,
+	}
+	if linkSession != nil {
+		oidcState.LinkDeviceId = linkSession.DeviceId
+		oidcState.LinkTokenFamily = linkSession.TokenFamily
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	authUrl, err := provider.AuthCodeUrl(ctx, redirectUri, state, nonce, oidcState.CodeVerifier)
+	if err != nil {
+		errorHandler.SaveError("Error on getting oidc discovery", err)
+		return nil, errors.New(response.ProviderLoginFailed)
+	}
+
+	err = setOidcStateCache(util.HashToken(state), &oidcState, oidcStateExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OidcStartRes{AuthUrl: authUrl, State: state}, nil
+}
+
+// LoginOidc completes the provider login with the code returned to redirect uri.
+// when the login is started for linking, the provider is linked and nil is returned,
+// refreshToken of the request must belong to the session that started it
+func (s *UserService) LoginOidc(loginReq *model.OidcLoginReq, ip string, refreshToken string) (*model.UserViewModel, error) {
+	err := checkLoginIpAllowed(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcState, err := getAndRemoveOidcStateCache(util.HashToken(loginReq.State))
+	if err != nil {
+		return nil, err
+	}
+	if oidcState == nil {
+		registerFailedLoginIp(ip)
+		return nil, errors.New(response.InvalidToken)
+	}
+	provider, ok := s.oidcProviders[oidcState.Provider]
+	if !ok {
+		return nil, errors.New(response.ProviderNotFound)
+	}
+	if oidcState.LinkUserId != 0 {
+		// otherwise a link url started by an attacker would link the provider account of whoever opens it
+		session, err := s.getRefreshTokenSession(oidcState.LinkUserId, refreshToken)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil || session.DeviceId != oidcState.LinkDeviceId || session.TokenFamily != oidcState.LinkTokenFamily {
+			registerFailedLoginIp(ip)
+			return nil, errors.New(response.LinkSessionMismatch)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	claims, err := provider.Exchange(ctx, oidcState.RedirectUri, loginReq.Code, oidcState.CodeVerifier, oidcState.Nonce)
+	if err != nil {
+		if !errors.Is(err, oidc.ErrInvalidIdToken) {
+			errorHandler.SaveError("Error on oidc login", err)
+		}
+		registerFailedLoginIp(ip)
+		return nil, errors.New(response.ProviderLoginFailed)
+	}
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+
+	if oidcState.LinkUserId != 0 {
+		auditCtx := &model.AuditContext{
+			ActorId:      oidcState.LinkUserId,
+			Ip:           ip,
+			RefreshToken: refreshToken,
+		}
+		return nil, s.linkOidcIdentity(oidcState.LinkUserId, oidcState.Provider, claims.Subject, email, auditCtx)
+	}
+
+	userData, err := s.getOidcUser(oidcState, claims, email, ip)
+	if err != nil {
+		return nil, err
+	}
+	return s.completeFirstFactor(userData, &oidcState.DeviceInfo, ip)
+}
+
+// getOidcUser finds the account of provider user, links or creates the account on first login
+func (s *UserService) getOidcUser(oidcState *model.OidcState, claims *oidc.IdTokenClaims, email string, ip string) (*model.UserDataModel, error) {
+	identity, err := s.userRepo.GetUserIdentity(oidcState.Provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		err = s.userRepo.UpdateUserIdentityLogin(identity.Id, email)
+		if err != nil {
+			return nil, err
+		}
+		userData, err := s.userRepo.GetUserMetaData(identity.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if userData.UserId == 0 {
+			return nil, errors.New(response.UserNotFound)
+		}
+		return userData, nil
+	}
+
+	if email == "" || !claims.EmailVerified {
+		return nil, errors.New(response.ProviderEmailNotVerified)
+	}
+
+	userData, err := s.userRepo.GetUserByUsernameEmail("", email)
+	if err != nil {
+		return nil, err
+	}
+	isNewUser := userData == nil
+	if isNewUser {
+		userData, err = s.signUpOidcUser(claims, email)
+		if err != nil {
+			return nil, err
+		}
+	} else if !userData.EmailVerified {
+		return nil, errors.New(response.EmailNotVerified)
+	}
+
+	newIdentity := model.UserIdentity{
+		UserId:        userData.UserId,
+		Provider:      oidcState.Provider,
+		Subject:       claims.Subject,
+		Email:         email,
+		CreatedAt:     time.Now().UTC(),
+		LastLoginDate: time.Now().UTC(),
+	}
+	err = s.userRepo.AddUserIdentity(&newIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNewUser {
+		// existing account is linked without the user being logged in, let the owner know
+		addAuditLog(model.AuditIdentityLink, &model.AuditContext{
+			ActorId:    userData.UserId,
+			Ip:         ip,
+			DeviceInfo: &oidcState.DeviceInfo,
+		}, userData.UserId, fmt.Sprintf("provider: %v, linked by verified email", oidcState.Provider))
+
+		message := fmt.Sprintf("Login with %v is linked to your account because it has the same email", oidcState.Provider)
+		notifQueueConf := rabbitmq.NewConfigPublish(rabbitmq.NotificationExchange, rabbitmq.NotificationBindingKey)
+		notification := model.CreateSecurityNotificationAction(userData.UserId, newIdentity.Id, message)
+		s.rabbitmq.Publish(context.TODO(), notification, notifQueueConf, userData.UserId)
+	}
+	return userData, nil
+}
+
+// signUpOidcUser creates an account with verified email and random password, user can set password with reset password
+func (s *UserService) signUpOidcUser(claims *oidc.IdTokenClaims, email string) (*model.UserDataModel, error) {
+	username, err := s.createOidcUsername(claims, email)
+	if err != nil {
+		return nil, err
+	}
+	password, err := util.CreateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	publicName := strings.TrimSpace(claims.Name)
+	if publicName == "" {
+		publicName = username
+	}
+
+	var user = model.User{
+		Username:       username,
+		RawUsername:    username,
+		PublicName:     publicName,
+		Email:          email,
+		EmailVerified:  true,
+		MbtiType:       model.ENTJ,
+		DefaultProfile: configs.GetConfigs().DefaultProfileImage,
+	}
+	err = user.EncryptPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	err = user.EncryptEmailToken(uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.userRepo.AddUser(&user, int64(model.DefaultUser))
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserDataModel{
+		UserId:        result.UserId,
+		Username:      result.Username,
+		Email:         result.Email,
+		Password:      result.Password,
+		EmailVerified: true,
+	}, nil
+}
+
+// createOidcUsername makes a valid username from preferred_username or email, random suffix is added if it's taken
+func (s *UserService) createOidcUsername(claims *oidc.IdTokenClaims, email string) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = usernameInvalidChars.ReplaceAllString(strings.ToLower(base), "_")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	for len(base) < 6 {
+		base += "_"
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		searchResult, err := s.userRepo.GetUserByUsernameEmail(username, "")
+		if err != nil {
+			return "", err
+		}
+		if searchResult == nil {
+			return username, nil
+		}
+		suffix, err := util.CreateRandomToken()
+		if err != nil {
+			return "", err
+		}
+		username = base + "_" + suffix[:6]
+	}
+	return "", errors.New(response.UsernameAlreadyExist)
+}
+
+func (s *UserService) linkOidcIdentity(userId int64, providerName string, subject string, email string, auditCtx *model.AuditContext) error {
+	identity, err := s.userRepo.GetUserIdentity(providerName, subject)
+	if err != nil {
+		return err
+	}
+	if identity != nil {
+		if identity.UserId == userId {
+			return s.userRepo.UpdateUserIdentityLogin(identity.Id, email)
+		}
+		return errors.New(response.IdentityAlreadyLinked)
+	}
+
+	identities, err := s.userRepo.GetUserIdentities(userId)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(identities, func(i model.UserIdentityDataModel) bool { return i.Provider == providerName }) {
+		// another account of this provider is linked, must be unlinked first
+		return errors.New(response.IdentityAlreadyLinked)
+	}
+
+	newIdentity := model.UserIdentity{
+		UserId:        userId,
+		Provider:      providerName,
+		Subject:       subject,
+		Email:         email,
+		CreatedAt:     time.Now().UTC(),
+		LastLoginDate: time.Now().UTC(),
+	}
+	err = s.userRepo.AddUserIdentity(&newIdentity)
+	if err != nil {
+		return err
+	}
+
+	addAuditLog(model.AuditIdentityLink, auditCtx, userId, fmt.Sprintf("provider: %v", providerName))
+
+	message := fmt.Sprintf("Login with %v is linked to your account", providerName)
+	notifQueueConf := rabbitmq.NewConfigPublish(rabbitmq.NotificationExchange, rabbitmq.NotificationBindingKey)
+	notification := model.CreateSecurityNotificationAction(userId, newIdentity.Id, message)
+	s.rabbitmq.Publish(context.TODO(), notification, notifQueueConf, userId)
+	return nil
+}
+
+func (s *UserService) GetUserIdentities(userId int64) ([]model.UserIdentityDataModel, error) {
+	return s.userRepo.GetUserIdentities(userId)
+}
+
+// UnlinkUserIdentity removes the provider, account is still accessible with password or magic link
+func (s *UserService) UnlinkUserIdentity(userId int64, providerName string, auditCtx *model.AuditContext) error {
+	err := s.userRepo.RemoveUserIdentity(userId, providerName)
+	if err != nil {
+		return err
+	}
+
+	addAuditLog(model.AuditIdentityUnlink, auditCtx, userId, fmt.Sprintf("provider: %v", providerName))
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// getRefreshTokenSession returns the session of the valid refreshToken, nil if token is invalid, revoked or of another user
+func (s *UserService) getRefreshTokenSession(userId int64, refreshToken string) (*model.ActiveSession, error) {
+	if refreshToken == "" {
+		return nil, nil
+	}
+	if result, err := GetJwtDataCache(refreshToken); result != "" && err == nil {
+		return nil, nil
+	}
+	_, claims, err := util.VerifyRefreshToken(refreshToken)
+	if err != nil || claims == nil || claims.UserId != userId {
+		return nil, nil
+	}
+
+	sessions, err := s.userRepo.GetUserActiveSessions(userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		if sessions[i].RefreshToken == refreshToken {
+			return &sessions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// isAllowedOidcRedirectUri accepts the app deeplink and the origins of CorsAllowedOrigins,
+// code of provider is sent to this uri so it must be controlled by us
+func isAllowedOidcRedirectUri(redirectUri string) bool {
+	appDeepLink := configs.GetConfigs().AppDeepLink
+	if appDeepLink != "" && strings.HasPrefix(redirectUri, strings.TrimSuffix(appDeepLink, "/")+"/") {
+		return true
+	}
+	u, err := url.Parse(redirectUri)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return false
+	}
+	origin := u.Scheme + "://" + u.Host
+	for _, allowed := range configs.GetConfigs().CorsAllowedOrigins {
+		if allowed != "" && strings.TrimSuffix(allowed, "/") == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -2,15 +2,20 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"downloader_gochat/cloudStorage"
 	"downloader_gochat/configs"
 	"downloader_gochat/internal/repository"
 	"downloader_gochat/model"
 	"downloader_gochat/pkg/email"
+	errorHandler "downloader_gochat/pkg/error"
 	"downloader_gochat/pkg/geoip"
+	"downloader_gochat/pkg/oidc"
 	"downloader_gochat/pkg/response"
+	"downloader_gochat/pkg/totp"
 	"downloader_gochat/rabbitmq"
 	"downloader_gochat/util"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"mime/multipart"
@@ -21,53 +26,96 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type IUserService interface {
 	SignUp(registerVM *model.RegisterViewModel, ip string) (*model.UserViewModel, error)
 	LoginUser(loginVM *model.LoginViewModel, ip string) (*model.UserViewModel, error)
+	LoginTwoFactor(loginReq *model.TwoFactorLoginReq, ip string) (*model.UserViewModel, error)
+	UnlockAccount(userId int64, token string) error
+	SendMagicLink(linkReq *model.MagicLinkReq, ip string) error
+	LoginMagicLink(loginReq *model.MagicLinkLoginReq, ip string) (*model.UserViewModel, error)
+	GetOidcProviders() *model.OidcProvidersRes
+	StartOidcLogin(providerName string, startReq *model.OidcStartReq, linkUserId int64, refreshToken string) (*model.OidcStartRes, error)
+	LoginOidc(loginReq *model.OidcLoginReq, ip string, refreshToken string) (*model.UserViewModel, error)
+	GetUserIdentities(userId int64) ([]model.UserIdentityDataModel, error)
+	UnlinkUserIdentity(userId int64, providerName string, auditCtx *model.AuditContext) error
+	StartDevicePairing(startReq *model.DevicePairingStartReq, ip string) (*model.DevicePairingStartRes, error)
+	GetDevicePairing(code string) (*model.DevicePairingInfo, error)
+	AnswerDevicePairing(userId int64, code string, approve bool, auditCtx *model.AuditContext) (*model.DevicePairingInfo, error)
+	GetDevicePairingStatus(code string, token string) (*model.DevicePairingInfo, error)
+	CompleteDevicePairing(completeReq *model.DevicePairingCompleteReq, ip string) (*model.UserViewModel, error)
 	GetToken(deviceVM *model.DeviceInfo, prevRefreshToken string, jwtUserData *util.MyJwtClaims, addProfileImages bool, ip string) (*model.UserViewModel, *util.TokenDetail, error)
 	LogOut(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, prevRefreshToken string) error
 	ForceLogoutDevice(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, refreshToken string, deviceId string) error
-	ForceLogoutAll(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, refreshToken string) error
+	ForceLogoutAll(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, refreshToken string, twoFactorCode string) error
 	SetNotifToken(jwtUserData *util.MyJwtClaims, refreshToken string, notifToken string) error
-	FollowUser(jwtUserData *util.MyJwtClaims, followId int64) error
+	FollowUser(jwtUserData *util.MyJwtClaims, followId int64) (bool, error)
 	UnFollowUser(jwtUserData *util.MyJwtClaims, followId int64) error
-	GetUserFollowers(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
-	GetUserFollowings(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
+	GetUserFollowers(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
+	GetUserFollowings(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
+	GetMutualFollowers(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error)
+	SetPrivateAccount(userId int64, privateAccount bool) error
+	GetFollowRequests(userId int64, skip int, limit int) ([]model.FollowRequestDataModel, error)
+	ApproveFollowRequest(userId int64, requesterId int64) error
+	DeclineFollowRequest(userId int64, requesterId int64) error
+	CancelFollowRequest(userId int64, followId int64) error
+	SearchUsers(viewerId int64, searchReq *model.UserSearchReq) ([]model.UserSearchDataModel, error)
 	GetUserSettings(userId int64, settingName model.SettingName) (*model.UserSettingsRes, error)
 	UpdateUserSettings(userId int64, settingName model.SettingName, settings *model.UserSettingsRes) error
 	UpdateUserFavoriteGenres(userId int64, genresArray []string) error
 	GetActiveSessions(userId int64, refreshToken string) (*model.ActiveSessionRes, error)
+	GetUserDevices(userId int64) ([]model.UserDeviceDataModel, error)
+	UpdateDeviceTrusted(userId int64, deviceKey string, trusted bool) error
+	GetLoginHistory(userId int64, skip int, limit int) ([]model.LoginHistoryDataModel, error)
 	GetUserProfile(requestParams *model.UserProfileReq) (*model.UserProfileRes, error)
 	GetUserRolePermission(requestParams *model.UserProfileReq) (*model.UserRolePermissionRes, error)
 	EditUserProfile(userId int64, editFields *model.EditProfileReq) (*model.UserDataModel, error)
-	UpdateUserPassword(userId int64, passwords *model.UpdatePasswordReq) error
+	UpdateUserPassword(userId int64, passwords *model.UpdatePasswordReq, twoFactorCode string, auditCtx *model.AuditContext) error
+	RequestEmailChange(userId int64, newEmail string, twoFactorCode string, auditCtx *model.AuditContext) error
+	ConfirmEmailChange(userId int64, token string, auditCtx *model.AuditContext) error
+	RevertEmailChange(userId int64, token string, auditCtx *model.AuditContext) error
+	SendResetPassword(userEmail string) error
+	ResetPassword(resetReq *model.ResetPasswordReq, auditCtx *model.AuditContext) error
 	SendVerifyEmail(userId int64) error
 	VerifyEmail(userId int64, token string) error
-	SendDeleteAccount(userId int64) error
-	DeleteUserAccount(userId int64, token string) error
+	SendDeleteAccount(userId int64, twoFactorCode string) error
+	DeleteUserAccount(userId int64, token string, auditCtx *model.AuditContext) error
+	SetupTwoFactor(userId int64) (*model.TwoFactorSetupRes, error)
+	ConfirmTwoFactor(userId int64, code string, auditCtx *model.AuditContext) (*model.TwoFactorRecoveryCodesRes, error)
+	DisableTwoFactor(userId int64, disableReq *model.DisableTwoFactorReq, auditCtx *model.AuditContext) error
+	RegenerateTwoFactorRecoveryCodes(userId int64, code string) (*model.TwoFactorRecoveryCodesRes, error)
 	GetProfileImagesCount(userId int64) (int64, error)
 	UploadProfileImage(userId int64, contentType string, fileSize int64, fileBuffer multipart.File) (*[]model.ProfileImageDataModel, error)
 	RemoveProfileImage(userId int64, fileName string) (*[]model.ProfileImageDataModel, error)
 }
 
 type UserService struct {
-	userRepo     repository.IUserRepository
-	rabbitmq     rabbitmq.RabbitMQ
-	cloudStorage cloudStorage.IS3Storage
-	timeout      time.Duration
+	userRepo      repository.IUserRepository
+	rabbitmq      rabbitmq.RabbitMQ
+	cloudStorage  cloudStorage.IS3Storage
+	oidcProviders map[string]*oidc.Provider
+	timeout       time.Duration
 }
 
 func NewUserService(userRepo repository.IUserRepository, rabbit rabbitmq.RabbitMQ, cloudStorage cloudStorage.IS3Storage) *UserService {
-	return &UserService{
-		userRepo:     userRepo,
-		rabbitmq:     rabbit,
-		cloudStorage: cloudStorage,
-		timeout:      time.Duration(2) * time.Second,
+	svc := &UserService{
+		userRepo:      userRepo,
+		rabbitmq:      rabbit,
+		cloudStorage:  cloudStorage,
+		oidcProviders: newOidcProviders(),
+		timeout:       time.Duration(2) * time.Second,
 	}
+
+	userSvc = svc
+	go svc.removeDeletedAccountsJob()
+
+	return svc
 }
 
+var userSvc *UserService
+
 //------------------------------------------
 //------------------------------------------
 
@@ -123,6 +171,13 @@ func (s *UserService) SignUp(registerVM *model.RegisterViewModel, ip string) (*m
 	if err != nil {
 		return nil, err
 	}
+	// first login of user, nothing to compare with
+	_ = s.recordLogin(result.UserId, &registerVM.DeviceInfo, deviceId, ipLocation)
+	addAuditLog(model.AuditSignup, &model.AuditContext{
+		ActorId:    result.UserId,
+		Ip:         ip,
+		DeviceInfo: &registerVM.DeviceInfo,
+	}, result.UserId, "")
 
 	//-----------------------------------
 	verifyEmailUrl := fmt.Sprintf("%v/v1/user/VerifyEmail/%v/%v",
@@ -159,20 +214,278 @@ func (s *UserService) SignUp(registerVM *model.RegisterViewModel, ip string) (*m
 }
 
 func (s *UserService) LoginUser(loginVM *model.LoginViewModel, ip string) (*model.UserViewModel, error) {
+	err := checkLoginIpAllowed(ip)
+	if err != nil {
+		return nil, err
+	}
+
 	searchResult, err := s.userRepo.GetUserByUsernameEmail(loginVM.Email, loginVM.Email)
 	if err != nil {
 		return nil, err
 	}
 	if searchResult == nil {
+		registerFailedLoginIp(ip)
 		return nil, nil
 	}
 
+	err = s.checkLoginAllowed(searchResult.UserId, ip, &loginVM.DeviceInfo)
+	if err != nil {
+		return nil, err
+	}
+
 	err = loginVM.CheckPassword(loginVM.Password, searchResult.Password)
+	if err != nil {
+		s.registerFailedLogin(searchResult, &loginVM.DeviceInfo, ip, "wrong password")
+		return nil, errors.New(response.UserPassNotMatch)
+	}
+
+	return s.completeFirstFactor(searchResult, &loginVM.DeviceInfo, ip)
+}
+
+// completeFirstFactor creates the session, or the two-factor challenge if account has two-factor authentication
+func (s *UserService) completeFirstFactor(userData *model.UserDataModel, deviceInfo *model.DeviceInfo, ip string) (*model.UserViewModel, error) {
+	if userData.TwoFactorEnabled {
+		err := s.checkUserSuspension(userData.UserId)
+		if err != nil {
+			return nil, err
+		}
+		challengeToken, err := createTwoFactorChallenge(userData.UserId, deviceInfo)
+		if err != nil {
+			return nil, err
+		}
+		return &model.UserViewModel{
+			UserId:            userData.UserId,
+			Username:          userData.Username,
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}, nil
+	}
+
+	return s.createLoginSession(userData, deviceInfo, ip)
+}
+
+// LoginTwoFactor is the second step of login for accounts with two-factor authentication
+func (s *UserService) LoginTwoFactor(loginReq *model.TwoFactorLoginReq, ip string) (*model.UserViewModel, error) {
+	challengeKey := util.HashToken(loginReq.ChallengeToken)
+	challenge, err := getAndRemoveTwoFactorChallengeCache(challengeKey)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, errors.New(response.InvalidToken)
+	}
+
+	err = s.checkLoginAllowed(challenge.UserId, ip, &challenge.DeviceInfo)
 	if err != nil {
 		return nil, err
 	}
 
-	roles, err := s.userRepo.GetUserRoles(searchResult.UserId)
+	twoFactorData, err := s.userRepo.GetUserTwoFactor(challenge.UserId)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkTwoFactorCode(twoFactorData, loginReq.Code)
+	if err != nil {
+		if err.Error() == response.InvalidTwoFactorCode {
+			// password is already known, count the attempt so new challenges can't be used to guess the code
+			s.registerFailedLogin(&model.UserDataModel{
+				UserId:   twoFactorData.UserId,
+				Username: twoFactorData.Username,
+				Email:    twoFactorData.Email,
+			}, &challenge.DeviceInfo, ip, "wrong two-factor code")
+			// challenge is removed on each attempt, put it back until attempts are exhausted
+			challenge.Attempts++
+			remainingTime := time.Until(time.UnixMilli(challenge.ExpiresAt))
+			if challenge.Attempts < twoFactorChallengeMaxAttempts && remainingTime > 0 {
+				_ = setTwoFactorChallengeCache(challengeKey, challenge, remainingTime)
+			}
+		}
+		return nil, err
+	}
+
+	userData := &model.UserDataModel{
+		UserId:           twoFactorData.UserId,
+		Username:         twoFactorData.Username,
+		Email:            twoFactorData.Email,
+		TwoFactorEnabled: twoFactorData.TwoFactorEnabled,
+	}
+	return s.createLoginSession(userData, &challenge.DeviceInfo, ip)
+}
+
+const (
+	magicLinkExpire      = 10 * time.Minute
+	magicLinkResendDelay = time.Minute
+)
+
+// SendMagicLink emails a single-use sign-in link, returns nil if account doesn't exist
+func (s *UserService) SendMagicLink(linkReq *model.MagicLinkReq, ip string) error {
+	err := checkLoginIpAllowed(ip)
+	if err != nil {
+		return err
+	}
+
+	searchResult, err := s.userRepo.GetUserByUsernameEmail("", linkReq.Email)
+	if err != nil {
+		return err
+	}
+	if searchResult == nil {
+		registerFailedLoginIp(ip)
+		return nil
+	}
+
+	canSend, err := setMagicLinkSentCache(searchResult.UserId, magicLinkResendDelay)
+	if err != nil || !canSend {
+		// previous email sent recently
+		return err
+	}
+
+	token, err := util.CreateRandomToken()
+	if err != nil {
+		return err
+	}
+	magicLink := model.MagicLink{
+		UserId:     searchResult.UserId,
+		DeviceInfo: linkReq.DeviceInfo,
+		ExpiresAt:  time.Now().Add(magicLinkExpire).UnixMilli(),
+	}
+	if linkReq.DeviceInfo.Fingerprint != "" {
+		magicLink.DeviceKey = getDeviceKey(&linkReq.DeviceInfo)
+	}
+	err = setMagicLinkCache(util.HashToken(token), &magicLink, magicLinkExpire)
+	if err != nil {
+		return err
+	}
+
+	//-----------------------------------
+	url := fmt.Sprintf("%v/v1/user/login/magicLink/%v", configs.GetConfigs().ServerAddress, token)
+	deepLink := ""
+	if appDeepLink := configs.GetConfigs().AppDeepLink; appDeepLink != "" {
+		deepLink = strings.TrimSuffix(appDeepLink, "/") + "/magicLink/" + token
+	}
+	queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+	queueConf.Expiration = strconv.FormatInt(magicLinkExpire.Milliseconds(), 10)
+	emailData := email.EmailQueueData{
+		Type:        email.MagicLinkLogin,
+		UserId:      searchResult.UserId,
+		RawUsername: searchResult.Username,
+		Email:       searchResult.Email,
+		Token:       "",
+		Host:        "",
+		Url:         url,
+		DeepLink:    deepLink,
+		DeviceInfo:  &linkReq.DeviceInfo,
+		IpLocation:  geoip.GetRequestLocation(ip),
+	}
+	s.rabbitmq.Publish(context.TODO(), emailData, queueConf, searchResult.UserId)
+	//-----------------------------------
+	return nil
+}
+
+// LoginMagicLink creates the session for the device that requested the link,
+// links requested with fingerprint can only be used by sending the same fingerprint
+func (s *UserService) LoginMagicLink(loginReq *model.MagicLinkLoginReq, ip string) (*model.UserViewModel, error) {
+	err := checkLoginIpAllowed(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	magicLinkKey := util.HashToken(loginReq.Token)
+	magicLink, err := getAndRemoveMagicLinkCache(magicLinkKey)
+	if err != nil {
+		return nil, err
+	}
+	if magicLink == nil {
+		return nil, errors.New(response.InvalidToken)
+	}
+	if magicLink.DeviceKey != "" && magicLink.DeviceKey != util.HashToken(loginReq.Fingerprint) {
+		// the link is removed, a leaked link can't be tried from other devices
+		return nil, errors.New(response.InvalidToken)
+	}
+
+	err = s.checkLoginAllowed(magicLink.UserId, ip, &magicLink.DeviceInfo)
+	if err != nil {
+		// keep the link usable after the lock is removed
+		if remainingTime := time.Until(time.UnixMilli(magicLink.ExpiresAt)); remainingTime > 0 {
+			_ = setMagicLinkCache(magicLinkKey, magicLink, remainingTime)
+		}
+		return nil, err
+	}
+
+	userData, err := s.userRepo.GetUserMetaData(magicLink.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if userData.UserId == 0 {
+		// account is removed after sending the link
+		return nil, errors.New(response.InvalidToken)
+	}
+
+	return s.completeFirstFactor(userData, &magicLink.DeviceInfo, ip)
+}
+
+// userNotSuspendedCacheExpire limits how often api and websocket read the suspension of active users from db
+const userNotSuspendedCacheExpire = 5 * time.Minute
+
+// GetUserSuspension is checked on each authorized request and websocket connection, it reads the db on cache miss.
+// returns nil if user is not suspended
+func GetUserSuspension(userId int64) (*model.UserSuspension, error) {
+	suspension, err := getUserSuspensionCache(userId)
+	if err == nil && suspension != nil {
+		if !suspension.IsActive() {
+			return nil, nil
+		}
+		return suspension, nil
+	}
+	if userSvc == nil {
+		return nil, err
+	}
+
+	suspension, err = userSvc.userRepo.GetUserSuspension(userId)
+	if err != nil {
+		return nil, err
+	}
+	if suspension == nil {
+		_ = setUserNotSuspendedCache(userId)
+		return nil, nil
+	}
+	_ = setUserSuspensionCache(suspension)
+	return suspension, nil
+}
+
+// checkUserSuspension reads the suspension from db on login and getToken, it restores the cache that api and websocket use
+func (s *UserService) checkUserSuspension(userId int64) error {
+	suspension, err := s.userRepo.GetUserSuspension(userId)
+	if err != nil {
+		return err
+	}
+	if suspension == nil {
+		return nil
+	}
+	_ = setUserSuspensionCache(suspension)
+	return suspension
+}
+
+func (s *UserService) createLoginSession(userData *model.UserDataModel, deviceInfo *model.DeviceInfo, ip string) (*model.UserViewModel, error) {
+	err := s.checkUserSuspension(userData.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	auditCtx := &model.AuditContext{
+		ActorId:    userData.UserId,
+		Ip:         ip,
+		DeviceInfo: deviceInfo,
+	}
+	// login in the grace period of account deletion restores the account
+	restored, err := s.userRepo.RestoreUserDeletion(userData.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if restored {
+		addAuditLog(model.AuditAccountRestore, auditCtx, userData.UserId, "")
+	}
+
+	roles, err := s.userRepo.GetUserRoles(userData.UserId)
 	if err != nil {
 		return nil, err
 	}
@@ -184,42 +497,33 @@ func (s *UserService) LoginUser(loginVM *model.LoginViewModel, ip string) (*mode
 		roleNames = append(roleNames, r.Name)
 	}
 
-	token, err := util.CreateJwtToken(searchResult.UserId, searchResult.Username, roleIds)
+	token, err := util.CreateJwtToken(userData.UserId, userData.Username, roleIds)
 	if err != nil {
 		return nil, err
 	}
 
-	deviceId := loginVM.DeviceInfo.Fingerprint
+	deviceId := deviceInfo.Fingerprint
 	if deviceId == "" {
 		deviceId = uuid.NewString() + "-" + strconv.FormatInt(time.Now().UnixMilli(), 10)
 	}
 
 	ipLocation := geoip.GetRequestLocation(ip)
-	isNewDevice, err := s.userRepo.UpdateSession(&loginVM.DeviceInfo, deviceId, searchResult.UserId, token.RefreshToken, ipLocation)
+	isNewDevice, err := s.userRepo.UpdateSession(deviceInfo, deviceId, userData.UserId, token.RefreshToken, ipLocation)
 	if err != nil {
 		return nil, err
 	}
+	clearFailedLogins(userData.UserId, ip)
+	addAuditLog(model.AuditLogin, auditCtx, userData.UserId, "")
 
-	if isNewDevice {
-		//-----------------------------------
-		queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
-		emailData := email.EmailQueueData{
-			Type:        email.UserLogin,
-			UserId:      searchResult.UserId,
-			RawUsername: searchResult.Username,
-			Email:       loginVM.Email,
-			Token:       "",
-			Host:        "",
-			Url:         "",
-			DeviceInfo:  &loginVM.DeviceInfo,
-			IpLocation:  ipLocation,
-		}
-		s.rabbitmq.Publish(context.TODO(), emailData, queueConf, searchResult.UserId)
-		//-----------------------------------
+	loginHistory := s.recordLogin(userData.UserId, deviceInfo, deviceId, ipLocation)
+	if loginHistory != nil && (loginHistory.NewDevice || loginHistory.NewLocation) {
+		s.sendNewLoginAlert(userData, deviceInfo, loginHistory)
+	}
 
-		activeSessions, err := s.userRepo.GetUserActiveSessions(searchResult.UserId)
+	if isNewDevice {
+		activeSessions, err := s.userRepo.GetUserActiveSessions(userData.UserId)
 		if err != nil {
-			err := s.userRepo.RemoveSession(searchResult.UserId, token.RefreshToken)
+			err := s.userRepo.RemoveSession(userData.UserId, token.RefreshToken)
 			return nil, err
 		}
 		if len(activeSessions) > configs.GetConfigs().ActiveSessionsLimit {
@@ -228,7 +532,7 @@ func (s *UserService) LoginUser(loginVM *model.LoginViewModel, ip string) (*mode
 			for i, session := range lastUsedSessions {
 				tokens[i] = session.RefreshToken
 			}
-			err := s.userRepo.RemoveSessions(searchResult.UserId, tokens)
+			err := s.userRepo.RemoveSessions(userData.UserId, tokens)
 			if err != nil {
 				return nil, err
 			}
@@ -236,9 +540,9 @@ func (s *UserService) LoginUser(loginVM *model.LoginViewModel, ip string) (*mode
 	}
 
 	userVM := model.UserViewModel{
-		UserId:   searchResult.UserId,
-		Username: searchResult.Username,
-		Email:    searchResult.Email,
+		UserId:   userData.UserId,
+		Username: userData.Username,
+		Email:    userData.Email,
 		Token: model.TokenViewModel{
 			AccessToken:       token.AccessToken,
 			AccessTokenExpire: token.ExpiresAt,
@@ -251,7 +555,60 @@ func (s *UserService) LoginUser(loginVM *model.LoginViewModel, ip string) (*mode
 	return &userVM, nil
 }
 
+// recordLogin saves the login in history, returns nil on error so login doesn't fail because of it
+func (s *UserService) recordLogin(userId int64, deviceInfo *model.DeviceInfo, deviceId string, ipLocation string) *model.LoginHistory {
+	loginHistory, err := s.userRepo.AddLoginHistory(userId, getDeviceKey(deviceInfo), deviceId, deviceInfo, ipLocation)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Error on saving login history: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return nil
+	}
+	return loginHistory
+}
+
+// getDeviceKey identifies the device across sessions, when the client doesn't send a fingerprint
+// the device info is used which can't tell apart two devices of the same model
+func getDeviceKey(deviceInfo *model.DeviceInfo) string {
+	if deviceInfo.Fingerprint != "" {
+		return util.HashToken(deviceInfo.Fingerprint)
+	}
+	return util.HashToken(deviceInfo.AppName + "|" + deviceInfo.Os + "|" + deviceInfo.DeviceModel)
+}
+
+func (s *UserService) sendNewLoginAlert(userData *model.UserDataModel, deviceInfo *model.DeviceInfo, loginHistory *model.LoginHistory) {
+	//-----------------------------------
+	queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+	emailData := email.EmailQueueData{
+		Type:        email.UserLogin,
+		UserId:      userData.UserId,
+		RawUsername: userData.Username,
+		Email:       userData.Email,
+		Token:       "",
+		Host:        "",
+		Url:         "",
+		DeviceInfo:  deviceInfo,
+		IpLocation:  loginHistory.IpLocation,
+	}
+	s.rabbitmq.Publish(context.TODO(), emailData, queueConf, userData.UserId)
+	//-----------------------------------
+
+	message := fmt.Sprintf("New login to your account from %v (%v)", deviceInfo.DeviceModel, deviceInfo.Os)
+	if loginHistory.NewLocation {
+		message += ", new location: " + loginHistory.IpLocation
+	} else if loginHistory.IpLocation != "" {
+		message += " in " + loginHistory.IpLocation
+	}
+	notifQueueConf := rabbitmq.NewConfigPublish(rabbitmq.NotificationExchange, rabbitmq.NotificationBindingKey)
+	notification := model.CreateSecurityNotificationAction(userData.UserId, loginHistory.Id, message)
+	s.rabbitmq.Publish(context.TODO(), notification, notifQueueConf, userData.UserId)
+}
+
 func (s *UserService) GetToken(deviceVM *model.DeviceInfo, prevRefreshToken string, jwtUserData *util.MyJwtClaims, addProfileImages bool, ip string) (*model.UserViewModel, *util.TokenDetail, error) {
+	err := s.checkUserSuspension(jwtUserData.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	roles, err := s.userRepo.GetUserRoles(jwtUserData.UserId)
 	if err != nil {
 		return nil, nil, err
@@ -275,9 +632,27 @@ func (s *UserService) GetToken(deviceVM *model.DeviceInfo, prevRefreshToken stri
 		return nil, nil, err
 	}
 	if sessionData == nil {
+		s.checkRefreshTokenReuse(jwtUserData, prevRefreshToken, ipLocation)
 		return nil, nil, nil
 	}
 
+	addAuditLog(model.AuditTokenRefresh, &model.AuditContext{
+		ActorId:    jwtUserData.UserId,
+		Ip:         ip,
+		DeviceInfo: deviceVM,
+	}, jwtUserData.UserId, "")
+
+	rotatedToken := model.RotatedRefreshToken{
+		UserId:      jwtUserData.UserId,
+		DeviceId:    sessionData.DeviceId,
+		TokenFamily: sessionData.TokenFamily,
+		RotatedAt:   time.Now().UTC(),
+	}
+	remainingTime := time.Until(time.UnixMilli(jwtUserData.ExpiresAt))
+	if remainingTime > 0 {
+		_ = setRotatedRefreshTokenCache(util.HashToken(prevRefreshToken), &rotatedToken, remainingTime)
+	}
+
 	userVM := model.UserViewModel{
 		UserId: jwtUserData.UserId,
 		Token: model.TokenViewModel{
@@ -304,7 +679,70 @@ func (s *UserService) GetToken(deviceVM *model.DeviceInfo, prevRefreshToken stri
 	return &userVM, token, nil
 }
 
+// refreshTokenReuseGracePeriod lets concurrent requests of a client refresh with the same token,
+// only the first one succeeds and the others are rejected without revoking the session
+const refreshTokenReuseGracePeriod = 10 * time.Second
+
+// checkRefreshTokenReuse revokes the token family if an already rotated refresh token is presented again
+func (s *UserService) checkRefreshTokenReuse(jwtUserData *util.MyJwtClaims, refreshToken string, ipLocation string) {
+	rotatedToken, err := getRotatedRefreshTokenCache(util.HashToken(refreshToken))
+	if err != nil || rotatedToken == nil || rotatedToken.UserId != jwtUserData.UserId {
+		return
+	}
+	if time.Since(rotatedToken.RotatedAt) < refreshTokenReuseGracePeriod {
+		return
+	}
+
+	session, err := s.userRepo.RemoveSessionByTokenFamily(rotatedToken.UserId, rotatedToken.DeviceId, rotatedToken.TokenFamily)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Error on revoking token family of reused refresh token: %v", err)
+		errorHandler.SaveError(errorMessage, err)
+		return
+	}
+	if session == nil {
+		// already logged out or logged in again
+		return
+	}
+
+	refreshTokenExpireDay := configs.GetConfigs().RefreshTokenExpireDay
+	_ = setJwtDataCache(refreshToken, "tokenReuse", time.Duration(refreshTokenExpireDay)*24*time.Hour)
+	_ = setJwtDataCache(session.RefreshToken, "tokenReuse", time.Duration(refreshTokenExpireDay)*24*time.Hour)
+	if session.NotifToken != "" {
+		_ = removeNotifTokenFromCachedUserData(session.UserId, session.NotifToken)
+	}
+	closeDeviceConnections(session.UserId, session.DeviceId, "session revoked")
+
+	searchResult, err := s.userRepo.GetUserMetaData(session.UserId)
+	if err == nil && searchResult.Email != "" {
+		//-----------------------------------
+		queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+		emailData := email.EmailQueueData{
+			Type:        email.SessionRevoked,
+			UserId:      searchResult.UserId,
+			RawUsername: searchResult.Username,
+			Email:       searchResult.Email,
+			Token:       "",
+			Host:        "",
+			Url:         "",
+			DeviceInfo: &model.DeviceInfo{
+				AppName:     session.AppName,
+				AppVersion:  session.AppVersion,
+				Os:          session.DeviceOs,
+				DeviceModel: session.DeviceModel,
+			},
+			IpLocation: ipLocation,
+		}
+		s.rabbitmq.Publish(context.TODO(), emailData, queueConf, searchResult.UserId)
+		//-----------------------------------
+	}
+}
+
 func (s *UserService) LogOut(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, prevRefreshToken string) error {
+	// read the device before the session is removed
+	auditCtx := NewAuditContext(c)
+	auditCtx.RefreshToken = prevRefreshToken
+	resolveAuditDevice(auditCtx)
+
 	err := s.userRepo.RemoveSession(jwtUserData.UserId, prevRefreshToken)
 	if err != nil {
 		return err
@@ -316,6 +754,7 @@ func (s *UserService) LogOut(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, prevRe
 		return err
 	}
 
+	addAuditLog(model.AuditLogout, auditCtx, jwtUserData.UserId, "")
 	return nil
 }
 
@@ -335,10 +774,16 @@ func (s *UserService) ForceLogoutDevice(c *fiber.Ctx, jwtUserData *util.MyJwtCla
 		_ = removeNotifTokenFromCachedUserData(jwtUserData.UserId, result.NotifToken)
 	}
 
+	addAuditLog(model.AuditForceLogout, NewAuditContext(c), jwtUserData.UserId, "device: "+deviceId)
 	return nil
 }
 
-func (s *UserService) ForceLogoutAll(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, refreshToken string) error {
+func (s *UserService) ForceLogoutAll(c *fiber.Ctx, jwtUserData *util.MyJwtClaims, refreshToken string, twoFactorCode string) error {
+	err := s.checkSecondFactor(jwtUserData.UserId, twoFactorCode)
+	if err != nil {
+		return err
+	}
+
 	result, err := s.userRepo.RemoveAllAuthSession(jwtUserData.UserId, refreshToken)
 	if err != nil {
 		return err
@@ -352,7 +797,9 @@ func (s *UserService) ForceLogoutAll(c *fiber.Ctx, jwtUserData *util.MyJwtClaims
 		}
 	}
 
-	return nil
+	auditCtx := NewAuditContext(c)
+	addAuditLog(model.AuditForceLogout, auditCtx, jwtUserData.UserId, fmt.Sprintf("all other sessions (%v)", len(result)))
+	return revokeAllApiTokens(jwtUserData.UserId, "logout of all sessions", auditCtx)
 }
 
 func (s *UserService) SetNotifToken(jwtUserData *util.MyJwtClaims, refreshToken string, notifToken string) error {
@@ -366,17 +813,45 @@ func (s *UserService) SetNotifToken(jwtUserData *util.MyJwtClaims, refreshToken
 //------------------------------------------
 //------------------------------------------
 
-func (s *UserService) FollowUser(jwtUserData *util.MyJwtClaims, followId int64) error {
-	err := s.userRepo.AddUserFollow(jwtUserData.UserId, followId)
+// FollowUser follows the user, if the account is private a follow request is sent instead and the returned flag is true
+func (s *UserService) FollowUser(jwtUserData *util.MyJwtClaims, followId int64) (bool, error) {
+	privateAccount, err := s.userRepo.IsPrivateAccount(followId)
+	if err != nil {
+		return false, err
+	}
+	if privateAccount {
+		isFollowing, err := s.userRepo.IsFollowing(jwtUserData.UserId, followId)
+		if err != nil {
+			return false, err
+		}
+		if isFollowing {
+			return false, gorm.ErrDuplicatedKey
+		}
+		err = s.userRepo.AddFollowRequest(jwtUserData.UserId, followId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return false, errors.New(response.FollowRequestAlreadySent)
+			}
+			return false, err
+		}
+		s.sendFollowNotification(jwtUserData.UserId, followId, model.NewFollowRequest)
+		return true, nil
+	}
+
+	err = s.userRepo.AddUserFollow(jwtUserData.UserId, followId)
 	if err == nil {
 		// need to save the notification, show notification in app, send push-notification to followed user
-		ctx, _ := context.WithCancel(context.Background())
-		//defer cancel()
-		readQueueConf := rabbitmq.NewConfigPublish(rabbitmq.NotificationExchange, rabbitmq.NotificationBindingKey)
-		message := model.CreateFollowNotificationAction(jwtUserData.UserId, followId)
-		s.rabbitmq.Publish(ctx, message, readQueueConf, followId)
+		s.sendFollowNotification(jwtUserData.UserId, followId, 0)
 	}
-	return err
+	return false, err
+}
+
+func (s *UserService) sendFollowNotification(userId int64, receiverId int64, subEntityTypeId model.SubEntityTypeId) {
+	ctx, _ := context.WithCancel(context.Background())
+	//defer cancel()
+	readQueueConf := rabbitmq.NewConfigPublish(rabbitmq.NotificationExchange, rabbitmq.NotificationBindingKey)
+	message := model.CreateFollowNotificationAction(userId, receiverId, subEntityTypeId)
+	s.rabbitmq.Publish(ctx, message, readQueueConf, receiverId)
 }
 
 func (s *UserService) UnFollowUser(jwtUserData *util.MyJwtClaims, followId int64) error {
@@ -384,16 +859,108 @@ func (s *UserService) UnFollowUser(jwtUserData *util.MyJwtClaims, followId int64
 	return err
 }
 
-func (s *UserService) GetUserFollowers(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
+func (s *UserService) GetUserFollowers(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
+	err := s.checkFollowListAccess(viewerId, userId)
+	if err != nil {
+		return nil, err
+	}
 	result, err := s.userRepo.GetUserFollowers(userId, skip, limit)
 	return result, err
 }
 
-func (s *UserService) GetUserFollowings(userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
+func (s *UserService) GetUserFollowings(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
+	err := s.checkFollowListAccess(viewerId, userId)
+	if err != nil {
+		return nil, err
+	}
 	result, err := s.userRepo.GetUserFollowings(userId, skip, limit)
 	return result, err
 }
 
+// GetMutualFollowers returns followings of viewer that follow the user
+func (s *UserService) GetMutualFollowers(viewerId int64, userId int64, skip int, limit int) ([]model.FollowUserDataModel, error) {
+	err := s.checkFollowListAccess(viewerId, userId)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.userRepo.GetMutualFollowers(viewerId, userId, skip, limit)
+	return result, err
+}
+
+// checkFollowListAccess returns error if userId has private account and viewer is not one of their followers
+func (s *UserService) checkFollowListAccess(viewerId int64, userId int64) error {
+	if viewerId == userId {
+		return nil
+	}
+	privateAccount, err := s.userRepo.IsPrivateAccount(userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// deleted accounts have no followers to show
+			return nil
+		}
+		return err
+	}
+	if !privateAccount {
+		return nil
+	}
+	isFollowing, err := s.userRepo.IsFollowing(viewerId, userId)
+	if err != nil {
+		return err
+	}
+	if !isFollowing {
+		return errors.New(response.PrivateAccount)
+	}
+	return nil
+}
+
+// SetPrivateAccount changes the privacy of the account, pending requests are accepted when account becomes public
+func (s *UserService) SetPrivateAccount(userId int64, privateAccount bool) error {
+	accepted, err := s.userRepo.SetPrivateAccount(userId, privateAccount)
+	if err != nil {
+		return err
+	}
+	for _, requesterId := range accepted {
+		s.sendFollowNotification(userId, requesterId, model.FollowRequestAccepted)
+	}
+	return nil
+}
+
+func (s *UserService) GetFollowRequests(userId int64, skip int, limit int) ([]model.FollowRequestDataModel, error) {
+	return s.userRepo.GetFollowRequests(userId, skip, limit)
+}
+
+func (s *UserService) ApproveFollowRequest(userId int64, requesterId int64) error {
+	err := s.userRepo.AcceptFollowRequest(requesterId, userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(response.FollowRequestNotFound)
+		}
+		return err
+	}
+	s.sendFollowNotification(userId, requesterId, model.FollowRequestAccepted)
+	return nil
+}
+
+func (s *UserService) DeclineFollowRequest(userId int64, requesterId int64) error {
+	err := s.userRepo.RemoveFollowRequest(requesterId, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New(response.FollowRequestNotFound)
+	}
+	return err
+}
+
+func (s *UserService) CancelFollowRequest(userId int64, followId int64) error {
+	err := s.userRepo.RemoveFollowRequest(userId, followId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New(response.FollowRequestNotFound)
+	}
+	return err
+}
+
+func (s *UserService) SearchUsers(viewerId int64, searchReq *model.UserSearchReq) ([]model.UserSearchDataModel, error) {
+	return s.userRepo.SearchUsers(viewerId, searchReq.Query, searchReq.Skip, searchReq.Limit)
+}
+
 //------------------------------------------
 //------------------------------------------
 
@@ -503,12 +1070,59 @@ func (s *UserService) GetActiveSessions(userId int64, refreshToken string) (*mod
 	return &result, err
 }
 
+func (s *UserService) GetUserDevices(userId int64) ([]model.UserDeviceDataModel, error) {
+	result, err := s.userRepo.GetUserDevices(userId)
+	return result, err
+}
+
+func (s *UserService) UpdateDeviceTrusted(userId int64, deviceKey string, trusted bool) error {
+	err := s.userRepo.UpdateUserDeviceTrusted(userId, deviceKey, trusted)
+	return err
+}
+
+func (s *UserService) GetLoginHistory(userId int64, skip int, limit int) ([]model.LoginHistoryDataModel, error) {
+	result, err := s.userRepo.GetLoginHistory(userId, skip, limit)
+	return result, err
+}
+
 //------------------------------------------
 //------------------------------------------
 
+const mutualFollowersPreviewLimit = 3
+
 func (s *UserService) GetUserProfile(requestParams *model.UserProfileReq) (*model.UserProfileRes, error) {
 	result, err := s.userRepo.GetUserProfile(requestParams)
-	return result, err
+	if err != nil || result == nil || requestParams.IsSelfProfile || requestParams.ViewerId == requestParams.UserId {
+		return result, err
+	}
+
+	relation, err := s.userRepo.GetFollowRelation(requestParams.ViewerId, requestParams.UserId)
+	if err != nil {
+		return nil, err
+	}
+	result.IsFollowing = relation.IsFollowing
+	result.FollowsYou = relation.FollowsYou
+	if result.PrivateAccount && !result.IsFollowing {
+		// only public data of private accounts are visible to non-followers
+		result.FollowRequested = relation.FollowRequested
+		result.Bio = ""
+		result.FavoriteGenres = nil
+		result.MbtiType = ""
+		result.LastSeenDate = time.Time{}
+		result.ComputedFavoriteGenres = nil
+		result.UserTorrent = nil
+		result.Roles = nil
+		result.RolesWithPermissions = nil
+		result.FollowersCount = 0
+		result.FollowingsCount = 0
+		return result, nil
+	}
+
+	result.MutualFollowersPreview, err = s.userRepo.GetMutualFollowers(requestParams.ViewerId, requestParams.UserId, 0, mutualFollowersPreviewLimit)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (s *UserService) GetUserRolePermission(requestParams *model.UserProfileReq) (*model.UserRolePermissionRes, error) {
@@ -534,17 +1148,17 @@ func (s *UserService) GetUserRolePermission(requestParams *model.UserProfileReq)
 	return res, nil
 }
 
+// EditUserProfile doesn't change the email, it's changed with RequestEmailChange and needs confirmation from both addresses
 func (s *UserService) EditUserProfile(userId int64, editFields *model.EditProfileReq) (*model.UserDataModel, error) {
-	searchResult, err := s.userRepo.GetUserByUsernameEmailAndUserId(userId, editFields.Username, editFields.Email)
+	searchResult, err := s.userRepo.GetUserByUsernameEmailAndUserId(userId, strings.ToLower(editFields.Username), "")
 	if err != nil {
 		return nil, err
 	}
 	if searchResult != nil {
-		// email or username already taken by another user
+		// username already taken by another user
 		return &model.UserDataModel{
 			UserId:   0,
 			Username: searchResult.Username,
-			Email:    searchResult.Email,
 		}, nil
 	} else {
 		searchResult, err = s.userRepo.GetUserMetaData(userId)
@@ -556,7 +1170,6 @@ func (s *UserService) EditUserProfile(userId int64, editFields *model.EditProfil
 	updateFields := map[string]interface{}{
 		"username":   editFields.Username,
 		"publicName": editFields.PublicName,
-		"email":      editFields.Email,
 		"bio":        editFields.Bio,
 		"mbtiType":   editFields.MbtiType,
 	}
@@ -565,12 +1178,6 @@ func (s *UserService) EditUserProfile(userId int64, editFields *model.EditProfil
 		updateFields["rawUsername"] = editFields.Username
 		editFields.Username = strings.ToLower(editFields.Username)
 	}
-	if searchResult.Email != editFields.Email {
-		updateFields["email"] = editFields.Email
-		updateFields["emailVerified"] = false
-		updateFields["emailVerifyToken"] = ""
-		updateFields["emailVerifyToken_expire"] = 0
-	}
 
 	err = s.userRepo.EditUserProfile(userId, editFields, updateFields)
 
@@ -585,11 +1192,17 @@ func (s *UserService) EditUserProfile(userId int64, editFields *model.EditProfil
 	return searchResult, err
 }
 
-func (s *UserService) UpdateUserPassword(userId int64, passwords *model.UpdatePasswordReq) error {
+func (s *UserService) UpdateUserPassword(userId int64, passwords *model.UpdatePasswordReq, twoFactorCode string, auditCtx *model.AuditContext) error {
 	searchResult, err := s.userRepo.GetUserMetaData(userId)
 	if err != nil {
 		return err
 	}
+	if searchResult.TwoFactorEnabled {
+		err = s.checkSecondFactor(userId, twoFactorCode)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = util.CheckPassword(passwords.NewPassword, searchResult.Password)
 	if err != nil {
@@ -603,6 +1216,10 @@ func (s *UserService) UpdateUserPassword(userId int64, passwords *model.UpdatePa
 	passwords.NewPassword = hashedNewPassword
 
 	err = s.userRepo.UpdateUserPassword(userId, passwords)
+	if err == nil {
+		addAuditLog(model.AuditPasswordChange, auditCtx, userId, "")
+		_ = revokeAllApiTokens(userId, "password change", auditCtx)
+	}
 
 	if err == nil && searchResult.Email != "" {
 		// send email
@@ -626,6 +1243,98 @@ func (s *UserService) UpdateUserPassword(userId int64, passwords *model.UpdatePa
 	return err
 }
 
+const (
+	resetPasswordTokenExpire = 30 * time.Minute
+	resetPasswordResendDelay = 2 * time.Minute
+)
+
+// SendResetPassword emails a single-use reset token to the user,
+// returns nil if account doesn't exist, the caller must not be able to tell the difference
+func (s *UserService) SendResetPassword(userEmail string) error {
+	searchResult, err := s.userRepo.GetUserByUsernameEmail("", userEmail)
+	if err != nil {
+		return err
+	}
+	if searchResult == nil {
+		return nil
+	}
+
+	resetToken, err := util.CreateRandomToken()
+	if err != nil {
+		return err
+	}
+	resetTokenExpire := time.Now().Add(resetPasswordTokenExpire).UnixMilli()
+	lastTokenExpireBefore := time.Now().Add(resetPasswordTokenExpire - resetPasswordResendDelay).UnixMilli()
+
+	saved, err := s.userRepo.SaveResetPasswordToken(searchResult.UserId, util.HashToken(resetToken), resetTokenExpire, lastTokenExpireBefore)
+	if err != nil || !saved {
+		// previous email sent recently
+		return err
+	}
+
+	//-----------------------------------
+	queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+	queueConf.Expiration = strconv.FormatInt(resetPasswordTokenExpire.Milliseconds(), 10)
+	emailData := email.EmailQueueData{
+		Type:        email.ResetPassword,
+		UserId:      searchResult.UserId,
+		RawUsername: searchResult.Username,
+		Email:       searchResult.Email,
+		Token:       resetToken,
+		Host:        "",
+		Url:         "",
+		DeviceInfo:  nil,
+		IpLocation:  "",
+	}
+	s.rabbitmq.Publish(context.TODO(), emailData, queueConf, searchResult.UserId)
+	//-----------------------------------
+	return nil
+}
+
+func (s *UserService) ResetPassword(resetReq *model.ResetPasswordReq, auditCtx *model.AuditContext) error {
+	hashedPassword, err := util.HashPassword(resetReq.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := s.userRepo.ResetPasswordWithToken(resetReq.UserId, util.HashToken(resetReq.Token), hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	refreshTokenExpireDay := configs.GetConfigs().RefreshTokenExpireDay
+	for i := range sessions {
+		_ = setJwtDataCache(sessions[i].RefreshToken, "resetPassword", time.Duration(refreshTokenExpireDay)*24*time.Hour)
+		if sessions[i].NotifToken != "" {
+			_ = removeNotifTokenFromCachedUserData(resetReq.UserId, sessions[i].NotifToken)
+		}
+	}
+	addAuditLog(model.AuditPasswordReset, auditCtx, resetReq.UserId, fmt.Sprintf("sessions removed: %v", len(sessions)))
+	_ = revokeAllApiTokens(resetReq.UserId, "password reset", auditCtx)
+	closeDeviceConnections(resetReq.UserId, "", "password is reset")
+
+	searchResult, err := s.userRepo.GetUserMetaData(resetReq.UserId)
+	if err == nil && searchResult.Email != "" {
+		//-----------------------------------
+		queueConf := rabbitmq.NewConfigPublish(rabbitmq.EmailExchange, rabbitmq.EmailBindingKey)
+		emailData := email.EmailQueueData{
+			Type:        email.PasswordUpdated,
+			UserId:      searchResult.UserId,
+			RawUsername: searchResult.Username,
+			Email:       searchResult.Email,
+			Token:       "",
+			Host:        "",
+			Url:         "",
+			DeviceInfo:  nil,
+			IpLocation:  "",
+		}
+		s.rabbitmq.Publish(context.TODO(), emailData, queueConf, searchResult.UserId)
+		//-----------------------------------
+	}
+
+	return nil
+}
+
 //------------------------------------------
 //------------------------------------------
 
@@ -673,11 +1382,17 @@ func (s *UserService) VerifyEmail(userId int64, token string) error {
 	return err
 }
 
-func (s *UserService) SendDeleteAccount(userId int64) error {
+func (s *UserService) SendDeleteAccount(userId int64, twoFactorCode string) error {
 	searchResult, err := s.userRepo.GetUserMetaData(userId)
 	if err != nil {
 		return err
 	}
+	if searchResult.TwoFactorEnabled {
+		err = s.checkSecondFactor(userId, twoFactorCode)
+		if err != nil {
+			return err
+		}
+	}
 
 	hashToken, err := util.HashPassword(uuid.NewString())
 	if err != nil {
@@ -712,7 +1427,7 @@ func (s *UserService) SendDeleteAccount(userId int64) error {
 	return err
 }
 
-func (s *UserService) DeleteUserAccount(userId int64, token string) error {
+func (s *UserService) DeleteUserAccount(userId int64, token string, auditCtx *model.AuditContext) error {
 	err := s.userRepo.VerifyDeleteAccountToken(userId, token)
 	if err != nil {
 		return err
@@ -728,6 +1443,68 @@ func (s *UserService) DeleteUserAccount(userId int64, token string) error {
 		}
 	}
 
+	graceDays := configs.GetConfigs().AccountDeletionGraceDays
+	deletionDate := time.Now().Add(time.Duration(graceDays) * 24 * time.Hour)
+	activeSessions, err := s.userRepo.ScheduleUserDeletion(userId, deletionDate.UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	accessTokenExpireHour := configs.GetConfigs().AccessTokenExpireHour
+	for i := range activeSessions {
+		_ = setJwtDataCache(activeSessions[i].RefreshToken, "deleteAccount", time.Duration(accessTokenExpireHour)*time.Hour)
+		if activeSessions[i].NotifToken != "" {
+			_ = removeNotifTokenFromCachedUserData(userId, activeSessions[i].NotifToken)
+		}
+	}
+	closeDeviceConnections(userId, "", "account is deleted")
+	addAuditLog(model.AuditAccountDelete, auditCtx, userId, "removal at "+deletionDate.UTC().Format(time.RFC3339))
+
+	return nil
+}
+
+//------------------------------------------
+//------------------------------------------
+
+const (
+	deletedAccountsCheckInterval = time.Hour
+	deletedAccountsBatchSize     = 100
+	// deletedAccountsClaimExpire is the time that other servers wait before retrying a claimed account
+	deletedAccountsClaimExpire = time.Hour
+)
+
+// removeDeletedAccountsJob removes the data of accounts that their grace period is ended,
+// accounts are claimed in batches so each one is removed by one server
+func (s *UserService) removeDeletedAccountsJob() {
+	ticker := time.NewTicker(deletedAccountsCheckInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			now := time.Now()
+			claimUntil := now.Add(deletedAccountsClaimExpire).UnixMilli()
+			userIds, err := s.userRepo.ClaimUsersToDelete(now.UnixMilli(), claimUntil, deletedAccountsBatchSize)
+			if err != nil {
+				errorMessage := fmt.Sprintf("error on getting deleted accounts: %v", err)
+				errorHandler.SaveError(errorMessage, err)
+				break
+			}
+			for _, userId := range userIds {
+				err = s.removeUserAccount(userId)
+				if err != nil {
+					errorMessage := fmt.Sprintf("error on removing deleted account (%v): %v", userId, err)
+					errorHandler.SaveError(errorMessage, err)
+				}
+			}
+			if len(userIds) < deletedAccountsBatchSize {
+				break
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// removeUserAccount removes the account and all the related data, including the profile images in storage
+func (s *UserService) removeUserAccount(userId int64) error {
 	profileImages, err := s.userRepo.RemoveAllProfileImageData(userId)
 	if err != nil {
 		return err
@@ -738,24 +1515,243 @@ func (s *UserService) DeleteUserAccount(userId int64, token string) error {
 		_ = s.cloudStorage.RemoveFile(cloudStorage.ProfileImageBucketName, filename)
 	}
 
-	activeSessions, err := s.userRepo.GetActiveSessions(userId)
+	err = s.userRepo.DeleteUserAndRelatedData(userId)
 	if err != nil {
 		return err
 	}
+	addAuditLog(model.AuditAccountRemove, nil, userId, "")
+	return nil
+}
 
-	err = s.userRepo.DeleteUserAndRelatedData(userId)
+//------------------------------------------
+//------------------------------------------
+
+const (
+	twoFactorIssuer               = "Downloader"
+	twoFactorChallengeExpire      = 5 * time.Minute
+	twoFactorChallengeMaxAttempts = 5
+	twoFactorRecoveryCodesCount   = 10
+)
+
+// SetupTwoFactor starts the enrollment, two-factor is not enabled until ConfirmTwoFactor gets a valid code
+func (s *UserService) SetupTwoFactor(userId int64) (*model.TwoFactorSetupRes, error) {
+	twoFactorData, err := s.userRepo.GetUserTwoFactor(userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactorData.TwoFactorEnabled {
+		return nil, errors.New(response.TwoFactorAlreadyEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = s.userRepo.SaveTwoFactorSecret(userId, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	accountName := twoFactorData.Email
+	if accountName == "" {
+		accountName = twoFactorData.Username
+	}
+	result := model.TwoFactorSetupRes{
+		Secret:          secret,
+		ProvisioningUri: totp.ProvisioningUri(twoFactorIssuer, accountName, secret),
+	}
+	return &result, nil
+}
+
+func (s *UserService) ConfirmTwoFactor(userId int64, code string, auditCtx *model.AuditContext) (*model.TwoFactorRecoveryCodesRes, error) {
+	twoFactorData, err := s.userRepo.GetUserTwoFactor(userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactorData.TwoFactorEnabled {
+		return nil, errors.New(response.TwoFactorAlreadyEnabled)
+	}
+	if twoFactorData.TwoFactorSecret == "" {
+		// setup is not started
+		return nil, errors.New(response.TwoFactorNotEnabled)
+	}
+
+	step := totp.Validate(code, twoFactorData.TwoFactorSecret, time.Now())
+	if step < 0 {
+		return nil, errors.New(response.InvalidTwoFactorCode)
+	}
+
+	codes, codeHashes, err := createTwoFactorRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.userRepo.EnableTwoFactor(userId, step, codeHashes)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(response.InvalidTwoFactorCode)
+		}
+		return nil, err
+	}
+
+	addAuditLog(model.AuditTwoFactorEnable, auditCtx, userId, "")
+	return &model.TwoFactorRecoveryCodesRes{RecoveryCodes: codes}, nil
+}
+
+func (s *UserService) DisableTwoFactor(userId int64, disableReq *model.DisableTwoFactorReq, auditCtx *model.AuditContext) error {
+	twoFactorData, err := s.userRepo.GetUserTwoFactor(userId)
 	if err != nil {
 		return err
 	}
+	if !twoFactorData.TwoFactorEnabled {
+		return errors.New(response.TwoFactorNotEnabled)
+	}
 
-	accessTokenExpireHour := configs.GetConfigs().AccessTokenExpireHour
-	for i := range activeSessions {
-		_ = setJwtDataCache(activeSessions[i].RefreshToken, "deleteAccount", time.Duration(accessTokenExpireHour)*time.Hour)
+	err = util.CheckPassword(disableReq.Password, twoFactorData.Password)
+	if err != nil {
+		return errors.New(response.PassNotMatch)
+	}
+	err = s.checkTwoFactorCode(twoFactorData, disableReq.Code)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.DisableTwoFactor(userId)
+	if err != nil {
+		return err
 	}
 
+	addAuditLog(model.AuditTwoFactorDisable, auditCtx, userId, "")
 	return nil
 }
 
+func (s *UserService) RegenerateTwoFactorRecoveryCodes(userId int64, code string) (*model.TwoFactorRecoveryCodesRes, error) {
+	twoFactorData, err := s.userRepo.GetUserTwoFactor(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !twoFactorData.TwoFactorEnabled {
+		return nil, errors.New(response.TwoFactorNotEnabled)
+	}
+	err = s.checkTwoFactorCode(twoFactorData, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, codeHashes, err := createTwoFactorRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.userRepo.ReplaceTwoFactorRecoveryCodes(userId, codeHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorRecoveryCodesRes{RecoveryCodes: codes}, nil
+}
+
+// checkSecondFactor is used by sensitive actions, does nothing when user has not enabled two-factor
+func (s *UserService) checkSecondFactor(userId int64, code string) error {
+	twoFactorData, err := s.userRepo.GetUserTwoFactor(userId)
+	if err != nil {
+		return err
+	}
+	if !twoFactorData.TwoFactorEnabled {
+		return nil
+	}
+
+	code = model.NormalizeTwoFactorCode(code)
+	if code == "" {
+		return errors.New(response.TwoFactorCodeRequired)
+	}
+	return s.checkTwoFactorCode(twoFactorData, code)
+}
+
+// checkPasswordOrSecondFactor re-authenticates the user, a two-factor code is needed when two-factor is enabled,
+// otherwise the password
+func (s *UserService) checkPasswordOrSecondFactor(userId int64, password string, code string) error {
+	twoFactorData, err := s.userRepo.GetUserTwoFactor(userId)
+	if err != nil {
+		return err
+	}
+	if twoFactorData.TwoFactorEnabled {
+		code = model.NormalizeTwoFactorCode(code)
+		if code == "" {
+			return errors.New(response.TwoFactorCodeRequired)
+		}
+		return s.checkTwoFactorCode(twoFactorData, code)
+	}
+
+	err = util.CheckPassword(password, twoFactorData.Password)
+	if err != nil {
+		return errors.New(response.PassNotMatch)
+	}
+	return nil
+}
+
+// checkTwoFactorCode accepts a totp code that is not used before or an unused recovery code
+func (s *UserService) checkTwoFactorCode(twoFactorData *model.UserTwoFactorDataModel, code string) error {
+	if len(code) == totp.Digits {
+		step := totp.Validate(code, twoFactorData.TwoFactorSecret, time.Now())
+		if step < 0 {
+			return errors.New(response.InvalidTwoFactorCode)
+		}
+		updated, err := s.userRepo.UpdateTwoFactorLastStep(twoFactorData.UserId, step)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// code replay
+			return errors.New(response.InvalidTwoFactorCode)
+		}
+		return nil
+	}
+
+	used, err := s.userRepo.UseTwoFactorRecoveryCode(twoFactorData.UserId, util.HashToken(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New(response.InvalidTwoFactorCode)
+	}
+	return nil
+}
+
+func createTwoFactorChallenge(userId int64, deviceInfo *model.DeviceInfo) (string, error) {
+	challengeToken, err := util.CreateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := model.TwoFactorChallenge{
+		UserId:     userId,
+		DeviceInfo: *deviceInfo,
+		Attempts:   0,
+		ExpiresAt:  time.Now().Add(twoFactorChallengeExpire).UnixMilli(),
+	}
+	err = setTwoFactorChallengeCache(util.HashToken(challengeToken), &challenge, twoFactorChallengeExpire)
+	if err != nil {
+		return "", err
+	}
+
+	return challengeToken, nil
+}
+
+// createTwoFactorRecoveryCodes returns the codes to show to user and their hashes to save
+func createTwoFactorRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, twoFactorRecoveryCodesCount)
+	codeHashes := make([]string, twoFactorRecoveryCodesCount)
+	for i := range codes {
+		randomBytes := make([]byte, 5)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(randomBytes)
+		codes[i] = code[:5] + "-" + code[5:]
+		codeHashes[i] = util.HashToken(code)
+	}
+	return codes, codeHashes, nil
+}
+
 //------------------------------------------
 //------------------------------------------
 
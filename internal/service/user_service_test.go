@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"downloader_gochat/configs"
+	"downloader_gochat/db/redis"
+	"downloader_gochat/db/redis/mockredis"
+	"downloader_gochat/internal/repository"
+	"downloader_gochat/model"
+	"downloader_gochat/pkg/email"
+	"downloader_gochat/pkg/response"
+	"downloader_gochat/pkg/totp"
+	"downloader_gochat/rabbitmq"
+	"downloader_gochat/util"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTwoFactorRepo keeps the two-factor state of one user in memory, other methods of IUserRepository are not implemented
+type fakeTwoFactorRepo struct {
+	repository.IUserRepository
+	data          *model.UserTwoFactorDataModel
+	recoveryCodes map[string]bool // hash: used
+}
+
+func (r *fakeTwoFactorRepo) GetUserTwoFactor(userId int64) (*model.UserTwoFactorDataModel, error) {
+	result := *r.data
+	return &result, nil
+}
+
+func (r *fakeTwoFactorRepo) UpdateTwoFactorLastStep(userId int64, step int64) (bool, error) {
+	if step <= r.data.TwoFactorLastStep {
+		return false, nil
+	}
+	r.data.TwoFactorLastStep = step
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepo) UseTwoFactorRecoveryCode(userId int64, codeHash string) (bool, error) {
+	used, ok := r.recoveryCodes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recoveryCodes[codeHash] = true
+	return true, nil
+}
+
+func newTwoFactorTestService(t *testing.T, enabled bool) (*UserService, *fakeTwoFactorRepo, []string) {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	password, err := util.HashPassword("old-password")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	codes, codeHashes, err := createTwoFactorRecoveryCodes()
+	if err != nil {
+		t.Fatalf("createTwoFactorRecoveryCodes: %v", err)
+	}
+
+	repo := &fakeTwoFactorRepo{
+		data: &model.UserTwoFactorDataModel{
+			UserId:           1,
+			Password:         password,
+			TwoFactorEnabled: enabled,
+			TwoFactorSecret:  secret,
+		},
+		recoveryCodes: make(map[string]bool),
+	}
+	for _, h := range codeHashes {
+		repo.recoveryCodes[h] = false
+	}
+	return &UserService{userRepo: repo}, repo, codes
+}
+
+// currentTotpCode is what an authenticator app shows now
+func currentTotpCode(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("invalid secret: %v", err)
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(time.Now().Unix()/totp.Period))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+func expectError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil || err.Error() != want {
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func TestCreateTwoFactorRecoveryCodes(t *testing.T) {
+	codes, codeHashes, err := createTwoFactorRecoveryCodes()
+	if err != nil {
+		t.Fatalf("createTwoFactorRecoveryCodes: %v", err)
+	}
+	if len(codes) != twoFactorRecoveryCodesCount || len(codeHashes) != twoFactorRecoveryCodesCount {
+		t.Fatalf("got %v codes and %v hashes, want %v", len(codes), len(codeHashes), twoFactorRecoveryCodesCount)
+	}
+
+	format := regexp.MustCompile(`^[0-9a-f]{5}-[0-9a-f]{5}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q doesn't match xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+		// hash is saved for the code that user enters, after normalization
+		if codeHashes[i] != util.HashToken(model.NormalizeTwoFactorCode(code)) {
+			t.Errorf("hash of code %q doesn't match its normalized form", code)
+		}
+	}
+}
+
+func TestCheckTwoFactorCode_Totp(t *testing.T) {
+	s, repo, _ := newTwoFactorTestService(t, true)
+	code := currentTotpCode(t, repo.data.TwoFactorSecret)
+
+	if err := s.checkTwoFactorCode(repo.data, code); err != nil {
+		t.Fatalf("valid code rejected: %v", err)
+	}
+	if repo.data.TwoFactorLastStep == 0 {
+		t.Error("last used step is not saved")
+	}
+	// same code again
+	expectError(t, s.checkTwoFactorCode(repo.data, code), response.InvalidTwoFactorCode)
+
+	wrong := "000000"
+	if wrong == code {
+		wrong = "000001"
+	}
+	expectError(t, s.checkTwoFactorCode(repo.data, wrong), response.InvalidTwoFactorCode)
+}
+
+func TestCheckTwoFactorCode_RecoveryCode(t *testing.T) {
+	s, repo, codes := newTwoFactorTestService(t, true)
+	code := model.NormalizeTwoFactorCode(strings.ToUpper(codes[0]))
+
+	if err := s.checkTwoFactorCode(repo.data, code); err != nil {
+		t.Fatalf("valid recovery code rejected: %v", err)
+	}
+	// recovery codes are single-use
+	expectError(t, s.checkTwoFactorCode(repo.data, code), response.InvalidTwoFactorCode)
+	// other codes still work
+	if err := s.checkTwoFactorCode(repo.data, model.NormalizeTwoFactorCode(codes[1])); err != nil {
+		t.Fatalf("second recovery code rejected: %v", err)
+	}
+	expectError(t, s.checkTwoFactorCode(repo.data, "0123456789"), response.InvalidTwoFactorCode)
+}
+
+func TestCheckSecondFactor(t *testing.T) {
+	s, _, _ := newTwoFactorTestService(t, false)
+	if err := s.checkSecondFactor(1, ""); err != nil {
+		t.Errorf("code must not be needed when two-factor is disabled: %v", err)
+	}
+
+	s, repo, codes := newTwoFactorTestService(t, true)
+	expectError(t, s.checkSecondFactor(1, ""), response.TwoFactorCodeRequired)
+	expectError(t, s.checkSecondFactor(1, " - "), response.TwoFactorCodeRequired)
+	if err := s.checkSecondFactor(1, " "+strings.ToUpper(codes[2])+" "); err != nil {
+		t.Errorf("recovery code with separators rejected: %v", err)
+	}
+	if err := s.checkSecondFactor(1, currentTotpCode(t, repo.data.TwoFactorSecret)); err != nil {
+		t.Errorf("totp code rejected: %v", err)
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// startMockRedis points db/redis to a new in-memory server for the test
+func startMockRedis(t *testing.T) *mockredis.Server {
+	t.Helper()
+	server, err := mockredis.NewServer()
+	if err != nil {
+		t.Fatalf("could not start mock redis: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+	t.Setenv("REDIS_URL", server.Addr())
+	t.Setenv("REFRESH_TOKEN_EXPIRE_DAY", "30")
+	configs.LoadEnvVariables()
+	redis.ConnectRedis()
+	if globalHub == nil {
+		globalHub = NewHub()
+	}
+	return server
+}
+
+// fakeRabbit records the published messages
+type fakeRabbit struct {
+	rabbitmq.RabbitMQ
+	lock      sync.Mutex
+	published []interface{}
+}
+
+func (r *fakeRabbit) Publish(ctx context.Context, myStruct interface{}, config rabbitmq.ConfigPublish, id int64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.published = append(r.published, myStruct)
+	return nil
+}
+
+// fakeSessionRepo has one session that can be removed by its token family
+type fakeSessionRepo struct {
+	repository.IUserRepository
+	session         *model.ActiveSession
+	removedFamilies []string
+}
+
+func (r *fakeSessionRepo) RemoveSessionByTokenFamily(userId int64, deviceId string, tokenFamily string) (*model.ActiveSession, error) {
+	r.removedFamilies = append(r.removedFamilies, tokenFamily)
+	if r.session == nil || r.session.UserId != userId || r.session.DeviceId != deviceId || r.session.TokenFamily != tokenFamily {
+		return nil, nil
+	}
+	session := r.session
+	r.session = nil
+	return session, nil
+}
+
+func (r *fakeSessionRepo) GetUserMetaData(id int64) (*model.UserDataModel, error) {
+	return &model.UserDataModel{UserId: id, Username: "user", Email: "user@example.com"}, nil
+}
+
+func newRefreshTokenTestService(t *testing.T) (*UserService, *fakeSessionRepo, *fakeRabbit, *mockredis.Server) {
+	t.Helper()
+	server := startMockRedis(t)
+	repo := &fakeSessionRepo{
+		session: &model.ActiveSession{
+			UserId:       1,
+			DeviceId:     "device-1",
+			RefreshToken: "current-refresh-token",
+			TokenFamily:  "family-1",
+		},
+	}
+	rabbit := &fakeRabbit{}
+	return &UserService{userRepo: repo, rabbitmq: rabbit}, repo, rabbit, server
+}
+
+// rotateRefreshToken saves the token as rotated, like GetToken does after a successful refresh
+func rotateRefreshToken(t *testing.T, token string, userId int64, family string, rotatedAt time.Time) {
+	t.Helper()
+	rotatedToken := model.RotatedRefreshToken{
+		UserId:      userId,
+		DeviceId:    "device-1",
+		TokenFamily: family,
+		RotatedAt:   rotatedAt,
+	}
+	if err := setRotatedRefreshTokenCache(util.HashToken(token), &rotatedToken, time.Hour); err != nil {
+		t.Fatalf("could not save rotated token: %v", err)
+	}
+}
+
+func TestCheckRefreshTokenReuse_RevokesFamily(t *testing.T) {
+	s, repo, rabbit, server := newRefreshTokenTestService(t)
+	rotateRefreshToken(t, "old-refresh-token", 1, "family-1", time.Now().Add(-time.Minute))
+
+	s.checkRefreshTokenReuse(&util.MyJwtClaims{UserId: 1}, "old-refresh-token", "")
+
+	if len(repo.removedFamilies) != 1 || repo.removedFamilies[0] != "family-1" {
+		t.Fatalf("removed families = %v, want [family-1]", repo.removedFamilies)
+	}
+	if repo.session != nil {
+		t.Error("session of the family is not removed")
+	}
+	// both the reused token and the current token of the family are rejected
+	for _, token := range []string{"old-refresh-token", "current-refresh-token"} {
+		if value, _ := server.Get(jwtDataCachePrefix + token); value != "tokenReuse" {
+			t.Errorf("token %v is not blacklisted, cache value = %q", token, value)
+		}
+	}
+
+	if len(rabbit.published) != 1 {
+		t.Fatalf("published %v messages, want 1 email", len(rabbit.published))
+	}
+	emailData, ok := rabbit.published[0].(email.EmailQueueData)
+	if !ok || emailData.Type != email.SessionRevoked || emailData.UserId != 1 {
+		data, _ := json.Marshal(rabbit.published[0])
+		t.Errorf("published %s, want session revoked email", data)
+	}
+}
+
+func TestCheckRefreshTokenReuse_Ignored(t *testing.T) {
+	tests := []struct {
+		name      string
+		userId    int64
+		rotatedAt time.Time
+		save      bool
+	}{
+		// concurrent requests of the client refreshed with the same token
+		{"grace period", 1, time.Now(), true},
+		{"token of another user", 2, time.Now().Add(-time.Minute), true},
+		{"not rotated", 1, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, rabbit, server := newRefreshTokenTestService(t)
+			if tt.save {
+				rotateRefreshToken(t, "old-refresh-token", tt.userId, "family-1", tt.rotatedAt)
+			}
+
+			s.checkRefreshTokenReuse(&util.MyJwtClaims{UserId: 1}, "old-refresh-token", "")
+
+			if len(repo.removedFamilies) != 0 || repo.session == nil {
+				t.Errorf("session is revoked")
+			}
+			if _, ok := server.Get(jwtDataCachePrefix + "current-refresh-token"); ok {
+				t.Errorf("current token is blacklisted")
+			}
+			if len(rabbit.published) != 0 {
+				t.Errorf("published %v messages, want none", len(rabbit.published))
+			}
+		})
+	}
+}
+
+func TestCheckRefreshTokenReuse_SessionAlreadyRemoved(t *testing.T) {
+	s, repo, rabbit, server := newRefreshTokenTestService(t)
+	// logged in again, the family of the rotated token doesn't exist anymore
+	rotateRefreshToken(t, "old-refresh-token", 1, "family-0", time.Now().Add(-time.Minute))
+
+	s.checkRefreshTokenReuse(&util.MyJwtClaims{UserId: 1}, "old-refresh-token", "")
+
+	if repo.session == nil {
+		t.Error("session of another family is removed")
+	}
+	if _, ok := server.Get(jwtDataCachePrefix + "current-refresh-token"); ok {
+		t.Error("token of another family is blacklisted")
+	}
+	if len(rabbit.published) != 0 {
+		t.Errorf("published %v messages, want none", len(rabbit.published))
+	}
+}
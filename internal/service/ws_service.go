@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"downloader_gochat/cloudStorage"
 	"downloader_gochat/internal/repository"
 	"downloader_gochat/model"
 	errorHandler "downloader_gochat/pkg/error"
+	"downloader_gochat/pkg/response"
 	"downloader_gochat/rabbitmq"
 	"encoding/json"
 	"errors"
@@ -24,17 +26,19 @@ import (
 )
 
 type IWsService interface {
-	AddClient(ctx *fasthttp.RequestCtx, userId int64, username string, deviceId string) error
+	AddClient(ctx *fasthttp.RequestCtx, userId int64, username string, deviceId string, ip string) error
 	GetSingleChatMessages(params *model.GetSingleMessagesReq) (*[]model.MessageDataModel, error)
 	GetSingleChatList(params *model.GetSingleChatListReq) (*[]model.ChatsCompressedDataModel, error)
+	SendSingleChatMessage(userId int64, username string, newMessage *model.NewMessage) error
 }
 
 type WsService struct {
-	wsRepo   repository.IWsRepository
-	userRep  repository.IUserRepository
-	rabbitmq rabbitmq.RabbitMQ
-	timeout  time.Duration
-	hub      *Hub
+	wsRepo       repository.IWsRepository
+	userRep      repository.IUserRepository
+	rabbitmq     rabbitmq.RabbitMQ
+	cloudStorage cloudStorage.IS3Storage
+	timeout      time.Duration
+	hub          *Hub
 }
 
 const (
@@ -44,14 +48,16 @@ const (
 )
 
 var globalHub *Hub
+var globalWsSvc *WsService
 
-func NewWsService(WsRepo repository.IWsRepository, userRep repository.IUserRepository, rabbit rabbitmq.RabbitMQ) *WsService {
+func NewWsService(WsRepo repository.IWsRepository, userRep repository.IUserRepository, rabbit rabbitmq.RabbitMQ, cloudStorage cloudStorage.IS3Storage) *WsService {
 	wsSvc := WsService{
-		wsRepo:   WsRepo,
-		userRep:  userRep,
-		rabbitmq: rabbit,
-		timeout:  time.Duration(2) * time.Second,
-		hub:      NewHub(),
+		wsRepo:       WsRepo,
+		userRep:      userRep,
+		rabbitmq:     rabbit,
+		cloudStorage: cloudStorage,
+		timeout:      time.Duration(2) * time.Second,
+		hub:          NewHub(),
 	}
 	globalHub = wsSvc.hub
 
@@ -101,6 +107,18 @@ func NewWsService(WsRepo repository.IWsRepository, userRep repository.IUserRepos
 		}()
 	}
 
+	wsConnectionConfig := rabbitmq.NewConfigConsume(rabbitmq.WsConnectionQueue, "")
+	go func() {
+		openConChan := make(chan struct{})
+		rabbitmq.NotifySetupDone(openConChan)
+		<-openConChan
+		if err := rabbit.Consume(context.Background(), wsConnectionConfig, &wsSvc, WsConnectionConsumer); err != nil {
+			errorMessage := fmt.Sprintf("error consuming from queue %s: %s", rabbitmq.WsConnectionQueue, err)
+			errorHandler.SaveError(errorMessage, err)
+		}
+	}()
+
+	globalWsSvc = &wsSvc
 	return &wsSvc
 }
 
@@ -119,6 +137,7 @@ type Client struct {
 type ClientConnection struct {
 	Conn     *websocket.Conn
 	DeviceId string
+	Ip       string
 }
 
 func getClientFromHub(userId int64) (*Client, bool) {
@@ -128,6 +147,61 @@ func getClientFromHub(userId int64) (*Client, bool) {
 	return cl, ok
 }
 
+// closeDeviceConnections closes websocket connections of the device on all the instances.
+// empty deviceId closes all the connections of user
+func closeDeviceConnections(userId int64, deviceId string, reason string) {
+	if globalWsSvc != nil {
+		message := &model.CloseConnectionsMessage{
+			UserId:   userId,
+			DeviceId: deviceId,
+			Reason:   reason,
+		}
+		conf := rabbitmq.NewConfigPublish(rabbitmq.WsConnectionExchange, "")
+		err := globalWsSvc.rabbitmq.Publish(context.TODO(), message, conf, userId)
+		if err == nil {
+			return
+		}
+		errorMessage := fmt.Sprintf("error publishing to exchange %s: %s", rabbitmq.WsConnectionExchange, err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+	closeLocalDeviceConnections(userId, deviceId, reason)
+}
+
+// WsConnectionConsumer receives the messages of closeDeviceConnections, each instance has its own queue
+func WsConnectionConsumer(d *amqp.Delivery, extraConsumerData interface{}) {
+	defer reviveWebsocket()
+	var message model.CloseConnectionsMessage
+	err := json.Unmarshal(d.Body, &message)
+	if err == nil {
+		closeLocalDeviceConnections(message.UserId, message.DeviceId, message.Reason)
+	}
+
+	if err = d.Ack(false); err != nil {
+		errorMessage := fmt.Sprintf("error acking [wsConnection] message: %s", err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+}
+
+// closeLocalDeviceConnections closes the connections of this instance, ReadMessage removes them from the hub
+func closeLocalDeviceConnections(userId int64, deviceId string, reason string) {
+	client, ok := getClientFromHub(userId)
+	if !ok {
+		return
+	}
+
+	globalHub.ClientsRwLock.RLock()
+	connections := slices.Clone(client.Connections)
+	globalHub.ClientsRwLock.RUnlock()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	for _, c := range connections {
+		if deviceId == "" || c.DeviceId == deviceId {
+			_ = c.Conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(writeWait))
+			_ = c.Conn.Close()
+		}
+	}
+}
+
 //------------------------------------------
 //------------------------------------------
 
@@ -343,9 +417,18 @@ func MessageStateConsumer(d *amqp.Delivery, extraConsumerData interface{}) {
 func HandleSingleChatMessage(receiveNewMessage *model.ReceiveNewMessage, wsSvc *WsService) error {
 	defer reviveWebsocket()
 	sender, senderExist, _ := wsSvc.hub.getClient(receiveNewMessage.UserId)
-	mid, err := wsSvc.wsRepo.SaveMessage(receiveNewMessage)
+	var mid int64
+	receiver, err := wsSvc.wsRepo.GetReceiverUser(receiveNewMessage.ReceiverId)
+	if err == nil {
+		if receiver == nil {
+			// receiver doesn't exist or is pending deletion
+			err = gorm.ErrRecordNotFound
+		} else {
+			mid, err = wsSvc.wsRepo.SaveMessage(receiveNewMessage)
+		}
+	}
 	if err != nil {
-		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) || errors.Is(err, gorm.ErrRecordNotFound) {
 			// receiver user not found
 			if senderExist {
 				messageSendResult := model.CreateNewMessageSendResult(
@@ -602,6 +685,31 @@ func (c *ClientConnection) ReadMessage(cc *Client, hub *Hub, rabbit rabbitmq.Rab
 			readQueueConf := rabbitmq.NewConfigPublish(rabbitmq.MessageStateExchange, rabbitmq.MessageStateBindingKey)
 			message := model.CreateSendUserIsTypingAction(clientMessage.UserStatusReq)
 			rabbit.Publish(ctx, message, readQueueConf, cc.UserId)
+		case model.DevicePairingInfoAction, model.DevicePairingAction:
+			validation := clientMessage.DevicePairingReq.Validate()
+			if len(validation) > 0 {
+				cc.Message <- model.CreateActionError(400, validation, clientMessage.Action, clientMessage.DevicePairingReq)
+				continue
+			}
+
+			var pairingInfo *model.DevicePairingInfo
+			if clientMessage.Action == model.DevicePairingInfoAction {
+				pairingInfo, err = getDevicePairingInfo(clientMessage.DevicePairingReq.Code)
+			} else {
+				auditCtx := &model.AuditContext{ActorId: cc.UserId, Ip: c.Ip}
+				pairingInfo, err = answerDevicePairing(cc.UserId, clientMessage.DevicePairingReq.Code, clientMessage.DevicePairingReq.Approve, auditCtx)
+			}
+			if err != nil {
+				code := 500
+				if err.Error() == response.DevicePairingNotFound {
+					code = 404
+				} else if err.Error() == response.DevicePairingAnswered {
+					code = 409
+				}
+				cc.Message <- model.CreateActionError(code, err.Error(), clientMessage.Action, clientMessage.DevicePairingReq)
+				continue
+			}
+			cc.Message <- model.CreateDevicePairingAction(clientMessage.Action, pairingInfo)
 		default:
 			cc.Message <- model.CreateActionError(400, "Invalid action", clientMessage.Action, nil)
 		}
@@ -630,11 +738,20 @@ func reviveWebsocket() {
 //------------------------------------------
 //------------------------------------------
 
-func (w *WsService) AddClient(ctx *fasthttp.RequestCtx, userId int64, username string, deviceId string) error {
-	err := upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+func (w *WsService) AddClient(ctx *fasthttp.RequestCtx, userId int64, username string, deviceId string, ip string) error {
+	suspension, err := GetUserSuspension(userId)
+	if err != nil {
+		return err
+	}
+	if suspension != nil {
+		return suspension
+	}
+
+	err = upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
 		connection := &ClientConnection{
 			Conn:     conn,
 			DeviceId: deviceId,
+			Ip:       ip,
 		}
 
 		client, ok, clientRwLock := w.hub.getClient(userId)
@@ -675,6 +792,7 @@ func (w *WsService) AddClient(ctx *fasthttp.RequestCtx, userId int64, username s
 				UserId:        notificationSettings.UserId,
 				Username:      notificationSettings.Username,
 				PublicName:    notificationSettings.PublicName,
+				Email:         notificationSettings.Email,
 				ProfileImages: notificationSettings.ProfileImages,
 				NotificationSettings: model.NotificationSettings{
 					UserId:                    userId,
@@ -706,8 +824,40 @@ func (w *WsService) AddClient(ctx *fasthttp.RequestCtx, userId int64, username s
 	return err
 }
 
+// SendSingleChatMessage queues a user-to-user message same as SendNewMessageAction of websocket,
+// result of saving the message is sent to the websocket connections of the sender
+func (w *WsService) SendSingleChatMessage(userId int64, username string, newMessage *model.NewMessage) error {
+	receiver, err := w.wsRepo.GetReceiverUser(newMessage.ReceiverId)
+	if err != nil {
+		return err
+	}
+	if receiver == nil {
+		return errors.New(response.UserNotFound)
+	}
+
+	message := &model.ReceiveNewMessage{
+		Id:         0,
+		Uuid:       newMessage.Uuid,
+		Content:    newMessage.Content,
+		RoomId:     -1,
+		ReceiverId: newMessage.ReceiverId,
+		Date:       time.Now(),
+		State:      1,
+		UserId:     userId,
+		Username:   username,
+	}
+	receiveMessage := model.CreateReceiveNewMessageAction(message)
+	conf := rabbitmq.NewConfigPublish(rabbitmq.ChatExchange, rabbitmq.SingleChatBindingKey)
+	return w.rabbitmq.Publish(context.Background(), receiveMessage, conf, userId)
+}
+
 func (w *WsService) GetSingleChatMessages(params *model.GetSingleMessagesReq) (*[]model.MessageDataModel, error) {
 	messages, err := w.wsRepo.GetSingleChatMessages(params)
+	if err == nil && messages != nil {
+		for i := range *messages {
+			signMediaFileUrls(w.cloudStorage, (*messages)[i].Medias)
+		}
+	}
 	return messages, err
 }
 
@@ -817,6 +967,7 @@ func (w *WsService) GetSingleChatList(params *model.GetSingleChatListReq) (*[]mo
 			slices.SortFunc(compressedChats[i].Messages[i2].Medias, func(a, b model.MediaFile) int {
 				return a.Date.Compare(b.Date)
 			})
+			signMediaFileUrls(w.cloudStorage, compressedChats[i].Messages[i2].Medias)
 		}
 	}
 	slices.SortFunc(compressedChats, func(a, b model.ChatsCompressedDataModel) int {
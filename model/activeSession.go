@@ -14,6 +14,7 @@ type ActiveSession struct {
 	LastUseDate  time.Time `gorm:"column:lastUseDate;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
 	LoginDate    time.Time `gorm:"column:loginDate;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
 	RefreshToken string    `gorm:"column:refreshToken;type:text;not null;uniqueIndex:ActiveSession_refreshToken_key;index:ActiveSession_userId_refreshToken_idx;"`
+	TokenFamily  string    `gorm:"column:tokenFamily;type:text;not null;default:'';"`
 }
 
 func (ActiveSession) TableName() string {
@@ -45,3 +46,12 @@ type ActiveSessionRes struct {
 	ThisDevice     *ActiveSessionDataModel   `json:"thisDevice"`
 	ActiveSessions *[]ActiveSessionDataModel `json:"activeSessions"`
 }
+
+// RotatedRefreshToken is cached after a refresh token gets rotated,
+// presenting it again means the token is leaked and the session (token family) gets revoked
+type RotatedRefreshToken struct {
+	UserId      int64     `json:"userId"`
+	DeviceId    string    `json:"deviceId"`
+	TokenFamily string    `json:"tokenFamily"`
+	RotatedAt   time.Time `json:"rotatedAt"`
+}
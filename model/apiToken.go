@@ -0,0 +1,102 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// ApiToken is a long-lived personal token for scripts and integrations, it's sent as bearer token instead of the access token.
+// only sha256 of the token is saved, plain token is shown once on creation
+type ApiToken struct {
+	Id          int64          `gorm:"column:id;type:serial;autoIncrement;primaryKey;" json:"id"`
+	UserId      int64          `gorm:"column:userId;type:integer;not null;index:ApiToken_userId_idx;" json:"userId"`
+	Name        string         `gorm:"column:name;type:text;not null;" json:"name"`
+	TokenHash   string         `gorm:"column:tokenHash;type:text;not null;uniqueIndex:ApiToken_tokenHash_key;" json:"-"`
+	TokenPrefix string         `gorm:"column:tokenPrefix;type:text;not null;" json:"tokenPrefix"` // first characters of the token, helps the user to recognize it
+	Scopes      pq.StringArray `gorm:"column:scopes;type:text[];not null;" json:"scopes" swaggertype:"array,string"`
+	CreatedAt   time.Time      `gorm:"column:createdAt;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;" json:"createdAt"`
+	ExpiresAt   *time.Time     `gorm:"column:expiresAt;type:timestamp(3);" json:"expiresAt"` // null: never expires
+	LastUsedAt  *time.Time     `gorm:"column:lastUsedAt;type:timestamp(3);" json:"lastUsedAt"`
+	LastUsedIp  string         `gorm:"column:lastUsedIp;type:text;not null;default:'';" json:"lastUsedIp"`
+	RevokedAt   *time.Time     `gorm:"column:revokedAt;type:timestamp(3);" json:"revokedAt"`
+}
+
+func (ApiToken) TableName() string {
+	return "ApiToken"
+}
+
+// IsActive reports whether the token is not revoked and not expired
+func (t *ApiToken) IsActive() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(time.Now()))
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// ApiTokenPrefix is the start of every personal api token, AuthMiddleware uses it to tell them apart from jwt access tokens
+const ApiTokenPrefix = "pat_"
+
+// ApiTokenMaxActive is the number of not revoked and not expired tokens that a user can have
+const ApiTokenMaxActive = 20
+
+const (
+	ApiTokenScopeReadNotifications = "notifications:read"
+	ApiTokenScopeSendMessages      = "messages:send"
+	ApiTokenScopeManageFollows     = "follows:manage"
+)
+
+var ApiTokenScopes = []string{
+	ApiTokenScopeReadNotifications, ApiTokenScopeSendMessages, ApiTokenScopeManageFollows,
+}
+
+type CreateApiTokenReq struct {
+	Name          string   `json:"name" minimum:"1" maximum:"50"`
+	Scopes        []string `json:"scopes" enums:"notifications:read,messages:send,follows:manage"`
+	ExpiresInDays int      `json:"expiresInDays" minimum:"0" maximum:"365"` // 0: never expires
+	Password      string   `json:"password"`                                // required when two-factor is not enabled
+}
+
+func (r *CreateApiTokenReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		errors = append(errors, "name Is Empty")
+	} else if utf8.RuneCountInString(r.Name) > 50 {
+		errors = append(errors, "name Length Must Be Less Than 50")
+	}
+	if len(r.Scopes) == 0 {
+		errors = append(errors, "scopes Is Empty")
+	}
+	for _, s := range r.Scopes {
+		if !slices.Contains(ApiTokenScopes, s) {
+			errors = append(errors, "Invalid scope: "+s)
+		}
+	}
+	slices.Sort(r.Scopes)
+	r.Scopes = slices.Compact(r.Scopes)
+	if r.ExpiresInDays < 0 || r.ExpiresInDays > 365 {
+		errors = append(errors, "expiresInDays must be in range of 0-365")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+type CreateApiTokenRes struct {
+	ApiToken
+	Token string `json:"token"` // plain token, it cannot be seen again
+}
+
+// ApiTokenAuthData is the data that AuthMiddleware needs for authorizing a request with api token, cached by hash of the token
+type ApiTokenAuthData struct {
+	Id        int64          `gorm:"column:id;" json:"id"`
+	UserId    int64          `gorm:"column:userId;" json:"userId"`
+	Username  string         `gorm:"column:username;" json:"username"`
+	Scopes    pq.StringArray `gorm:"column:scopes;type:text[];" json:"scopes"`
+	RoleIds   pq.Int64Array  `gorm:"column:roleIds;type:integer[];" json:"roleIds"`
+	ExpiresAt *time.Time     `gorm:"column:expiresAt;" json:"expiresAt"`
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditLog is append-only, updating or deleting rows is blocked by a trigger that is created on migration.
+// it has no foreign key to users, logs of deleted accounts are kept
+type AuditLog struct {
+	Id          int64       `gorm:"column:id;type:bigserial;autoIncrement;primaryKey;" json:"id"`
+	Date        time.Time   `gorm:"column:date;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;index:AuditLog_date_idx;" json:"date"`
+	Action      AuditAction `gorm:"column:action;type:text;not null;index:AuditLog_action_idx;" json:"action"`
+	ActorId     int64       `gorm:"column:actorId;type:integer;not null;default:0;index:AuditLog_actorId_idx;" json:"actorId"`    // 0: not authenticated
+	TargetId    int64       `gorm:"column:targetId;type:integer;not null;default:0;index:AuditLog_targetId_idx;" json:"targetId"` // 0: not a user (roles, permissions)
+	Ip          string      `gorm:"column:ip;type:text;not null;default:'';" json:"ip"`
+	IpLocation  string      `gorm:"column:ipLocation;type:text;not null;default:'';" json:"ipLocation"`
+	AppName     string      `gorm:"column:appName;type:text;not null;default:'';" json:"appName"`
+	AppVersion  string      `gorm:"column:appVersion;type:text;not null;default:'';" json:"appVersion"`
+	DeviceOs    string      `gorm:"column:deviceOs;type:text;not null;default:'';" json:"deviceOs"`
+	DeviceModel string      `gorm:"column:deviceModel;type:text;not null;default:'';" json:"deviceModel"`
+	Details     string      `gorm:"column:details;type:text;not null;default:'';" json:"details"`
+}
+
+func (AuditLog) TableName() string {
+	return "AuditLog"
+}
+
+//------------------------------------------
+//------------------------------------------
+
+type AuditAction string
+
+const (
+	AuditSignup             AuditAction = "signup"
+	AuditLogin              AuditAction = "login"
+	AuditLoginFailed        AuditAction = "login_failed"
+	AuditTokenRefresh       AuditAction = "token_refresh"
+	AuditLogout             AuditAction = "logout"
+	AuditForceLogout        AuditAction = "force_logout"
+	AuditPasswordChange     AuditAction = "password_change"
+	AuditPasswordReset      AuditAction = "password_reset"
+	AuditEmailChangeRequest AuditAction = "email_change_request"
+	AuditEmailChange        AuditAction = "email_change"
+	AuditEmailChangeRevert  AuditAction = "email_change_revert"
+	AuditAccountDelete      AuditAction = "account_delete"
+	AuditAccountRestore     AuditAction = "account_restore"
+	AuditAccountRemove      AuditAction = "account_remove" // data is removed after the grace period of account_delete
+	AuditRoleCreate         AuditAction = "role_create"
+	AuditRoleUpdate         AuditAction = "role_update"
+	AuditRoleDelete         AuditAction = "role_delete"
+	AuditRolePermissions    AuditAction = "role_permissions"
+	AuditPermissionCreate   AuditAction = "permission_create"
+	AuditPermissionUpdate   AuditAction = "permission_update"
+	AuditPermissionDelete   AuditAction = "permission_delete"
+	AuditUserRoleAdd        AuditAction = "user_role_add"
+	AuditUserRoleRemove     AuditAction = "user_role_remove"
+	AuditUserSuspend        AuditAction = "user_suspend"
+	AuditUserUnsuspend      AuditAction = "user_unsuspend"
+	AuditAccountUnlock      AuditAction = "account_unlock"
+	AuditDataExport         AuditAction = "data_export"
+	AuditDataExportDownload AuditAction = "data_export_download"
+	AuditApiTokenCreate     AuditAction = "api_token_create"
+	AuditApiTokenRevoke     AuditAction = "api_token_revoke"
+	AuditIdentityLink       AuditAction = "identity_link"
+	AuditIdentityUnlink     AuditAction = "identity_unlink"
+	AuditTwoFactorEnable    AuditAction = "two_factor_enable"
+	AuditTwoFactorDisable   AuditAction = "two_factor_disable"
+	AuditDevicePairing      AuditAction = "device_pairing" // new device is approved by a logged-in device
+)
+
+var AuditActions = []AuditAction{
+	AuditSignup, AuditLogin, AuditLoginFailed, AuditTokenRefresh, AuditLogout, AuditForceLogout,
+	AuditPasswordChange, AuditPasswordReset, AuditEmailChangeRequest, AuditEmailChange, AuditEmailChangeRevert,
+	AuditAccountDelete, AuditAccountRestore, AuditAccountRemove, AuditRoleCreate, AuditRoleUpdate, AuditRoleDelete, AuditRolePermissions,
+	AuditPermissionCreate, AuditPermissionUpdate, AuditPermissionDelete, AuditUserRoleAdd, AuditUserRoleRemove,
+	AuditUserSuspend, AuditUserUnsuspend, AuditAccountUnlock, AuditDataExport, AuditDataExportDownload,
+	AuditApiTokenCreate, AuditApiTokenRevoke, AuditIdentityLink, AuditIdentityUnlink, AuditTwoFactorEnable, AuditTwoFactorDisable,
+	AuditDevicePairing,
+}
+
+// AuditLogsPermission is needed for searching and exporting the audit logs of all users
+const AuditLogsPermission = "admin_audit_logs"
+
+//------------------------------------------
+//------------------------------------------
+
+// AuditContext is the request data that is saved with the audit log.
+// when DeviceInfo is nil, device is read from the session of RefreshToken
+type AuditContext struct {
+	ActorId      int64
+	Ip           string
+	RefreshToken string
+	DeviceInfo   *DeviceInfo
+}
+
+//------------------------------------------
+//------------------------------------------
+
+type AuditLogReq struct {
+	UserId   int64       `json:"userId" query:"-" swaggerignore:"true"`
+	ActorId  int64       `json:"actorId" minimum:"0"`  // admin only, 0: all
+	TargetId int64       `json:"targetId" minimum:"0"` // admin only, 0: all
+	Action   AuditAction `json:"action"`
+	Ip       string      `json:"ip"` // admin only
+	From     time.Time   `json:"from"`
+	To       time.Time   `json:"to"`
+	Skip     int         `json:"skip" minimum:"0"`
+	Limit    int         `json:"limit" minimum:"1" maximum:"100"`
+}
+
+func (r *AuditLogReq) Validate(maxLimit int) string {
+	errors := make([]string, 0)
+
+	r.Ip = strings.TrimSpace(r.Ip)
+	if r.ActorId < 0 {
+		errors = append(errors, "actorId cannot be smaller than 0")
+	}
+	if r.TargetId < 0 {
+		errors = append(errors, "targetId cannot be smaller than 0")
+	}
+	if r.Action != "" && !slices.Contains(AuditActions, r.Action) {
+		errors = append(errors, "Invalid action")
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.To.Before(r.From) {
+		errors = append(errors, "to cannot be before from")
+	}
+	if r.Skip < 0 {
+		errors = append(errors, "skip cannot be smaller than 0")
+	}
+	if r.Limit < 1 || r.Limit > maxLimit {
+		errors = append(errors, "limit must be in range of 1-"+strconv.Itoa(maxLimit))
+	}
+
+	return strings.Join(errors, ", ")
+}
@@ -4,6 +4,7 @@ type CachedUserData struct {
 	UserId               int64                   `gorm:"column:userId" json:"userId"`
 	Username             string                  `gorm:"column:username" json:"username"`
 	PublicName           string                  `gorm:"column:publicName" json:"publicName"`
+	Email                string                  `gorm:"column:email" json:"email"`
 	ProfileImages        []ProfileImageDataModel `gorm:"foreignKey:UserId;references:UserId;" json:"profileImages"`
 	NotificationSettings NotificationSettings    `gorm:"foreignKey:UserId;references:UserId;"`
 	NotifTokens          []string                `json:"notifTokens"`
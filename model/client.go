@@ -20,6 +20,7 @@ const ErrorAction ActionType = "action-error"
 const FollowNotifAction ActionType = "new-follow-notification"
 const NewMessageNotifAction ActionType = "new-message-notification"
 const MovieNotifAction ActionType = "movie-notification"
+const SecurityNotifAction ActionType = "security-notification"
 const UpdateProfileImagesAction ActionType = "update-profile-images"
 const UpdateProfileAction ActionType = "update-profile"
 
@@ -29,6 +30,8 @@ const SingleChatMessagesAction ActionType = "single-chat-messages"
 const NotificationSettingsAction ActionType = "notification-settings"
 const UserStatusAction ActionType = "user-status"
 const UserIsTypingAction ActionType = "user-status-isTyping"
+const DevicePairingInfoAction ActionType = "device-pairing-info"
+const DevicePairingAction ActionType = "device-pairing"
 
 type UserStatusResultType string
 
@@ -45,6 +48,8 @@ type ClientMessage struct {
 	ChatMessagesReq GetSingleMessagesReq `json:"chatMessagesReq,omitempty"` //action is SingleChatMessagesAction
 	ChatsListReq    GetSingleChatListReq `json:"chatsListReq,omitempty"`    //action is SingleChatsListAction
 	UserStatusReq   *UserStatusReq       `json:"userStatusReq,omitempty"`   //action is UserStatusAction
+	// action is DevicePairingInfoAction or DevicePairingAction
+	DevicePairingReq *DevicePairingReq `json:"devicePairingReq,omitempty"`
 }
 
 type ChannelMessage struct {
@@ -63,6 +68,7 @@ type ChannelMessage struct {
 	EditProfile          *EditProfileReq             `json:"editProfile,omitempty"`
 	UserStatusReq        *UserStatusReq              `json:"userStatusReq,omitempty"`
 	UserStatusRes        *UserStatusRes              `json:"userStatusRes,omitempty"`
+	DevicePairing        *DevicePairingInfo          `json:"devicePairing,omitempty"`
 }
 
 // for documentation usage
@@ -78,6 +84,7 @@ type ServerResultMessage struct {
 	ProfileImages        *[]ProfileImageDataModel    `json:"profileImages,omitempty"`        //action is UpdateProfileImagesAction
 	EditProfile          *EditProfileReq             `json:"editProfile,omitempty"`          //action is UpdateProfileAction
 	UserStatusRes        *UserStatusRes              `json:"userStatusRes,omitempty"`        //action is UserStatusAction
+	DevicePairing        *DevicePairingInfo          `json:"devicePairing,omitempty"`        //action is DevicePairingInfoAction or DevicePairingAction
 }
 
 //------------------------------------------
@@ -315,18 +322,45 @@ func CreateActionError(code int, errorMessage string, action ActionType, actionD
 	}
 }
 
-func CreateFollowNotificationAction(userId int64, followId int64) *ChannelMessage {
+// CreateFollowNotificationAction creates notification of a new follower, subEntityTypeId is used for follow requests of private accounts
+func CreateFollowNotificationAction(userId int64, followId int64, subEntityTypeId SubEntityTypeId) *ChannelMessage {
 	return &ChannelMessage{
 		Action: FollowNotifAction,
+		NotificationData: &NotificationDataModel{
+			Id:              0,
+			CreatorId:       userId,
+			ReceiverId:      followId,
+			Date:            time.Now(),
+			Status:          1,
+			EntityId:        strconv.FormatInt(userId, 10),
+			EntityTypeId:    FollowNotificationTypeId,
+			SubEntityTypeId: subEntityTypeId,
+			Message:         "",
+		},
+		ReceiveNewMessage:    nil,
+		ChatsListReq:         nil,
+		ChatMessages:         nil,
+		ChatMessagesReq:      nil,
+		NewMessageSendResult: nil,
+		Chats:                nil,
+		MessageRead:          nil,
+		ActionError:          nil,
+	}
+}
+
+// CreateSecurityNotificationAction notifies the user about their own account, like login from a new device
+func CreateSecurityNotificationAction(userId int64, entityId int64, message string) *ChannelMessage {
+	return &ChannelMessage{
+		Action: SecurityNotifAction,
 		NotificationData: &NotificationDataModel{
 			Id:           0,
 			CreatorId:    userId,
-			ReceiverId:   followId,
+			ReceiverId:   userId,
 			Date:         time.Now(),
 			Status:       1,
-			EntityId:     strconv.FormatInt(userId, 10),
-			EntityTypeId: FollowNotificationTypeId,
-			Message:      "",
+			EntityId:     strconv.FormatInt(entityId, 10),
+			EntityTypeId: SecurityNotificationTypeId,
+			Message:      message,
 		},
 		ReceiveNewMessage:    nil,
 		ChatsListReq:         nil,
@@ -473,3 +507,34 @@ func CreateSendUserIsTypingAction(userStatusReq *UserStatusReq) *ChannelMessage
 		ActionError:          nil,
 	}
 }
+
+func CreateDevicePairingAction(action ActionType, devicePairing *DevicePairingInfo) *ChannelMessage {
+	return &ChannelMessage{
+		Action:               action,
+		DevicePairing:        devicePairing,
+		UserStatusReq:        nil,
+		UserStatusRes:        nil,
+		EditProfile:          nil,
+		ProfileImages:        nil,
+		NotificationSettings: nil,
+		NotificationData:     nil,
+		ReceiveNewMessage:    nil,
+		ChatsListReq:         nil,
+		ChatMessages:         nil,
+		ChatMessagesReq:      nil,
+		NewMessageSendResult: nil,
+		Chats:                nil,
+		MessageRead:          nil,
+		ActionError:          nil,
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// CloseConnectionsMessage is sent to all instances to close websocket connections of the user, empty DeviceId closes all of them
+type CloseConnectionsMessage struct {
+	UserId   int64  `json:"userId"`
+	DeviceId string `json:"deviceId"`
+	Reason   string `json:"reason"`
+}
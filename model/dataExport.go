@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// DataExport is a request of user for downloading all of their data, the archive is created by a background job.
+// rows are kept after the file expires, they are used for limiting the number of exports
+type DataExport struct {
+	Id           int64            `gorm:"column:id;type:serial;autoIncrement;primaryKey;" json:"id"`
+	UserId       int64            `gorm:"column:userId;type:integer;not null;index:DataExport_userId_idx;" json:"userId"`
+	Status       DataExportStatus `gorm:"column:status;type:text;not null;index:DataExport_status_idx;" json:"status"`
+	RequestDate  time.Time        `gorm:"column:requestDate;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;" json:"requestDate"`
+	StartDate    *time.Time       `gorm:"column:startDate;type:timestamp(3);" json:"startDate"`
+	CompleteDate *time.Time       `gorm:"column:completeDate;type:timestamp(3);" json:"completeDate"`
+	ExpireDate   *time.Time       `gorm:"column:expireDate;type:timestamp(3);" json:"expireDate"`
+	FileName     string           `gorm:"column:fileName;type:text;not null;default:'';" json:"-"`
+	Size         int64            `gorm:"column:size;type:bigint;not null;default:0;" json:"size"`
+	Token        string           `gorm:"column:token;type:text;not null;default:'';" json:"-"`
+	ErrorMessage string           `gorm:"column:errorMessage;type:text;not null;default:'';" json:"errorMessage"`
+}
+
+func (DataExport) TableName() string {
+	return "DataExport"
+}
+
+type DataExportStatus string
+
+const (
+	DataExportPending    DataExportStatus = "pending"
+	DataExportProcessing DataExportStatus = "processing"
+	DataExportReady      DataExportStatus = "ready"
+	DataExportFailed     DataExportStatus = "failed"
+	DataExportExpired    DataExportStatus = "expired"
+)
+
+// IsDownloadable reports whether the archive exists and its link is not expired
+func (e *DataExport) IsDownloadable() bool {
+	return e.Status == DataExportReady && e.ExpireDate != nil && e.ExpireDate.After(time.Now())
+}
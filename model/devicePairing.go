@@ -0,0 +1,117 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type DevicePairingStatus string
+
+const (
+	DevicePairingPending  DevicePairingStatus = "pending"
+	DevicePairingApproved DevicePairingStatus = "approved"
+	DevicePairingRejected DevicePairingStatus = "rejected"
+	DevicePairingExpired  DevicePairingStatus = "expired"
+)
+
+// DevicePairing is cached from the new device requesting a pairing code until it receives the session
+type DevicePairing struct {
+	Code       string              `json:"code"`
+	TokenHash  string              `json:"tokenHash"`
+	DeviceInfo DeviceInfo          `json:"deviceInfo"`
+	IpLocation string              `json:"ipLocation"`
+	Status     DevicePairingStatus `json:"status"`
+	// UserId is the user that approved the pairing
+	UserId    int64 `json:"userId"`
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+func (p *DevicePairing) GetInfo() *DevicePairingInfo {
+	status := p.Status
+	if status == DevicePairingPending && time.Now().UnixMilli() > p.ExpiresAt {
+		status = DevicePairingExpired
+	}
+	return &DevicePairingInfo{
+		Code:        p.Code,
+		AppName:     p.DeviceInfo.AppName,
+		AppVersion:  p.DeviceInfo.AppVersion,
+		DeviceModel: p.DeviceInfo.DeviceModel,
+		Os:          p.DeviceInfo.Os,
+		IpLocation:  p.IpLocation,
+		Status:      status,
+		ExpiresAt:   p.ExpiresAt,
+	}
+}
+
+//---------------------------------------
+//---------------------------------------
+
+type DevicePairingStartRes struct {
+	Code string `json:"code"`
+	// Token is secret of the new device, it's needed to wait for approval and to receive the session
+	Token string `json:"token"`
+	// QrData is the content of qr code that is shown on the new device
+	QrData    string `json:"qrData"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// DevicePairingInfo is shown on the logged-in device before approving the pairing
+type DevicePairingInfo struct {
+	Code        string              `json:"code"`
+	AppName     string              `json:"appName"`
+	AppVersion  string              `json:"appVersion"`
+	DeviceModel string              `json:"deviceModel"`
+	Os          string              `json:"os"`
+	IpLocation  string              `json:"ipLocation"`
+	Status      DevicePairingStatus `json:"status"`
+	ExpiresAt   int64               `json:"expiresAt"`
+}
+
+//---------------------------------------
+//---------------------------------------
+
+type DevicePairingStartReq struct {
+	DeviceInfo DeviceInfo `json:"deviceInfo"`
+}
+
+func (r *DevicePairingStartReq) Validate() string {
+	r.DeviceInfo.Normalize()
+	return strings.Join(r.DeviceInfo.Validate(), ", ")
+}
+
+type DevicePairingCompleteReq struct {
+	Code  string `json:"code" validate:"required"`
+	Token string `json:"token" validate:"required"`
+}
+
+func (r *DevicePairingCompleteReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Code = strings.ToUpper(strings.TrimSpace(r.Code))
+	r.Token = strings.TrimSpace(r.Token)
+	if r.Code == "" {
+		errors = append(errors, "code Is Empty")
+	}
+	if r.Token == "" {
+		errors = append(errors, "token Is Empty")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+// DevicePairingReq is sent from websocket by the logged-in device
+type DevicePairingReq struct {
+	Code    string `json:"code"`
+	Approve bool   `json:"approve"` // only used in DevicePairingAction
+}
+
+func (r *DevicePairingReq) Validate() string {
+	if r == nil {
+		return "devicePairingReq Is Empty"
+	}
+	r.Code = strings.ToUpper(strings.TrimSpace(r.Code))
+	if r.Code == "" {
+		return "code Is Empty"
+	}
+	return ""
+}
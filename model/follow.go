@@ -7,13 +7,24 @@ type Follow struct {
 	// followerId follow followingId
 	AddDate     time.Time `gorm:"column:addDate;type:timestamp(3);not null;"`
 	FollowerId  int64     `gorm:"column:followerId;type:integer;primaryKey"`
-	FollowingId int64     `gorm:"column:followingId;type:integer;primaryKey"`
+	FollowingId int64     `gorm:"column:followingId;type:integer;primaryKey;index:Follow_followingId_idx;"`
 }
 
 func (Follow) TableName() string {
 	return "Follow"
 }
 
+// FollowRequest is a pending follow of a private account, it's moved to Follow when target user approves it
+type FollowRequest struct {
+	Date        time.Time `gorm:"column:date;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+	RequesterId int64     `gorm:"column:requesterId;type:integer;primaryKey"`
+	TargetId    int64     `gorm:"column:targetId;type:integer;primaryKey;index:FollowRequest_targetId_idx;"`
+}
+
+func (FollowRequest) TableName() string {
+	return "FollowRequest"
+}
+
 //------------------------------------------
 //------------------------------------------
 
@@ -25,3 +36,20 @@ type FollowUserDataModel struct {
 	Bio           string                            `gorm:"column:bio" json:"bio"`
 	ProfileImages []FollowListProfileImageDataModel `gorm:"foreignKey:UserId;references:UserId;" json:"profileImages"`
 }
+
+type FollowRequestDataModel struct {
+	UserId        int64                             `gorm:"column:userId" json:"userId"`
+	Username      string                            `gorm:"column:username" json:"username"`
+	RawUsername   string                            `gorm:"column:rawUsername" json:"rawUsername"`
+	PublicName    string                            `gorm:"column:publicName" json:"publicName"`
+	Bio           string                            `gorm:"column:bio" json:"bio"`
+	RequestDate   time.Time                         `gorm:"column:date" json:"requestDate"`
+	ProfileImages []FollowListProfileImageDataModel `gorm:"foreignKey:UserId;references:UserId;" json:"profileImages"`
+}
+
+// FollowRelation is the relation of the viewer with another user
+type FollowRelation struct {
+	IsFollowing     bool `gorm:"column:isFollowing" json:"isFollowing"`
+	FollowsYou      bool `gorm:"column:followsYou" json:"followsYou"`
+	FollowRequested bool `gorm:"column:followRequested" json:"followRequested"`
+}
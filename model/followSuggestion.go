@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// FollowSuggestionDismiss keeps the suggestions that user doesn't want to see, they are skipped on computing suggestions
+type FollowSuggestionDismiss struct {
+	UserId      int64     `gorm:"column:userId;type:integer;primaryKey;"`
+	SuggestedId int64     `gorm:"column:suggestedId;type:integer;primaryKey;index:FollowSuggestionDismiss_suggestedId_idx;"`
+	Date        time.Time `gorm:"column:date;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+}
+
+func (FollowSuggestionDismiss) TableName() string {
+	return "FollowSuggestionDismiss"
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// FollowSuggestion is the computed score of a suggested user, suggestions of each user are cached in redis
+type FollowSuggestion struct {
+	UserId        int64   `gorm:"column:userId" json:"userId"`
+	Score         float64 `gorm:"column:score" json:"score"`
+	MutualFollows int64   `gorm:"column:mutualFollows" json:"mutualFollows"` // followings of user that follow the suggested user
+	CommonGenres  int64   `gorm:"column:commonGenres" json:"commonGenres"`
+	CommonMovies  int64   `gorm:"column:commonMovies" json:"commonMovies"` // followed or liked movies of both users
+}
+
+type FollowSuggestionDataModel struct {
+	UserId        int64                             `gorm:"column:userId" json:"userId"`
+	Username      string                            `gorm:"column:username" json:"username"`
+	RawUsername   string                            `gorm:"column:rawUsername" json:"rawUsername"`
+	PublicName    string                            `gorm:"column:publicName" json:"publicName"`
+	ProfileImages []FollowListProfileImageDataModel `gorm:"foreignKey:UserId;references:UserId;" json:"profileImages"`
+	MutualFollows int64                             `gorm:"-" json:"mutualFollows"`
+	CommonGenres  int64                             `gorm:"-" json:"commonGenres"`
+	CommonMovies  int64                             `gorm:"-" json:"commonMovies"`
+}
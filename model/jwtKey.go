@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+type JwtKeyStatus string
+
+const (
+	// JwtKeySigning is the key that signs new access tokens, only one key has this status
+	JwtKeySigning JwtKeyStatus = "signing"
+	// JwtKeyVerifying is published in jwks and verifies tokens but doesn't sign,
+	// new keys start with this status so other services can fetch them before they are used
+	JwtKeyVerifying JwtKeyStatus = "verifying"
+	JwtKeyRetired   JwtKeyStatus = "retired"
+)
+
+type JwtKey struct {
+	Kid        string       `gorm:"column:kid;type:text;primaryKey;"`
+	Algorithm  string       `gorm:"column:algorithm;type:text;not null;"`
+	PrivateKey string       `gorm:"column:privateKey;type:text;not null;"`
+	PublicKey  string       `gorm:"column:publicKey;type:text;not null;"`
+	Status     JwtKeyStatus `gorm:"column:status;type:text;not null;index:JwtKey_status_idx;"`
+	CreatedAt  time.Time    `gorm:"column:createdAt;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+	UpdatedAt  time.Time    `gorm:"column:updatedAt;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+	// ActivatedAt is the first time the key started signing, hmac access tokens are rejected
+	// after one access token lifetime has passed from the first activation of any key
+	ActivatedAt *time.Time `gorm:"column:activatedAt;type:timestamp(3);"`
+}
+
+func (JwtKey) TableName() string {
+	return "JwtKey"
+}
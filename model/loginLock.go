@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// LoginLock is cached while the account is locked because of failed login attempts from a network,
+// logins from other networks are not affected
+type LoginLock struct {
+	UserId          int64     `json:"userId"`
+	Username        string    `json:"username"`
+	Network         string    `json:"network"` // ipv4 /24 or ipv6 /64 of the failed logins
+	FailedAttempts  int64     `json:"failedAttempts"`
+	LastIpLocation  string    `json:"lastIpLocation"`
+	LockedAt        time.Time `json:"lockedAt"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+	UnlockTokenHash string    `json:"unlockTokenHash,omitempty"`
+}
+
+// LoginLocksPermission is needed for listing and unlocking the accounts that are locked because of failed logins
+const LoginLocksPermission = "admin_login_locks"
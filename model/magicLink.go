@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/badoux/checkmail"
+)
+
+// MagicLink is cached until the sign-in link is used or expired
+type MagicLink struct {
+	UserId     int64      `json:"userId"`
+	DeviceInfo DeviceInfo `json:"deviceInfo"`
+	// DeviceKey is set when the link is requested with fingerprint, only that device can use it
+	DeviceKey string `json:"deviceKey"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+//---------------------------------------
+//---------------------------------------
+
+type MagicLinkReq struct {
+	Email      string     `json:"email" validate:"required"`
+	DeviceInfo DeviceInfo `json:"deviceInfo"`
+}
+
+func (r *MagicLinkReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+	r.DeviceInfo.Normalize()
+	if r.Email == "" {
+		errors = append(errors, "email Is Empty")
+	} else if err := checkmail.ValidateFormat(r.Email); err != nil {
+		errors = append(errors, "Email Is in Wrong Format")
+	}
+	errors = append(errors, r.DeviceInfo.Validate()...)
+
+	return strings.Join(errors, ", ")
+}
+
+// MagicLinkLoginReq is sent as json, or as form from the page of the web link
+type MagicLinkLoginReq struct {
+	Token       string `json:"token" form:"token" validate:"required"`
+	Fingerprint string `json:"fingerprint" form:"fingerprint"` // required if the link is requested with fingerprint
+}
+
+func (r *MagicLinkLoginReq) Validate() string {
+	r.Token = strings.TrimSpace(r.Token)
+	if r.Token == "" {
+		return "token Is Empty"
+	}
+	return ""
+}
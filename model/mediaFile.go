@@ -1,6 +1,8 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 	"time"
 )
@@ -14,12 +16,32 @@ type MediaFile struct {
 	Size      int64     `gorm:"column:size;type:integer;not null;" json:"size"`
 	Thumbnail string    `gorm:"column:thumbnail;type:text;not null;" json:"thumbnail"`
 	BlurHash  string    `gorm:"column:blurHash;type:text;not null;" json:"blurHash"`
+	Hash      string    `gorm:"column:hash;type:text;not null;default:'';index:MediaFile_hash_idx;" json:"-"`
 }
 
 func (MediaFile) TableName() string {
 	return "MediaFile"
 }
 
+// MediaBlob is the content-addressed object shared by all MediaFiles with the same content (sha256),
+// refCount is updated by a trigger on MediaFile, so cascade deletes are counted too
+type MediaBlob struct {
+	Hash      string    `gorm:"column:hash;type:text;primaryKey;" json:"hash"`
+	FileName  string    `gorm:"column:fileName;type:text;not null;" json:"fileName"`
+	Url       string    `gorm:"column:url;type:text;not null;" json:"url"`
+	Type      string    `gorm:"column:type;type:text;not null;" json:"type"`
+	Size      int64     `gorm:"column:size;type:integer;not null;" json:"size"`
+	Thumbnail string    `gorm:"column:thumbnail;type:text;not null;" json:"thumbnail"`
+	BlurHash  string    `gorm:"column:blurHash;type:text;not null;" json:"blurHash"`
+	RefCount  int64     `gorm:"column:refCount;type:integer;not null;default:0;index:MediaBlob_refCount_updatedAt_idx;" json:"refCount"`
+	CreatedAt time.Time `gorm:"column:createdAt;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updatedAt;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;index:MediaBlob_refCount_updatedAt_idx;" json:"updatedAt"`
+}
+
+func (MediaBlob) TableName() string {
+	return "MediaBlob"
+}
+
 //---------------------------------------
 //---------------------------------------
 
@@ -42,3 +64,76 @@ func (m *UploadMediaReq) Validate() string {
 
 	return strings.Join(errors, ", ")
 }
+
+//---------------------------------------
+//---------------------------------------
+
+type PresignedUploadReq struct {
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size" minimum:"1"`
+	Sha256      string `json:"sha256"` // hex sha256 of the file, storage rejects the upload if the content doesn't match it
+}
+
+func (m *PresignedUploadReq) Validate() string {
+	errors := make([]string, 0)
+
+	if m.FileName == "" {
+		errors = append(errors, "fileName cannot be empty")
+	}
+	if m.ContentType == "" || !strings.Contains(m.ContentType, "/") {
+		errors = append(errors, "invalid contentType")
+	}
+	if m.Size < 1 {
+		errors = append(errors, "size cannot be smaller than 1")
+	}
+	m.Sha256 = strings.ToLower(m.Sha256)
+	if b, err := hex.DecodeString(m.Sha256); err != nil || len(b) != sha256.Size {
+		errors = append(errors, "invalid sha256")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+type PresignedUploadRes struct {
+	UploadId  string            `json:"uploadId"`
+	Url       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+type ConfirmUploadReq struct {
+	UploadId   string `json:"uploadId"`
+	Content    string `json:"content"`
+	RoomId     int64  `json:"roomId" minimum:"-1"` // value -1 means its user-to-user message
+	ReceiverId int64  `json:"receiverId" minimum:"1"`
+	Uuid       string `json:"uuid"`
+}
+
+func (m *ConfirmUploadReq) Validate() string {
+	errors := make([]string, 0)
+
+	if m.UploadId == "" {
+		errors = append(errors, "uploadId cannot be empty")
+	}
+	if m.RoomId < -1 {
+		errors = append(errors, "roomId cannot be smaller than -1")
+	}
+	if m.ReceiverId < 1 {
+		errors = append(errors, "receiverId cannot be smaller than 1")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+// PendingMediaUpload is kept in redis between presigning the upload and confirming it
+type PendingMediaUpload struct {
+	UserId      int64     `json:"userId"`
+	FileName    string    `json:"fileName"`
+	Url         string    `json:"url"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Hash        string    `json:"hash"` // hex sha256, checked by storage on upload
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
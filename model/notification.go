@@ -41,12 +41,17 @@ var NotificationEntityTypesAndId = []*NotificationEntityType{
 		EntityTypeId: 3,
 		EntityType:   "Movie",
 	},
+	{
+		EntityTypeId: 4,
+		EntityType:   "Security",
+	},
 }
 
 const (
 	FollowNotificationTypeId     = 1
 	NewMessageNotificationTypeId = 2
 	MoviesNotificationTypeId     = 3
+	SecurityNotificationTypeId   = 4
 )
 
 type SubEntityTypeId int
@@ -59,6 +64,8 @@ const (
 	FutureList                SubEntityTypeId = 5
 	FutureListSerialSeasonEnd SubEntityTypeId = 6
 	FutureListSubtitle        SubEntityTypeId = 7
+	NewFollowRequest          SubEntityTypeId = 8
+	FollowRequestAccepted     SubEntityTypeId = 9
 )
 
 //-----------------------------------
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// StoragePermission is needed for the reports of media storage buckets
+const StoragePermission = "admin_storage"
+
+type OrphanMediaReport struct {
+	DryRun           bool                 `json:"dryRun"`
+	GracePeriodHours int                  `json:"gracePeriodHours"`
+	Date             time.Time            `json:"date"`
+	Buckets          []OrphanBucketReport `json:"buckets"`
+}
+
+type OrphanBucketReport struct {
+	BucketName   string            `json:"bucketName"`
+	TotalFiles   int               `json:"totalFiles"`
+	OrphanCount  int               `json:"orphanCount"`
+	OrphanSize   int64             `json:"orphanSize"`
+	RemovedCount int               `json:"removedCount"`
+	OrphanFiles  []OrphanMediaFile `json:"orphanFiles"`
+	ErrorMessage string            `json:"errorMessage"`
+}
+
+type OrphanMediaFile struct {
+	FileName     string    `json:"fileName"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	// Removable is true when the file is older than the grace period, newer files may belong to an unfinished upload
+	Removable bool `json:"removable"`
+}
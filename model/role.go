@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
 
 // sync with :: https://github.com/ashkan-esz/downloader_api/blob/master/src/data/db/admin/roleAndPermissionsDbMethods.js
 
@@ -104,3 +109,94 @@ const (
 	TestUserRole     DefaultRoleName = "test_user_role"
 	DefaultBotRole   DefaultRoleName = "default_bot_role"
 )
+
+// ManageRolesPermission is needed for the role and permission administration api
+const ManageRolesPermission = "admin_manage_roles"
+
+// ManageUsersPermission is needed for the user administration api (search, suspend, ban, force logout)
+const ManageUsersPermission = "admin_manage_users"
+
+// DefaultRoles are created by the main server, they cannot be removed or renamed
+var DefaultRoles = map[DefaultRoleId]DefaultRoleName{
+	MainAdmin:    MainAdminRole,
+	DefaultAdmin: DefaultAdminRole,
+	DefaultUser:  DefaultUserRole,
+	TestUser:     TestUserRole,
+	DefaultBot:   DefaultBotRole,
+}
+
+//------------------------------------------
+//------------------------------------------
+
+var roleNameRegex = regexp.MustCompile("^[a-z0-9_]+$")
+
+type RoleReq struct {
+	Name                string `json:"name" minimum:"3" maximum:"50"` //format: ^[a-z0-9_]+$
+	Description         string `json:"description"`
+	TorrentLeachLimitGb int    `json:"torrentLeachLimitGb"`
+	TorrentSearchLimit  int    `json:"torrentSearchLimit"`
+	BotsNotification    bool   `json:"botsNotification"`
+}
+
+func (r *RoleReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Name = strings.ToLower(strings.TrimSpace(r.Name))
+	r.Description = strings.TrimSpace(r.Description)
+	if len(r.Name) < 3 {
+		errors = append(errors, "name Length Must Be More Than 3")
+	} else if len(r.Name) > 50 {
+		errors = append(errors, "name Length Must Be Less Than 50")
+	}
+	if !roleNameRegex.MatchString(r.Name) {
+		errors = append(errors, "Only a-z, 0-9, and underscores are allowed in name")
+	}
+	if r.TorrentLeachLimitGb < 0 {
+		errors = append(errors, "torrentLeachLimitGb cannot be negative")
+	}
+	if r.TorrentSearchLimit < 0 {
+		errors = append(errors, "torrentSearchLimit cannot be negative")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+type PermissionReq struct {
+	Name        string `json:"name" minimum:"3" maximum:"50"` //format: ^[a-z0-9_]+$
+	Description string `json:"description"`
+}
+
+func (r *PermissionReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Name = strings.ToLower(strings.TrimSpace(r.Name))
+	r.Description = strings.TrimSpace(r.Description)
+	if len(r.Name) < 3 {
+		errors = append(errors, "name Length Must Be More Than 3")
+	} else if len(r.Name) > 50 {
+		errors = append(errors, "name Length Must Be Less Than 50")
+	}
+	if !roleNameRegex.MatchString(r.Name) {
+		errors = append(errors, "Only a-z, 0-9, and underscores are allowed in name")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+type RolePermissionsReq struct {
+	PermissionIds []int64 `json:"permissionIds"`
+}
+
+func (r *RolePermissionsReq) Validate() string {
+	if r.PermissionIds == nil {
+		r.PermissionIds = []int64{}
+	}
+	slices.Sort(r.PermissionIds)
+	r.PermissionIds = slices.Compact(r.PermissionIds)
+	for _, id := range r.PermissionIds {
+		if id < 1 {
+			return "permissionIds cannot be smaller than 1"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+type TwoFactorRecoveryCode struct {
+	Id        int64     `gorm:"column:id;type:serial;autoIncrement;primaryKey;"`
+	UserId    int64     `gorm:"column:userId;type:integer;not null;index:TwoFactorRecoveryCode_userId_idx;"`
+	CodeHash  string    `gorm:"column:codeHash;type:text;not null;"`
+	CreatedAt time.Time `gorm:"column:createdAt;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+}
+
+func (TwoFactorRecoveryCode) TableName() string {
+	return "TwoFactorRecoveryCode"
+}
+
+//---------------------------------------
+//---------------------------------------
+
+type UserTwoFactorDataModel struct {
+	UserId            int64  `gorm:"column:userId;"`
+	Username          string `gorm:"column:username;"`
+	Email             string `gorm:"column:email;"`
+	Password          string `gorm:"column:password;"`
+	TwoFactorEnabled  bool   `gorm:"column:twoFactorEnabled;"`
+	TwoFactorSecret   string `gorm:"column:twoFactorSecret;"`
+	TwoFactorLastStep int64  `gorm:"column:twoFactorLastStep;"`
+}
+
+func (UserTwoFactorDataModel) TableName() string {
+	return "User"
+}
+
+// TwoFactorChallenge is cached after the first step of login and consumed by the second step
+type TwoFactorChallenge struct {
+	UserId     int64      `json:"userId"`
+	DeviceInfo DeviceInfo `json:"deviceInfo"`
+	Attempts   int        `json:"attempts"`
+	ExpiresAt  int64      `json:"expiresAt"`
+}
+
+//---------------------------------------
+//---------------------------------------
+
+type TwoFactorSetupRes struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioningUri"`
+}
+
+type TwoFactorRecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code" validate:"required"` // 6 digit totp code or a recovery code
+}
+
+func (r *TwoFactorCodeReq) Validate() string {
+	r.Code = NormalizeTwoFactorCode(r.Code)
+	if r.Code == "" {
+		return "code Is Empty"
+	}
+	return ""
+}
+
+type DisableTwoFactorReq struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"` // 6 digit totp code or a recovery code
+}
+
+func (r *DisableTwoFactorReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Code = NormalizeTwoFactorCode(r.Code)
+	if r.Password == "" {
+		errors = append(errors, "password Is Empty")
+	}
+	if r.Code == "" {
+		errors = append(errors, "code Is Empty")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+type TwoFactorLoginReq struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required"` // 6 digit totp code or a recovery code
+}
+
+func (r *TwoFactorLoginReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Code = NormalizeTwoFactorCode(r.Code)
+	if r.ChallengeToken == "" {
+		errors = append(errors, "challengeToken Is Empty")
+	}
+	if r.Code == "" {
+		errors = append(errors, "code Is Empty")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+var twoFactorCodeSeparators = regexp.MustCompile(`[\s-]`)
+
+// NormalizeTwoFactorCode removes separators, recovery codes are shown as xxxxx-xxxxx
+func NormalizeTwoFactorCode(code string) string {
+	return strings.ToLower(twoFactorCodeSeparators.ReplaceAllString(code, ""))
+}
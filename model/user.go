@@ -28,6 +28,25 @@ type User struct {
 	EmailVerifyTokenExpire         int64          `gorm:"column:emailVerifyToken_expire;type:bigint;not null;default:0;"`
 	DeleteAccountVerifyToken       string         `gorm:"column:deleteAccountVerifyToken;type:text;not null;default:'';"`
 	DeleteAccountVerifyTokenExpire int64          `gorm:"column:deleteAccountVerifyToken_expire;type:bigint;not null;default:0;"`
+	ResetPasswordToken             string         `gorm:"column:resetPasswordToken;type:text;not null;default:'';"`
+	ResetPasswordTokenExpire       int64          `gorm:"column:resetPasswordToken_expire;type:bigint;not null;default:0;"`
+	PendingEmail                   string         `gorm:"column:pendingEmail;type:text;not null;default:'';"`
+	PreviousEmail                  string         `gorm:"column:previousEmail;type:text;not null;default:'';"`
+	EmailChangeToken               string         `gorm:"column:emailChangeToken;type:text;not null;default:'';"`
+	EmailChangeTokenExpire         int64          `gorm:"column:emailChangeToken_expire;type:bigint;not null;default:0;"`
+	EmailRevertToken               string         `gorm:"column:emailRevertToken;type:text;not null;default:'';"`
+	EmailRevertTokenExpire         int64          `gorm:"column:emailRevertToken_expire;type:bigint;not null;default:0;"`
+	TwoFactorEnabled               bool           `gorm:"column:twoFactorEnabled;type:boolean;not null;default:false;"`
+	TwoFactorSecret                string         `gorm:"column:twoFactorSecret;type:text;not null;default:'';"`
+	TwoFactorLastStep              int64          `gorm:"column:twoFactorLastStep;type:bigint;not null;default:0;"`
+	Banned                         bool           `gorm:"column:banned;type:boolean;not null;default:false;"`
+	SuspendedUntil                 int64          `gorm:"column:suspendedUntil;type:bigint;not null;default:0;"`
+	SuspensionReason               string         `gorm:"column:suspensionReason;type:text;not null;default:'';"`
+	SuspendedBy                    int64          `gorm:"column:suspendedBy;type:bigint;not null;default:0;"`
+	DeletionDate                   int64          `gorm:"column:deletionDate;type:bigint;not null;default:0;"` // unix milli of hard delete, 0: not pending deletion
+	PrivateAccount                 bool           `gorm:"column:privateAccount;type:boolean;not null;default:false;"`
+	FollowersCount                 int64          `gorm:"column:followersCount;type:integer;not null;default:0;"`  // updated by a trigger on Follow
+	FollowingsCount                int64          `gorm:"column:followingsCount;type:integer;not null;default:0;"` // updated by a trigger on Follow
 	//-----------------------------------
 	//-----------------------------------
 	UserId   int64  `gorm:"column:userId;type:serial;autoIncrement;primaryKey;uniqueIndex:User_userId_key;"`
@@ -35,35 +54,44 @@ type User struct {
 	Email    string `gorm:"column:email;type:text;not null;uniqueIndex:User_email_key;"`
 	//-----------------------------------
 	//-----------------------------------
-	Followers              []Follow                 `gorm:"foreignKey:FollowerId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	Following              []Follow                 `gorm:"foreignKey:FollowingId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	ProfileImages          []ProfileImage           `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	ActiveSessions         []ActiveSession          `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	ComputedFavoriteGenres []ComputedFavoriteGenres `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	DownloadLinksSettings  DownloadLinksSettings    `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	NotificationSettings   NotificationSettings     `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	MovieSettings          MovieSettings            `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	UserTorrent            UserTorrent              `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	FavoriteCharacters     []FavoriteCharacter      `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	LikeDislikeCharacter   []LikeDislikeCharacter   `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	FollowStaff            []FollowStaff            `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	LikeDislikeStaff       []LikeDislikeStaff       `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	FollowMovies           []FollowMovie            `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	LikeDislikeMovies      []LikeDislikeMovie       `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	WatchedMovies          []WatchedMovie           `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	WatchListGroup         []WatchListGroup         `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	WatchListMovies        []WatchListMovie         `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	UserCollection         []UserCollection         `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	UserCollectionMovie    []UserCollectionMovie    `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	CreatedRooms           []Room                   `gorm:"foreignKey:CreatorId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	ReceiverRooms          []Room                   `gorm:"foreignKey:ReceiverId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	SendedMessages         []Message                `gorm:"foreignKey:CreatorId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	ReceivedMessages       []Message                `gorm:"foreignKey:ReceiverId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	UserMessageRead        UserMessageRead          `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	CreatedNotifications   []Notification           `gorm:"foreignKey:CreatorId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	ReceivedNotifications  []Notification           `gorm:"foreignKey:ReceiverId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	UserBots               []UserBot                `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	Roles                  []UserToRole             `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Followers              []Follow                  `gorm:"foreignKey:FollowerId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Following              []Follow                  `gorm:"foreignKey:FollowingId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	SentFollowRequests     []FollowRequest           `gorm:"foreignKey:RequesterId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FollowRequests         []FollowRequest           `gorm:"foreignKey:TargetId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DismissedSuggestions   []FollowSuggestionDismiss `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DismissedBy            []FollowSuggestionDismiss `gorm:"foreignKey:SuggestedId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ProfileImages          []ProfileImage            `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ActiveSessions         []ActiveSession           `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ComputedFavoriteGenres []ComputedFavoriteGenres  `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DownloadLinksSettings  DownloadLinksSettings     `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	NotificationSettings   NotificationSettings      `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	MovieSettings          MovieSettings             `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserTorrent            UserTorrent               `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FavoriteCharacters     []FavoriteCharacter       `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	LikeDislikeCharacter   []LikeDislikeCharacter    `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FollowStaff            []FollowStaff             `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	LikeDislikeStaff       []LikeDislikeStaff        `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	FollowMovies           []FollowMovie             `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	LikeDislikeMovies      []LikeDislikeMovie        `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	WatchedMovies          []WatchedMovie            `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	WatchListGroup         []WatchListGroup          `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	WatchListMovies        []WatchListMovie          `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserCollection         []UserCollection          `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserCollectionMovie    []UserCollectionMovie     `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedRooms           []Room                    `gorm:"foreignKey:CreatorId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ReceiverRooms          []Room                    `gorm:"foreignKey:ReceiverId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	SendedMessages         []Message                 `gorm:"foreignKey:CreatorId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ReceivedMessages       []Message                 `gorm:"foreignKey:ReceiverId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserMessageRead        UserMessageRead           `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedNotifications   []Notification            `gorm:"foreignKey:CreatorId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ReceivedNotifications  []Notification            `gorm:"foreignKey:ReceiverId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserBots               []UserBot                 `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Roles                  []UserToRole              `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TwoFactorRecoveryCodes []TwoFactorRecoveryCode   `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Devices                []UserDevice              `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	LoginHistory           []LoginHistory            `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Identities             []UserIdentity            `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ApiTokens              []ApiToken                `gorm:"foreignKey:UserId;references:UserId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
 func (User) TableName() string {
@@ -81,6 +109,9 @@ type UserViewModel struct {
 	ProfileImages []ProfileImage `json:"profileImages,omitempty"`
 	RoleIds       []int64        `json:"roleIds"`
 	RoleNames     []string       `json:"roleNames"`
+	// set when account has two-factor authentication, Token is empty and login must be completed with ChallengeToken
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
 }
 
 type TokenViewModel struct {
@@ -116,10 +147,12 @@ type DeviceInfo struct {
 //---------------------------------------
 
 type UserDataModel struct {
-	UserId   int64  `db:"userId" gorm:"column:userId" json:"userId"`
-	Username string `db:"username" gorm:"column:username" json:"username"`
-	Email    string `db:"email" gorm:"column:email" json:"email"`
-	Password string `db:"password" gorm:"column:password" json:"-"`
+	UserId           int64  `db:"userId" gorm:"column:userId" json:"userId"`
+	Username         string `db:"username" gorm:"column:username" json:"username"`
+	Email            string `db:"email" gorm:"column:email" json:"email"`
+	Password         string `db:"password" gorm:"column:password" json:"-"`
+	TwoFactorEnabled bool   `db:"twoFactorEnabled" gorm:"column:twoFactorEnabled" json:"-"`
+	EmailVerified    bool   `db:"emailVerified" gorm:"column:emailVerified" json:"-"`
 }
 
 type UserWithImageDataModel struct {
@@ -134,6 +167,7 @@ type UserMetaWithNotificationSettings struct {
 	UserId         int64                   `gorm:"column:userId" json:"userId"`
 	Username       string                  `gorm:"column:username" json:"username"`
 	PublicName     string                  `gorm:"column:publicName" json:"publicName"`
+	Email          string                  `gorm:"column:email" json:"email"`
 	ProfileImages  []ProfileImageDataModel `gorm:"foreignKey:UserId;references:UserId;" json:"profileImages"`
 	ActiveSessions []ActiveSession         `gorm:"foreignKey:UserId;references:UserId;" json:"activeSessions"`
 	//NotificationSettings NotificationSettings `gorm:"foreignKey:UserId;references:UserId;" json:"notificationSettings"`
@@ -169,7 +203,6 @@ type EditProfileReq struct {
 	Username   string   `gorm:"column:username" json:"username" minimum:"6" maximum:"50"` //format: (?i)^[a-z|\d_-]+$
 	PublicName string   `gorm:"column:publicName" json:"publicName"`
 	Bio        string   `gorm:"column:bio" json:"bio"`
-	Email      string   `gorm:"column:email" json:"email"`
 	MbtiType   MbtiType `gorm:"column:mbtiType" json:"mbtiType"`
 }
 
@@ -196,8 +229,24 @@ func (p *EditProfileReq) Validate() string {
 	return strings.Join(errors, ", ")
 }
 
+type ChangeEmailReq struct {
+	Email string `json:"email" validate:"required"`
+}
+
+func (p *ChangeEmailReq) Validate() string {
+	p.Email = strings.ToLower(strings.TrimSpace(p.Email))
+	if p.Email == "" {
+		return "email Is Empty"
+	}
+	if err := checkmail.ValidateFormat(p.Email); err != nil {
+		return "Email Is in Wrong Format"
+	}
+	return ""
+}
+
 type UserProfileReq struct {
 	UserId                     int64  `json:"userId"`
+	ViewerId                   int64  `json:"viewerId"`
 	IsSelfProfile              bool   `json:"isSelfProfile"`
 	LoadSettings               bool   `json:"loadSettings"`
 	LoadFollowersCount         bool   `json:"loadFollowersCount"`
@@ -235,6 +284,64 @@ func (p *UpdatePasswordReq) Validate() string {
 	return strings.Join(errors, ", ")
 }
 
+type ForgetPasswordReq struct {
+	Email string `json:"email" validate:"required"`
+}
+
+func (p *ForgetPasswordReq) Validate() string {
+	p.Email = strings.ToLower(strings.TrimSpace(p.Email))
+	if p.Email == "" {
+		return "email Is Empty"
+	}
+	if err := checkmail.ValidateFormat(p.Email); err != nil {
+		return "Email Is in Wrong Format"
+	}
+	return ""
+}
+
+type ResetPasswordReq struct {
+	UserId          int64  `json:"userId" validate:"required"`
+	Token           string `json:"token" validate:"required"`
+	NewPassword     string `json:"newPassword" minimum:"8" maximum:"50"` // contain one number, one uppercase letter, cannot have space
+	ConfirmPassword string `json:"confirmPassword"`
+}
+
+func (p *ResetPasswordReq) Validate() string {
+	errors := make([]string, 0)
+
+	p.NewPassword = strings.TrimSpace(p.NewPassword)
+	p.ConfirmPassword = strings.TrimSpace(p.ConfirmPassword)
+	if p.UserId < 1 {
+		errors = append(errors, "userId cannot be smaller than 1")
+	}
+	if p.Token == "" {
+		errors = append(errors, "token Is Empty")
+	}
+	if p.NewPassword == "" {
+		errors = append(errors, "newPassword Is Empty")
+	} else {
+		if len(p.NewPassword) < 8 {
+			errors = append(errors, "newPassword Length Must Be More Than 8")
+		} else if len(p.NewPassword) > 50 {
+			errors = append(errors, "newPassword Length Must Be Less Than 50")
+		}
+		if matched, _ := regexp.MatchString("[0-9]", p.NewPassword); !matched {
+			errors = append(errors, "newPassword Must Contain A Number")
+		}
+		if matched, _ := regexp.MatchString("[A-Z]", p.NewPassword); !matched {
+			errors = append(errors, "newPassword Must Contain An Uppercase")
+		}
+		if strings.Contains(p.NewPassword, " ") {
+			errors = append(errors, "newPassword Cannot Have Space")
+		}
+		if p.NewPassword != p.ConfirmPassword {
+			errors = append(errors, "Passwords Don't Match")
+		}
+	}
+
+	return strings.Join(errors, ", ")
+}
+
 type UserProfileRes struct {
 	UserId                  int64                             `gorm:"column:userId" json:"userId"`
 	Username                string                            `gorm:"column:username" json:"username"`
@@ -242,6 +349,7 @@ type UserProfileRes struct {
 	PublicName              string                            `gorm:"column:publicName" json:"publicName"`
 	Email                   string                            `gorm:"column:email" json:"email"`
 	EmailVerified           bool                              `gorm:"column:emailVerified" json:"emailVerified"`
+	PendingEmail            string                            `gorm:"column:pendingEmail" json:"pendingEmail"`
 	Bio                     string                            `gorm:"column:bio" json:"bio"`
 	RegistrationDate        time.Time                         `gorm:"column:registrationDate;" json:"registrationDate"`
 	LastSeenDate            time.Time                         `gorm:"column:lastSeenDate;" json:"lastSeenDate"`
@@ -249,6 +357,7 @@ type UserProfileRes struct {
 	ComputedStatsLastUpdate int64                             `gorm:"column:ComputedStatsLastUpdate;" json:"computedStatsLastUpdate"`
 	FavoriteGenres          pq.StringArray                    `gorm:"column:favoriteGenres;type:text[];" json:"favoriteGenres" swaggertype:"array,string"`
 	MbtiType                MbtiType                          `gorm:"column:mbtiType" json:"mbtiType"`
+	PrivateAccount          bool                              `gorm:"column:privateAccount" json:"privateAccount"`
 	ProfileImages           []FollowListProfileImageDataModel `gorm:"foreignKey:UserId;references:UserId;" json:"profileImages"`
 	ComputedFavoriteGenres  []ComputedFavoriteGenres          `gorm:"foreignKey:UserId;references:UserId;" json:"computedFavoriteGenres"`
 	NotificationSettings    *NotificationSettings             `gorm:"foreignKey:UserId;references:UserId;" json:"notificationSettings"`
@@ -258,8 +367,12 @@ type UserProfileRes struct {
 	ThisDevice              *ActiveSessionDataModel           `gorm:"foreignKey:UserId;references:UserId;" json:"thisDevice"`
 	Roles                   []Role                            `gorm:"-" json:"roles"`
 	RolesWithPermissions    []RoleWithPermissions             `gorm:"-" json:"RolesWithPermissions"`
-	FollowersCount          int64                             `gorm:"-" json:"followersCount"`
-	FollowingsCount         int64                             `gorm:"-" json:"followingsCount"`
+	FollowersCount          int64                             `gorm:"column:followersCount" json:"followersCount"`
+	FollowingsCount         int64                             `gorm:"column:followingsCount" json:"followingsCount"`
+	IsFollowing             bool                              `gorm:"-" json:"isFollowing"`
+	FollowsYou              bool                              `gorm:"-" json:"followsYou"`
+	FollowRequested         bool                              `gorm:"-" json:"followRequested"`
+	MutualFollowersPreview  []FollowUserDataModel             `gorm:"-" json:"mutualFollowersPreview"` // followings of viewer that follow the user
 }
 
 //---------------------------------------
@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+// UserDevice is a device that user has logged in with, identified by DeviceKey (hash of the device fingerprint)
+type UserDevice struct {
+	UserId         int64     `gorm:"column:userId;type:integer;not null;primaryKey;"`
+	DeviceKey      string    `gorm:"column:deviceKey;type:text;not null;primaryKey;"`
+	DeviceId       string    `gorm:"column:deviceId;type:text;not null;"`
+	AppName        string    `gorm:"column:appName;type:text;not null;"`
+	DeviceModel    string    `gorm:"column:deviceModel;type:text;not null;"`
+	DeviceOs       string    `gorm:"column:deviceOs;type:text;not null;"`
+	LastIpLocation string    `gorm:"column:lastIpLocation;type:text;not null;default:'';"`
+	Trusted        bool      `gorm:"column:trusted;type:boolean;not null;default:false;"`
+	FirstLoginDate time.Time `gorm:"column:firstLoginDate;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+	LastLoginDate  time.Time `gorm:"column:lastLoginDate;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+}
+
+func (UserDevice) TableName() string {
+	return "UserDevice"
+}
+
+type LoginHistory struct {
+	Id          int64     `gorm:"column:id;type:serial;autoIncrement;primaryKey;"`
+	UserId      int64     `gorm:"column:userId;type:integer;not null;index:LoginHistory_userId_date_idx;index:LoginHistory_userId_ipLocation_idx;"`
+	DeviceKey   string    `gorm:"column:deviceKey;type:text;not null;"`
+	DeviceId    string    `gorm:"column:deviceId;type:text;not null;"`
+	AppName     string    `gorm:"column:appName;type:text;not null;"`
+	AppVersion  string    `gorm:"column:appVersion;type:text;not null;"`
+	DeviceModel string    `gorm:"column:deviceModel;type:text;not null;"`
+	DeviceOs    string    `gorm:"column:deviceOs;type:text;not null;"`
+	IpLocation  string    `gorm:"column:ipLocation;type:text;not null;index:LoginHistory_userId_ipLocation_idx;"`
+	NewDevice   bool      `gorm:"column:newDevice;type:boolean;not null;default:false;"`
+	NewLocation bool      `gorm:"column:newLocation;type:boolean;not null;default:false;"`
+	Date        time.Time `gorm:"column:date;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;index:LoginHistory_userId_date_idx;"`
+}
+
+func (LoginHistory) TableName() string {
+	return "LoginHistory"
+}
+
+//------------------------------------------
+//------------------------------------------
+
+type UserDeviceDataModel struct {
+	UserId         int64     `gorm:"column:userId;" json:"-"`
+	DeviceKey      string    `gorm:"column:deviceKey;" json:"deviceKey"`
+	DeviceId       string    `gorm:"column:deviceId;" json:"deviceId"`
+	AppName        string    `gorm:"column:appName;" json:"appName"`
+	DeviceModel    string    `gorm:"column:deviceModel;" json:"deviceModel"`
+	DeviceOs       string    `gorm:"column:deviceOs;" json:"deviceOs"`
+	LastIpLocation string    `gorm:"column:lastIpLocation;" json:"lastIpLocation"`
+	Trusted        bool      `gorm:"column:trusted;" json:"trusted"`
+	FirstLoginDate time.Time `gorm:"column:firstLoginDate;" json:"firstLoginDate"`
+	LastLoginDate  time.Time `gorm:"column:lastLoginDate;" json:"lastLoginDate"`
+}
+
+func (UserDeviceDataModel) TableName() string {
+	return "UserDevice"
+}
+
+type LoginHistoryDataModel struct {
+	Id          int64     `gorm:"column:id;" json:"id"`
+	UserId      int64     `gorm:"column:userId;" json:"-"`
+	DeviceKey   string    `gorm:"column:deviceKey;" json:"deviceKey"`
+	AppName     string    `gorm:"column:appName;" json:"appName"`
+	AppVersion  string    `gorm:"column:appVersion;" json:"appVersion"`
+	DeviceModel string    `gorm:"column:deviceModel;" json:"deviceModel"`
+	DeviceOs    string    `gorm:"column:deviceOs;" json:"deviceOs"`
+	IpLocation  string    `gorm:"column:ipLocation;" json:"ipLocation"`
+	NewDevice   bool      `gorm:"column:newDevice;" json:"newDevice"`
+	NewLocation bool      `gorm:"column:newLocation;" json:"newLocation"`
+	Date        time.Time `gorm:"column:date;" json:"date"`
+}
+
+func (LoginHistoryDataModel) TableName() string {
+	return "LoginHistory"
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// UserIdentity links an account to a user of an OpenID Connect provider, Subject is the 'sub' claim of provider
+type UserIdentity struct {
+	Id            int64     `gorm:"column:id;type:serial;autoIncrement;primaryKey;"`
+	UserId        int64     `gorm:"column:userId;type:integer;not null;uniqueIndex:UserIdentity_userId_provider_key;"`
+	Provider      string    `gorm:"column:provider;type:text;not null;uniqueIndex:UserIdentity_userId_provider_key;uniqueIndex:UserIdentity_provider_subject_key;"`
+	Subject       string    `gorm:"column:subject;type:text;not null;uniqueIndex:UserIdentity_provider_subject_key;"`
+	Email         string    `gorm:"column:email;type:text;not null;default:'';"`
+	CreatedAt     time.Time `gorm:"column:createdAt;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+	LastLoginDate time.Time `gorm:"column:lastLoginDate;type:timestamp(3);not null;default:CURRENT_TIMESTAMP;"`
+}
+
+func (UserIdentity) TableName() string {
+	return "UserIdentity"
+}
+
+// OidcState is cached from starting the provider login until the callback
+type OidcState struct {
+	Provider     string     `json:"provider"`
+	CodeVerifier string     `json:"codeVerifier"`
+	Nonce        string     `json:"nonce"`
+	RedirectUri  string     `json:"redirectUri"`
+	DeviceInfo   DeviceInfo `json:"deviceInfo"`
+	// LinkUserId is set when a logged-in user links the provider to the account,
+	// the callback must be sent with a refreshToken of the same session (deviceId and token family)
+	LinkUserId      int64  `json:"linkUserId"`
+	LinkDeviceId    string `json:"linkDeviceId"`
+	LinkTokenFamily string `json:"linkTokenFamily"`
+}
+
+//---------------------------------------
+//---------------------------------------
+
+type UserIdentityDataModel struct {
+	UserId        int64     `gorm:"column:userId;" json:"-"`
+	Provider      string    `gorm:"column:provider;" json:"provider"`
+	Email         string    `gorm:"column:email;" json:"email"`
+	CreatedAt     time.Time `gorm:"column:createdAt;" json:"createdAt"`
+	LastLoginDate time.Time `gorm:"column:lastLoginDate;" json:"lastLoginDate"`
+}
+
+func (UserIdentityDataModel) TableName() string {
+	return "UserIdentity"
+}
+
+type OidcProvidersRes struct {
+	Providers []string `json:"providers"`
+}
+
+type OidcStartRes struct {
+	AuthUrl string `json:"authUrl"`
+	State   string `json:"state"`
+}
+
+//---------------------------------------
+//---------------------------------------
+
+type OidcStartReq struct {
+	DeviceInfo DeviceInfo `json:"deviceInfo"`
+	// RedirectUri is the uri registered in provider, default is the callback of this server
+	RedirectUri string `json:"redirectUri"`
+}
+
+func (r *OidcStartReq) Validate() string {
+	r.RedirectUri = strings.TrimSpace(r.RedirectUri)
+	r.DeviceInfo.Normalize()
+	return strings.Join(r.DeviceInfo.Validate(), ", ")
+}
+
+type OidcLoginReq struct {
+	State string `json:"state" validate:"required"`
+	Code  string `json:"code" validate:"required"`
+}
+
+func (r *OidcLoginReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.State = strings.TrimSpace(r.State)
+	r.Code = strings.TrimSpace(r.Code)
+	if r.State == "" {
+		errors = append(errors, "state Is Empty")
+	}
+	if r.Code == "" {
+		errors = append(errors, "code Is Empty")
+	}
+
+	return strings.Join(errors, ", ")
+}
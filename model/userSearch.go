@@ -0,0 +1,45 @@
+package model
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// UserSearchMinFuzzyLength is the shortest query that is matched by trigram similarity, shorter ones only match the prefix
+const UserSearchMinFuzzyLength = 3
+
+type UserSearchReq struct {
+	Query string `json:"query" minimum:"1" maximum:"50"` // prefix or similar text of username or publicName
+	Skip  int    `json:"skip" minimum:"0"`
+	Limit int    `json:"limit" minimum:"1" maximum:"50"`
+}
+
+func (r *UserSearchReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Query = strings.ToLower(strings.TrimSpace(r.Query))
+	if r.Query == "" {
+		errors = append(errors, "query Is Empty")
+	} else if utf8.RuneCountInString(r.Query) > 50 {
+		errors = append(errors, "query Length Must Be Less Than 50")
+	}
+	if r.Skip < 0 {
+		errors = append(errors, "skip cannot be smaller than 0")
+	}
+	if r.Limit < 1 || r.Limit > 50 {
+		errors = append(errors, "limit must be in range of 1-50")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+type UserSearchDataModel struct {
+	UserId      int64  `gorm:"column:userId" json:"userId"`
+	Username    string `gorm:"column:username" json:"username"`
+	RawUsername string `gorm:"column:rawUsername" json:"rawUsername"`
+	PublicName  string `gorm:"column:publicName" json:"publicName"`
+	Thumbnail   string `gorm:"column:thumbnail" json:"thumbnail"` // thumbnail of the newest profile image
+	BlurHash    string `gorm:"column:blurHash" json:"blurHash"`
+	IsFollowing bool   `gorm:"column:isFollowing" json:"isFollowing"`
+	FollowsYou  bool   `gorm:"column:followsYou" json:"followsYou"`
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserSuspension blocks login, api and websocket of the user until it's lifted or expired, banned users have no expire time.
+// it's also returned as error, so handlers can respond with the reason
+type UserSuspension struct {
+	UserId      int64  `json:"userId"`
+	Banned      bool   `json:"banned"`
+	Until       int64  `json:"until"` // unix milli, 0 when banned
+	Reason      string `json:"reason"`
+	SuspendedBy int64  `json:"suspendedBy"`
+}
+
+func (s *UserSuspension) IsActive() bool {
+	return s.Banned || s.Until > time.Now().UnixMilli()
+}
+
+func (s *UserSuspension) Error() string {
+	if s.Banned {
+		return fmt.Sprintf("Account is banned, reason: %v", s.Reason)
+	}
+	until := time.UnixMilli(s.Until).UTC().Format(time.RFC3339)
+	return fmt.Sprintf("Account is suspended until %v, reason: %v", until, s.Reason)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+type SuspendUserReq struct {
+	Reason        string `json:"reason" minimum:"3" maximum:"500"`
+	DurationHours int    `json:"durationHours" minimum:"1"` // ignored when permanent is true
+	Permanent     bool   `json:"permanent"`                 // ban the user
+}
+
+func (r *SuspendUserReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Reason = strings.TrimSpace(r.Reason)
+	if len(r.Reason) < 3 {
+		errors = append(errors, "Reason Length Must Be More Than 3")
+	}
+	if len(r.Reason) > 500 {
+		errors = append(errors, "Reason Length Must Be Less Than 500")
+	}
+	if !r.Permanent && r.DurationHours < 1 {
+		errors = append(errors, "durationHours cannot be smaller than 1")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+//------------------------------------------
+//------------------------------------------
+
+type AdminSearchUsersReq struct {
+	Query string `json:"query"` // userId, or prefix of username or email
+	Skip  int    `json:"skip" minimum:"0"`
+	Limit int    `json:"limit" minimum:"1" maximum:"50"`
+}
+
+func (r *AdminSearchUsersReq) Validate() string {
+	errors := make([]string, 0)
+
+	r.Query = strings.ToLower(strings.TrimSpace(r.Query))
+	if r.Skip < 0 {
+		errors = append(errors, "skip cannot be smaller than 0")
+	}
+	if r.Limit < 1 || r.Limit > 50 {
+		errors = append(errors, "limit must be in range of 1-50")
+	}
+
+	return strings.Join(errors, ", ")
+}
+
+type AdminUserDataModel struct {
+	UserId           int64     `gorm:"column:userId" json:"userId"`
+	Username         string    `gorm:"column:username" json:"username"`
+	RawUsername      string    `gorm:"column:rawUsername" json:"rawUsername"`
+	PublicName       string    `gorm:"column:publicName" json:"publicName"`
+	Email            string    `gorm:"column:email" json:"email"`
+	EmailVerified    bool      `gorm:"column:emailVerified" json:"emailVerified"`
+	TwoFactorEnabled bool      `gorm:"column:twoFactorEnabled" json:"twoFactorEnabled"`
+	RegistrationDate time.Time `gorm:"column:registrationDate" json:"registrationDate"`
+	LastSeenDate     time.Time `gorm:"column:lastSeenDate" json:"lastSeenDate"`
+	Banned           bool      `gorm:"column:banned" json:"banned"`
+	SuspendedUntil   int64     `gorm:"column:suspendedUntil" json:"suspendedUntil"`
+	SuspensionReason string    `gorm:"column:suspensionReason" json:"suspensionReason"`
+	SuspendedBy      int64     `gorm:"column:suspendedBy" json:"suspendedBy"`
+	DeletionDate     int64     `gorm:"column:deletionDate" json:"deletionDate"`
+}
+
+func (AdminUserDataModel) TableName() string {
+	return "User"
+}
+
+type AdminUserCounts struct {
+	Followers      int64 `gorm:"column:followers" json:"followers"`
+	Followings     int64 `gorm:"column:followings" json:"followings"`
+	ProfileImages  int64 `gorm:"column:profileImages" json:"profileImages"`
+	ActiveSessions int64 `gorm:"column:activeSessions" json:"activeSessions"`
+	Bots           int64 `gorm:"column:bots" json:"bots"`
+	SentMessages   int64 `gorm:"column:sentMessages" json:"sentMessages"`
+}
+
+type AdminUserDetailsRes struct {
+	User           AdminUserDataModel       `json:"user"`
+	Roles          []Role                   `json:"roles"`
+	ActiveSessions []ActiveSessionDataModel `json:"activeSessions"`
+	Bots           []UserBotDataModel       `json:"bots"`
+	Counts         AdminUserCounts          `json:"counts"`
+}
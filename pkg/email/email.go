@@ -13,6 +13,12 @@ const (
 	ResetPassword    EmailType = "reset password"
 	VerifyEmail      EmailType = "verify email"
 	DeleteAccount    EmailType = "delete account"
+	SessionRevoked   EmailType = "session revoked"
+	AccountLocked    EmailType = "account locked"
+	MagicLinkLogin   EmailType = "magic link login"
+	ChangeEmail      EmailType = "change email"
+	EmailChanged     EmailType = "email changed"
+	DataExportReady  EmailType = "data export ready"
 )
 
 type EmailQueueData struct {
@@ -20,9 +26,11 @@ type EmailQueueData struct {
 	UserId      int64             `json:"userId"`
 	RawUsername string            `json:"rawUsername"`
 	Email       string            `json:"email"`
+	NewEmail    string            `json:"newEmail"`
 	Token       string            `json:"token"`
 	Host        string            `json:"host"`
 	Url         string            `json:"url"`
+	DeepLink    string            `json:"deepLink"`
 	DeviceInfo  *model.DeviceInfo `json:"deviceInfo"`
 	IpLocation  string            `json:"ipLocation"`
 }
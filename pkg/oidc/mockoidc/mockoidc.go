@@ -0,0 +1,217 @@
+package mockoidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// local OpenID Connect issuer for testing provider login, users are approved without a login page.
+// it's served by cmd/mockoidc and used by the tests of pkg/oidc.
+// the email of each login can be changed with 'login_hint' query param of the authorization url
+
+const KeyId = "mock-key"
+
+type authCode struct {
+	clientId      string
+	redirectUri   string
+	nonce         string
+	codeChallenge string
+	email         string
+	expiresAt     time.Time
+}
+
+type Server struct {
+	// Issuer must be the url that the server is reachable at, it can be set after starting the listener
+	Issuer        string
+	ClientId      string
+	ClientSecret  string // empty accepts any
+	Email         string
+	EmailVerified bool
+
+	key   *rsa.PrivateKey
+	mux   *http.ServeMux
+	lock  sync.Mutex
+	codes map[string]authCode
+}
+
+func NewServer(issuer string, clientId string, clientSecret string, email string, emailVerified bool) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Issuer:        strings.TrimSuffix(issuer, "/"),
+		ClientId:      clientId,
+		ClientSecret:  clientSecret,
+		Email:         email,
+		EmailVerified: emailVerified,
+		key:           key,
+		mux:           http.NewServeMux(),
+		codes:         make(map[string]authCode),
+	}
+
+	s.mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	s.mux.HandleFunc("/jwks", s.jwks)
+	s.mux.HandleFunc("/authorize", s.authorize)
+	s.mux.HandleFunc("/token", s.token)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// SignIdToken signs the claims with the key of jwks, for testing tokens that the token endpoint doesn't return
+func (s *Server) SignIdToken(claims jwt.MapClaims) (string, error) {
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = KeyId
+	return idToken.SignedString(s.key)
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": KeyId,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize approves the login immediately and redirects back with the code
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectUri, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") != s.ClientId || query.Get("response_type") != "code" {
+		http.Error(w, "invalid client_id or response_type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "S256 code_challenge is required", http.StatusBadRequest)
+		return
+	}
+
+	email := query.Get("login_hint")
+	if email == "" {
+		email = s.Email
+	}
+	code := randomString()
+	s.lock.Lock()
+	s.codes[code] = authCode{
+		clientId:      s.ClientId,
+		redirectUri:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		email:         email,
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	s.lock.Unlock()
+
+	params := redirectUri.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectUri.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientId != s.ClientId || (s.ClientSecret != "" && clientSecret != s.ClientSecret) {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.lock.Lock()
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.lock.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		time.Now().After(code.expiresAt) ||
+		code.redirectUri != r.PostForm.Get("redirect_uri") ||
+		code.codeChallenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	subject := sha256.Sum256([]byte(code.email))
+	now := time.Now()
+	signed, err := s.SignIdToken(jwt.MapClaims{
+		"iss":            s.Issuer,
+		"aud":            code.clientId,
+		"sub":            hex.EncodeToString(subject[:8]),
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          code.nonce,
+		"email":          code.email,
+		"email_verified": s.EmailVerified,
+		"name":           strings.Split(code.email, "@")[0],
+	})
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJson(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,321 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// generic OpenID Connect client, authorization code flow with PKCE.
+// provider endpoints are read from discovery document and id tokens are verified with the provider's jwks
+
+const (
+	httpTimeout = 10 * time.Second
+	// unknown kid refreshes jwks, but not more than once in this duration
+	jwksMinRefreshInterval = time.Minute
+	jwksMaxAge             = 24 * time.Hour
+	clockSkew              = time.Minute
+)
+
+var (
+	ErrInvalidIdToken = errors.New("invalid id token")
+	httpClient        = &http.Client{Timeout: httpTimeout}
+)
+
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+
+	mux       sync.Mutex
+	discovery *Discovery
+	keys      map[string]interface{}
+	keysDate  time.Time
+}
+
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type IdTokenClaims struct {
+	Email             string     `json:"email"`
+	EmailVerified     stringBool `json:"email_verified"`
+	Name              string     `json:"name"`
+	PreferredUsername string     `json:"preferred_username"`
+	Nonce             string     `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// stringBool accepts both true and "true", some providers send email_verified as string
+type stringBool bool
+
+func (b *stringBool) UnmarshalJSON(data []byte) error {
+	*b = stringBool(strings.Trim(string(data), "\"") == "true")
+	return nil
+}
+
+func NewProvider(name string, issuer string, clientId string, clientSecret string) *Provider {
+	return &Provider{
+		Name:         name,
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// AuthCodeUrl returns the url of provider login page, codeVerifier must be kept for Exchange
+func (p *Provider) AuthCodeUrl(ctx context.Context, redirectUri string, state string, nonce string, codeVerifier string) (string, error) {
+	config, err := p.oauthConfig(ctx, redirectUri)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(codeVerifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+// Exchange gets the tokens of code and returns the verified claims of id token
+func (p *Provider) Exchange(ctx context.Context, redirectUri string, code string, codeVerifier string, nonce string) (*IdTokenClaims, error) {
+	config, err := p.oauthConfig(ctx, redirectUri)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc code exchange: %w", err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIdToken == "" {
+		return nil, ErrInvalidIdToken
+	}
+	return p.VerifyIdToken(ctx, rawIdToken, nonce)
+}
+
+func (p *Provider) VerifyIdToken(ctx context.Context, rawIdToken string, nonce string) (*IdTokenClaims, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := IdTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIdToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, discovery, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIdToken, err)
+	}
+	if claims.Subject == "" || claims.Nonce != nonce {
+		return nil, ErrInvalidIdToken
+	}
+	return &claims, nil
+}
+
+// NewCodeVerifier returns a random PKCE code verifier
+func NewCodeVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+//------------------------------------------
+//------------------------------------------
+
+func (p *Provider) oauthConfig(ctx context.Context, redirectUri string) (*oauth2.Config, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.ClientId,
+		ClientSecret: p.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+		RedirectURL: redirectUri,
+		Scopes:      p.Scopes,
+	}, nil
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*Discovery, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery Discovery
+	err := getJson(ctx, p.Issuer+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery of %v: %w", p.Name, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("oidc discovery of %v: issuer mismatch %v", p.Name, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksUri == "" {
+		return nil, fmt.Errorf("oidc discovery of %v: missing endpoints", p.Name)
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+func (p *Provider) getKey(ctx context.Context, discovery *Discovery, kid string) (interface{}, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if key, ok := p.keys[kid]; ok && time.Since(p.keysDate) < jwksMaxAge {
+		return key, nil
+	}
+	if time.Since(p.keysDate) < jwksMinRefreshInterval {
+		return nil, fmt.Errorf("unknown key id: %v", kid)
+	}
+
+	keys, err := fetchJwks(ctx, discovery.JwksUri)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysDate = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	// tokens without kid are accepted when provider has only one key
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id: %v", kid)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func fetchJwks(ctx context.Context, jwksUri string) (map[string]interface{}, error) {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJson(ctx, jwksUri, &jwks); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// skip key types that are not supported
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %v", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %v", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %v", k.Kty)
+	}
+}
+
+func getJson(ctx context.Context, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %v from %v", res.StatusCode, url)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"context"
+	"downloader_gochat/pkg/oidc/mockoidc"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// runs the provider against cmd/mockoidc issuer: discovery, authorization code flow with PKCE and id token validation
+
+const (
+	testClientId     = "test-client"
+	testClientSecret = "test-secret"
+	testEmail        = "oidc.user@example.com"
+	testRedirectUri  = "http://localhost/v1/user/oidc/callback"
+)
+
+func startMockIssuer(t *testing.T) *mockoidc.Server {
+	t.Helper()
+	server, err := mockoidc.NewServer("", testClientId, testClientSecret, testEmail, true)
+	if err != nil {
+		t.Fatalf("could not create mock issuer: %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	server.Issuer = httpServer.URL
+	return server
+}
+
+// authorize opens the login page of mock issuer and returns the code of the redirect
+func authorize(t *testing.T, authUrl string, state string) string {
+	t.Helper()
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Get(authUrl)
+	if err != nil {
+		t.Fatalf("authorize request: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %v, want %v", res.StatusCode, http.StatusFound)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect location: %v", err)
+	}
+	if !strings.HasPrefix(location.String(), testRedirectUri) {
+		t.Fatalf("redirected to %v, want %v", location, testRedirectUri)
+	}
+	if location.Query().Get("state") != state {
+		t.Fatalf("state = %v, want %v", location.Query().Get("state"), state)
+	}
+	return location.Query().Get("code")
+}
+
+func TestDiscovery(t *testing.T) {
+	issuer := startMockIssuer(t)
+	provider := NewProvider("mock", issuer.Issuer+"/", testClientId, testClientSecret)
+
+	authUrl, err := provider.AuthCodeUrl(context.Background(), testRedirectUri, "state", "nonce", NewCodeVerifier())
+	if err != nil {
+		t.Fatalf("AuthCodeUrl: %v", err)
+	}
+	parsed, err := url.Parse(authUrl)
+	if err != nil {
+		t.Fatalf("invalid auth url: %v", err)
+	}
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != issuer.Issuer+"/authorize" {
+		t.Errorf("authorization endpoint = %v, want %v", got, issuer.Issuer+"/authorize")
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != testClientId || query.Get("redirect_uri") != testRedirectUri || query.Get("nonce") != "nonce" {
+		t.Errorf("unexpected auth url params: %v", query)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Errorf("auth url doesn't have S256 code challenge: %v", query)
+	}
+	if !strings.Contains(query.Get("scope"), "openid") {
+		t.Errorf("scope = %v, must have openid", query.Get("scope"))
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	issuer := startMockIssuer(t)
+	provider := NewProvider("mock", issuer.Issuer, testClientId, testClientSecret)
+	issuer.Issuer = "https://other-issuer.example.com"
+
+	_, err := provider.AuthCodeUrl(context.Background(), testRedirectUri, "state", "nonce", NewCodeVerifier())
+	if err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("AuthCodeUrl error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestExchangeWithPkce(t *testing.T) {
+	issuer := startMockIssuer(t)
+	provider := NewProvider("mock", issuer.Issuer, testClientId, testClientSecret)
+	ctx := context.Background()
+
+	codeVerifier := NewCodeVerifier()
+	authUrl, err := provider.AuthCodeUrl(ctx, testRedirectUri, "login-state", "login-nonce", codeVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeUrl: %v", err)
+	}
+	code := authorize(t, authUrl, "login-state")
+
+	claims, err := provider.Exchange(ctx, testRedirectUri, code, codeVerifier, "login-nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Email != testEmail || !bool(claims.EmailVerified) || claims.Subject == "" {
+		t.Errorf("unexpected claims: email %v, verified %v, sub %v", claims.Email, claims.EmailVerified, claims.Subject)
+	}
+
+	// code can be used once
+	_, err = provider.Exchange(ctx, testRedirectUri, code, codeVerifier, "login-nonce")
+	if err == nil {
+		t.Errorf("Exchange with used code succeeded")
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	issuer := startMockIssuer(t)
+	provider := NewProvider("mock", issuer.Issuer, testClientId, testClientSecret)
+	ctx := context.Background()
+
+	authUrl, err := provider.AuthCodeUrl(ctx, testRedirectUri, "state", "nonce", NewCodeVerifier())
+	if err != nil {
+		t.Fatalf("AuthCodeUrl: %v", err)
+	}
+	code := authorize(t, authUrl, "state")
+
+	_, err = provider.Exchange(ctx, testRedirectUri, code, NewCodeVerifier(), "nonce")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("Exchange error = %v, want invalid_grant", err)
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	issuer := startMockIssuer(t)
+	provider := NewProvider("mock", issuer.Issuer, testClientId, testClientSecret)
+	ctx := context.Background()
+
+	codeVerifier := NewCodeVerifier()
+	authUrl, err := provider.AuthCodeUrl(ctx, testRedirectUri, "state", "nonce", codeVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeUrl: %v", err)
+	}
+	code := authorize(t, authUrl, "state")
+
+	_, err = provider.Exchange(ctx, testRedirectUri, code, codeVerifier, "other-nonce")
+	if !errors.Is(err, ErrInvalidIdToken) {
+		t.Fatalf("Exchange error = %v, want ErrInvalidIdToken", err)
+	}
+}
+
+func TestVerifyIdToken(t *testing.T) {
+	issuer := startMockIssuer(t)
+	otherIssuer := startMockIssuer(t)
+	provider := NewProvider("mock", issuer.Issuer, testClientId, testClientSecret)
+	ctx := context.Background()
+
+	validClaims := func() jwt.MapClaims {
+		now := time.Now()
+		return jwt.MapClaims{
+			"iss":   issuer.Issuer,
+			"aud":   testClientId,
+			"sub":   "subject",
+			"iat":   now.Unix(),
+			"exp":   now.Add(5 * time.Minute).Unix(),
+			"nonce": "nonce",
+			"email": testEmail,
+		}
+	}
+
+	token, err := issuer.SignIdToken(validClaims())
+	if err != nil {
+		t.Fatalf("SignIdToken: %v", err)
+	}
+	claims, err := provider.VerifyIdToken(ctx, token, "nonce")
+	if err != nil {
+		t.Fatalf("VerifyIdToken of valid token: %v", err)
+	}
+	if claims.Subject != "subject" || claims.Email != testEmail {
+		t.Errorf("unexpected claims: sub %v, email %v", claims.Subject, claims.Email)
+	}
+
+	tests := []struct {
+		name   string
+		signer *mockoidc.Server
+		change func(claims jwt.MapClaims)
+		nonce  string
+	}{
+		{"wrong nonce", issuer, func(claims jwt.MapClaims) {}, "other-nonce"},
+		{"wrong audience", issuer, func(claims jwt.MapClaims) { claims["aud"] = "other-client" }, "nonce"},
+		{"wrong issuer", issuer, func(claims jwt.MapClaims) { claims["iss"] = otherIssuer.Issuer }, "nonce"},
+		{"expired", issuer, func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }, "nonce"},
+		{"without expiration", issuer, func(claims jwt.MapClaims) { delete(claims, "exp") }, "nonce"},
+		{"without subject", issuer, func(claims jwt.MapClaims) { delete(claims, "sub") }, "nonce"},
+		{"signed by other key", otherIssuer, func(claims jwt.MapClaims) {}, "nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.change(claims)
+			token, err := tt.signer.SignIdToken(claims)
+			if err != nil {
+				t.Fatalf("SignIdToken: %v", err)
+			}
+			_, err = provider.VerifyIdToken(ctx, token, tt.nonce)
+			if !errors.Is(err, ErrInvalidIdToken) {
+				t.Errorf("VerifyIdToken error = %v, want ErrInvalidIdToken", err)
+			}
+		})
+	}
+
+	t.Run("hmac signed", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte(testClientSecret))
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		_, err = provider.VerifyIdToken(ctx, token, "nonce")
+		if !errors.Is(err, ErrInvalidIdToken) {
+			t.Errorf("VerifyIdToken error = %v, want ErrInvalidIdToken", err)
+		}
+	})
+}
@@ -5,6 +5,7 @@ const (
 	//----------------------
 	ExceedProfileImage = "Exceeded profile image counts"
 	ExceedGenres       = "Exceeded number of genres limit (6)"
+	ExceedApiTokens    = "Exceeded number of active api tokens limit (20)"
 	//----------------------
 	MovieSourcesNotFound      = "Movie sources not found"
 	CrawlerSourceNotFound     = "Crawler source not found"
@@ -27,8 +28,20 @@ const (
 	//----------------------
 	UserNotFound         = "Cannot find user"
 	SessionNotFound      = "Cannot find session"
+	DeviceNotFound       = "Cannot find device"
 	ProfileImageNotFound = "Cannot find profile image"
 	EmailNotFound        = "Cannot find user email"
+	UploadNotFound       = "Cannot find upload, it may be expired"
+	ProviderNotFound     = "Cannot find login provider"
+	IdentityNotFound     = "Cannot find linked login provider"
+	RoleNotFound         = "Cannot find role"
+	PermissionNotFound   = "Cannot find permission"
+	ApiTokenNotFound     = "Cannot find api token"
+	//----------------------
+	DevicePairingNotFound = "Cannot find pairing code, it may be expired"
+	DevicePairingAnswered = "Pairing code is already answered"
+	DevicePairingPending  = "Pairing is not approved yet"
+	DevicePairingRejected = "Pairing is rejected"
 	//----------------------
 	InvalidRefreshToken = "Invalid RefreshToken"
 	InvalidToken        = "Invalid/Stale Token"
@@ -36,14 +49,51 @@ const (
 	//----------------------
 	UserPassNotMatch = "Username and password do not match"
 	OldPassNotMatch  = "Old password does not match"
+	PassNotMatch     = "Password does not match"
+	//----------------------
+	TwoFactorCodeRequired   = "Two-factor code is required"
+	InvalidTwoFactorCode    = "Invalid/Used two-factor code"
+	TwoFactorAlreadyEnabled = "Two-factor authentication is already enabled"
+	TwoFactorNotEnabled     = "Two-factor authentication is not enabled"
+	//----------------------
+	EmailNotChanged      = "New email is the same as the current email"
+	EmailChangedRecently = "Email is changed recently, try again after the revert link expires"
+	//----------------------
+	TooManyLoginAttempts = "Too many failed login attempts, try again later"
+	AccountLocked        = "Account is temporarily locked, use the link sent to your email to unlock it"
+	//----------------------
+	ProviderEmailNotVerified = "Email is not verified by login provider"
+	ProviderLoginFailed      = "Login with provider failed, try again"
+	InvalidRedirectUri       = "Redirect uri is not allowed"
+	IdentityAlreadyLinked    = "This provider account is already linked to a user"
+	EmailNotVerified         = "Account email is not verified, login with password and link the provider"
+	LinkSessionMismatch      = "Linking must be completed from the session that started it"
+	//----------------------
+	DefaultRoleChanged      = "Default roles cannot be removed or renamed"
+	MainAdminRoleChanged    = "Main admin role cannot be assigned, revoked or edited"
+	ProtectedPermissionUsed = "This permission is needed for managing roles and cannot be removed or renamed"
+	SelfSuspended           = "You cannot suspend your own account"
+	MainAdminSuspended      = "Main admin cannot be suspended"
+	//----------------------
+	DataExportInProgress        = "An export of your data is already in progress"
+	DataExportRequestedRecently = "Data export is requested recently, try again later"
+	DataExportExpired           = "Data export link is expired, request a new export"
+	//----------------------
+	PrivateAccount           = "This account is private, follow it to see its followers and followings"
+	FollowRequestAlreadySent = "Follow request is already sent"
+	FollowRequestNotFound    = "Cannot find follow request"
 	//----------------------
 	BadRequestBody = "Incorrect request body"
 	//----------------------
-	UserIdAlreadyExist   = "This userId already exists"
-	UsernameAlreadyExist = "This username already exists"
-	EmailAlreadyExist    = "This email already exists"
-	AlreadyExist         = "Already exist"
-	AlreadyFollowed      = "Already followed"
+	InvalidUploadedFile = "Uploaded file does not match the requested upload"
+	//----------------------
+	UserIdAlreadyExist     = "This userId already exists"
+	UsernameAlreadyExist   = "This username already exists"
+	EmailAlreadyExist      = "This email already exists"
+	RoleAlreadyExist       = "This role already exists"
+	PermissionAlreadyExist = "This permission already exists"
+	AlreadyExist           = "Already exist"
+	AlreadyFollowed        = "Already followed"
 	//----------------------
 	BotIsDisabled = "This bot is disabled"
 	//----------------------
@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// time-based one-time password (RFC 6238) with the defaults that authenticator apps support,
+// HMAC-SHA1, 6 digits and 30 seconds period
+
+const (
+	Digits     = 6
+	Period     = 30
+	secretSize = 20
+	// number of periods before and after current time that are accepted, handles clock drift
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to create totp secret: %w", err)
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// ProvisioningUri returns the otpauth uri that authenticator apps read from qr code
+func ProvisioningUri(issuer string, accountName string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Validate returns the time step that code belongs to, or -1 if code is invalid.
+// the caller should reject steps that are not greater than the last used step to prevent replay
+func Validate(code string, secret string, t time.Time) int64 {
+	if len(code) != Digits {
+		return -1
+	}
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return -1
+	}
+
+	step := t.Unix() / Period
+	for i := -skew; i <= skew; i++ {
+		if hmac.Equal([]byte(generateCode(key, step+int64(i))), []byte(code)) {
+			return step + int64(i)
+		}
+	}
+	return -1
+}
+
+func generateCode(key []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1000000)
+}
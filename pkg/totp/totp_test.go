@@ -0,0 +1,125 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret of the test vectors in RFC 6238 appendix B, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode_RfcVectors(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatalf("could not decode secret: %v", err)
+	}
+
+	// last 6 digits of the 8 digit SHA1 vectors
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := generateCode(key, tt.unix/Period); got != tt.want {
+			t.Errorf("generateCode(T=%v) = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / Period
+
+	if got := Validate("050471", rfcSecret, now); got != step {
+		t.Errorf("current code: step = %v, want %v", got, step)
+	}
+	if got := Validate("050471", strings.ToLower(rfcSecret), now); got != step {
+		t.Errorf("lowercase secret: step = %v, want %v", got, step)
+	}
+
+	key, _ := encoding.DecodeString(rfcSecret)
+	for _, offset := range []int64{-1, 1} {
+		code := generateCode(key, step+offset)
+		if got := Validate(code, rfcSecret, now); got != step+offset {
+			t.Errorf("code of step %+d: step = %v, want %v", offset, got, step+offset)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		code := generateCode(key, step+offset)
+		if got := Validate(code, rfcSecret, now); got != -1 {
+			t.Errorf("code of step %+d must be rejected, got step %v", offset, got)
+		}
+	}
+
+	invalid := []struct {
+		name   string
+		code   string
+		secret string
+	}{
+		{"wrong code", "000000", rfcSecret},
+		{"short code", "05047", rfcSecret},
+		{"long code", "0504711", rfcSecret},
+		{"invalid secret", "050471", "not base32!"},
+	}
+	for _, tt := range invalid {
+		if got := Validate(tt.code, tt.secret, now); got != -1 {
+			t.Errorf("%v: step = %v, want -1", tt.name, got)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	key, err := encoding.DecodeString(first)
+	if err != nil {
+		t.Fatalf("secret is not base32: %v", err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret size = %v, want %v", len(key), secretSize)
+	}
+
+	second, _ := GenerateSecret()
+	if first == second {
+		t.Error("two generated secrets are equal")
+	}
+}
+
+func TestProvisioningUri(t *testing.T) {
+	uri := ProvisioningUri("Downloader App", "user@example.com", rfcSecret)
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid uri %v: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("uri = %v, want otpauth://totp/...", uri)
+	}
+	if parsed.Path != "/Downloader App:user@example.com" {
+		t.Errorf("label = %v", parsed.Path)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "Downloader App",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for k, v := range want {
+		if query.Get(k) != v {
+			t.Errorf("%v = %v, want %v", k, query.Get(k), v)
+		}
+	}
+}
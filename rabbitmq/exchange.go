@@ -22,6 +22,8 @@ const (
 	BlurHashExchangeType     = "direct"
 	EmailExchange            = "EmailExchange"
 	EmailExchangeType        = "direct"
+	WsConnectionExchange     = "WsConnectionExchange"
+	WsConnectionExchangeType = "fanout"
 )
 
 func (r *rabbit) createExchanges() {
@@ -99,6 +101,21 @@ func (r *rabbit) createExchanges() {
 		errorMessage := fmt.Sprintf("error creating exchange %v: %s", EmailExchange, err)
 		errorHandler.SaveError(errorMessage, err)
 	}
+
+	wsConnectionConfig := ConfigExchange{
+		Name:       WsConnectionExchange,
+		Type:       WsConnectionExchangeType,
+		Durable:    true,
+		AutoDelete: false,
+		Internal:   false,
+		NoWait:     false,
+		Args:       nil,
+	}
+	err = r.CreateExchange(wsConnectionConfig)
+	if err != nil {
+		errorMessage := fmt.Sprintf("error creating exchange %v: %s", WsConnectionExchange, err)
+		errorHandler.SaveError(errorMessage, err)
+	}
 }
 
 // CreateExchange creates an exchange
@@ -4,6 +4,7 @@ import (
 	errorHandler "downloader_gochat/pkg/error"
 	"fmt"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -33,6 +34,10 @@ const (
 	EmailBindingKey        = "email"
 )
 
+// WsConnectionQueue is declared by each instance and removed when the instance disconnects,
+// so the messages of fanout WsConnectionExchange reach all the instances
+var WsConnectionQueue = "wsConnection." + uuid.NewString()
+
 func (r *rabbit) createQueuesAndBind() {
 	config := ConfigQueue{
 		Name:       SingleChatQueue,
@@ -204,6 +209,35 @@ func (r *rabbit) createQueuesAndBind() {
 		errorMessage := fmt.Sprintf("error binding queue %s: %s", EmailQueue, err)
 		errorHandler.SaveError(errorMessage, err)
 	}
+
+	//------------------------------------
+	//------------------------------------
+
+	wsConnectionConfig := ConfigQueue{
+		Name:       WsConnectionQueue,
+		Durable:    false,
+		AutoDelete: true,
+		Exclusive:  true,
+		NoWait:     false,
+		Args:       nil,
+	}
+	_, err = r.CreateQueue(wsConnectionConfig)
+	if err != nil {
+		errorMessage := fmt.Sprintf("error creating queue %s: %s", WsConnectionQueue, err)
+		errorHandler.SaveError(errorMessage, err)
+	}
+
+	wsConnectionBindConfig := ConfigBindQueue{
+		QueueName:  WsConnectionQueue,
+		Exchange:   WsConnectionExchange,
+		RoutingKey: "",
+		NoWait:     false,
+	}
+	err = r.BindQueueExchange(wsConnectionBindConfig)
+	if err != nil {
+		errorMessage := fmt.Sprintf("error binding queue %s: %s", WsConnectionQueue, err)
+		errorHandler.SaveError(errorMessage, err)
+	}
 }
 
 // CreateQueue creates a queue
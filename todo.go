@@ -2,11 +2,6 @@ package main
 
 // todo : implement encryption
 
-// todo : add api for forget password
-// todo : implement resetPassword
-
-// todo : handle device fingerprint on auth apis
-
 // todo : track system growth, number of messages per day
 // todo : write benchmarks
 
@@ -1,6 +1,9 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 
 	"golang.org/x/crypto/bcrypt"
@@ -17,3 +20,19 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(password string, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// CreateRandomToken returns a url-safe random token with 256 bits of entropy
+func CreateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to create token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the sha256 of a random token, used to keep single-use tokens hashed in db.
+// tokens have enough entropy, no need for bcrypt
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
@@ -16,6 +16,9 @@ type MyJwtClaims struct {
 	GeneratedAt  int64   `json:"generatedAt"`
 	ExpiresAt    int64   `json:"expiresAt"`
 	IsBotRequest bool    `json:"isBotRequest"`
+	// ApiTokenId and ApiTokenScopes are set when request is authorized by a personal api token instead of jwt
+	ApiTokenId     int64    `json:"apiTokenId,omitempty"`
+	ApiTokenScopes []string `json:"apiTokenScopes,omitempty"`
 	//BotId        string  `json:"botId"`
 	//ChatId       string  `json:"chatId"`
 	//BotUsername  string  `json:"botUsername"`
@@ -31,7 +34,14 @@ type TokenDetail struct {
 func CreateJwtToken(id int64, username string, roleIds []int64) (*TokenDetail, error) {
 	myConfigs := configs.GetConfigs()
 	accessExpire := jwt.NewNumericDate(time.Now().Add(time.Duration(myConfigs.AccessTokenExpireHour) * time.Hour))
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, MyJwtClaims{
+	var accessMethod jwt.SigningMethod = jwt.SigningMethodHS256
+	var accessKey interface{} = []byte(myConfigs.AccessTokenSecret)
+	signingKey := getJwtSigningKey()
+	if signingKey != nil {
+		accessMethod = signingKey.Method
+		accessKey = signingKey.PrivateKey
+	}
+	token := jwt.NewWithClaims(accessMethod, MyJwtClaims{
 		UserId:      id,
 		Username:    username,
 		RoleIds:     roleIds,
@@ -43,6 +53,9 @@ func CreateJwtToken(id int64, username string, roleIds []int64) (*TokenDetail, e
 			ID:        strconv.FormatInt(time.Now().UnixNano(), 10),
 		},
 	})
+	if signingKey != nil {
+		token.Header["kid"] = signingKey.Kid
+	}
 
 	refreshExpire := jwt.NewNumericDate(time.Now().Add(time.Duration(myConfigs.RefreshTokenExpireDay) * 24 * time.Hour))
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, MyJwtClaims{
@@ -58,7 +71,7 @@ func CreateJwtToken(id int64, username string, roleIds []int64) (*TokenDetail, e
 		},
 	})
 
-	accToken, err := token.SignedString([]byte(myConfigs.AccessTokenSecret))
+	accToken, err := token.SignedString(accessKey)
 	if err != nil {
 		return nil, err
 	}
@@ -69,12 +82,26 @@ func CreateJwtToken(id int64, username string, roleIds []int64) (*TokenDetail, e
 	return &TokenDetail{AccessToken: accToken, ExpiresAt: accessExpire.UnixMilli(), RefreshToken: refToken}, nil
 }
 
+// VerifyToken accepts access tokens signed with one of the jwt keys (has kid header) or with AccessTokenSecret
 func VerifyToken(tokenString string) (*jwt.Token, *MyJwtClaims, error) {
 	claims := MyJwtClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if kid, ok := token.Header["kid"].(string); ok {
+			key, ok := getJwtKey(kid)
+			if !ok {
+				return nil, fmt.Errorf("unknown key id")
+			}
+			if token.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("wrong signature method")
+			}
+			return key.PublicKey, nil
+		}
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("wrong signature method")
 		}
+		if !hmacAccessTokenAllowed() {
+			return nil, fmt.Errorf("hmac access tokens are not accepted")
+		}
 		return []byte(configs.GetConfigs().AccessTokenSecret), nil
 	})
 
@@ -0,0 +1,226 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"downloader_gochat/configs"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// asymmetric keys for signing access tokens, other services verify them with the public keys from jwks.
+// refresh tokens are only verified by this service and still use RefreshTokenSecret
+
+const (
+	JwtAlgorithmRS256 = "RS256"
+	JwtAlgorithmEdDSA = "EdDSA"
+	rsaKeySize        = 2048
+)
+
+type JwtKey struct {
+	Kid        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+type Jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+var jwtKeySet = struct {
+	sync.RWMutex
+	signingKey      *JwtKey
+	keys            map[string]*JwtKey
+	firstActivation *time.Time
+}{keys: map[string]*JwtKey{}}
+
+// SetJwtKeys replaces the keys used for access tokens, signingKid can be empty to sign with AccessTokenSecret.
+// firstActivation is the first time any key started signing, nil if no key is activated yet
+func SetJwtKeys(keys []JwtKey, signingKid string, firstActivation *time.Time) {
+	keyMap := make(map[string]*JwtKey, len(keys))
+	var signingKey *JwtKey
+	for i := range keys {
+		keyMap[keys[i].Kid] = &keys[i]
+		if keys[i].Kid == signingKid {
+			signingKey = &keys[i]
+		}
+	}
+
+	jwtKeySet.Lock()
+	defer jwtKeySet.Unlock()
+	jwtKeySet.keys = keyMap
+	jwtKeySet.signingKey = signingKey
+	jwtKeySet.firstActivation = firstActivation
+}
+
+// hmacAccessTokenAllowed reports whether access tokens signed with AccessTokenSecret are still accepted,
+// they are accepted until one access token lifetime after the first key activation
+func hmacAccessTokenAllowed() bool {
+	if configs.GetConfigs().AllowHmacAccessToken {
+		return true
+	}
+	jwtKeySet.RLock()
+	defer jwtKeySet.RUnlock()
+	if jwtKeySet.firstActivation == nil {
+		return true
+	}
+	accessTokenExpire := time.Duration(configs.GetConfigs().AccessTokenExpireHour) * time.Hour
+	return time.Now().Before(jwtKeySet.firstActivation.Add(accessTokenExpire))
+}
+
+func getJwtSigningKey() *JwtKey {
+	jwtKeySet.RLock()
+	defer jwtKeySet.RUnlock()
+	return jwtKeySet.signingKey
+}
+
+func getJwtKey(kid string) (*JwtKey, bool) {
+	jwtKeySet.RLock()
+	defer jwtKeySet.RUnlock()
+	key, ok := jwtKeySet.keys[kid]
+	return key, ok
+}
+
+func GetJwks() *Jwks {
+	jwtKeySet.RLock()
+	defer jwtKeySet.RUnlock()
+
+	result := Jwks{Keys: make([]Jwk, 0, len(jwtKeySet.keys))}
+	for _, key := range jwtKeySet.keys {
+		jwk := Jwk{
+			Kid: key.Kid,
+			Alg: key.Method.Alg(),
+			Use: "sig",
+		}
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		result.Keys = append(result.Keys, jwk)
+	}
+	return &result
+}
+
+//------------------------------------------
+//------------------------------------------
+
+// GenerateJwtKey returns a new kid with the PEM encoded private (PKCS #8) and public (PKIX) keys
+func GenerateJwtKey(algorithm string) (string, string, string, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch algorithm {
+	case JwtAlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case JwtAlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", "", "", fmt.Errorf("unsupported jwt algorithm: %v", algorithm)
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+
+	privateDer, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", "", "", err
+	}
+	publicDer, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return "", "", "", err
+	}
+
+	kidBytes := make([]byte, 8)
+	if _, err = rand.Read(kidBytes); err != nil {
+		return "", "", "", err
+	}
+
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer})
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer})
+	return hex.EncodeToString(kidBytes), string(privatePem), string(publicPem), nil
+}
+
+// ParseJwtKey parses the keys created by GenerateJwtKey, privatePem can be empty for verify only keys
+func ParseJwtKey(kid string, algorithm string, privatePem string, publicPem string) (*JwtKey, error) {
+	key := JwtKey{Kid: kid}
+	switch algorithm {
+	case JwtAlgorithmRS256:
+		key.Method = jwt.SigningMethodRS256
+	case JwtAlgorithmEdDSA:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm: %v", algorithm)
+	}
+
+	block, _ := pem.Decode([]byte(publicPem))
+	if block == nil {
+		return nil, errors.New("invalid public key pem")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key.PublicKey = publicKey
+
+	if privatePem != "" {
+		block, _ = pem.Decode([]byte(privatePem))
+		if block == nil {
+			return nil, errors.New("invalid private key pem")
+		}
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("private key cannot sign")
+		}
+		key.PrivateKey = signer
+	}
+
+	// check the key type matches the algorithm
+	switch key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if algorithm != JwtAlgorithmRS256 {
+			return nil, fmt.Errorf("rsa key cannot be used with %v", algorithm)
+		}
+	case ed25519.PublicKey:
+		if algorithm != JwtAlgorithmEdDSA {
+			return nil, fmt.Errorf("ed25519 key cannot be used with %v", algorithm)
+		}
+	default:
+		return nil, errors.New("unsupported public key type")
+	}
+
+	return &key, nil
+}